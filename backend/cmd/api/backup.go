@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/traefikx/backend/internal/backup"
+	"github.com/traefikx/backend/internal/config"
+	"github.com/traefikx/backend/internal/database"
+	"gorm.io/gorm/logger"
+)
+
+// backupOptions are the backup settings from the configuration
+func backupOptions(cfg *config.Config) backup.Options {
+	return backup.Options{
+		Compress:   cfg.BackupCompress,
+		Passphrase: cfg.BackupEncryptionKey,
+	}
+}
+
+// runBackup implements the backup subcommand. It writes to the backup
+// directory by default, "-o -" writes to stdout.
+func runBackup(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	output := flags.String("o", "", "write the backup to this file instead of BACKUP_DIR (- for stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := database.Init(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	// The query log would end up in a backup written to stdout
+	db.Logger = logger.Discard
+
+	opts := backupOptions(cfg)
+	if *output == "" {
+		manager := backup.NewManager(db, cfg.BackupDir, 0, cfg.BackupRetention, opts)
+		file, err := manager.Create()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Backup written to %s/%s (%d bytes)\n", manager.Dir(), file.Name, file.Size)
+		return nil
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	manifest, err := backup.Write(db, w, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Backup of schema version %d written (%s snapshot)\n", manifest.SchemaVersion, manifest.Snapshot)
+	return nil
+}
+
+// runRestore implements the restore subcommand. An empty database is
+// migrated first; stop the server, or use the API, so it doesn't keep
+// serving the replaced data from memory.
+func runRestore(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only validate the backup")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: api restore [-dry-run] <file|->")
+	}
+
+	var r io.Reader = os.Stdin
+	if name := flags.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	opened, err := backup.Open(r, cfg.BackupEncryptionKey)
+	if err != nil {
+		return err
+	}
+	manifest := opened.Manifest
+	fmt.Printf("Backup taken %s from %s, schema version %d\n", manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"), manifest.Driver, manifest.SchemaVersion)
+
+	db, err := database.Init(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	if version, err := database.SchemaVersion(db); err == nil && version == 0 {
+		if _, err := database.MigrateUp(db); err != nil {
+			return err
+		}
+	}
+
+	if *dryRun {
+		if err := opened.Validate(db); err != nil {
+			return err
+		}
+		fmt.Println("Backup is valid")
+		return nil
+	}
+	if err := opened.Restore(db); err != nil {
+		return err
+	}
+	fmt.Println("Backup restored")
+	return nil
+}
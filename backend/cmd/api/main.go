@@ -1,56 +1,227 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/traefikx/backend/internal/auth"
+	"github.com/traefikx/backend/internal/backup"
 	"github.com/traefikx/backend/internal/config"
 	"github.com/traefikx/backend/internal/database"
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/ha"
+	"github.com/traefikx/backend/internal/logging"
+	"github.com/traefikx/backend/internal/mailer"
+	"github.com/traefikx/backend/internal/metrics"
+	"github.com/traefikx/backend/internal/notify"
+	"github.com/traefikx/backend/internal/publisher"
 	"github.com/traefikx/backend/internal/routes"
+	"github.com/traefikx/backend/internal/secrets"
 	"github.com/traefikx/backend/internal/services"
+	"github.com/traefikx/backend/internal/tracing"
+	"github.com/traefikx/backend/internal/version"
+	"gorm.io/gorm"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// Structured logs, written through by the log package as well
+	if err := logging.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.SecretsKeyFromJWT {
+		slog.Warn("SECRETS_KEY is not set, secrets are encrypted with a key derived from JWT_SECRET; " +
+			"changing JWT_SECRET makes them unreadable unless it is listed in SECRETS_PREVIOUS_KEYS")
+	}
+
+	// Schema migrations, backups and key rotation can be run separately from the server
+	if len(os.Args) > 1 {
+		var run func(*config.Config, []string) error
+		switch os.Args[1] {
+		case "migrate":
+			run = runMigrate
+		case "backup":
+			run = runBackup
+		case "restore":
+			run = runRestore
+		case "secrets":
+			run = runSecrets
+		}
+		if run != nil {
+			if err := run(cfg, os.Args[2:]); err != nil {
+				fatal("Command failed", err)
+			}
+			return
+		}
+	}
+
+	// Spans exported over OTLP or to stdout, if enabled
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:   cfg.TracesExporter,
+		Protocol:   cfg.TracesProtocol,
+		InstanceID: cfg.HAInstanceID,
+	})
+	if err != nil {
+		fatal("Failed to initialize tracing", err)
+	}
+
 	// Set Gin mode
 	if cfg.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Key for secrets stored encrypted in the database
+	if err := secrets.Init(cfg.SecretsKey, cfg.SecretsPreviousKeys...); err != nil {
+		fatal("Failed to initialize secrets encryption", err)
+	}
+
 	// Initialize database
 	db, err := database.Init(cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		fatal("Failed to initialize database", err)
 	}
 
-	// Run migrations
-	if err := database.Migrate(); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	// Run migrations, or check they were run
+	if cfg.DatabaseAutoMigrate {
+		if err := database.Migrate(); err != nil {
+			fatal("Failed to run migrations", err)
+		}
+	} else if err := database.CheckSchema(db); err != nil {
+		fatal("Database schema out of date", err)
 	}
 
 	// Create default admin user if no users exist
 	if err := database.CreateDefaultAdmin(cfg); err != nil {
-		log.Fatalf("Failed to create default admin: %v", err)
+		fatal("Failed to create default admin", err)
+	}
+
+	// Replicas share OIDC login states through the database
+	if cfg.HAEnabled {
+		auth.SetOIDCStateStore(auth.NewDBOIDCStateStore(db))
 	}
 
 	// Initialize OIDC if enabled
 	if cfg.OIDCEnabled {
 		if err := auth.InitOIDC(cfg); err != nil {
-			log.Printf("Warning: Failed to initialize OIDC: %v", err)
+			slog.Warn("Failed to initialize OIDC", "error", err)
 		} else {
-			log.Println("OIDC initialized successfully")
+			slog.Info("OIDC initialized")
 		}
 	}
 
-	// Initialize and start the Traefik endpoint aggregator service
-	aggregatorService := services.NewAggregatorService(db)
-	go aggregatorService.Start()
+	// Initialize mailer for invitations and password resets
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		fatal("Failed to initialize mailer", err)
+	}
+
+	// Internal event bus for configuration changes
+	bus := events.NewBus()
+
+	// Initialize the Traefik endpoint aggregator service
+	aggregatorService := services.NewAggregatorService(db, bus, cfg.AggregatorMaxConcurrentFetches, cfg.ProviderHistoryLimit, cfg.ProviderHistoryMaxAge)
+	aggregatorService.SetMaxResponseSize(int64(cfg.ProviderMaxResponseSize))
+
+	compiler := services.NewConfigCompiler(db, aggregatorService, bus)
+
+	// Push compiled config to the file provider directory and KV stores
+	publishers, err := publisher.New(cfg)
+	if err != nil {
+		fatal("Failed to initialize publishers", err)
+	}
+	publisherManager := publisher.NewManager(compiler, bus, publishers, cfg.PublishTimeout, cfg.PublishRetryInterval)
+
+	// Scheduled backups, taken by the leader in HA mode
+	backups := backup.NewManager(db, cfg.BackupDir, cfg.BackupInterval, cfg.BackupRetention, backupOptions(cfg))
+
+	// Health probes of the servers of services with a health check path
+	var prober *services.UpstreamProber
+	if cfg.ProbeEnabled {
+		prober = services.NewUpstreamProber(db, bus, cfg.ProbeTimeout)
+	}
+
+	// Notification channels, queued for by every replica and sent by the leader in HA mode
+	dispatcher := notify.NewDispatcher(db, bus, mail, notify.Options{
+		Timeout:      cfg.NotifyTimeout,
+		MaxAttempts:  cfg.NotifyMaxAttempts,
+		RetryBackoff: cfg.NotifyRetryBackoff,
+		MaxAge:       cfg.NotifyDeliveryMaxAge,
+	})
+	dispatcher.Listen()
+	if cfg.ConflictWebhookURL != "" {
+		if err := notify.EnsureConflictWebhook(db, cfg.ConflictWebhookURL); err != nil {
+			slog.Error("Failed to add the conflict webhook channel", "error", err)
+		} else {
+			slog.Warn("CONFLICT_WEBHOOK_URL is deprecated, it is kept as a notification channel", "channel", notify.ConflictWebhookChannel)
+		}
+	}
+
+	// Provider, config and server state, read when /metrics is scraped
+	if cfg.MetricsEnabled {
+		metrics.Registry.MustRegister(services.NewStateCollector(db, aggregatorService, compiler))
+	}
+
+	// With HA only the leader polls providers and publishes; every replica
+	// compiles the config from the shared database to serve it
+	var elector *ha.Elector
+	var relay *ha.Relay
+	if cfg.HAEnabled {
+		elector = ha.NewElector(db, cfg.HAInstanceID, cfg.HALeaseTTL,
+			func() {
+				reencryptSecrets(db)
+				aggregatorService.Start()
+				publisherManager.Start()
+				backups.Start()
+				prober.Start()
+				dispatcher.Start()
+				// Record the conflicts followers left alone
+				if _, err := compiler.Rebuild(context.Background()); err != nil {
+					slog.Error("Failed to rebuild config", "error", err)
+				}
+			},
+			func() {
+				aggregatorService.Stop()
+				publisherManager.Stop()
+				backups.Stop()
+				prober.Stop()
+				dispatcher.Stop()
+			},
+		)
+		compiler.SetLeaderCheck(elector.IsLeader)
+		relay = ha.NewRelay(db, bus, cfg.HAInstanceID, cfg.HASyncInterval)
+	}
+
+	// Start the config compiler before the aggregator so no provider update is missed
+	compiler.Start()
+	if cfg.HAEnabled {
+		relay.Start()
+		elector.Start()
+		slog.Info("High availability enabled", "instance", cfg.HAInstanceID)
+	} else {
+		reencryptSecrets(db)
+		aggregatorService.Start()
+		publisherManager.Start()
+		backups.Start()
+		prober.Start()
+		dispatcher.Start()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Setup router
-	r := routes.SetupRouter(cfg, db, aggregatorService)
+	r := routes.SetupRouter(ctx, cfg, db, aggregatorService, compiler, publisherManager, backups, bus, mail, elector, dispatcher)
 
 	// Start server
 	port := cfg.Port
@@ -58,8 +229,64 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+
+	go func() {
+		slog.Info("Server starting", "port", port, "version", version.Version)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("Failed to start server", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop() // A second signal kills the process
+	slog.Info("Shutting down")
+
+	// Let in-flight requests finish, then stop the background workers
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Server shutdown", "error", err)
+	}
+
+	if cfg.HAEnabled {
+		// Hand over the lease before anything else stops
+		elector.Stop()
+		relay.Stop()
+	}
+	backups.Stop()
+	prober.Stop()
+	aggregatorService.Stop()
+	compiler.Stop()
+	publisherManager.Close()
+	dispatcher.Close()
+
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	// Export the last spans
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelTracing()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+	slog.Info("Server stopped")
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// reencryptSecrets moves secrets under the current master key after a
+// rotation, and encrypts those stored before their column was. Only the
+// leader runs it, replicas would otherwise rewrite the same rows.
+func reencryptSecrets(db *gorm.DB) {
+	if count, err := database.ReencryptSecrets(db); err != nil {
+		slog.Warn("Failed to re-encrypt secrets", "error", err)
+	} else if count > 0 {
+		slog.Info("Re-encrypted secrets", "count", count, "key_id", secrets.KeyID())
 	}
 }
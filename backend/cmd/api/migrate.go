@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/traefikx/backend/internal/config"
+	"github.com/traefikx/backend/internal/database"
+)
+
+const migrateUsage = `usage: api migrate <command>
+
+commands:
+  up         apply all pending migrations
+  down [n]   revert the last n migrations (default 1)
+  status     list migrations and whether they are applied
+  version    print the current schema version`
+
+// runMigrate implements the migrate subcommand
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+
+	db, err := database.Init(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(db)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migrations, schema version %d\n", applied, database.LatestVersion())
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations: %s", args[1])
+			}
+		}
+		reverted, err := database.MigrateDown(db, steps)
+		if err != nil {
+			return err
+		}
+		version, err := database.SchemaVersion(db)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Reverted %d migrations, schema version %d\n", reverted, version)
+
+	case "status":
+		states, err := database.MigrationStatus(db)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, state := range states {
+			applied := "pending"
+			if state.AppliedAt != nil {
+				applied = state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", state.Version, state.Name, applied)
+		}
+		w.Flush()
+
+	case "version":
+		version, err := database.SchemaVersion(db)
+		if err != nil {
+			return err
+		}
+		fmt.Println(version)
+
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], migrateUsage)
+	}
+	return nil
+}
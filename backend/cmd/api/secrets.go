@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/traefikx/backend/internal/config"
+	"github.com/traefikx/backend/internal/database"
+	"github.com/traefikx/backend/internal/secrets"
+)
+
+const secretsUsage = `usage: api secrets <command>
+
+commands:
+  status   count the stored secrets per master key
+  rotate   re-encrypt all secrets with the current master key`
+
+// runSecrets implements the secrets subcommand
+func runSecrets(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", secretsUsage)
+	}
+
+	if err := secrets.Init(cfg.SecretsKey, cfg.SecretsPreviousKeys...); err != nil {
+		return err
+	}
+	db, err := database.Init(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	switch args[0] {
+	case "status":
+		counts, err := database.SecretStatus(db)
+		if err != nil {
+			return err
+		}
+		ids := make([]string, 0, len(counts))
+		for id := range counts {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tSECRETS\t")
+		for _, id := range ids {
+			note := ""
+			if id == secrets.KeyID() {
+				note = "current"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\n", id, counts[id], note)
+		}
+		w.Flush()
+
+	case "rotate":
+		count, err := database.ReencryptSecrets(db)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Re-encrypted %d secrets with key %s\n", count, secrets.KeyID())
+
+	default:
+		return fmt.Errorf("unknown secrets command %q\n%s", args[0], secretsUsage)
+	}
+	return nil
+}
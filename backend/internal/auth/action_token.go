@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/traefikx/backend/internal/config"
+)
+
+// Purposes for single-use action tokens
+const (
+	TokenPurposeInvite        = "invite"
+	TokenPurposePasswordReset = "password_reset"
+)
+
+// actionTokenKey derives the key signing action tokens from the JWT secret.
+// A key of their own keeps them from being accepted as access tokens.
+func actionTokenKey() []byte {
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.JWTSecret))
+	mac.Write([]byte("traefikx action token"))
+	return mac.Sum(nil)
+}
+
+// ActionClaims are the claims of a signed invitation or password reset token
+type ActionClaims struct {
+	UserID  uint   `json:"user_id"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// GenerateActionToken signs a token for userID and purpose. The returned ID
+// (jti) is stored by the caller to make the token single-use.
+func GenerateActionToken(userID uint, purpose string, ttl time.Duration) (string, string, time.Time, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", time.Time{}, err
+	}
+	tokenID := hex.EncodeToString(b)
+	expiresAt := time.Now().Add(ttl)
+
+	claims := ActionClaims{
+		UserID:  userID,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Subject:   fmt.Sprintf("%d", userID),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString(actionTokenKey())
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	return tokenString, tokenID, expiresAt, nil
+}
+
+// ParseActionToken verifies the signature, expiry and purpose of a token
+func ParseActionToken(tokenString, purpose string) (*ActionClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &ActionClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return actionTokenKey(), nil
+	})
+
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errors.New("link has expired")
+		}
+		return nil, errors.New("invalid link")
+	}
+
+	claims, ok := token.Claims.(*ActionClaims)
+	if !ok || !token.Valid || claims.Purpose != purpose || claims.ID == "" {
+		return nil, errors.New("invalid link")
+	}
+
+	return claims, nil
+}
@@ -57,10 +57,17 @@ func GenerateTokenPair(userID uint, email, role string) (*TokenPair, error) {
 	}, nil
 }
 
+// ParseToken verifies an access token. Refresh and action tokens, which
+// carry no email and role or a purpose, are rejected.
 func ParseToken(tokenString string) (*Claims, error) {
 	cfg := config.AppConfig
 
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+	// Purpose is only read to turn action tokens away
+	var parsed struct {
+		Claims
+		Purpose string `json:"purpose"`
+	}
+	token, err := jwt.ParseWithClaims(tokenString, &parsed, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
@@ -74,11 +81,11 @@ func ParseToken(tokenString string) (*Claims, error) {
 		return nil, errors.New("invalid token")
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	if !token.Valid || parsed.Purpose != "" || parsed.Email == "" || parsed.Role == "" {
+		return nil, errors.New("invalid token claims")
 	}
 
-	return nil, errors.New("invalid token claims")
+	return &parsed.Claims, nil
 }
 
 func RefreshAccessToken(refreshToken string) (string, error) {
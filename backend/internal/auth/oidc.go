@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -17,13 +18,14 @@ import (
 
 var (
 	oauthConfig    *oauth2.Config
-	oidcStateStore = make(map[string]*OIDCState)
+	oidcStateStore OIDCStateStore = NewMemoryOIDCStateStore()
 )
 
 type OIDCState struct {
-	State      string
-	ExpiresAt  time.Time
-	LinkToUser uint // If > 0, link to existing user instead of creating new
+	State         string
+	ExpiresAt     time.Time
+	LinkToUser    uint // If > 0, link to existing user instead of creating new
+	InviteTokenID uint // If > 0, the link completes this invitation
 }
 
 // InitOIDC initializes the OIDC configuration
@@ -58,41 +60,56 @@ func InitOIDC(cfg *config.Config) error {
 		},
 	}
 
-	// Clean up expired states periodically
-	go cleanupExpiredStates()
-
 	return nil
 }
 
+// OIDCInitialized reports whether InitOIDC configured the OIDC client
+func OIDCInitialized() bool {
+	return oauthConfig != nil
+}
+
+// SetOIDCStateStore replaces the in-memory store, e.g. with one shared by
+// all replicas. Call it before serving requests.
+func SetOIDCStateStore(store OIDCStateStore) {
+	oidcStateStore = store
+}
+
 // GenerateOIDCState generates a random state for OIDC flow
 func GenerateOIDCState(linkToUser uint) string {
+	return generateOIDCState(linkToUser, 0)
+}
+
+// GenerateOIDCInviteState generates a state for accepting an invitation via OIDC
+func GenerateOIDCInviteState(userID, inviteTokenID uint) string {
+	return generateOIDCState(userID, inviteTokenID)
+}
+
+func generateOIDCState(linkToUser, inviteTokenID uint) string {
 	b := make([]byte, 32)
 	rand.Read(b)
 	state := base64.URLEncoding.EncodeToString(b)
 
-	oidcStateStore[state] = &OIDCState{
-		State:      state,
-		ExpiresAt:  time.Now().Add(10 * time.Minute),
-		LinkToUser: linkToUser,
+	err := oidcStateStore.Save(&OIDCState{
+		State:         state,
+		ExpiresAt:     time.Now().Add(10 * time.Minute),
+		LinkToUser:    linkToUser,
+		InviteTokenID: inviteTokenID,
+	})
+	if err != nil {
+		// The callback will reject the unknown state
+		slog.Error("Failed to save OIDC state", "error", err)
 	}
 
 	return state
 }
 
-// ValidateOIDCState validates and returns the state
+// ValidateOIDCState validates and returns the state. A state can only be
+// used once.
 func ValidateOIDCState(state string) (*OIDCState, bool) {
-	oidcState, exists := oidcStateStore[state]
-	if !exists {
-		return nil, false
-	}
-
-	if time.Now().After(oidcState.ExpiresAt) {
-		delete(oidcStateStore, state)
+	oidcState, exists := oidcStateStore.Take(state)
+	if !exists || time.Now().After(oidcState.ExpiresAt) {
 		return nil, false
 	}
-
-	// Clean up after use
-	delete(oidcStateStore, state)
 	return oidcState, true
 }
 
@@ -166,18 +183,6 @@ type OIDCUserInfo struct {
 	Name    string `json:"name"`
 }
 
-func cleanupExpiredStates() {
-	ticker := time.NewTicker(5 * time.Minute)
-	for range ticker.C {
-		now := time.Now()
-		for state, oidcState := range oidcStateStore {
-			if now.After(oidcState.ExpiresAt) {
-				delete(oidcStateStore, state)
-			}
-		}
-	}
-}
-
 // IsOIDCEnabled returns whether OIDC is configured and enabled
 func IsOIDCEnabled() bool {
 	return oauthConfig != nil
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/traefikx/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// OIDCStateStore keeps the states of OIDC logins until their callback.
+// The in-memory implementation is per-process; replicas behind a load
+// balancer share the database store.
+type OIDCStateStore interface {
+	Save(state *OIDCState) error
+	// Take returns and removes a state, so it can only be used once
+	Take(state string) (*OIDCState, bool)
+}
+
+// MemoryOIDCStateStore is an in-memory OIDCStateStore
+type MemoryOIDCStateStore struct {
+	mu     sync.Mutex
+	states map[string]*OIDCState
+}
+
+// NewMemoryOIDCStateStore creates an in-memory store and starts a cleanup
+// loop that drops expired states
+func NewMemoryOIDCStateStore() *MemoryOIDCStateStore {
+	s := &MemoryOIDCStateStore{states: make(map[string]*OIDCState)}
+	go s.cleanup(5 * time.Minute)
+	return s
+}
+
+func (s *MemoryOIDCStateStore) Save(state *OIDCState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[state.State] = state
+	return nil
+}
+
+func (s *MemoryOIDCStateStore) Take(state string) (*OIDCState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	oidcState, exists := s.states[state]
+	delete(s.states, state)
+	return oidcState, exists
+}
+
+func (s *MemoryOIDCStateStore) cleanup(every time.Duration) {
+	ticker := time.NewTicker(every)
+	for range ticker.C {
+		now := time.Now()
+		s.mu.Lock()
+		for state, oidcState := range s.states {
+			if now.After(oidcState.ExpiresAt) {
+				delete(s.states, state)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// DBOIDCStateStore keeps OIDC states in the database, so the callback can
+// reach any replica
+type DBOIDCStateStore struct {
+	db *gorm.DB
+}
+
+// NewDBOIDCStateStore creates a database store and starts a cleanup loop
+// that deletes expired states
+func NewDBOIDCStateStore(db *gorm.DB) *DBOIDCStateStore {
+	s := &DBOIDCStateStore{db: db}
+	go s.cleanup(5 * time.Minute)
+	return s
+}
+
+func (s *DBOIDCStateStore) Save(state *OIDCState) error {
+	return s.db.Create(&models.OIDCLoginState{
+		State:         state.State,
+		LinkToUser:    state.LinkToUser,
+		InviteTokenID: state.InviteTokenID,
+		ExpiresAt:     state.ExpiresAt,
+	}).Error
+}
+
+func (s *DBOIDCStateStore) Take(state string) (*OIDCState, bool) {
+	var record models.OIDCLoginState
+	if err := s.db.First(&record, "state = ?", state).Error; err != nil {
+		return nil, false
+	}
+	// Only the request that deletes the row may use it
+	result := s.db.Delete(&models.OIDCLoginState{}, "state = ?", state)
+	if result.Error != nil || result.RowsAffected != 1 {
+		return nil, false
+	}
+	return &OIDCState{
+		State:         record.State,
+		ExpiresAt:     record.ExpiresAt,
+		LinkToUser:    record.LinkToUser,
+		InviteTokenID: record.InviteTokenID,
+	}, true
+}
+
+func (s *DBOIDCStateStore) cleanup(every time.Duration) {
+	ticker := time.NewTicker(every)
+	for range ticker.C {
+		s.db.Where("expires_at < ?", time.Now()).Delete(&models.OIDCLoginState{})
+	}
+}
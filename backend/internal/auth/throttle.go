@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/traefikx/backend/internal/config"
+)
+
+// RateLimitStore keeps token buckets and consecutive failure counters by key.
+// The in-memory implementation is per-process; a shared store (e.g. Redis)
+// can be plugged in to throttle across replicas.
+type RateLimitStore interface {
+	// Take consumes a token from the bucket for key. When the bucket is empty
+	// it returns false and how long until the next token is available.
+	Take(key string, capacity int, refillEvery time.Duration) (bool, time.Duration)
+	// RecordFailure increments the failure counter for key and returns the new count
+	RecordFailure(key string) int
+	// Failures returns the failure count for key and when the last one happened
+	Failures(key string) (int, time.Time)
+	// Reset clears the failure counter for key
+	Reset(key string)
+}
+
+type bucket struct {
+	tokens   float64
+	lastFill time.Time
+}
+
+type failureRecord struct {
+	count int
+	last  time.Time
+}
+
+// MemoryRateLimitStore is an in-memory RateLimitStore
+type MemoryRateLimitStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	failures map[string]*failureRecord
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// NewMemoryRateLimitStore creates an in-memory store and starts a cleanup loop
+// that drops idle entries until Close
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	s := &MemoryRateLimitStore{
+		buckets:  make(map[string]*bucket),
+		failures: make(map[string]*failureRecord),
+		stop:     make(chan struct{}),
+	}
+	go s.cleanup(time.Hour)
+	return s
+}
+
+// Close stops the cleanup loop
+func (s *MemoryRateLimitStore) Close() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+func (s *MemoryRateLimitStore) Take(key string, capacity int, refillEvery time.Duration) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(capacity), lastFill: now}
+		s.buckets[key] = b
+	}
+
+	// Refill based on elapsed time
+	if refillEvery > 0 {
+		b.tokens += float64(now.Sub(b.lastFill)) / float64(refillEvery)
+		if b.tokens > float64(capacity) {
+			b.tokens = float64(capacity)
+		}
+	}
+	b.lastFill = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) * float64(refillEvery))
+}
+
+func (s *MemoryRateLimitStore) RecordFailure(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, exists := s.failures[key]
+	if !exists {
+		f = &failureRecord{}
+		s.failures[key] = f
+	}
+	f.count++
+	f.last = time.Now()
+	return f.count
+}
+
+func (s *MemoryRateLimitStore) Failures(key string) (int, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, exists := s.failures[key]; exists {
+		return f.count, f.last
+	}
+	return 0, time.Time{}
+}
+
+func (s *MemoryRateLimitStore) Reset(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, key)
+}
+
+// cleanup removes buckets and failure records that have been idle for maxIdle
+func (s *MemoryRateLimitStore) cleanup(maxIdle time.Duration) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.stop:
+			return
+		}
+		s.removeIdle(maxIdle)
+	}
+}
+
+// removeIdle drops the buckets and failure records untouched for maxIdle
+func (s *MemoryRateLimitStore) removeIdle(maxIdle time.Duration) {
+	cutoff := time.Now().Add(-maxIdle)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, b := range s.buckets {
+		if b.lastFill.Before(cutoff) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, f := range s.failures {
+		if f.last.Before(cutoff) {
+			delete(s.failures, key)
+		}
+	}
+}
+
+// LoginThrottle applies per-IP and per-account rate limiting with
+// exponential backoff after failed authentication attempts
+type LoginThrottle struct {
+	store       RateLimitStore
+	burst       int
+	refillEvery time.Duration
+	backoffBase time.Duration
+	backoffMax  time.Duration
+}
+
+// NewLoginThrottle creates a login throttle backed by the given store
+func NewLoginThrottle(cfg *config.Config, store RateLimitStore) *LoginThrottle {
+	return &LoginThrottle{
+		store:       store,
+		burst:       cfg.LoginRateLimitBurst,
+		refillEvery: cfg.LoginRateLimitRefill,
+		backoffBase: cfg.LoginBackoffBase,
+		backoffMax:  cfg.LoginBackoffMax,
+	}
+}
+
+// Prefix of the keys throttling password reset requests
+const resetKeyPrefix = "reset:"
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func accountKey(account string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(account))
+}
+
+// Allow checks whether an attempt from ip for account may proceed.
+// account may be empty (e.g. refresh token requests). When the attempt is
+// rejected, the returned duration tells the client when to retry.
+func (t *LoginThrottle) Allow(ip, account string) (bool, time.Duration) {
+	keys := []string{ipKey(ip)}
+	if account != "" {
+		keys = append(keys, accountKey(account))
+	}
+
+	// Exponential backoff after consecutive failures
+	for _, key := range keys {
+		if wait := t.backoffRemaining(key); wait > 0 {
+			return false, wait
+		}
+	}
+
+	// Token buckets
+	for _, key := range keys {
+		if ok, wait := t.store.Take(key, t.burst, t.refillEvery); !ok {
+			return false, wait
+		}
+	}
+
+	return true, 0
+}
+
+// AllowPasswordReset checks whether a password reset email may be requested
+// from ip for account. Reset requests have buckets of their own, so they
+// can't use up the account's login attempts.
+func (t *LoginThrottle) AllowPasswordReset(ip, account string) (bool, time.Duration) {
+	for _, key := range []string{resetKeyPrefix + ipKey(ip), resetKeyPrefix + accountKey(account)} {
+		if ok, wait := t.store.Take(key, t.burst, t.refillEvery); !ok {
+			return false, wait
+		}
+	}
+	return true, 0
+}
+
+// Fail records a failed attempt for ip and account
+func (t *LoginThrottle) Fail(ip, account string) {
+	t.store.RecordFailure(ipKey(ip))
+	if account != "" {
+		t.store.RecordFailure(accountKey(account))
+	}
+}
+
+// Succeed clears the failure counter for account. The IP's counter is left
+// to expire, or one valid account would reset the backoff of an IP trying
+// passwords against others.
+func (t *LoginThrottle) Succeed(account string) {
+	t.store.Reset(accountKey(account))
+}
+
+// ResetAccount clears the failure counter for account, e.g. after an admin unlock
+func (t *LoginThrottle) ResetAccount(account string) {
+	t.store.Reset(accountKey(account))
+}
+
+// backoffRemaining returns how long the caller still has to wait for key
+func (t *LoginThrottle) backoffRemaining(key string) time.Duration {
+	count, last := t.store.Failures(key)
+	if count == 0 || t.backoffBase <= 0 {
+		return 0
+	}
+
+	delay := time.Duration(float64(t.backoffBase) * math.Pow(2, float64(count-1)))
+	if delay > t.backoffMax || delay <= 0 {
+		delay = t.backoffMax
+	}
+
+	return time.Until(last.Add(delay))
+}
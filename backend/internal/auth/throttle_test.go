@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/traefikx/backend/internal/config"
+	"go.uber.org/goleak"
+)
+
+// newTestThrottle returns a throttle backed by a store closed with the test
+func newTestThrottle(t *testing.T, burst int, backoffBase, backoffMax time.Duration) (*LoginThrottle, *MemoryRateLimitStore) {
+	t.Helper()
+	store := NewMemoryRateLimitStore()
+	t.Cleanup(store.Close)
+	return NewLoginThrottle(&config.Config{
+		LoginRateLimitBurst:  burst,
+		LoginRateLimitRefill: time.Minute,
+		LoginBackoffBase:     backoffBase,
+		LoginBackoffMax:      backoffMax,
+	}, store), store
+}
+
+func TestMemoryRateLimitStore_Refill(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	defer store.Close()
+	refill := 50 * time.Millisecond
+
+	for i := range 2 {
+		if ok, _ := store.Take("key", 2, refill); !ok {
+			t.Fatalf("expected token %d of the burst", i+1)
+		}
+	}
+	ok, wait := store.Take("key", 2, refill)
+	if ok || wait <= 0 || wait > refill {
+		t.Fatalf("expected an empty bucket with a wait up to %v, got %v, %v", refill, ok, wait)
+	}
+
+	// Refilled, but never beyond the capacity
+	time.Sleep(4 * refill)
+	for i := range 2 {
+		if ok, _ := store.Take("key", 2, refill); !ok {
+			t.Fatalf("expected refilled token %d", i+1)
+		}
+	}
+	if ok, _ := store.Take("key", 2, refill); ok {
+		t.Error("expected the refill to stop at the capacity")
+	}
+
+	// Buckets are independent
+	if ok, _ := store.Take("other", 2, refill); !ok {
+		t.Error("expected another key to have its own bucket")
+	}
+}
+
+func TestLoginThrottle_Backoff(t *testing.T) {
+	throttle, _ := newTestThrottle(t, 100, time.Minute, 5*time.Minute)
+
+	// Doubles with every failure, up to the maximum
+	for _, step := range []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 5 * time.Minute},
+		{30, 5 * time.Minute},
+	} {
+		for {
+			if count, _ := throttle.store.Failures(accountKey("user@example.com")); count >= step.failures {
+				break
+			}
+			throttle.Fail("192.0.2.1", "user@example.com")
+		}
+		wait := throttle.backoffRemaining(accountKey("user@example.com"))
+		if wait > step.want || wait < step.want-time.Second {
+			t.Errorf("after %d failures: backoff %v, want %v", step.failures, wait, step.want)
+		}
+	}
+
+	ok, wait := throttle.Allow("192.0.2.2", " User@Example.com ")
+	if ok || wait < 4*time.Minute {
+		t.Errorf("expected the account to be in backoff from another IP, got %v, %v", ok, wait)
+	}
+	if ok, _ := throttle.Allow("192.0.2.2", "other@example.com"); !ok {
+		t.Error("expected other accounts from another IP to be allowed")
+	}
+
+	throttle.ResetAccount("user@example.com")
+	if ok, _ := throttle.Allow("192.0.2.2", "user@example.com"); !ok {
+		t.Error("expected the account to be allowed after a reset")
+	}
+}
+
+func TestLoginThrottle_SucceedKeepsIPBackoff(t *testing.T) {
+	throttle, store := newTestThrottle(t, 100, time.Minute, time.Hour)
+
+	throttle.Fail("192.0.2.1", "victim@example.com")
+	throttle.Fail("192.0.2.1", "victim@example.com")
+	throttle.Succeed("attacker@example.com")
+	throttle.Succeed("victim@example.com")
+
+	if count, _ := store.Failures(accountKey("victim@example.com")); count != 0 {
+		t.Errorf("expected the account's failures to be reset, got %d", count)
+	}
+	if count, _ := store.Failures(ipKey("192.0.2.1")); count != 2 {
+		t.Errorf("expected the IP's failures to be kept, got %d", count)
+	}
+	if ok, wait := throttle.Allow("192.0.2.1", "third@example.com"); ok || wait <= 0 {
+		t.Error("expected the IP to stay in backoff after a successful login")
+	}
+}
+
+func TestLoginThrottle_Burst(t *testing.T) {
+	throttle, _ := newTestThrottle(t, 2, 0, 0)
+
+	for range 2 {
+		if ok, _ := throttle.Allow("192.0.2.1", ""); !ok {
+			t.Fatal("expected the burst to be allowed")
+		}
+	}
+	if ok, wait := throttle.Allow("192.0.2.1", ""); ok || wait <= 0 {
+		t.Errorf("expected the IP to be limited, got %v, %v", ok, wait)
+	}
+	if ok, _ := throttle.Allow("192.0.2.2", ""); !ok {
+		t.Error("expected another IP to be allowed")
+	}
+}
+
+func TestMemoryRateLimitStore_RemoveIdle(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	store := NewMemoryRateLimitStore()
+	store.Take("idle", 1, time.Minute)
+	store.RecordFailure("idle")
+	store.Take("active", 1, time.Minute)
+	store.RecordFailure("active")
+
+	store.mu.Lock()
+	store.buckets["idle"].lastFill = time.Now().Add(-2 * time.Hour)
+	store.failures["idle"].last = time.Now().Add(-2 * time.Hour)
+	store.mu.Unlock()
+
+	store.removeIdle(time.Hour)
+	if _, ok := store.buckets["idle"]; ok {
+		t.Error("expected the idle bucket to be removed")
+	}
+	if count, _ := store.Failures("idle"); count != 0 {
+		t.Error("expected the idle failures to be removed")
+	}
+	if _, ok := store.buckets["active"]; !ok {
+		t.Error("expected the active bucket to be kept")
+	}
+	if count, _ := store.Failures("active"); count != 1 {
+		t.Error("expected the active failures to be kept")
+	}
+
+	// Close ends the cleanup loop and may be called again
+	store.Close()
+	store.Close()
+}
+
+func TestLoginThrottle_PasswordResetBuckets(t *testing.T) {
+	throttle, _ := newTestThrottle(t, 2, time.Minute, time.Hour)
+
+	for range 2 {
+		if ok, _ := throttle.AllowPasswordReset("192.0.2.1", "user@example.com"); !ok {
+			t.Fatal("expected the burst of reset requests to be allowed")
+		}
+	}
+	if ok, _ := throttle.AllowPasswordReset("192.0.2.2", "User@example.com"); ok {
+		t.Error("expected the account's reset requests to be limited")
+	}
+
+	// Logins are unaffected, and the other way around
+	if ok, _ := throttle.Allow("192.0.2.1", "user@example.com"); !ok {
+		t.Error("expected reset requests to leave the login attempts alone")
+	}
+	throttle.Fail("192.0.2.3", "other@example.com")
+	if ok, _ := throttle.AllowPasswordReset("192.0.2.3", "other@example.com"); !ok {
+		t.Error("expected a failed login to leave reset requests alone")
+	}
+}
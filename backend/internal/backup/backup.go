@@ -0,0 +1,303 @@
+// Package backup takes consistent snapshots of the TraefikX database and
+// restores them. SQLite databases are copied with VACUUM INTO while the
+// server runs, PostgreSQL and MySQL are exported table by table from a
+// single read transaction.
+//
+// A backup is a tar archive holding a manifest and the snapshot, optionally
+// gzipped and encrypted with a passphrase.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/traefikx/backend/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// formatVersion is the version of the archive layout
+const formatVersion = 1
+
+// Snapshot kinds
+const (
+	SnapshotSQLite  = "sqlite"  // A copy of the SQLite database file
+	SnapshotLogical = "logical" // The rows of every table as JSON
+)
+
+// Archive entries
+const (
+	manifestEntry = "manifest.json"
+	sqliteEntry   = "traefikx.db"
+	logicalEntry  = "data.json"
+)
+
+// Manifest describes the snapshot in a backup
+type Manifest struct {
+	Format        int            `json:"format"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Driver        string         `json:"driver"`
+	SchemaVersion int            `json:"schema_version"`
+	Snapshot      string         `json:"snapshot"`
+	SHA256        string         `json:"sha256"` // Of the snapshot entry
+	Tables        map[string]int `json:"tables"` // Rows per table
+}
+
+// Options control how a backup is written
+type Options struct {
+	Compress   bool
+	Passphrase string // Encrypts the backup when set
+}
+
+// Write takes a snapshot of db and writes the backup to w
+func Write(db *gorm.DB, w io.Writer, opts Options) (*Manifest, error) {
+	version, err := database.SchemaVersion(db)
+	if err != nil {
+		return nil, fmt.Errorf("read schema version: %w", err)
+	}
+
+	manifest := &Manifest{
+		Format:        formatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Driver:        db.Dialector.Name(),
+		SchemaVersion: version,
+	}
+
+	var entry string
+	var data []byte
+	if manifest.Driver == database.DriverSQLite {
+		entry, manifest.Snapshot = sqliteEntry, SnapshotSQLite
+		data, manifest.Tables, err = snapshotSQLite(db)
+	} else {
+		entry, manifest.Snapshot = logicalEntry, SnapshotLogical
+		data, manifest.Tables, err = snapshotLogical(db)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	manifest.SHA256 = hex.EncodeToString(sum[:])
+
+	archive, err := pack(manifest, entry, data, opts.Compress)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Passphrase != "" {
+		if archive, err = encrypt(archive, opts.Passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := w.Write(archive); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// snapshotSQLite copies the database into a new file. VACUUM INTO reads
+// in a single transaction, so the copy is consistent while writes go on.
+func snapshotSQLite(db *gorm.DB) ([]byte, map[string]int, error) {
+	dir, err := os.MkdirTemp("", "traefikx-backup-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, sqliteEntry)
+	if err := db.Exec("VACUUM INTO ?", path).Error; err != nil {
+		return nil, nil, fmt.Errorf("snapshot database: %w", err)
+	}
+
+	src, err := openSQLite(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	counts, err := countRows(src.db, src.tables)
+	src.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	return data, counts, err
+}
+
+// snapshotLogical exports every table from one repeatable read
+// transaction, so the rows are consistent with each other
+func snapshotLogical(db *gorm.DB) ([]byte, map[string]int, error) {
+	tables, err := dataTables(db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dump := make(map[string][]map[string]interface{}, len(tables))
+	counts := make(map[string]int, len(tables))
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range tables {
+			var rows []map[string]interface{}
+			if err := tx.Table(table.Table).Find(&rows).Error; err != nil {
+				return fmt.Errorf("export %s: %w", table.Table, err)
+			}
+			for _, row := range rows {
+				for column, value := range row {
+					// MySQL returns text columns as bytes
+					if b, ok := value.([]byte); ok {
+						row[column] = string(b)
+					}
+				}
+			}
+			dump[table.Table] = rows
+			counts[table.Table] = len(rows)
+		}
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := json.Marshal(dump)
+	return data, counts, err
+}
+
+// dataTables parses the models of the tables copied by backups
+func dataTables(db *gorm.DB) ([]*schema.Schema, error) {
+	models := database.DataModels()
+	tables := make([]*schema.Schema, 0, len(models))
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		tables = append(tables, stmt.Schema)
+	}
+	return tables, nil
+}
+
+func countRows(db *gorm.DB, tables []*schema.Schema) (map[string]int, error) {
+	counts := make(map[string]int, len(tables))
+	for _, table := range tables {
+		var count int64
+		if err := db.Table(table.Table).Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("count %s: %w", table.Table, err)
+		}
+		counts[table.Table] = int(count)
+	}
+	return counts, nil
+}
+
+// pack writes the manifest and the snapshot into a tar archive
+func pack(manifest *Manifest, entry string, data []byte, compress bool) ([]byte, error) {
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	var out io.Writer = &buf
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		out = gz
+	}
+
+	tw := tar.NewWriter(out)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{manifestEntry, manifestJSON},
+		{entry, data},
+	} {
+		header := &tar.Header{
+			Name:    file.name,
+			Mode:    0600,
+			Size:    int64(len(file.data)),
+			ModTime: manifest.CreatedAt,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// unpack reads the manifest and the snapshot from a tar archive, gzipped
+// or not
+func unpack(archive []byte) (*Manifest, []byte, error) {
+	var in io.Reader = bytes.NewReader(archive)
+	if len(archive) > 2 && archive[0] == 0x1f && archive[1] == 0x8b {
+		gz, err := gzip.NewReader(in)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid backup: %w", err)
+		}
+		defer gz.Close()
+		in = gz
+	}
+
+	var manifest *Manifest
+	entries := map[string][]byte{}
+	tr := tar.NewReader(in)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid backup: %w", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid backup: %w", err)
+		}
+		if header.Name == manifestEntry {
+			manifest = &Manifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, nil, fmt.Errorf("invalid backup manifest: %w", err)
+			}
+			continue
+		}
+		entries[header.Name] = data
+	}
+	if manifest == nil {
+		return nil, nil, errors.New("invalid backup: no manifest")
+	}
+	if manifest.Format != formatVersion {
+		return nil, nil, fmt.Errorf("unsupported backup format %d", manifest.Format)
+	}
+
+	entry := logicalEntry
+	if manifest.Snapshot == SnapshotSQLite {
+		entry = sqliteEntry
+	}
+	data, ok := entries[entry]
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid backup: no %s", entry)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != manifest.SHA256 {
+		return nil, nil, errors.New("invalid backup: checksum mismatch")
+	}
+	return manifest, data, nil
+}
@@ -0,0 +1,84 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// encryptedMagic starts encrypted backups, followed by the salt, the nonce
+// and the AES-256-GCM sealed archive
+const encryptedMagic = "TXBKENC1"
+
+const saltSize = 16
+
+// ErrPassphraseRequired is returned when opening an encrypted backup
+// without a passphrase
+var ErrPassphraseRequired = errors.New("backup is encrypted, a passphrase is required")
+
+// isEncrypted reports whether data is an encrypted backup
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptedMagic))
+}
+
+func encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(encryptedMagic)+saltSize+len(nonce)+len(plaintext)+gcm.Overhead())
+	out = append(out, encryptedMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, nil), nil
+}
+
+func decrypt(data []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+	data = data[len(encryptedMagic):]
+	if len(data) < saltSize {
+		return nil, errors.New("invalid backup: truncated")
+	}
+	gcm, err := newGCM(passphrase, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[saltSize:]
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("invalid backup: truncated")
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("backup decryption failed, wrong passphrase?")
+	}
+	return plaintext, nil
+}
+
+// newGCM derives the key from the passphrase with scrypt
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,264 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// filePrefix starts the names of backups kept by a Manager
+const filePrefix = "traefikx-"
+
+// ErrInvalidName is returned for names that aren't backups of the directory
+var ErrInvalidName = errors.New("invalid backup name")
+
+// File is a backup stored in the backup directory
+type File struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Manager keeps backups in a directory, taking them every interval and
+// keeping the newest retention ones
+type Manager struct {
+	db        *gorm.DB
+	dir       string
+	interval  time.Duration // 0 disables scheduled backups
+	retention int           // 0 keeps all backups
+	opts      Options
+
+	mu       sync.Mutex
+	running  bool
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// NewManager creates a manager for the backups in dir
+func NewManager(db *gorm.DB, dir string, interval time.Duration, retention int, opts Options) *Manager {
+	return &Manager{
+		db:        db,
+		dir:       dir,
+		interval:  interval,
+		retention: retention,
+		opts:      opts,
+	}
+}
+
+// Dir returns the backup directory
+func (m *Manager) Dir() string {
+	return m.dir
+}
+
+// Options returns how backups are written
+func (m *Manager) Options() Options {
+	return m.opts
+}
+
+// Interval returns the period of scheduled backups, 0 if disabled
+func (m *Manager) Interval() time.Duration {
+	return m.interval
+}
+
+// Retention returns the number of backups kept, 0 for all
+func (m *Manager) Retention() int {
+	return m.retention
+}
+
+// Start takes backups on schedule. It can be called again after Stop, in
+// HA mode the leader takes them.
+func (m *Manager) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running || m.interval <= 0 {
+		return
+	}
+	m.running = true
+	m.stopChan = make(chan struct{})
+	m.done = make(chan struct{})
+	go m.run(m.stopChan, m.done)
+	slog.Info("Scheduled backups", "interval", m.interval, "dir", m.dir)
+}
+
+// Stop ends scheduled backups, waiting for one in progress
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	if !m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.running = false
+	close(m.stopChan)
+	done := m.done
+	m.mu.Unlock()
+	<-done
+}
+
+func (m *Manager) run(stop, done chan struct{}) {
+	defer close(done)
+
+	for {
+		// A restart doesn't reset the schedule
+		delay := m.interval
+		if files, err := m.List(); err == nil && len(files) > 0 {
+			delay = time.Until(files[0].CreatedAt.Add(m.interval))
+		} else if err == nil {
+			delay = 0
+		}
+
+		timer := time.NewTimer(max(delay, 0))
+		select {
+		case <-timer.C:
+			if file, err := m.Create(); err != nil {
+				slog.Error("Scheduled backup failed", "error", err)
+				// Retry on the next interval rather than right away
+				if !sleep(m.interval, stop) {
+					return
+				}
+			} else {
+				slog.Info("Scheduled backup written", "file", file.Name)
+			}
+		case <-stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func sleep(d time.Duration, stop chan struct{}) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// Create takes a backup into the directory and removes those beyond the
+// retention
+func (m *Manager) Create() (*File, error) {
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	manifest, err := Write(m.db, &buf, m.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	name := FileName(manifest.CreatedAt, m.opts)
+	path := filepath.Join(m.dir, name)
+	// Written under a temporary name, so a partial file is never listed
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+
+	if err := m.prune(); err != nil {
+		slog.Error("Failed to remove old backups", "error", err)
+	}
+	return &File{Name: name, Size: int64(buf.Len()), CreatedAt: manifest.CreatedAt}, nil
+}
+
+// FileName names a backup taken at t with opts
+func FileName(t time.Time, opts Options) string {
+	name := filePrefix + t.UTC().Format("20060102-150405") + ".tar"
+	if opts.Compress {
+		name += ".gz"
+	}
+	if opts.Passphrase != "" {
+		name += ".enc"
+	}
+	return name
+}
+
+// List returns the backups in the directory, newest first
+func (m *Manager) List() ([]File, error) {
+	entries, err := os.ReadDir(m.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []File{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	files := []File{}
+	for _, entry := range entries {
+		if entry.IsDir() || !isBackupName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		createdAt := info.ModTime()
+		if stamp, err := time.Parse("20060102-150405", strings.TrimPrefix(entry.Name(), filePrefix)[:15]); err == nil {
+			createdAt = stamp
+		}
+		files = append(files, File{Name: entry.Name(), Size: info.Size(), CreatedAt: createdAt})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].CreatedAt.After(files[j].CreatedAt)
+	})
+	return files, nil
+}
+
+// Path returns the path of a backup in the directory
+func (m *Manager) Path(name string) (string, error) {
+	if !isBackupName(name) || filepath.Base(name) != name {
+		return "", ErrInvalidName
+	}
+	path := filepath.Join(m.dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Delete removes a backup from the directory
+func (m *Manager) Delete(name string) error {
+	path, err := m.Path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (m *Manager) prune() error {
+	if m.retention <= 0 {
+		return nil
+	}
+	files, err := m.List()
+	if err != nil {
+		return err
+	}
+	for _, file := range files[min(m.retention, len(files)):] {
+		if err := os.Remove(filepath.Join(m.dir, file.Name)); err != nil {
+			return fmt.Errorf("remove %s: %w", file.Name, err)
+		}
+	}
+	return nil
+}
+
+// isBackupName reports whether name was given by FileName
+func isBackupName(name string) bool {
+	return strings.HasPrefix(name, filePrefix) &&
+		len(name) >= len(filePrefix)+15 &&
+		(strings.HasSuffix(name, ".tar") || strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".enc"))
+}
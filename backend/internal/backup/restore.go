@@ -0,0 +1,297 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/traefikx/backend/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// restoreBatchSize is the number of rows inserted per statement
+const restoreBatchSize = 100
+
+// Backup is a decrypted backup whose checksum was verified
+type Backup struct {
+	Manifest Manifest
+	data     []byte
+}
+
+// Open reads a backup, decrypting it with passphrase if it is encrypted
+func Open(r io.Reader, passphrase string) (*Backup, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted(data) {
+		if data, err = decrypt(data, passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	manifest, snapshot, err := unpack(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Backup{Manifest: *manifest, data: snapshot}, nil
+}
+
+// Validate checks that the backup can be restored into db: it must come
+// from the same schema version and hold the rows its manifest lists
+func (b *Backup) Validate(db *gorm.DB) error {
+	version, err := database.SchemaVersion(db)
+	if err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	if b.Manifest.SchemaVersion != version {
+		return fmt.Errorf("backup schema version %d does not match the database (%d); restore it with the TraefikX version that took it, then upgrade", b.Manifest.SchemaVersion, version)
+	}
+
+	tables, err := dataTables(db)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		known[table.Table] = true
+	}
+	for table := range b.Manifest.Tables {
+		if !known[table] {
+			return fmt.Errorf("backup contains unknown table %s", table)
+		}
+	}
+
+	src, err := b.source()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	for _, table := range tables {
+		rows, err := src.rows(table)
+		if err != nil {
+			return err
+		}
+		if len(rows) != b.Manifest.Tables[table.Table] {
+			return fmt.Errorf("backup has %d rows in %s, the manifest lists %d", len(rows), table.Table, b.Manifest.Tables[table.Table])
+		}
+	}
+	return nil
+}
+
+// Restore validates the backup and replaces all data in db with it, in a
+// single transaction
+func (b *Backup) Restore(db *gorm.DB) error {
+	if err := b.Validate(db); err != nil {
+		return err
+	}
+	tables, err := dataTables(db)
+	if err != nil {
+		return err
+	}
+	src, err := b.source()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Children first
+		for i := len(tables) - 1; i >= 0; i-- {
+			if err := tx.Exec("DELETE FROM ?", clause.Table{Name: tables[i].Table}).Error; err != nil {
+				return fmt.Errorf("clear %s: %w", tables[i].Table, err)
+			}
+		}
+
+		for _, table := range tables {
+			rows, err := src.rows(table)
+			if err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				continue
+			}
+			for _, row := range rows {
+				if err := normalizeRow(table, row); err != nil {
+					return err
+				}
+			}
+			if err := tx.Table(table.Table).CreateInBatches(rows, restoreBatchSize).Error; err != nil {
+				return fmt.Errorf("restore %s: %w", table.Table, err)
+			}
+		}
+
+		if tx.Dialector.Name() == database.DriverPostgres {
+			return resetSequences(tx, tables)
+		}
+		return nil
+	})
+}
+
+// resetSequences moves PostgreSQL's ID sequences past the restored rows
+func resetSequences(tx *gorm.DB, tables []*schema.Schema) error {
+	for _, table := range tables {
+		field := table.PrioritizedPrimaryField
+		if field == nil || (field.DataType != schema.Int && field.DataType != schema.Uint) {
+			continue
+		}
+		err := tx.Exec("SELECT setval(pg_get_serial_sequence(?, ?), COALESCE(MAX(?), 0) + 1, false) FROM ?",
+			table.Table, field.DBName, clause.Column{Name: field.DBName}, clause.Table{Name: table.Table}).Error
+		if err != nil {
+			return fmt.Errorf("reset %s sequence: %w", table.Table, err)
+		}
+	}
+	return nil
+}
+
+// normalizeRow converts the values read from the snapshot to the types of
+// the target columns, e.g. SQLite's integer booleans and JSON numbers
+func normalizeRow(table *schema.Schema, row map[string]interface{}) error {
+	for column, value := range row {
+		field := table.LookUpField(column)
+		if field == nil {
+			return fmt.Errorf("backup contains unknown column %s.%s", table.Table, column)
+		}
+		normalized, err := normalizeValue(field, value)
+		if err != nil {
+			return fmt.Errorf("restore %s.%s: %w", table.Table, column, err)
+		}
+		row[column] = normalized
+	}
+	return nil
+}
+
+func normalizeValue(field *schema.Field, value interface{}) (interface{}, error) {
+	if number, ok := value.(json.Number); ok {
+		if field.DataType == schema.Float {
+			return number.Float64()
+		}
+		if field.DataType != schema.Bool {
+			return number.Int64()
+		}
+		value = number.String()
+	}
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+
+	switch field.DataType {
+	case schema.Bool:
+		switch v := value.(type) {
+		case int64:
+			return v != 0, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+	case schema.Time:
+		if v, ok := value.(string); ok {
+			return parseTime(v)
+		}
+	}
+	return value, nil
+}
+
+// timeLayouts are the formats timestamps are stored in as text
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+}
+
+// source reads the rows of a snapshot
+type source interface {
+	rows(table *schema.Schema) ([]map[string]interface{}, error)
+	Close()
+}
+
+func (b *Backup) source() (source, error) {
+	if b.Manifest.Snapshot != SnapshotSQLite {
+		var dump map[string][]map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(b.data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&dump); err != nil {
+			return nil, fmt.Errorf("invalid backup data: %w", err)
+		}
+		return logicalSource(dump), nil
+	}
+
+	dir, err := os.MkdirTemp("", "traefikx-restore-")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, sqliteEntry)
+	if err := os.WriteFile(path, b.data, 0600); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	src, err := openSQLite(path)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	src.dir = dir
+	return src, nil
+}
+
+type logicalSource map[string][]map[string]interface{}
+
+func (s logicalSource) rows(table *schema.Schema) ([]map[string]interface{}, error) {
+	return s[table.Table], nil
+}
+
+func (s logicalSource) Close() {}
+
+// sqliteSource reads a snapshot database file
+type sqliteSource struct {
+	db     *gorm.DB
+	tables []*schema.Schema
+	dir    string // Removed on Close, if set
+}
+
+func openSQLite(path string) (*sqliteSource, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return nil, fmt.Errorf("open snapshot: %w", err)
+	}
+	tables, err := dataTables(db)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteSource{db: db, tables: tables}, nil
+}
+
+func (s *sqliteSource) rows(table *schema.Schema) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	if err := s.db.Table(table.Table).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("read %s: %w", table.Table, err)
+	}
+	return rows, nil
+}
+
+func (s *sqliteSource) Close() {
+	if sqlDB, err := s.db.DB(); err == nil {
+		sqlDB.Close()
+	}
+	if s.dir != "" {
+		os.RemoveAll(s.dir)
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"log"
 	"os"
 	"strconv"
@@ -12,18 +13,33 @@ import (
 
 type Config struct {
 	// Server
-	Port      string
-	JWTSecret string
-	Env       string
+	Port            string
+	JWTSecret       string
+	Env             string
+	ShutdownTimeout time.Duration // Time in-flight requests get to finish on SIGTERM
+	LogLevel        string        // debug, info, warn or error
+	LogFormat       string        // text or json
 
 	// Database
-	DatabasePath string
+	DatabaseDriver      string // sqlite, postgres or mysql
+	DatabasePath        string // SQLite file, used when DatabaseDSN is empty
+	DatabaseDSN         string
+	DatabaseAutoMigrate bool // Apply pending migrations on startup
 
 	// Security
 	BcryptCost           int
 	AccessTokenDuration  time.Duration
 	RefreshTokenDuration time.Duration
 
+	// Brute-force protection
+	LoginMaxAttempts     int           // Failed attempts before an account is locked
+	LoginLockoutDuration time.Duration // How long an account stays locked
+	LoginRateLimitBurst  int           // Attempts allowed in a burst per IP / account
+	LoginRateLimitRefill time.Duration // Time to regain one attempt
+	LoginBackoffBase     time.Duration // Delay after the first failure, doubled for each further one
+	LoginBackoffMax      time.Duration
+	LoginHistoryMaxAge   time.Duration // Age after which login attempts are deleted, 0 = never
+
 	// OIDC
 	OIDCEnabled      bool
 	OIDCProviderName string
@@ -45,6 +61,82 @@ type Config struct {
 
 	// Traefik HTTP Provider
 	TraefikProviderToken string // Token for /api/provider endpoint authentication
+
+	// Master key used to encrypt secrets stored in the database, and
+	// previous keys still accepted for decryption during a rotation
+	SecretsKey          string
+	SecretsKeyFile      string // File holding the key, previous keys on the following lines
+	SecretsPreviousKeys []string
+	SecretsKeyFromJWT   bool // No key was set, JWT_SECRET is used instead
+
+	// Public URL of the UI, used for links in emails
+	AppBaseURL string
+
+	// Mail
+	MailDriver   string // smtp, file or log
+	MailFrom     string
+	MailFileDir  string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPStartTLS bool
+	SMTPTimeout  time.Duration // Limit for connecting and sending one email
+
+	// Invitation and password reset links
+	InviteTokenDuration        time.Duration
+	PasswordResetTokenDuration time.Duration
+
+	// Config publishers, each disabled while its target is empty
+	PublishFileDir       string // Directory watched by Traefik's file provider
+	PublishFileName      string
+	PublishFileFormat    string // yaml or toml
+	PublishKVRootKey     string // Root key of Traefik's KV providers
+	PublishRedisAddr     string
+	PublishRedisUsername string
+	PublishRedisPassword string
+	PublishRedisDB       int
+	PublishEtcdEndpoints []string
+	PublishEtcdUsername  string
+	PublishEtcdPassword  string
+	PublishTimeout       time.Duration
+	PublishRetryInterval time.Duration // Retry period for publishers whose last attempt failed
+
+	// HTTP provider aggregation
+	AggregatorMaxConcurrentFetches int
+	ProviderHistoryLimit           int           // Fetches kept per provider, 0 = no limit
+	ProviderHistoryMaxAge          time.Duration // 0 = no limit
+	ProviderMaxResponseSize        int           // Bytes, larger provider responses fail the fetch
+
+	// Notifications
+	ConflictWebhookURL   string        // Deprecated, kept as a webhook channel subscribed to conflict.detected
+	NotifyTimeout        time.Duration // Per delivery attempt
+	NotifyMaxAttempts    int
+	NotifyRetryBackoff   time.Duration // Delay after the first failed attempt, doubled for each further one
+	NotifyDeliveryMaxAge time.Duration // Age after which the delivery log is pruned, 0 = never
+
+	// Backups
+	BackupDir           string
+	BackupInterval      time.Duration // 0 disables scheduled backups
+	BackupRetention     int           // Backups kept in BackupDir, 0 = all
+	BackupCompress      bool
+	BackupEncryptionKey string // Passphrase encrypting backups, empty = unencrypted
+
+	// High availability: replicas sharing the database elect a leader
+	HAEnabled      bool
+	HAInstanceID   string        // Unique per replica
+	HALeaseTTL     time.Duration // Time before a silent leader is replaced
+	HASyncInterval time.Duration // Poll period for other replicas' changes
+
+	// Monitoring
+	MetricsEnabled bool
+	MetricsToken   string        // Bearer token required on /metrics, empty = open
+	ProbeEnabled   bool          // Probe the servers of services with a health check
+	ProbeTimeout   time.Duration // Per request, capped by the service's interval
+
+	// Tracing, the OTLP exporter reads the other OTEL_* variables itself
+	TracesExporter string // none, otlp or stdout
+	TracesProtocol string // OTLP protocol, http/protobuf or grpc
 }
 
 var AppConfig *Config
@@ -61,14 +153,30 @@ func Load() *Config {
 		JWTSecret: getEnv("JWT_SECRET", "change-this-secret-key-in-production-min-32-chars"),
 		Env:       getEnv("ENV", "development"),
 
+		ShutdownTimeout: getEnvAsDuration("SHUTDOWN_TIMEOUT", 15*time.Second),
+		LogLevel:        getEnv("LOG_LEVEL", "info"),
+		LogFormat:       getEnv("LOG_FORMAT", "text"),
+
 		// Database defaults
-		DatabasePath: getEnv("DATABASE_PATH", "./data/traefikx.db"),
+		DatabaseDriver:      getEnv("DATABASE_DRIVER", "sqlite"),
+		DatabasePath:        getEnv("DATABASE_PATH", "./data/traefikx.db"),
+		DatabaseDSN:         getEnv("DATABASE_DSN", ""),
+		DatabaseAutoMigrate: getEnvAsBool("DATABASE_AUTO_MIGRATE", true),
 
 		// Security defaults
 		BcryptCost:           getEnvAsInt("BCRYPT_COST", 12),
 		AccessTokenDuration:  getEnvAsDuration("ACCESS_TOKEN_DURATION", 15*time.Minute),
 		RefreshTokenDuration: getEnvAsDuration("REFRESH_TOKEN_DURATION", 7*24*time.Hour),
 
+		// Brute-force protection defaults
+		LoginMaxAttempts:     getEnvAsInt("LOGIN_MAX_ATTEMPTS", 5),
+		LoginLockoutDuration: getEnvAsDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		LoginRateLimitBurst:  getEnvAsInt("LOGIN_RATE_LIMIT_BURST", 10),
+		LoginRateLimitRefill: getEnvAsDuration("LOGIN_RATE_LIMIT_REFILL", 6*time.Second),
+		LoginBackoffBase:     getEnvAsDuration("LOGIN_BACKOFF_BASE", time.Second),
+		LoginBackoffMax:      getEnvAsDuration("LOGIN_BACKOFF_MAX", 5*time.Minute),
+		LoginHistoryMaxAge:   getEnvAsDuration("LOGIN_HISTORY_MAX_AGE", 90*24*time.Hour),
+
 		// OIDC defaults
 		OIDCEnabled:      getEnvAsBool("OIDC_ENABLED", false),
 		OIDCProviderName: getEnv("OIDC_PROVIDER_NAME", "Pocket ID"),
@@ -90,6 +198,77 @@ func Load() *Config {
 
 		// Traefik HTTP Provider
 		TraefikProviderToken: getEnv("TRAEFIK_PROVIDER_TOKEN", "change-me-in-production-traefik-token"),
+
+		SecretsKey:          getEnv("SECRETS_KEY", ""),
+		SecretsKeyFile:      getEnv("SECRETS_KEY_FILE", ""),
+		SecretsPreviousKeys: getEnvAsSlice("SECRETS_PREVIOUS_KEYS", nil),
+
+		AppBaseURL: strings.TrimRight(getEnv("APP_BASE_URL", "http://localhost:8080"), "/"),
+
+		// Mail defaults - log emails until SMTP is configured
+		MailDriver:   getEnv("MAIL_DRIVER", "log"),
+		MailFrom:     getEnv("MAIL_FROM", "TraefikX <noreply@traefikx.local>"),
+		MailFileDir:  getEnv("MAIL_FILE_DIR", "./data/mail"),
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnvAsInt("SMTP_PORT", 587),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPStartTLS: getEnvAsBool("SMTP_STARTTLS", true),
+		SMTPTimeout:  getEnvAsDuration("SMTP_TIMEOUT", 30*time.Second),
+
+		InviteTokenDuration:        getEnvAsDuration("INVITE_TOKEN_DURATION", 72*time.Hour),
+		PasswordResetTokenDuration: getEnvAsDuration("PASSWORD_RESET_TOKEN_DURATION", time.Hour),
+
+		// Publishers
+		PublishFileDir:       getEnv("PUBLISH_FILE_DIR", ""),
+		PublishFileName:      getEnv("PUBLISH_FILE_NAME", "traefikx"),
+		PublishFileFormat:    getEnv("PUBLISH_FILE_FORMAT", "yaml"),
+		PublishKVRootKey:     getEnv("PUBLISH_KV_ROOT_KEY", "traefik"),
+		PublishRedisAddr:     getEnv("PUBLISH_REDIS_ADDR", ""),
+		PublishRedisUsername: getEnv("PUBLISH_REDIS_USERNAME", ""),
+		PublishRedisPassword: getEnv("PUBLISH_REDIS_PASSWORD", ""),
+		PublishRedisDB:       getEnvAsInt("PUBLISH_REDIS_DB", 0),
+		PublishEtcdEndpoints: getEnvAsSlice("PUBLISH_ETCD_ENDPOINTS", nil),
+		PublishEtcdUsername:  getEnv("PUBLISH_ETCD_USERNAME", ""),
+		PublishEtcdPassword:  getEnv("PUBLISH_ETCD_PASSWORD", ""),
+		PublishTimeout:       getEnvAsDuration("PUBLISH_TIMEOUT", 10*time.Second),
+		PublishRetryInterval: getEnvAsDuration("PUBLISH_RETRY_INTERVAL", 30*time.Second),
+
+		// HTTP provider aggregation
+		AggregatorMaxConcurrentFetches: getEnvAsInt("AGGREGATOR_MAX_CONCURRENT_FETCHES", 4),
+		ProviderHistoryLimit:           getEnvAsInt("PROVIDER_HISTORY_LIMIT", 1000),
+		ProviderHistoryMaxAge:          getEnvAsDuration("PROVIDER_HISTORY_MAX_AGE", 7*24*time.Hour),
+		ProviderMaxResponseSize:        getEnvAsInt("PROVIDER_MAX_RESPONSE_SIZE", 10<<20),
+
+		// Notifications
+		ConflictWebhookURL:   getEnv("CONFLICT_WEBHOOK_URL", ""),
+		NotifyTimeout:        getEnvAsDuration("NOTIFY_TIMEOUT", 10*time.Second),
+		NotifyMaxAttempts:    getEnvAsInt("NOTIFY_MAX_ATTEMPTS", 5),
+		NotifyRetryBackoff:   getEnvAsDuration("NOTIFY_RETRY_BACKOFF", 30*time.Second),
+		NotifyDeliveryMaxAge: getEnvAsDuration("NOTIFY_DELIVERY_MAX_AGE", 30*24*time.Hour),
+
+		// Backups
+		BackupDir:           getEnv("BACKUP_DIR", "./data/backups"),
+		BackupInterval:      getEnvAsDuration("BACKUP_INTERVAL", 0),
+		BackupRetention:     getEnvAsInt("BACKUP_RETENTION", 7),
+		BackupCompress:      getEnvAsBool("BACKUP_COMPRESS", true),
+		BackupEncryptionKey: getEnv("BACKUP_ENCRYPTION_KEY", ""),
+
+		// High availability
+		HAEnabled:      getEnvAsBool("HA_ENABLED", false),
+		HAInstanceID:   getEnv("HA_INSTANCE_ID", defaultInstanceID()),
+		HALeaseTTL:     getEnvAsDuration("HA_LEASE_TTL", 15*time.Second),
+		HASyncInterval: getEnvAsDuration("HA_SYNC_INTERVAL", 2*time.Second),
+
+		// Monitoring
+		MetricsEnabled: getEnvAsBool("METRICS_ENABLED", true),
+		MetricsToken:   getEnv("METRICS_TOKEN", ""),
+		ProbeEnabled:   getEnvAsBool("PROBE_ENABLED", true),
+		ProbeTimeout:   getEnvAsDuration("PROBE_TIMEOUT", 5*time.Second),
+
+		// Tracing, named like the OpenTelemetry SDK variables
+		TracesExporter: getEnv("OTEL_TRACES_EXPORTER", "none"),
+		TracesProtocol: getEnv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", getEnv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/protobuf")),
 	}
 
 	// Validate JWT secret length
@@ -97,10 +276,56 @@ func Load() *Config {
 		log.Fatal("JWT_SECRET must be at least 32 characters long")
 	}
 
+	if config.HAEnabled && config.DatabaseDriver == "sqlite" {
+		log.Println("HA_ENABLED with SQLite: replicas must share the database file on one host")
+	}
+
+	if config.SecretsKeyFile != "" {
+		if err := loadSecretsKeyFile(config); err != nil {
+			log.Fatalf("Failed to read SECRETS_KEY_FILE: %v", err)
+		}
+	}
+
+	if config.SecretsKey == "" {
+		config.SecretsKey = config.JWTSecret
+		config.SecretsKeyFromJWT = true
+	}
+
 	AppConfig = config
 	return config
 }
 
+// loadSecretsKeyFile reads the master key from the first non-empty line
+// of the key file, and previous keys from the following ones
+func loadSecretsKeyFile(config *Config) error {
+	data, err := os.ReadFile(config.SecretsKeyFile)
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			keys = append(keys, line)
+		}
+	}
+	if len(keys) == 0 {
+		return errors.New("no key in file")
+	}
+	config.SecretsKey = keys[0]
+	config.SecretsPreviousKeys = append(keys[1:], config.SecretsPreviousKeys...)
+	return nil
+}
+
+// defaultInstanceID identifies this process among replicas
+func defaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "traefikx"
+	}
+	return hostname + "-" + strconv.Itoa(os.Getpid())
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -1,69 +1,122 @@
 package database
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/glebarez/sqlite"
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"github.com/traefikx/backend/internal/config"
 	"github.com/traefikx/backend/internal/models"
+	"github.com/traefikx/backend/internal/tracing"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
+// Supported database drivers
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
 func Init(cfg *config.Config) (*gorm.DB, error) {
-	// Ensure database directory exists
-	dbDir := filepath.Dir(cfg.DatabasePath)
-	if dbDir != "" && dbDir != "." {
-		if err := os.MkdirAll(dbDir, 0755); err != nil {
-			return nil, err
-		}
+	dialector, err := openDialector(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	var logLevel logger.LogLevel
-	if cfg.Env == "development" {
+	// Queries are logged at debug level, without their parameters which
+	// may be secrets; errors and slow queries always
+	logLevel := logger.Warn
+	if strings.EqualFold(cfg.LogLevel, "debug") {
 		logLevel = logger.Info
-	} else {
-		logLevel = logger.Error
 	}
 
-	db, err := gorm.Open(sqlite.Open(cfg.DatabasePath), &gorm.Config{
-		Logger: logger.Default.LogMode(logLevel),
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.NewSlogLogger(slog.Default(), logger.Config{
+			LogLevel:                  logLevel,
+			SlowThreshold:             200 * time.Millisecond,
+			ParameterizedQueries:      true,
+			IgnoreRecordNotFoundError: true,
+		}),
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	// Spans for the queries of traced requests and fetches
+	if err := db.Use(tracing.GORMPlugin{}); err != nil {
+		return nil, err
+	}
+
 	DB = db
 	return db, nil
 }
 
+// openDialector selects the driver. SQLite uses DATABASE_PATH unless a DSN
+// is given, the server databases require one.
+func openDialector(cfg *config.Config) (gorm.Dialector, error) {
+	switch cfg.DatabaseDriver {
+	case "", DriverSQLite:
+		if cfg.DatabaseDSN != "" {
+			return sqlite.Open(cfg.DatabaseDSN), nil
+		}
+
+		// Ensure database directory exists
+		dbDir := filepath.Dir(cfg.DatabasePath)
+		if dbDir != "" && dbDir != "." {
+			if err := os.MkdirAll(dbDir, 0755); err != nil {
+				return nil, err
+			}
+		}
+		return sqlite.Open(cfg.DatabasePath), nil
+
+	case DriverPostgres:
+		if cfg.DatabaseDSN == "" {
+			return nil, fmt.Errorf("DATABASE_DSN is required for the postgres driver")
+		}
+		return postgres.Open(cfg.DatabaseDSN), nil
+
+	case DriverMySQL:
+		if cfg.DatabaseDSN == "" {
+			return nil, fmt.Errorf("DATABASE_DSN is required for the mysql driver")
+		}
+		// Timestamps are scanned into time.Time
+		dsn, err := mysqldriver.ParseDSN(cfg.DatabaseDSN)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DATABASE_DSN: %w", err)
+		}
+		dsn.ParseTime = true
+		return mysql.Open(dsn.FormatDSN()), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported DATABASE_DRIVER %q, use sqlite, postgres or mysql", cfg.DatabaseDriver)
+	}
+}
+
+// Migrate applies the pending migrations
 func Migrate() error {
 	if DB == nil {
 		return nil
 	}
 
-	log.Println("Running database migrations...")
-
-	// Auto-migrate models
-	if err := DB.AutoMigrate(
-		&models.User{},
-		&models.Session{},
-		&models.Router{},
-		&models.RouterHostname{},
-		&models.RouterMiddleware{},
-		&models.Service{},
-		&models.ServiceServer{},
-		&models.Middleware{},
-		&models.HTTPProvider{},
-	); err != nil {
+	slog.Info("Running database migrations")
+
+	applied, err := MigrateUp(DB)
+	if err != nil {
 		return err
 	}
 
-	log.Println("Database migrations completed")
+	slog.Info("Database migrations completed", "applied", applied)
 	return nil
 }
 
@@ -79,7 +132,7 @@ func CreateDefaultAdmin(cfg *config.Config) error {
 
 	// Only create default admin if no users exist
 	if count == 0 {
-		log.Println("Creating default admin user...")
+		slog.Info("Creating default admin user")
 
 		// Hash password
 		hashedPassword, err := HashPassword(cfg.DefaultAdminPassword)
@@ -88,7 +141,7 @@ func CreateDefaultAdmin(cfg *config.Config) error {
 		}
 
 		admin := models.User{
-			Email:           cfg.DefaultAdminEmail,
+			Email:           models.NormalizeEmail(cfg.DefaultAdminEmail),
 			Password:        hashedPassword,
 			Role:            models.RoleAdmin,
 			IsActive:        true,
@@ -100,8 +153,8 @@ func CreateDefaultAdmin(cfg *config.Config) error {
 			return err
 		}
 
-		log.Printf("Default admin user created: %s", cfg.DefaultAdminEmail)
-		log.Println("IMPORTANT: Please change the default admin password immediately!")
+		slog.Info("Default admin user created", "email", cfg.DefaultAdminEmail)
+		slog.Warn("Please change the default admin password immediately")
 	}
 
 	return nil
@@ -0,0 +1,345 @@
+package database
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/traefikx/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Migration is a versioned schema change. Up and Down run in a transaction,
+// which MySQL commits implicitly on DDL statements.
+//
+// Applied migrations must never change. Changes to the models need a new
+// migration: AutoMigrate is only used by the baseline, and later
+// migrations alter the schema explicitly so columns can be dropped and
+// renamed. The baseline creates tables from the current models, so later
+// migrations check for columns before adding them.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// schemaMigration records an applied migration in the schema version table
+type schemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// migrations in version order
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial schema",
+		// Databases created before versioned migrations already have these
+		// tables, AutoMigrate then leaves them as they are
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(baselineModels()...)
+		},
+		Down: func(tx *gorm.DB) error {
+			models := baselineModels()
+			for i := len(models) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropTable(models[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 2,
+		Name:    "high availability",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&models.Lease{}, &models.ClusterEvent{}, &models.OIDCLoginState{}); err != nil {
+				return err
+			}
+			if tx.Migrator().HasColumn(&models.HTTPProvider{}, "State") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&models.HTTPProvider{}, "State")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&models.HTTPProvider{}, "State"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&models.OIDCLoginState{}, &models.ClusterEvent{}, &models.Lease{})
+		},
+	},
+	{
+		Version: 3,
+		Name:    "notifications",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&models.NotificationChannel{}, &models.NotificationDelivery{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&models.NotificationDelivery{}, &models.NotificationChannel{})
+		},
+	},
+	{
+		Version: 4,
+		Name:    "normalize user emails",
+		// Emails are looked up as stored. One differing only in case from
+		// another user's is left alone, as the unique index would reject it.
+		Up: func(tx *gorm.DB) error {
+			var users []models.User
+			if err := tx.Select("id", "email").Find(&users).Error; err != nil {
+				return err
+			}
+			taken := make(map[string]bool, len(users))
+			for _, user := range users {
+				taken[user.Email] = true
+			}
+			for _, user := range users {
+				email := models.NormalizeEmail(user.Email)
+				if email == user.Email {
+					continue
+				}
+				if taken[email] {
+					slog.Warn("Email differs from another user's only in case, left as is", "user_id", user.ID, "email", user.Email)
+					continue
+				}
+				if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Update("email", email).Error; err != nil {
+					return err
+				}
+				taken[email] = true
+			}
+			return nil
+		},
+		// The original case is lost, normalized emails work either way
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	},
+	{
+		Version: 5,
+		Name:    "hash session tokens",
+		// Refresh tokens were stored as issued, sessions keep working once
+		// their token is hashed
+		Up: func(tx *gorm.DB) error {
+			var sessions []models.Session
+			if err := tx.Select("id", "token").Find(&sessions).Error; err != nil {
+				return err
+			}
+			for _, session := range sessions {
+				if err := tx.Model(&models.Session{}).Where("id = ?", session.ID).
+					Update("token", models.HashToken(session.TokenHash)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		// Hashes can't be turned back into tokens, users sign in again
+		Down: func(tx *gorm.DB) error {
+			return tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Session{}).Error
+		},
+	},
+}
+
+// baselineModels are the tables of version 1, parents first
+func baselineModels() []interface{} {
+	return []interface{}{
+		&models.User{},
+		&models.Session{},
+		&models.LoginAttempt{},
+		&models.UserToken{},
+		&models.Service{},
+		&models.ServiceServer{},
+		&models.Middleware{},
+		&models.Router{},
+		&models.RouterHostname{},
+		&models.RouterMiddleware{},
+		&models.HTTPProvider{},
+		&models.ProviderFetch{},
+		&models.ConflictPolicy{},
+		&models.ConfigConflict{},
+	}
+}
+
+// DataModels are the tables holding application data, parents first, as
+// copied by backups. Cluster state such as the leader lease is left out.
+// Tables added by later migrations belong here too.
+func DataModels() []interface{} {
+	return append(baselineModels(),
+		&models.NotificationChannel{},
+		&models.NotificationDelivery{},
+	)
+}
+
+// LatestVersion is the schema version this binary expects
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// MigrationState is a migration and whether it was applied
+type MigrationState struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// SchemaVersion returns the version of the last applied migration, 0 for
+// an empty database
+func SchemaVersion(db *gorm.DB) (int, error) {
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return 0, nil
+	}
+	var version int
+	err := db.Model(&schemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// CheckSchema fails unless the database is at the latest version, for
+// deployments that run migrations separately
+func CheckSchema(db *gorm.DB) error {
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version != LatestVersion() {
+		return fmt.Errorf("database schema is at version %d, this version of TraefikX requires %d; run \"migrate up\"", version, LatestVersion())
+	}
+	return nil
+}
+
+// MigrationStatus lists all migrations known to this binary
+func MigrationStatus(db *gorm.DB) ([]MigrationState, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := MigrationState{Version: m.Version, Name: m.Name}
+		if record, ok := applied[m.Version]; ok {
+			state.AppliedAt = &record.AppliedAt
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// MigrateUp applies the pending migrations and returns how many ran. It
+// refuses to run against a database migrated by a newer version.
+func MigrateUp(db *gorm.DB) (int, error) {
+	count := 0
+	err := withMigrationLock(db, func(conn *gorm.DB) error {
+		if err := conn.AutoMigrate(&schemaMigration{}); err != nil {
+			return err
+		}
+		version, err := SchemaVersion(conn)
+		if err != nil {
+			return err
+		}
+		if version > LatestVersion() {
+			return fmt.Errorf("database schema version %d is newer than this version of TraefikX supports (%d)", version, LatestVersion())
+		}
+
+		for _, m := range migrations {
+			if m.Version <= version {
+				continue
+			}
+			slog.Info("Applying migration", "version", m.Version, "name", m.Name)
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := m.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// MigrateDown reverts the last steps applied migrations and returns how
+// many were reverted
+func MigrateDown(db *gorm.DB, steps int) (int, error) {
+	count := 0
+	err := withMigrationLock(db, func(conn *gorm.DB) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			slog.Info("Reverting migration", "version", m.Version, "name", m.Name)
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := m.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{}, m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+func appliedMigrations(db *gorm.DB) (map[int]schemaMigration, error) {
+	applied := map[int]schemaMigration{}
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return applied, nil
+	}
+	var records []schemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// migrationLockID identifies the advisory lock held while migrating
+const migrationLockID = 7_420_113
+
+// withMigrationLock runs fn on a single connection holding a database-wide
+// lock, so instances starting together don't migrate concurrently. SQLite
+// serializes writers itself.
+func withMigrationLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		// Every statement starts fresh while staying on this connection
+		conn = conn.Session(&gorm.Session{NewDB: true})
+
+		switch conn.Dialector.Name() {
+		case DriverPostgres:
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockID).Error; err != nil {
+				return fmt.Errorf("acquire migration lock: %w", err)
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockID)
+
+		case DriverMySQL:
+			var acquired int
+			if err := conn.Raw("SELECT GET_LOCK(?, 300)", "traefikx_migrations").Scan(&acquired).Error; err != nil {
+				return fmt.Errorf("acquire migration lock: %w", err)
+			}
+			if acquired != 1 {
+				return fmt.Errorf("acquire migration lock: timed out")
+			}
+			defer conn.Exec("SELECT RELEASE_LOCK(?)", "traefikx_migrations")
+		}
+		return fn(conn)
+	})
+}
@@ -0,0 +1,260 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/traefikx/backend/internal/config"
+	"github.com/traefikx/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB opens an empty database for each driver: SQLite always,
+// Postgres and MySQL when TEST_POSTGRES_DSN or TEST_MYSQL_DSN is set. The
+// server databases are reset before and after the test.
+func openTestDB(t *testing.T, driver string) *gorm.DB {
+	t.Helper()
+	cfg := &config.Config{DatabaseDriver: driver}
+	if driver == DriverSQLite {
+		cfg.DatabasePath = filepath.Join(t.TempDir(), "test.db")
+	} else {
+		env := "TEST_" + strings.ToUpper(driver) + "_DSN"
+		if cfg.DatabaseDSN = os.Getenv(env); cfg.DatabaseDSN == "" {
+			t.Skipf("set %s to test against %s", env, driver)
+		}
+	}
+
+	dialector, err := openDialector(cfg)
+	if err != nil {
+		t.Fatalf("open %s: %v", driver, err)
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open %s: %v", driver, err)
+	}
+
+	reset := func() {
+		if _, err := MigrateDown(db, len(migrations)); err != nil {
+			t.Errorf("reset: %v", err)
+		}
+		db.Migrator().DropTable(&schemaMigration{})
+	}
+	if driver != DriverSQLite {
+		reset()
+	}
+	t.Cleanup(func() {
+		if driver != DriverSQLite {
+			reset()
+		}
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// forEachDriver runs test against every available database
+func forEachDriver(t *testing.T, test func(t *testing.T, db *gorm.DB)) {
+	for _, driver := range []string{DriverSQLite, DriverPostgres, DriverMySQL} {
+		t.Run(driver, func(t *testing.T) {
+			test(t, openTestDB(t, driver))
+		})
+	}
+}
+
+// expectVersion fails unless the database is at version
+func expectVersion(t *testing.T, db *gorm.DB, version int) {
+	t.Helper()
+	got, err := SchemaVersion(db)
+	if err != nil {
+		t.Fatalf("SchemaVersion: %v", err)
+	}
+	if got != version {
+		t.Fatalf("expected schema version %d, got %d", version, got)
+	}
+}
+
+func TestMigrateUpDown(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		expectVersion(t, db, 0)
+		if err := CheckSchema(db); err == nil {
+			t.Error("expected CheckSchema to fail on an empty database")
+		}
+
+		applied, err := MigrateUp(db)
+		if err != nil {
+			t.Fatalf("MigrateUp: %v", err)
+		}
+		if applied != len(migrations) {
+			t.Errorf("expected %d migrations to run, got %d", len(migrations), applied)
+		}
+		expectVersion(t, db, LatestVersion())
+		if err := CheckSchema(db); err != nil {
+			t.Errorf("CheckSchema: %v", err)
+		}
+		for _, model := range append(DataModels(), &models.Lease{}, &models.ClusterEvent{}, &models.OIDCLoginState{}) {
+			if !db.Migrator().HasTable(model) {
+				t.Errorf("expected table for %T", model)
+			}
+		}
+		if !db.Migrator().HasColumn(&models.HTTPProvider{}, "State") {
+			t.Error("expected http_providers.state")
+		}
+
+		// Nothing left to apply
+		if applied, err := MigrateUp(db); err != nil || applied != 0 {
+			t.Errorf("expected a second MigrateUp to do nothing, got %d, %v", applied, err)
+		}
+
+		// Revert the migrations after version 2, then apply them again
+		later := LatestVersion() - 2
+		reverted, err := MigrateDown(db, later)
+		if err != nil || reverted != later {
+			t.Fatalf("MigrateDown(%d) = %d, %v", later, reverted, err)
+		}
+		expectVersion(t, db, 2)
+		if err := CheckSchema(db); err == nil {
+			t.Error("expected CheckSchema to fail after reverting a migration")
+		}
+		if db.Migrator().HasTable(&models.NotificationChannel{}) {
+			t.Error("expected the notification tables to be dropped")
+		}
+		if applied, err := MigrateUp(db); err != nil || applied != later {
+			t.Fatalf("expected MigrateUp to apply %d migrations, got %d, %v", later, applied, err)
+		}
+
+		// Revert everything, down to an empty database, and back up
+		reverted, err = MigrateDown(db, len(migrations)+1)
+		if err != nil || reverted != len(migrations) {
+			t.Fatalf("MigrateDown(all) = %d, %v", reverted, err)
+		}
+		expectVersion(t, db, 0)
+		for _, model := range DataModels() {
+			if db.Migrator().HasTable(model) {
+				t.Errorf("expected table for %T to be dropped", model)
+			}
+		}
+		if applied, err := MigrateUp(db); err != nil || applied != len(migrations) {
+			t.Fatalf("expected MigrateUp to apply all migrations again, got %d, %v", applied, err)
+		}
+		expectVersion(t, db, LatestVersion())
+	})
+}
+
+func TestMigrationStatus(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		if _, err := MigrateUp(db); err != nil {
+			t.Fatalf("MigrateUp: %v", err)
+		}
+		if _, err := MigrateDown(db, 1); err != nil {
+			t.Fatalf("MigrateDown: %v", err)
+		}
+
+		states, err := MigrationStatus(db)
+		if err != nil {
+			t.Fatalf("MigrationStatus: %v", err)
+		}
+		if len(states) != len(migrations) {
+			t.Fatalf("expected %d migrations, got %d", len(migrations), len(states))
+		}
+		for i, state := range states {
+			pending := i == len(states)-1
+			if (state.AppliedAt == nil) != pending {
+				t.Errorf("migration %d: applied at %v, expected pending %v", state.Version, state.AppliedAt, pending)
+			}
+		}
+	})
+}
+
+func TestMigrateUp_NewerSchema(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		if _, err := MigrateUp(db); err != nil {
+			t.Fatalf("MigrateUp: %v", err)
+		}
+		newer := schemaMigration{Version: LatestVersion() + 1, Name: "from the future", AppliedAt: time.Now()}
+		if err := db.Create(&newer).Error; err != nil {
+			t.Fatalf("record migration: %v", err)
+		}
+
+		if _, err := MigrateUp(db); err == nil {
+			t.Error("expected MigrateUp to refuse a newer schema")
+		}
+		if err := CheckSchema(db); err == nil {
+			t.Error("expected CheckSchema to fail on a newer schema")
+		}
+		if err := db.Delete(&newer).Error; err != nil {
+			t.Fatalf("delete migration: %v", err)
+		}
+	})
+}
+
+func TestMigrateUp_NormalizesEmails(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		if _, err := MigrateUp(db); err != nil {
+			t.Fatalf("MigrateUp: %v", err)
+		}
+		if _, err := MigrateDown(db, LatestVersion()-3); err != nil {
+			t.Fatalf("MigrateDown: %v", err)
+		}
+		want := []string{"alice@example.com", "bob@example.com"}
+		for _, email := range []string{" Alice@Example.com", "bob@example.com"} {
+			if err := db.Create(&models.User{Email: email}).Error; err != nil {
+				t.Fatalf("create user: %v", err)
+			}
+		}
+		// MySQL's default collation already rejects the case-only twin
+		if err := db.Create(&models.User{Email: "BOB@example.com"}).Error; err == nil {
+			want = append(want, "BOB@example.com")
+		}
+
+		if _, err := MigrateUp(db); err != nil {
+			t.Fatalf("MigrateUp: %v", err)
+		}
+		var emails []string
+		db.Model(&models.User{}).Order("id").Pluck("email", &emails)
+		if strings.Join(emails, ",") != strings.Join(want, ",") {
+			t.Errorf("emails %q, want %q", emails, want)
+		}
+	})
+}
+
+func TestMigrateUp_HashesSessionTokens(t *testing.T) {
+	forEachDriver(t, func(t *testing.T, db *gorm.DB) {
+		if _, err := MigrateUp(db); err != nil {
+			t.Fatalf("MigrateUp: %v", err)
+		}
+		if _, err := MigrateDown(db, 1); err != nil {
+			t.Fatalf("MigrateDown: %v", err)
+		}
+		user := models.User{Email: "alice@example.com"}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		// Stored as issued before the migration
+		if err := db.Create(&models.Session{UserID: user.ID, TokenHash: "refresh-token", ExpiresAt: time.Now().Add(time.Hour)}).Error; err != nil {
+			t.Fatalf("create session: %v", err)
+		}
+
+		if _, err := MigrateUp(db); err != nil {
+			t.Fatalf("MigrateUp: %v", err)
+		}
+		var session models.Session
+		if err := db.Where("token = ?", models.HashToken("refresh-token")).First(&session).Error; err != nil {
+			t.Errorf("expected the session to be found by the token's hash: %v", err)
+		}
+
+		// Reverting signs everyone out
+		if _, err := MigrateDown(db, 1); err != nil {
+			t.Fatalf("MigrateDown: %v", err)
+		}
+		var count int64
+		db.Model(&models.Session{}).Count(&count)
+		if count != 0 {
+			t.Errorf("expected the sessions to be deleted, got %d", count)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/traefikx/backend/internal/secrets"
+	"gorm.io/gorm"
+)
+
+// encryptedColumns are the columns using the encrypted serializer of a table
+type encryptedColumns struct {
+	table   string
+	key     string // Primary key column
+	columns []string
+}
+
+func findEncryptedColumns(db *gorm.DB) ([]encryptedColumns, error) {
+	var found []encryptedColumns
+	for _, model := range DataModels() {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		if stmt.Schema.PrioritizedPrimaryField == nil {
+			continue
+		}
+
+		table := encryptedColumns{table: stmt.Schema.Table, key: stmt.Schema.PrioritizedPrimaryField.DBName}
+		for _, field := range stmt.Schema.Fields {
+			if field.TagSettings["SERIALIZER"] == "encrypted" && field.DBName != "" {
+				table.columns = append(table.columns, field.DBName)
+			}
+		}
+		if len(table.columns) > 0 {
+			found = append(found, table)
+		}
+	}
+	return found, nil
+}
+
+// SecretCount is the number of stored secrets per master key ID, with
+// "plaintext" and "v1" for values not yet under envelope encryption
+type SecretCount map[string]int
+
+// SecretStatus counts the encrypted values in the database by master key
+func SecretStatus(db *gorm.DB) (SecretCount, error) {
+	tables, err := findEncryptedColumns(db)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := SecretCount{}
+	err = eachSecret(db, tables, func(table encryptedColumns, id interface{}, column, value string) error {
+		if keyID := secrets.StoredKeyID(value); keyID != "" {
+			counts[keyID]++
+		}
+		return nil
+	})
+	return counts, err
+}
+
+// ReencryptSecrets re-encrypts the values that aren't under the current
+// master key: values sealed with a previous key, in the v1 format, or
+// stored before their column was encrypted. It returns how many values
+// were rewritten. A value changed meanwhile is left to the writer.
+func ReencryptSecrets(db *gorm.DB) (int, error) {
+	tables, err := findEncryptedColumns(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = eachSecret(db, tables, func(table encryptedColumns, id interface{}, column, value string) error {
+		if !secrets.NeedsRotation(value) {
+			return nil
+		}
+		plaintext, err := secrets.Decrypt(value)
+		if err != nil {
+			return fmt.Errorf("%s %v: %w", table.table, id, err)
+		}
+		encrypted, err := secrets.Encrypt(plaintext)
+		if err != nil {
+			return err
+		}
+
+		result := db.Table(table.table).
+			Where(table.key+" = ? AND "+column+" = ?", id, value).
+			UpdateColumn(column, encrypted)
+		if result.Error != nil {
+			return fmt.Errorf("%s %v: %w", table.table, id, result.Error)
+		}
+		count += int(result.RowsAffected)
+		return nil
+	})
+	return count, err
+}
+
+// eachSecret calls fn with the stored value of every encrypted column
+func eachSecret(db *gorm.DB, tables []encryptedColumns, fn func(table encryptedColumns, id interface{}, column, value string) error) error {
+	for _, table := range tables {
+		var rows []map[string]interface{}
+		columns := append([]string{table.key}, table.columns...)
+		if err := db.Table(table.table).Select(columns).Find(&rows).Error; err != nil {
+			return fmt.Errorf("read %s: %w", table.table, err)
+		}
+
+		for _, row := range rows {
+			for _, column := range table.columns {
+				var value string
+				switch v := row[column].(type) {
+				case string:
+					value = v
+				case []byte:
+					value = string(v)
+				}
+				if err := fn(table, row[table.key], column, value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,421 @@
+package declarative
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/traefikx/backend/internal/models"
+	"github.com/traefikx/backend/internal/secrets"
+	"gorm.io/gorm"
+)
+
+// Apply executes a plan produced by Plan in a single transaction. IDs of
+// created resources are filled into result.Changes.
+func Apply(db *gorm.DB, doc *Document, result *ImportResult, opts ImportOptions) error {
+	services := specsByName(doc.Services, func(s ServiceSpec) string { return s.Name })
+	middlewares := specsByName(doc.Middlewares, func(m MiddlewareSpec) string { return m.Name })
+	routers := specsByName(doc.Routers, func(r RouterSpec) string { return r.Name })
+	providers := specsByName(doc.HTTPProviders, func(p HTTPProviderSpec) string { return p.Name })
+	users := specsByName(doc.Users, func(u UserSpec) string { return models.NormalizeEmail(u.Email) })
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Dependencies first: users own routers, routers reference services and middlewares
+		order := []struct {
+			kind  string
+			specs map[string]interface{}
+		}{
+			{KindUser, users},
+			{KindService, services},
+			{KindMiddleware, middlewares},
+			{KindRouter, routers},
+			{KindHTTPProvider, providers},
+		}
+
+		for _, step := range order {
+			for i := range result.Changes {
+				change := &result.Changes[i]
+				if change.Kind != step.kind || (change.Action != ActionCreate && change.Action != ActionUpdate) {
+					continue
+				}
+				id, err := upsert(tx, change, step.specs[change.Name], opts)
+				if err != nil {
+					return fmt.Errorf("%s %q: %w", change.Kind, change.Name, err)
+				}
+				change.ID = id
+			}
+		}
+
+		// Deletes in reverse dependency order
+		for i := len(order) - 1; i >= 0; i-- {
+			for _, change := range result.Changes {
+				if change.Kind != order[i].kind || change.Action != ActionDelete {
+					continue
+				}
+				if err := remove(tx, change); err != nil {
+					return fmt.Errorf("%s %q: %w", change.Kind, change.Name, err)
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	result.Applied = true
+	return nil
+}
+
+func upsert(tx *gorm.DB, change *Change, spec interface{}, opts ImportOptions) (uint, error) {
+	switch s := spec.(type) {
+	case ServiceSpec:
+		return upsertService(tx, change.ID, s)
+	case MiddlewareSpec:
+		return upsertMiddleware(tx, change.ID, s)
+	case RouterSpec:
+		return upsertRouter(tx, change.ID, s, opts)
+	case HTTPProviderSpec:
+		return upsertHTTPProvider(tx, change.ID, s, opts)
+	case UserSpec:
+		return upsertUser(tx, change.ID, s)
+	default:
+		return 0, fmt.Errorf("no definition in document")
+	}
+}
+
+// save creates or updates a record. Boolean columns with a database default
+// of true are written explicitly since GORM skips zero values on create.
+func save(tx *gorm.DB, id uint, record interface{}, bools map[string]interface{}) error {
+	if id == 0 {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		return tx.Model(record).Updates(bools).Error
+	}
+	return tx.Save(record).Error
+}
+
+func upsertService(tx *gorm.DB, id uint, spec ServiceSpec) (uint, error) {
+	service := models.Service{ID: id}
+	if id != 0 {
+		if err := tx.First(&service, id).Error; err != nil {
+			return 0, err
+		}
+		if err := tx.Where("service_id = ?", id).Delete(&models.ServiceServer{}).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	service.Name = spec.Name
+	service.LoadBalancerType = spec.LoadBalancerType
+	service.PassHostHeader = spec.PassHostHeader
+	service.IsActive = !spec.Disabled
+	service.HealthCheckEnabled = spec.HealthCheck != nil
+	service.HealthCheckPath = ""
+	service.HealthCheckInterval = 10
+	if spec.HealthCheck != nil {
+		service.HealthCheckPath = spec.HealthCheck.Path
+		service.HealthCheckInterval = spec.HealthCheck.Interval
+	}
+	service.Servers = nil
+
+	if err := save(tx, id, &service, map[string]interface{}{
+		"PassHostHeader": spec.PassHostHeader,
+		"IsActive":       !spec.Disabled,
+	}); err != nil {
+		return 0, err
+	}
+
+	for _, server := range spec.Servers {
+		if err := tx.Create(&models.ServiceServer{
+			ServiceID: service.ID,
+			URL:       server.URL,
+			Weight:    server.Weight,
+		}).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	return service.ID, nil
+}
+
+func upsertMiddleware(tx *gorm.DB, id uint, spec MiddlewareSpec) (uint, error) {
+	middleware := models.Middleware{ID: id}
+	if id != 0 {
+		if err := tx.First(&middleware, id).Error; err != nil {
+			return 0, err
+		}
+		// Exports are redacted, their redacted values are kept as stored
+		var stored models.MiddlewareConfig
+		if json.Unmarshal([]byte(middleware.Config), &stored) == nil {
+			spec.Config.RestoreRedacted(stored)
+		}
+	}
+
+	config, err := json.Marshal(spec.Config)
+	if err != nil {
+		return 0, err
+	}
+
+	middleware.Name = spec.Name
+	middleware.Type = spec.Type
+	middleware.Config = string(config)
+	middleware.IsActive = !spec.Disabled
+
+	if err := save(tx, id, &middleware, map[string]interface{}{
+		"IsActive": !spec.Disabled,
+	}); err != nil {
+		return 0, err
+	}
+	return middleware.ID, nil
+}
+
+func upsertRouter(tx *gorm.DB, id uint, spec RouterSpec, opts ImportOptions) (uint, error) {
+	router := models.Router{ID: id}
+	if id != 0 {
+		if err := tx.First(&router, id).Error; err != nil {
+			return 0, err
+		}
+		if err := tx.Where("router_id = ?", id).Delete(&models.RouterHostname{}).Error; err != nil {
+			return 0, err
+		}
+		if err := tx.Where("router_id = ?", id).Delete(&models.RouterMiddleware{}).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	var service models.Service
+	if err := tx.Where("name = ?", spec.Service).First(&service).Error; err != nil {
+		return 0, fmt.Errorf("service %q not found", spec.Service)
+	}
+
+	ownerID := opts.ActorID
+	if spec.Owner != "" {
+		var owner models.User
+		if err := tx.Where("email = ?", models.NormalizeEmail(spec.Owner)).First(&owner).Error; err != nil {
+			return 0, fmt.Errorf("owner %q not found", spec.Owner)
+		}
+		ownerID = owner.ID
+	}
+
+	router.Name = spec.Name
+	router.ServiceID = service.ID
+	router.UserID = ownerID
+	router.EntryPoints = strings.Join(spec.EntryPoints, ",")
+	router.RedirectHTTPS = spec.RedirectHTTPS
+	router.IsActive = !spec.Disabled
+	router.TLSEnabled = spec.TLS != nil
+	router.TLSCertResolver = "letsencrypt"
+	if spec.TLS != nil {
+		router.TLSCertResolver = spec.TLS.CertResolver
+	}
+	router.Hostnames = nil
+	router.Middlewares = nil
+
+	if err := save(tx, id, &router, map[string]interface{}{
+		"RedirectHTTPS": spec.RedirectHTTPS,
+		"IsActive":      !spec.Disabled,
+	}); err != nil {
+		return 0, err
+	}
+
+	for _, hostname := range spec.Hostnames {
+		if err := tx.Create(&models.RouterHostname{
+			RouterID: router.ID,
+			Hostname: strings.TrimSpace(hostname),
+		}).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	for i, name := range spec.Middlewares {
+		var middleware models.Middleware
+		if err := tx.Where("name = ?", name).First(&middleware).Error; err != nil {
+			return 0, fmt.Errorf("middleware %q not found", name)
+		}
+		if err := tx.Create(&models.RouterMiddleware{
+			RouterID:     router.ID,
+			MiddlewareID: middleware.ID,
+			Priority:     i,
+		}).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	return router.ID, nil
+}
+
+func upsertHTTPProvider(tx *gorm.DB, id uint, spec HTTPProviderSpec, opts ImportOptions) (uint, error) {
+	provider := models.HTTPProvider{ID: id}
+	var stored models.HTTPProvider
+	if id != 0 {
+		if err := tx.First(&provider, id).Error; err != nil {
+			return 0, err
+		}
+		stored = provider
+	}
+
+	setProviderSpec(&provider, spec)
+	// Exports are redacted, their redacted values are kept as stored
+	if provider.AuthSecret == secrets.Redacted {
+		provider.AuthSecret = stored.AuthSecret
+	}
+	if provider.TLSKey == secrets.Redacted {
+		provider.TLSKey = stored.TLSKey
+	}
+	headers := provider.GetHeaders()
+	for name, value := range headers {
+		if value == secrets.Redacted {
+			headers[name] = stored.GetHeaders()[name]
+		}
+	}
+	provider.SetHeaders(headers)
+
+	if opts.ValidateProvider != nil {
+		if err := opts.ValidateProvider(&provider); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := save(tx, id, &provider, map[string]interface{}{
+		"IsActive":         !spec.Disabled,
+		"StaleGracePeriod": provider.StaleGracePeriod,
+	}); err != nil {
+		return 0, err
+	}
+	return provider.ID, nil
+}
+
+// providerFromSpec returns the provider a spec describes
+func providerFromSpec(spec HTTPProviderSpec) models.HTTPProvider {
+	var provider models.HTTPProvider
+	setProviderSpec(&provider, spec)
+	return provider
+}
+
+// setProviderSpec sets the settings of a normalized spec on provider
+func setProviderSpec(provider *models.HTTPProvider, spec HTTPProviderSpec) {
+	provider.Name = spec.Name
+	provider.Type = spec.Type
+	if provider.Type == "" {
+		provider.Type = models.ProviderTypeHTTP
+	}
+	provider.URL = spec.URL
+	provider.Priority = spec.Priority
+	provider.RefreshInterval = spec.RefreshInterval
+	provider.Timeout = spec.Timeout
+	provider.FailurePolicy = spec.FailurePolicy
+	provider.StaleGracePeriod = *spec.StaleGracePeriod
+	provider.IsActive = !spec.Disabled
+
+	provider.NamespaceMode = models.NamespaceNone
+	provider.NamespacePrefix = ""
+	if spec.Namespace != nil {
+		provider.NamespaceMode = spec.Namespace.Mode
+		provider.NamespacePrefix = spec.Namespace.Prefix
+	}
+	provider.SetFilters(spec.Include, spec.Exclude)
+
+	provider.AuthType = models.ProviderAuthNone
+	provider.AuthUsername = ""
+	provider.AuthSecret = ""
+	if spec.Auth != nil {
+		provider.AuthType = spec.Auth.Type
+		provider.AuthUsername = spec.Auth.Username
+		provider.AuthSecret = spec.Auth.Secret
+	}
+	provider.SetHeaders(spec.Headers)
+
+	var tls ProviderTLSSpec
+	if spec.TLS != nil {
+		tls = *spec.TLS
+	}
+	provider.TLSCA = tls.CA
+	provider.TLSCert = tls.Cert
+	provider.TLSKey = tls.Key
+	provider.TLSInsecureSkipVerify = tls.InsecureSkipVerify
+}
+
+// hasRedactedSecrets reports whether a provider built from a spec still
+// holds redacted secrets
+func hasRedactedSecrets(provider *models.HTTPProvider) bool {
+	if provider.AuthSecret == secrets.Redacted || provider.TLSKey == secrets.Redacted {
+		return true
+	}
+	for _, value := range provider.GetHeaders() {
+		if value == secrets.Redacted {
+			return true
+		}
+	}
+	return false
+}
+
+// upsertUser creates users without credentials; they have to be invited or
+// have their password reset before they can sign in
+func upsertUser(tx *gorm.DB, id uint, spec UserSpec) (uint, error) {
+	user := models.User{ID: id}
+	if id != 0 {
+		if err := tx.First(&user, id).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	user.Email = models.NormalizeEmail(spec.Email)
+	user.Role = spec.Role
+	user.OIDCEnabled = spec.OIDCEnabled
+	user.IsActive = !spec.Disabled
+
+	if err := save(tx, id, &user, map[string]interface{}{
+		"IsActive":        !spec.Disabled,
+		"PasswordEnabled": user.Password != "",
+	}); err != nil {
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+func remove(tx *gorm.DB, change Change) error {
+	switch change.Kind {
+	case KindRouter:
+		if err := tx.Where("router_id = ?", change.ID).Delete(&models.RouterHostname{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("router_id = ?", change.ID).Delete(&models.RouterMiddleware{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Router{}, change.ID).Error
+	case KindMiddleware:
+		if err := tx.Where("middleware_id = ?", change.ID).Delete(&models.RouterMiddleware{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Middleware{}, change.ID).Error
+	case KindService:
+		var count int64
+		if err := tx.Model(&models.Router{}).Where("service_id = ?", change.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("still used by %d router(s)", count)
+		}
+		if err := tx.Where("service_id = ?", change.ID).Delete(&models.ServiceServer{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Service{}, change.ID).Error
+	case KindHTTPProvider:
+		if err := tx.Where("provider_id = ?", change.ID).Delete(&models.ProviderFetch{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("source = ? OR overridden_by = ?", change.Name, change.Name).Delete(&models.ConfigConflict{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.HTTPProvider{}, change.ID).Error
+	case KindUser:
+		if err := tx.Where("user_id = ?", change.ID).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, change.ID).Error
+	}
+	return nil
+}
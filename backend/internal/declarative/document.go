@@ -0,0 +1,222 @@
+package declarative
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/traefikx/backend/internal/models"
+	"sigs.k8s.io/yaml"
+)
+
+// DocumentVersion is the current version of the declarative document format
+const DocumentVersion = 1
+
+// Document is a declarative description of the TraefikX state that can be
+// versioned in git and imported again
+type Document struct {
+	Version       int                `json:"version"`
+	Services      []ServiceSpec      `json:"services,omitempty"`
+	Middlewares   []MiddlewareSpec   `json:"middlewares,omitempty"`
+	Routers       []RouterSpec       `json:"routers,omitempty"`
+	HTTPProviders []HTTPProviderSpec `json:"httpProviders,omitempty"`
+	Users         []UserSpec         `json:"users,omitempty"`
+}
+
+type ServiceSpec struct {
+	Name             string           `json:"name"`
+	Servers          []ServerSpec     `json:"servers"`
+	LoadBalancerType string           `json:"loadBalancerType,omitempty"`
+	PassHostHeader   bool             `json:"passHostHeader"`
+	HealthCheck      *HealthCheckSpec `json:"healthCheck,omitempty"`
+	Disabled         bool             `json:"disabled,omitempty"`
+}
+
+type ServerSpec struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight,omitempty"`
+}
+
+type HealthCheckSpec struct {
+	Path     string `json:"path"`
+	Interval int    `json:"interval,omitempty"` // seconds
+}
+
+type MiddlewareSpec struct {
+	Name     string                  `json:"name"`
+	Type     string                  `json:"type"`
+	Config   models.MiddlewareConfig `json:"config"`
+	Disabled bool                    `json:"disabled,omitempty"`
+}
+
+type RouterSpec struct {
+	Name          string   `json:"name"`
+	Hostnames     []string `json:"hostnames"`
+	Service       string   `json:"service"`
+	Middlewares   []string `json:"middlewares,omitempty"`
+	EntryPoints   []string `json:"entryPoints,omitempty"`
+	TLS           *TLSSpec `json:"tls,omitempty"`
+	RedirectHTTPS bool     `json:"redirectHttps,omitempty"`
+	Owner         string   `json:"owner,omitempty"` // Email of the owning user
+	Disabled      bool     `json:"disabled,omitempty"`
+}
+
+type TLSSpec struct {
+	CertResolver string `json:"certResolver,omitempty"`
+}
+
+// HTTPProviderSpec describes a provider. Secrets (the auth secret, the TLS
+// key and header values) are exported redacted; importing a redacted value
+// keeps the stored one.
+type HTTPProviderSpec struct {
+	Name             string            `json:"name"`
+	Type             string            `json:"type,omitempty"` // docker, file or consul; http when empty
+	URL              string            `json:"url"`
+	Priority         int               `json:"priority,omitempty"`
+	RefreshInterval  int               `json:"refreshInterval,omitempty"`  // seconds
+	Timeout          int               `json:"timeout,omitempty"`          // seconds
+	FailurePolicy    string            `json:"failurePolicy,omitempty"`    // keep or drop
+	StaleGracePeriod *int              `json:"staleGracePeriod,omitempty"` // seconds, 0 = no limit
+	Namespace        *NamespaceSpec    `json:"namespace,omitempty"`
+	Include          []string          `json:"include,omitempty"` // Router filters
+	Exclude          []string          `json:"exclude,omitempty"`
+	Auth             *ProviderAuthSpec `json:"auth,omitempty"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	TLS              *ProviderTLSSpec  `json:"tls,omitempty"`
+	Disabled         bool              `json:"disabled,omitempty"`
+}
+
+type NamespaceSpec struct {
+	Mode   string `json:"mode"` // suffix or prefix
+	Prefix string `json:"prefix,omitempty"`
+}
+
+type ProviderAuthSpec struct {
+	Type     string `json:"type"` // bearer or basic
+	Username string `json:"username,omitempty"`
+	Secret   string `json:"secret,omitempty"`
+}
+
+type ProviderTLSSpec struct {
+	CA                 string `json:"ca,omitempty"`
+	Cert               string `json:"cert,omitempty"`
+	Key                string `json:"key,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+}
+
+// UserSpec describes a user account. Credentials are never exported; imported
+// users have to be invited or have their password reset.
+type UserSpec struct {
+	Email       string          `json:"email"`
+	Role        models.UserRole `json:"role"`
+	OIDCEnabled bool            `json:"oidcEnabled,omitempty"`
+	Disabled    bool            `json:"disabled,omitempty"`
+}
+
+// Supported document formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// NormalizeFormat maps a format name, file extension or content type to a
+// supported format
+func NormalizeFormat(format string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch {
+	case format == "" || format == "json" || strings.Contains(format, "json"):
+		return FormatJSON, nil
+	case format == "yaml" || format == "yml" || strings.Contains(format, "yaml"):
+		return FormatYAML, nil
+	case format == "toml" || strings.Contains(format, "toml"):
+		return FormatTOML, nil
+	default:
+		return "", fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// ContentType returns the MIME type for a format
+func ContentType(format string) string {
+	switch format {
+	case FormatYAML:
+		return "application/yaml"
+	case FormatTOML:
+		return "application/toml"
+	default:
+		return "application/json"
+	}
+}
+
+// Marshal encodes v in the given format. JSON tags are used for all formats.
+func Marshal(v interface{}, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatYAML:
+		return yaml.JSONToYAML(data)
+	case FormatTOML:
+		var generic map[string]interface{}
+		if err := json.Unmarshal(data, &generic); err != nil {
+			return nil, err
+		}
+		return toml.Marshal(tomlValues(generic))
+	default:
+		return data, nil
+	}
+}
+
+// tomlValues prepares decoded JSON for TOML: whole numbers become integers
+// so 1 isn't written as 1.0, and nulls are dropped since TOML has none
+func tomlValues(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			if item == nil {
+				delete(value, k)
+				continue
+			}
+			value[k] = tomlValues(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = tomlValues(item)
+		}
+	case float64:
+		if value == float64(int64(value)) {
+			return int64(value)
+		}
+	}
+	return v
+}
+
+// Unmarshal decodes data in the given format into v
+func Unmarshal(data []byte, format string, v interface{}) error {
+	switch format {
+	case FormatYAML:
+		converted, err := yaml.YAMLToJSON(data)
+		if err != nil {
+			return fmt.Errorf("invalid YAML: %w", err)
+		}
+		data = converted
+	case FormatTOML:
+		var generic map[string]interface{}
+		if err := toml.Unmarshal(data, &generic); err != nil {
+			return fmt.Errorf("invalid TOML: %w", err)
+		}
+		converted, err := json.Marshal(generic)
+		if err != nil {
+			return err
+		}
+		data = converted
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid document: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package declarative
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/traefikx/backend/internal/models"
+	"github.com/traefikx/backend/internal/secrets"
+	"gorm.io/gorm"
+)
+
+// Export builds a document from the current database state. Entries are
+// sorted by name so the output diffs cleanly in git.
+func Export(db *gorm.DB, includeUsers bool) (*Document, error) {
+	doc := &Document{Version: DocumentVersion}
+
+	var services []models.Service
+	if err := db.Preload("Servers").Order("name").Find(&services).Error; err != nil {
+		return nil, err
+	}
+	for i := range services {
+		doc.Services = append(doc.Services, serviceToSpec(&services[i]))
+	}
+
+	var middlewares []models.Middleware
+	if err := db.Order("name").Find(&middlewares).Error; err != nil {
+		return nil, err
+	}
+	for i := range middlewares {
+		doc.Middlewares = append(doc.Middlewares, middlewareToSpec(&middlewares[i]))
+	}
+
+	var users []models.User
+	if err := db.Order("email").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	userEmails := make(map[uint]string, len(users))
+	for _, user := range users {
+		userEmails[user.ID] = user.Email
+	}
+
+	var routers []models.Router
+	if err := db.Preload("Hostnames").
+		Preload("Service").
+		Preload("Middlewares.Middleware").
+		Order("name").
+		Find(&routers).Error; err != nil {
+		return nil, err
+	}
+	for i := range routers {
+		doc.Routers = append(doc.Routers, routerToSpec(&routers[i], userEmails))
+	}
+
+	var providers []models.HTTPProvider
+	if err := db.Order("name").Find(&providers).Error; err != nil {
+		return nil, err
+	}
+	for i := range providers {
+		doc.HTTPProviders = append(doc.HTTPProviders, httpProviderToSpec(&providers[i]))
+	}
+
+	if includeUsers {
+		for i := range users {
+			doc.Users = append(doc.Users, userToSpec(&users[i]))
+		}
+	}
+
+	return doc, nil
+}
+
+func serviceToSpec(service *models.Service) ServiceSpec {
+	spec := ServiceSpec{
+		Name:             service.Name,
+		Servers:          make([]ServerSpec, len(service.Servers)),
+		LoadBalancerType: service.LoadBalancerType,
+		PassHostHeader:   service.PassHostHeader,
+		Disabled:         !service.IsActive,
+	}
+	for i, server := range service.Servers {
+		spec.Servers[i] = ServerSpec{URL: server.URL, Weight: server.Weight}
+	}
+	if service.HealthCheckEnabled {
+		spec.HealthCheck = &HealthCheckSpec{
+			Path:     service.HealthCheckPath,
+			Interval: service.HealthCheckInterval,
+		}
+	}
+	return spec
+}
+
+func middlewareToSpec(middleware *models.Middleware) MiddlewareSpec {
+	spec := MiddlewareSpec{
+		Name:     middleware.Name,
+		Type:     middleware.Type,
+		Disabled: !middleware.IsActive,
+	}
+	// Invalid stored config exports as empty config
+	json.Unmarshal([]byte(middleware.Config), &spec.Config)
+	spec.Config.Redact()
+	return spec
+}
+
+func routerToSpec(router *models.Router, userEmails map[uint]string) RouterSpec {
+	spec := RouterSpec{
+		Name:          router.Name,
+		Hostnames:     make([]string, len(router.Hostnames)),
+		Service:       router.Service.Name,
+		EntryPoints:   splitAndTrim(router.EntryPoints),
+		RedirectHTTPS: router.RedirectHTTPS,
+		Owner:         userEmails[router.UserID],
+		Disabled:      !router.IsActive,
+	}
+	for i, hostname := range router.Hostnames {
+		spec.Hostnames[i] = hostname.Hostname
+	}
+
+	// Keep middleware execution order
+	routerMiddlewares := append([]models.RouterMiddleware(nil), router.Middlewares...)
+	sort.SliceStable(routerMiddlewares, func(i, j int) bool {
+		return routerMiddlewares[i].Priority < routerMiddlewares[j].Priority
+	})
+	for _, rm := range routerMiddlewares {
+		spec.Middlewares = append(spec.Middlewares, rm.Middleware.Name)
+	}
+
+	if router.TLSEnabled {
+		spec.TLS = &TLSSpec{CertResolver: router.TLSCertResolver}
+	}
+	return spec
+}
+
+func httpProviderToSpec(provider *models.HTTPProvider) HTTPProviderSpec {
+	staleGracePeriod := provider.StaleGracePeriod
+	spec := HTTPProviderSpec{
+		Name:             provider.Name,
+		Type:             provider.Type,
+		URL:              provider.URL,
+		Priority:         provider.Priority,
+		RefreshInterval:  provider.RefreshInterval,
+		Timeout:          provider.Timeout,
+		FailurePolicy:    provider.FailurePolicy,
+		StaleGracePeriod: &staleGracePeriod,
+		Namespace: &NamespaceSpec{
+			Mode:   provider.NamespaceMode,
+			Prefix: provider.NamespacePrefix,
+		},
+		Include: provider.GetIncludeFilters(),
+		Exclude: provider.GetExcludeFilters(),
+		Auth: &ProviderAuthSpec{
+			Type:     provider.AuthType,
+			Username: provider.AuthUsername,
+			Secret:   redact(provider.AuthSecret),
+		},
+		Headers: provider.GetHeaders(),
+		TLS: &ProviderTLSSpec{
+			CA:                 provider.TLSCA,
+			Cert:               provider.TLSCert,
+			Key:                redact(provider.TLSKey),
+			InsecureSkipVerify: provider.TLSInsecureSkipVerify,
+		},
+		Disabled: !provider.IsActive,
+	}
+	// Header values may hold credentials
+	for name, value := range spec.Headers {
+		spec.Headers[name] = redact(value)
+	}
+	normalizeHTTPProvider(&spec)
+	return spec
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return secrets.Redacted
+}
+
+func userToSpec(user *models.User) UserSpec {
+	return UserSpec{
+		Email:       user.Email,
+		Role:        user.Role,
+		OIDCEnabled: user.OIDCEnabled,
+		Disabled:    !user.IsActive,
+	}
+}
@@ -0,0 +1,632 @@
+package declarative
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/traefikx/backend/internal/models"
+	"github.com/traefikx/backend/internal/secrets"
+	"gorm.io/gorm"
+)
+
+// Import modes
+const (
+	ModeMerge   = "merge"   // Create and update resources from the document, keep everything else
+	ModeReplace = "replace" // Also delete resources that are not in the document
+)
+
+// Conflict policies for resources that already exist with a different definition
+const (
+	OnConflictOverwrite = "overwrite"
+	OnConflictSkip      = "skip"
+)
+
+// Resource kinds
+const (
+	KindService      = "service"
+	KindMiddleware   = "middleware"
+	KindRouter       = "router"
+	KindHTTPProvider = "httpProvider"
+	KindUser         = "user"
+)
+
+// Change actions
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionDelete    = "delete"
+	ActionUnchanged = "unchanged"
+	ActionSkip      = "skip"
+)
+
+// ImportOptions control how a document is applied
+type ImportOptions struct {
+	Mode       string
+	OnConflict string
+	DryRun     bool
+	ActorID    uint // User performing the import; owns routers without owner and is never deleted
+
+	// ValidateProvider checks provider settings beyond their values, such
+	// as filter patterns and TLS certificates. Optional.
+	ValidateProvider func(*models.HTTPProvider) error
+}
+
+// Change describes what happens to a single resource
+type Change struct {
+	Kind   string      `json:"kind"`
+	Name   string      `json:"name"`
+	Action string      `json:"action"`
+	ID     uint        `json:"id,omitempty"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// Conflict is a problem that prevents (part of) a document from being applied
+type Conflict struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// ImportResult is the plan for a document and, unless it was a dry run,
+// whether it has been applied
+type ImportResult struct {
+	Mode      string         `json:"mode"`
+	DryRun    bool           `json:"dry_run"`
+	Applied   bool           `json:"applied"`
+	Changes   []Change       `json:"changes"`
+	Conflicts []Conflict     `json:"conflicts"`
+	Summary   map[string]int `json:"summary"`
+
+	// Unsupported lists constructs that were skipped or dropped while
+	// converting a Traefik configuration. They never block an import.
+	Unsupported []Conflict `json:"unsupported,omitempty"`
+}
+
+// HasBlockingConflicts reports whether conflicts prevent the import. Skipped
+// resources are reported as conflicts but don't block.
+func (r *ImportResult) HasBlockingConflicts() bool {
+	skipped := make(map[string]bool)
+	for _, change := range r.Changes {
+		if change.Action == ActionSkip {
+			skipped[change.Kind+"/"+change.Name] = true
+		}
+	}
+	for _, conflict := range r.Conflicts {
+		if !skipped[conflict.Kind+"/"+conflict.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+var validMiddlewareTypes = map[string]bool{
+	"redirectScheme": true,
+	"headers":        true,
+	"stripPrefix":    true,
+	"addPrefix":      true,
+}
+
+// Plan compares doc against the database and returns the changes an import
+// would make, together with any conflicts
+func Plan(db *gorm.DB, doc *Document, opts ImportOptions) (*ImportResult, error) {
+	if opts.Mode == "" {
+		opts.Mode = ModeMerge
+	}
+	if opts.OnConflict == "" {
+		opts.OnConflict = OnConflictOverwrite
+	}
+
+	result := &ImportResult{
+		Mode:      opts.Mode,
+		DryRun:    opts.DryRun,
+		Changes:   []Change{},
+		Conflicts: []Conflict{},
+		Summary:   make(map[string]int),
+	}
+
+	if doc.Version > DocumentVersion {
+		result.Conflicts = append(result.Conflicts, Conflict{
+			Kind:   "document",
+			Reason: fmt.Sprintf("unsupported document version %d (max %d)", doc.Version, DocumentVersion),
+		})
+		return result, nil
+	}
+
+	normalize(doc)
+	if err := redactStoredSecrets(db, doc); err != nil {
+		return nil, err
+	}
+
+	current, err := Export(db, true)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := loadIDs(db)
+	if err != nil {
+		return nil, err
+	}
+
+	validate(doc, result, opts)
+
+	// Services
+	planKind(result, opts, KindService, ids[KindService],
+		specsByName(current.Services, func(s ServiceSpec) string { return s.Name }),
+		specsByName(doc.Services, func(s ServiceSpec) string { return s.Name }))
+
+	// Middlewares
+	planKind(result, opts, KindMiddleware, ids[KindMiddleware],
+		specsByName(current.Middlewares, func(m MiddlewareSpec) string { return m.Name }),
+		specsByName(doc.Middlewares, func(m MiddlewareSpec) string { return m.Name }))
+
+	// Routers - routers without owner keep their current owner
+	currentRouters := specsByName(current.Routers, func(r RouterSpec) string { return r.Name })
+	for i := range doc.Routers {
+		if doc.Routers[i].Owner == "" {
+			if existing, ok := currentRouters[doc.Routers[i].Name]; ok {
+				doc.Routers[i].Owner = existing.(RouterSpec).Owner
+			}
+		}
+	}
+	planKind(result, opts, KindRouter, ids[KindRouter],
+		currentRouters,
+		specsByName(doc.Routers, func(r RouterSpec) string { return r.Name }))
+
+	// HTTP providers
+	planKind(result, opts, KindHTTPProvider, ids[KindHTTPProvider],
+		specsByName(current.HTTPProviders, func(p HTTPProviderSpec) string { return p.Name }),
+		specsByName(doc.HTTPProviders, func(p HTTPProviderSpec) string { return p.Name }))
+
+	// Users are only managed when the document lists them
+	if doc.Users != nil {
+		planKind(result, opts, KindUser, ids[KindUser],
+			specsByName(current.Users, func(u UserSpec) string { return models.NormalizeEmail(u.Email) }),
+			specsByName(doc.Users, func(u UserSpec) string { return models.NormalizeEmail(u.Email) }))
+
+		for i, change := range result.Changes {
+			if change.Kind == KindUser && change.Action == ActionDelete && change.ID == opts.ActorID {
+				result.Changes[i].Action = ActionSkip
+				result.Conflicts = append(result.Conflicts, Conflict{
+					Kind:   KindUser,
+					Name:   change.Name,
+					Reason: "the importing user cannot be deleted; it was kept",
+				})
+			}
+		}
+	}
+
+	checkReferences(doc, current, result, opts)
+	checkRedactedSecrets(doc, result)
+
+	for _, change := range result.Changes {
+		result.Summary[change.Action]++
+	}
+
+	return result, nil
+}
+
+// normalize fills in the defaults used by the REST handlers so that
+// documents compare equal to exported state
+func normalize(doc *Document) {
+	for i := range doc.Services {
+		s := &doc.Services[i]
+		if s.LoadBalancerType == "" {
+			s.LoadBalancerType = "wrr"
+		}
+		for j := range s.Servers {
+			if s.Servers[j].Weight == 0 {
+				s.Servers[j].Weight = 1
+			}
+		}
+		if s.HealthCheck != nil && s.HealthCheck.Interval == 0 {
+			s.HealthCheck.Interval = 10
+		}
+	}
+	for i := range doc.Routers {
+		r := &doc.Routers[i]
+		if len(r.EntryPoints) == 0 {
+			r.EntryPoints = []string{"web", "websecure"}
+		}
+		if r.TLS != nil && r.TLS.CertResolver == "" {
+			r.TLS.CertResolver = "letsencrypt"
+		}
+	}
+	for i := range doc.HTTPProviders {
+		normalizeHTTPProvider(&doc.HTTPProviders[i])
+	}
+	for i := range doc.Users {
+		if doc.Users[i].Role == "" {
+			doc.Users[i].Role = models.RoleUser
+		}
+	}
+}
+
+// normalizeHTTPProvider fills in the defaults of a provider and drops empty
+// settings, so exports and documents compare equal
+func normalizeHTTPProvider(p *HTTPProviderSpec) {
+	if p.Type == models.ProviderTypeHTTP {
+		p.Type = ""
+	}
+	if p.RefreshInterval < 5 {
+		p.RefreshInterval = 30
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = 5
+	}
+	if p.FailurePolicy == "" {
+		p.FailurePolicy = models.ProviderFailureKeep
+	}
+	if p.StaleGracePeriod == nil {
+		staleGracePeriod := 300
+		p.StaleGracePeriod = &staleGracePeriod
+	}
+	if p.Namespace != nil && (p.Namespace.Mode == "" || p.Namespace.Mode == models.NamespaceNone) {
+		p.Namespace = nil
+	}
+	if len(p.Include) == 0 {
+		p.Include = nil
+	}
+	if len(p.Exclude) == 0 {
+		p.Exclude = nil
+	}
+	if p.Auth != nil && (p.Auth.Type == "" || p.Auth.Type == models.ProviderAuthNone) {
+		p.Auth = nil
+	}
+	if len(p.Headers) == 0 {
+		p.Headers = nil
+	}
+	if p.TLS != nil && *p.TLS == (ProviderTLSSpec{}) {
+		p.TLS = nil
+	}
+}
+
+// redactStoredSecrets redacts the secrets in doc that equal the stored ones,
+// so a document holding them compares equal to the redacted export.
+// Applying a redacted value keeps the stored one, the import is the same.
+func redactStoredSecrets(db *gorm.DB, doc *Document) error {
+	var providers []models.HTTPProvider
+	if err := db.Find(&providers).Error; err != nil {
+		return err
+	}
+	storedProviders := make(map[string]*models.HTTPProvider, len(providers))
+	for i := range providers {
+		storedProviders[providers[i].Name] = &providers[i]
+	}
+	for i := range doc.HTTPProviders {
+		p := &doc.HTTPProviders[i]
+		stored, ok := storedProviders[p.Name]
+		if !ok {
+			continue
+		}
+		if p.Auth != nil && p.Auth.Secret != "" && p.Auth.Secret == stored.AuthSecret {
+			p.Auth.Secret = secrets.Redacted
+		}
+		if p.TLS != nil && p.TLS.Key != "" && p.TLS.Key == stored.TLSKey {
+			p.TLS.Key = secrets.Redacted
+		}
+		storedHeaders := stored.GetHeaders()
+		for name, value := range p.Headers {
+			if value != "" && value == storedHeaders[name] {
+				p.Headers[name] = secrets.Redacted
+			}
+		}
+	}
+
+	var middlewares []models.Middleware
+	if err := db.Find(&middlewares).Error; err != nil {
+		return err
+	}
+	storedConfigs := make(map[string]models.MiddlewareConfig, len(middlewares))
+	for _, m := range middlewares {
+		var config models.MiddlewareConfig
+		if json.Unmarshal([]byte(m.Config), &config) == nil {
+			storedConfigs[m.Name] = config
+		}
+	}
+	for i := range doc.Middlewares {
+		m := &doc.Middlewares[i]
+		stored, ok := storedConfigs[m.Name]
+		if !ok {
+			continue
+		}
+		redactStoredHeaders(m.Config.CustomRequestHeaders, stored.CustomRequestHeaders)
+		redactStoredHeaders(m.Config.CustomResponseHeaders, stored.CustomResponseHeaders)
+	}
+	return nil
+}
+
+// redactStoredHeaders redacts the sensitive headers equal to the stored ones
+func redactStoredHeaders(headers, stored map[string]string) {
+	for name, value := range headers {
+		if value != "" && value == stored[name] && secrets.IsSensitiveName(name) {
+			headers[name] = secrets.Redacted
+		}
+	}
+}
+
+// validate reports duplicate names and invalid definitions
+func validate(doc *Document, result *ImportResult, opts ImportOptions) {
+	conflict := func(kind, name, reason string) {
+		result.Conflicts = append(result.Conflicts, Conflict{Kind: kind, Name: name, Reason: reason})
+	}
+
+	seen := make(map[string]bool)
+	duplicate := func(kind, name string) bool {
+		key := kind + "/" + name
+		if name == "" {
+			conflict(kind, name, "name is required")
+			return true
+		}
+		if seen[key] {
+			conflict(kind, name, "defined more than once")
+			return true
+		}
+		seen[key] = true
+		return false
+	}
+
+	for _, s := range doc.Services {
+		if duplicate(KindService, s.Name) {
+			continue
+		}
+		if len(s.Servers) == 0 {
+			conflict(KindService, s.Name, "at least one server is required")
+		}
+		for _, server := range s.Servers {
+			if !strings.HasPrefix(server.URL, "http://") && !strings.HasPrefix(server.URL, "https://") {
+				conflict(KindService, s.Name, "invalid server URL: "+server.URL)
+			}
+		}
+		if s.LoadBalancerType != "wrr" && s.LoadBalancerType != "drr" {
+			conflict(KindService, s.Name, "invalid load balancer type: "+s.LoadBalancerType)
+		}
+	}
+
+	for _, m := range doc.Middlewares {
+		if duplicate(KindMiddleware, m.Name) {
+			continue
+		}
+		if !validMiddlewareTypes[m.Type] {
+			conflict(KindMiddleware, m.Name, "unsupported middleware type: "+m.Type)
+		}
+	}
+
+	for _, r := range doc.Routers {
+		if duplicate(KindRouter, r.Name) {
+			continue
+		}
+		if len(r.Hostnames) == 0 {
+			conflict(KindRouter, r.Name, "at least one hostname is required")
+		}
+		for _, hostname := range r.Hostnames {
+			if hostname == "" || strings.Contains(hostname, " ") {
+				conflict(KindRouter, r.Name, "invalid hostname: "+hostname)
+			}
+		}
+		if r.Service == "" {
+			conflict(KindRouter, r.Name, "service is required")
+		}
+	}
+
+	for _, p := range doc.HTTPProviders {
+		if duplicate(KindHTTPProvider, p.Name) {
+			continue
+		}
+		provider := providerFromSpec(p)
+		if err := provider.ValidateURL(); err != nil {
+			conflict(KindHTTPProvider, p.Name, err.Error())
+		}
+		if p.FailurePolicy != models.ProviderFailureKeep && p.FailurePolicy != models.ProviderFailureDrop {
+			conflict(KindHTTPProvider, p.Name, "invalid failure policy: "+p.FailurePolicy)
+		}
+		if *p.StaleGracePeriod < 0 {
+			conflict(KindHTTPProvider, p.Name, "staleGracePeriod can't be negative")
+		}
+		if p.Namespace != nil && p.Namespace.Mode != models.NamespaceSuffix && p.Namespace.Mode != models.NamespacePrefix {
+			conflict(KindHTTPProvider, p.Name, "invalid namespace mode: "+p.Namespace.Mode)
+		}
+		if p.Auth != nil && p.Auth.Type != models.ProviderAuthBearer && p.Auth.Type != models.ProviderAuthBasic {
+			conflict(KindHTTPProvider, p.Name, "invalid auth type: "+p.Auth.Type)
+		}
+		// Redacted secrets are checked once restored, when applying
+		if opts.ValidateProvider != nil && !hasRedactedSecrets(&provider) {
+			if err := opts.ValidateProvider(&provider); err != nil {
+				conflict(KindHTTPProvider, p.Name, err.Error())
+			}
+		}
+	}
+
+	for _, u := range doc.Users {
+		if duplicate(KindUser, models.NormalizeEmail(u.Email)) {
+			continue
+		}
+		if u.Role != models.RoleAdmin && u.Role != models.RoleUser {
+			conflict(KindUser, u.Email, "invalid role: "+string(u.Role))
+		}
+	}
+}
+
+// checkReferences makes sure routers only point at services, middlewares and
+// owners that will exist after the import
+func checkReferences(doc *Document, current *Document, result *ImportResult, opts ImportOptions) {
+	services := make(map[string]bool)
+	middlewares := make(map[string]bool)
+	users := make(map[string]bool)
+
+	if opts.Mode == ModeMerge {
+		for _, s := range current.Services {
+			services[s.Name] = true
+		}
+		for _, m := range current.Middlewares {
+			middlewares[m.Name] = true
+		}
+	}
+	if opts.Mode == ModeMerge || doc.Users == nil {
+		for _, u := range current.Users {
+			users[models.NormalizeEmail(u.Email)] = true
+		}
+	}
+	for _, s := range doc.Services {
+		services[s.Name] = true
+	}
+	for _, m := range doc.Middlewares {
+		middlewares[m.Name] = true
+	}
+	for _, u := range doc.Users {
+		users[models.NormalizeEmail(u.Email)] = true
+	}
+
+	for _, r := range doc.Routers {
+		if r.Service != "" && !services[r.Service] {
+			result.Conflicts = append(result.Conflicts, Conflict{
+				Kind: KindRouter, Name: r.Name, Reason: "unknown service: " + r.Service,
+			})
+		}
+		for _, m := range r.Middlewares {
+			if !middlewares[m] {
+				result.Conflicts = append(result.Conflicts, Conflict{
+					Kind: KindRouter, Name: r.Name, Reason: "unknown middleware: " + m,
+				})
+			}
+		}
+		if r.Owner != "" && !users[models.NormalizeEmail(r.Owner)] {
+			result.Conflicts = append(result.Conflicts, Conflict{
+				Kind: KindRouter, Name: r.Name, Reason: "unknown owner: " + r.Owner,
+			})
+		}
+	}
+}
+
+// checkRedactedSecrets reports providers to be created with redacted
+// secrets, there is no stored value to keep
+func checkRedactedSecrets(doc *Document, result *ImportResult) {
+	providers := specsByName(doc.HTTPProviders, func(p HTTPProviderSpec) string { return p.Name })
+	for _, change := range result.Changes {
+		if change.Kind != KindHTTPProvider || change.Action != ActionCreate {
+			continue
+		}
+		provider := providerFromSpec(providers[change.Name].(HTTPProviderSpec))
+		if hasRedactedSecrets(&provider) {
+			result.Conflicts = append(result.Conflicts, Conflict{
+				Kind: KindHTTPProvider, Name: change.Name, Reason: "redacted secrets can only be kept for existing providers",
+			})
+		}
+	}
+}
+
+// planKind diffs the current and desired specs of one resource kind
+func planKind(result *ImportResult, opts ImportOptions, kind string, ids map[string]uint, current, desired map[string]interface{}) {
+	for _, name := range sortedKeys(desired) {
+		want := desired[name]
+		have, exists := current[name]
+
+		change := Change{Kind: kind, Name: name, ID: ids[name]}
+		switch {
+		case !exists:
+			change.Action = ActionCreate
+			change.After = want
+		case reflect.DeepEqual(have, want):
+			change.Action = ActionUnchanged
+		case opts.OnConflict == OnConflictSkip:
+			change.Action = ActionSkip
+			change.Before = have
+			change.After = want
+			result.Conflicts = append(result.Conflicts, Conflict{
+				Kind: kind, Name: name, Reason: "already exists with a different definition",
+			})
+		default:
+			change.Action = ActionUpdate
+			change.Before = have
+			change.After = want
+		}
+		result.Changes = append(result.Changes, change)
+	}
+
+	if opts.Mode != ModeReplace {
+		return
+	}
+
+	for _, name := range sortedKeys(current) {
+		if _, keep := desired[name]; keep {
+			continue
+		}
+		result.Changes = append(result.Changes, Change{
+			Kind:   kind,
+			Name:   name,
+			ID:     ids[name],
+			Action: ActionDelete,
+			Before: current[name],
+		})
+	}
+}
+
+func specsByName[T any](specs []T, name func(T) string) map[string]interface{} {
+	byName := make(map[string]interface{}, len(specs))
+	for _, spec := range specs {
+		byName[name(spec)] = spec
+	}
+	return byName
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// loadIDs maps resource names to their database IDs
+func loadIDs(db *gorm.DB) (map[string]map[string]uint, error) {
+	type row struct {
+		ID   uint
+		Name string
+	}
+
+	ids := make(map[string]map[string]uint)
+	load := func(kind string, model interface{}, nameColumn string) error {
+		var rows []row
+		if err := db.Model(model).Select("id, " + nameColumn + " AS name").Scan(&rows).Error; err != nil {
+			return err
+		}
+		ids[kind] = make(map[string]uint, len(rows))
+		for _, r := range rows {
+			ids[kind][r.Name] = r.ID
+		}
+		return nil
+	}
+
+	if err := load(KindService, &models.Service{}, "name"); err != nil {
+		return nil, err
+	}
+	if err := load(KindMiddleware, &models.Middleware{}, "name"); err != nil {
+		return nil, err
+	}
+	if err := load(KindRouter, &models.Router{}, "name"); err != nil {
+		return nil, err
+	}
+	if err := load(KindHTTPProvider, &models.HTTPProvider{}, "name"); err != nil {
+		return nil, err
+	}
+	if err := load(KindUser, &models.User{}, "email"); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func splitAndTrim(s string) []string {
+	if s == "" {
+		return []string{"web", "websecure"}
+	}
+	parts := []string{}
+	for _, p := range strings.Split(s, ",") {
+		if trimmed := strings.TrimSpace(p); trimmed != "" {
+			parts = append(parts, trimmed)
+		}
+	}
+	return parts
+}
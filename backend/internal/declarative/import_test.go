@@ -0,0 +1,439 @@
+package declarative
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/traefikx/backend/internal/database"
+	"github.com/traefikx/backend/internal/models"
+	"github.com/traefikx/backend/internal/secrets"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB returns a migrated SQLite database in a temporary directory
+// with an admin performing the imports
+func newTestDB(t *testing.T) (*gorm.DB, *models.User) {
+	t.Helper()
+	if err := secrets.Init("declarative-test-key"); err != nil {
+		t.Fatalf("secrets: %v", err)
+	}
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	admin := &models.User{Email: "admin@example.com", Role: models.RoleAdmin, IsActive: true}
+	if err := db.Create(admin).Error; err != nil {
+		t.Fatalf("create admin: %v", err)
+	}
+	return db, admin
+}
+
+// baseDocument has a resource of every kind, with secrets that export
+// redacted
+func baseDocument() *Document {
+	return &Document{
+		Version: DocumentVersion,
+		Services: []ServiceSpec{
+			{Name: "api", Servers: []ServerSpec{{URL: "http://api:8080"}}, PassHostHeader: true},
+			{Name: "web", Servers: []ServerSpec{{URL: "http://web:8080"}}, PassHostHeader: true},
+		},
+		Middlewares: []MiddlewareSpec{{
+			Name:   "upstream-auth",
+			Type:   "headers",
+			Config: models.MiddlewareConfig{CustomRequestHeaders: map[string]string{"Authorization": "Bearer upstream-token", "X-Team": "core"}},
+		}},
+		Routers: []RouterSpec{
+			{Name: "api", Hostnames: []string{"api.example.com"}, Service: "api", Middlewares: []string{"upstream-auth"}},
+			{Name: "web", Hostnames: []string{"www.example.com"}, Service: "web"},
+		},
+		HTTPProviders: []HTTPProviderSpec{{
+			Name:    "upstream",
+			URL:     "https://config.example.com/traefik",
+			Auth:    &ProviderAuthSpec{Type: models.ProviderAuthBearer, Secret: "provider-token"},
+			Headers: map[string]string{"X-Api-Key": "header-key", "X-Team": "core"},
+		}},
+	}
+}
+
+// importDocument plans doc and applies it unless conflicts block it
+func importDocument(t *testing.T, db *gorm.DB, doc *Document, opts ImportOptions) *ImportResult {
+	t.Helper()
+	result, err := Plan(db, doc, opts)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if result.HasBlockingConflicts() {
+		return result
+	}
+	if err := Apply(db, doc, result, opts); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	return result
+}
+
+// actions maps kind/name to the planned action
+func actions(result *ImportResult) map[string]string {
+	byName := make(map[string]string, len(result.Changes))
+	for _, change := range result.Changes {
+		byName[change.Kind+"/"+change.Name] = change.Action
+	}
+	return byName
+}
+
+// names returns the names of the services and routers in the database
+func names(t *testing.T, db *gorm.DB) string {
+	t.Helper()
+	doc, err := Export(db, false)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	var all []string
+	for _, s := range doc.Services {
+		all = append(all, "service/"+s.Name)
+	}
+	for _, r := range doc.Routers {
+		all = append(all, "router/"+r.Name)
+	}
+	return strings.Join(all, ",")
+}
+
+func TestImport_Modes(t *testing.T) {
+	// The api service moves to another server, a worker service is added
+	// and web is left out
+	edited := func() *Document {
+		doc := baseDocument()
+		doc.Services = []ServiceSpec{
+			{Name: "api", Servers: []ServerSpec{{URL: "http://api-v2:8080"}}, PassHostHeader: true},
+			{Name: "worker", Servers: []ServerSpec{{URL: "http://worker:8080"}}, PassHostHeader: true},
+		}
+		doc.Routers = doc.Routers[:1]
+		return doc
+	}
+
+	tests := []struct {
+		name       string
+		opts       ImportOptions
+		actions    map[string]string
+		conflicts  []string
+		names      string
+		apiServers string
+	}{
+		{
+			name: "merge overwrites",
+			opts: ImportOptions{Mode: ModeMerge, OnConflict: OnConflictOverwrite},
+			actions: map[string]string{
+				"service/api":              ActionUpdate,
+				"service/worker":           ActionCreate,
+				"router/api":               ActionUnchanged,
+				"middleware/upstream-auth": ActionUnchanged,
+				"httpProvider/upstream":    ActionUnchanged,
+			},
+			names:      "service/api,service/web,service/worker,router/api,router/web",
+			apiServers: "http://api-v2:8080",
+		},
+		{
+			name: "merge skips",
+			opts: ImportOptions{Mode: ModeMerge, OnConflict: OnConflictSkip},
+			actions: map[string]string{
+				"service/api":    ActionSkip,
+				"service/worker": ActionCreate,
+				"router/api":     ActionUnchanged,
+			},
+			conflicts:  []string{"service/api: already exists with a different definition"},
+			names:      "service/api,service/web,service/worker,router/api,router/web",
+			apiServers: "http://api:8080",
+		},
+		{
+			name: "replace overwrites",
+			opts: ImportOptions{Mode: ModeReplace, OnConflict: OnConflictOverwrite},
+			actions: map[string]string{
+				"service/api":    ActionUpdate,
+				"service/worker": ActionCreate,
+				"service/web":    ActionDelete,
+				"router/web":     ActionDelete,
+				"router/api":     ActionUnchanged,
+			},
+			names:      "service/api,service/worker,router/api",
+			apiServers: "http://api-v2:8080",
+		},
+		{
+			name: "replace skips",
+			opts: ImportOptions{Mode: ModeReplace, OnConflict: OnConflictSkip},
+			actions: map[string]string{
+				"service/api":    ActionSkip,
+				"service/worker": ActionCreate,
+				"service/web":    ActionDelete,
+				"router/web":     ActionDelete,
+			},
+			conflicts:  []string{"service/api: already exists with a different definition"},
+			names:      "service/api,service/worker,router/api",
+			apiServers: "http://api:8080",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, admin := newTestDB(t)
+			tt.opts.ActorID = admin.ID
+			if result := importDocument(t, db, baseDocument(), tt.opts); len(result.Conflicts) > 0 {
+				t.Fatalf("unexpected conflicts importing the base document: %+v", result.Conflicts)
+			}
+
+			result := importDocument(t, db, edited(), tt.opts)
+			if !result.Applied {
+				t.Fatalf("expected the import to be applied, conflicts %+v", result.Conflicts)
+			}
+			got := actions(result)
+			for resource, want := range tt.actions {
+				if got[resource] != want {
+					t.Errorf("%s: action %q, want %q", resource, got[resource], want)
+				}
+			}
+			var conflicts []string
+			for _, c := range result.Conflicts {
+				conflicts = append(conflicts, c.Kind+"/"+c.Name+": "+c.Reason)
+			}
+			if strings.Join(conflicts, "\n") != strings.Join(tt.conflicts, "\n") {
+				t.Errorf("conflicts %q, want %q", conflicts, tt.conflicts)
+			}
+
+			if got := names(t, db); got != tt.names {
+				t.Errorf("resources %s, want %s", got, tt.names)
+			}
+			var api models.Service
+			db.Preload("Servers").First(&api, "name = ?", "api")
+			if len(api.Servers) != 1 || api.Servers[0].URL != tt.apiServers {
+				t.Errorf("api servers %+v, want %s", api.Servers, tt.apiServers)
+			}
+
+			// Importing the same document again changes nothing
+			again, err := Plan(db, edited(), tt.opts)
+			if err != nil {
+				t.Fatalf("Plan: %v", err)
+			}
+			if again.Summary[ActionCreate]+again.Summary[ActionUpdate]+again.Summary[ActionDelete] != 0 {
+				t.Errorf("expected a second import to change nothing, got %v", again.Summary)
+			}
+		})
+	}
+}
+
+func TestImport_RestoresRedactedSecrets(t *testing.T) {
+	db, admin := newTestDB(t)
+	opts := ImportOptions{ActorID: admin.ID}
+	importDocument(t, db, baseDocument(), opts)
+
+	exported, err := Export(db, false)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	provider := exported.HTTPProviders[0]
+	if provider.Auth.Secret != secrets.Redacted || provider.Headers["X-Api-Key"] != secrets.Redacted {
+		t.Fatalf("expected the provider's secrets to be exported redacted, got %+v %v", provider.Auth, provider.Headers)
+	}
+	if header := exported.Middlewares[0].Config.CustomRequestHeaders["Authorization"]; header != secrets.Redacted {
+		t.Fatalf("expected the middleware's Authorization header to be exported redacted, got %q", header)
+	}
+
+	// The export imports unchanged
+	result, err := Plan(db, exported, opts)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if result.Summary[ActionUnchanged] != len(result.Changes) {
+		t.Errorf("expected the export to import unchanged, got %v", result.Summary)
+	}
+
+	// Edited next to the redacted values, which keep their stored values
+	exported, _ = Export(db, false)
+	exported.HTTPProviders[0].Priority = 7
+	exported.HTTPProviders[0].Headers["X-Team"] = "platform"
+	exported.Middlewares[0].Config.CustomRequestHeaders["X-Team"] = "platform"
+	result = importDocument(t, db, exported, opts)
+	if got := actions(result); got["httpProvider/upstream"] != ActionUpdate || got["middleware/upstream-auth"] != ActionUpdate {
+		t.Fatalf("unexpected changes %v", got)
+	}
+
+	var stored models.HTTPProvider
+	db.First(&stored, "name = ?", "upstream")
+	if stored.Priority != 7 || stored.AuthSecret != "provider-token" {
+		t.Errorf("expected the auth secret to be kept, got priority %d secret %q", stored.Priority, stored.AuthSecret)
+	}
+	if headers := stored.GetHeaders(); headers["X-Api-Key"] != "header-key" || headers["X-Team"] != "platform" {
+		t.Errorf("expected the secret header to be kept, got %v", headers)
+	}
+	var middleware models.Middleware
+	db.First(&middleware, "name = ?", "upstream-auth")
+	if !strings.Contains(middleware.Config, "Bearer upstream-token") || !strings.Contains(middleware.Config, "platform") {
+		t.Errorf("expected the Authorization header to be kept, got %s", middleware.Config)
+	}
+
+	// A new provider has no stored value to keep
+	exported.HTTPProviders[0].Name = "copy"
+	result, err = Plan(db, exported, opts)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if !result.HasBlockingConflicts() || !hasConflict(result, KindHTTPProvider, "copy", "redacted secrets") {
+		t.Errorf("expected a conflict for the redacted secrets of a new provider, got %+v", result.Conflicts)
+	}
+}
+
+// hasConflict reports whether result has a conflict for kind and name
+// whose reason contains reason
+func hasConflict(result *ImportResult, kind, name, reason string) bool {
+	for _, c := range result.Conflicts {
+		if c.Kind == kind && c.Name == name && strings.Contains(c.Reason, reason) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPlan_Conflicts(t *testing.T) {
+	tests := []struct {
+		name   string
+		edit   func(doc *Document)
+		opts   ImportOptions
+		kind   string
+		target string
+		reason string
+	}{
+		{
+			name:   "duplicate name",
+			edit:   func(doc *Document) { doc.Services = append(doc.Services, doc.Services[0]) },
+			kind:   KindService,
+			target: "api",
+			reason: "defined more than once",
+		},
+		{
+			name:   "invalid server URL",
+			edit:   func(doc *Document) { doc.Services[1].Servers[0].URL = "web:8080" },
+			kind:   KindService,
+			target: "web",
+			reason: "invalid server URL: web:8080",
+		},
+		{
+			name:   "unsupported middleware",
+			edit:   func(doc *Document) { doc.Middlewares[0].Type = "basicAuth" },
+			kind:   KindMiddleware,
+			target: "upstream-auth",
+			reason: "unsupported middleware type: basicAuth",
+		},
+		{
+			name:   "unknown service",
+			edit:   func(doc *Document) { doc.Routers[1].Service = "missing" },
+			kind:   KindRouter,
+			target: "web",
+			reason: "unknown service: missing",
+		},
+		{
+			name:   "unknown middleware",
+			edit:   func(doc *Document) { doc.Routers[1].Middlewares = []string{"missing"} },
+			kind:   KindRouter,
+			target: "web",
+			reason: "unknown middleware: missing",
+		},
+		{
+			name:   "unknown owner",
+			edit:   func(doc *Document) { doc.Routers[0].Owner = "nobody@example.com" },
+			kind:   KindRouter,
+			target: "api",
+			reason: "unknown owner: nobody@example.com",
+		},
+		{
+			name: "service removed by replace",
+			edit: func(doc *Document) {
+				doc.Services = doc.Services[:1]
+			},
+			opts:   ImportOptions{Mode: ModeReplace},
+			kind:   KindRouter,
+			target: "web",
+			reason: "unknown service: web",
+		},
+		{
+			name:   "invalid provider",
+			edit:   func(doc *Document) { doc.HTTPProviders[0].Namespace = &NamespaceSpec{Mode: "infix"} },
+			kind:   KindHTTPProvider,
+			target: "upstream",
+			reason: "invalid namespace mode: infix",
+		},
+		{
+			name:   "invalid role",
+			edit:   func(doc *Document) { doc.Users = []UserSpec{{Email: "Ops@Example.com", Role: "root"}} },
+			kind:   KindUser,
+			target: "Ops@Example.com",
+			reason: "invalid role: root",
+		},
+		{
+			name:   "importing user deleted",
+			edit:   func(doc *Document) { doc.Users = []UserSpec{{Email: "ops@example.com"}} },
+			opts:   ImportOptions{Mode: ModeReplace},
+			kind:   KindUser,
+			target: "admin@example.com",
+			reason: "the importing user cannot be deleted",
+		},
+		{
+			name:   "newer document",
+			edit:   func(doc *Document) { doc.Version = DocumentVersion + 1 },
+			kind:   "document",
+			target: "",
+			reason: "unsupported document version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, admin := newTestDB(t)
+			tt.opts.ActorID = admin.ID
+			importDocument(t, db, baseDocument(), ImportOptions{ActorID: admin.ID})
+
+			doc := baseDocument()
+			tt.edit(doc)
+			result, err := Plan(db, doc, tt.opts)
+			if err != nil {
+				t.Fatalf("Plan: %v", err)
+			}
+			if !hasConflict(result, tt.kind, tt.target, tt.reason) {
+				t.Errorf("expected a conflict for %s %q containing %q, got %+v", tt.kind, tt.target, tt.reason, result.Conflicts)
+			}
+		})
+	}
+}
+
+func TestPlan_RoutersKeepTheirOwner(t *testing.T) {
+	db, admin := newTestDB(t)
+	importDocument(t, db, baseDocument(), ImportOptions{ActorID: admin.ID})
+
+	// Owned by another user, imported again by someone else without owner
+	other := &models.User{Email: "ops@example.com", Role: models.RoleUser, IsActive: true}
+	db.Create(other)
+	doc := baseDocument()
+	doc.Routers[0].Owner = "OPS@example.com"
+	importDocument(t, db, doc, ImportOptions{ActorID: admin.ID})
+
+	result := importDocument(t, db, baseDocument(), ImportOptions{ActorID: other.ID})
+	if got := actions(result)["router/api"]; got != ActionUnchanged {
+		t.Errorf("expected the router to be unchanged, got %s", got)
+	}
+	var router models.Router
+	db.First(&router, "name = ?", "api")
+	if router.UserID != other.ID {
+		t.Errorf("expected the router to be owned by user %d, got %d", other.ID, router.UserID)
+	}
+}
@@ -0,0 +1,488 @@
+package declarative
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/traefik/paerser/file"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"github.com/traefik/traefik/v3/pkg/config/label"
+	"github.com/traefikx/backend/internal/models"
+)
+
+// LabelSet holds the labels of a single docker container
+type LabelSet struct {
+	Name   string            `json:"name"`
+	Host   string            `json:"host,omitempty"` // Address of the container, optionally with port
+	Labels map[string]string `json:"labels"`
+
+	// Further instances sharing the labels (catalog services), each becoming a
+	// server next to the one on Host
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+func (s LabelSet) hosts() []string {
+	if s.Host == "" {
+		return s.Hosts
+	}
+	return append([]string{s.Host}, s.Hosts...)
+}
+
+// ParseDynamicConfig decodes a Traefik dynamic configuration file
+func ParseDynamicConfig(data []byte, format string) (*dynamic.Configuration, error) {
+	cfg := &dynamic.Configuration{}
+	if err := file.DecodeContent(string(data), "."+format, cfg); err != nil {
+		return nil, fmt.Errorf("invalid Traefik configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// FromLabels converts docker label sets into a document
+func FromLabels(sets []LabelSet) (*Document, []Conflict) {
+	combined, issues := LabelsToDynamic(sets)
+	doc, more := FromDynamic(combined)
+	return doc, append(issues, more...)
+}
+
+// LabelsToDynamic converts label sets into a Traefik configuration. Label
+// sets are combined the same way the Traefik docker provider does: a router
+// without a service uses the only service of its container, or a service
+// named after the container pointing at its host.
+func LabelsToDynamic(sets []LabelSet) (*dynamic.Configuration, []Conflict) {
+	combined := &dynamic.Configuration{HTTP: &dynamic.HTTPConfiguration{
+		Routers:     make(map[string]*dynamic.Router),
+		Services:    make(map[string]*dynamic.Service),
+		Middlewares: make(map[string]*dynamic.Middleware),
+	}}
+	var issues []Conflict
+	origin := make(map[string]string)
+
+	for _, set := range sets {
+		if strings.EqualFold(set.Labels["traefik.enable"], "false") {
+			continue
+		}
+
+		cfg, err := label.DecodeConfiguration(set.Labels)
+		if err != nil {
+			issues = append(issues, Conflict{Kind: "container", Name: set.Name, Reason: "invalid labels: " + err.Error()})
+			continue
+		}
+		if cfg.TCP != nil && len(cfg.TCP.Routers)+len(cfg.TCP.Services) > 0 {
+			issues = append(issues, Conflict{Kind: "container", Name: set.Name, Reason: "TCP routing is not supported"})
+		}
+		if cfg.UDP != nil && len(cfg.UDP.Routers)+len(cfg.UDP.Services) > 0 {
+			issues = append(issues, Conflict{Kind: "container", Name: set.Name, Reason: "UDP routing is not supported"})
+		}
+		if cfg.HTTP == nil {
+			continue
+		}
+		if cfg.HTTP.Services == nil {
+			cfg.HTTP.Services = make(map[string]*dynamic.Service)
+		}
+
+		// Servers declared by port only run on the container
+		for name, service := range cfg.HTTP.Services {
+			if service.LoadBalancer == nil {
+				continue
+			}
+			if len(service.LoadBalancer.Servers) == 0 {
+				service.LoadBalancer.Servers = []dynamic.Server{{}}
+			}
+			servers := make([]dynamic.Server, 0, len(service.LoadBalancer.Servers))
+			for _, server := range service.LoadBalancer.Servers {
+				if server.URL != "" {
+					servers = append(servers, server)
+					continue
+				}
+				urls, ok := containerURLs(set.hosts(), server.Scheme, server.Port)
+				if !ok {
+					issues = append(issues, Conflict{Kind: KindService, Name: name, Reason: "server address unknown; set host for container " + set.Name})
+					servers = append(servers, server)
+					continue
+				}
+				for _, url := range urls {
+					server.URL = url
+					servers = append(servers, server)
+				}
+			}
+			service.LoadBalancer.Servers = servers
+		}
+
+		// Routers without service
+		for name, router := range cfg.HTTP.Routers {
+			if router.Service != "" {
+				continue
+			}
+			switch len(cfg.HTTP.Services) {
+			case 0:
+				urls, ok := containerURLs(set.hosts(), "", "")
+				if !ok {
+					issues = append(issues, Conflict{Kind: KindRouter, Name: name, Reason: "router has no service and container " + set.Name + " has no host with port"})
+					delete(cfg.HTTP.Routers, name)
+					continue
+				}
+				servers := make([]dynamic.Server, len(urls))
+				for i, url := range urls {
+					servers[i] = dynamic.Server{URL: url}
+				}
+				cfg.HTTP.Services[set.Name] = &dynamic.Service{LoadBalancer: &dynamic.ServersLoadBalancer{
+					Servers: servers,
+				}}
+				router.Service = set.Name
+			case 1:
+				for serviceName := range cfg.HTTP.Services {
+					router.Service = serviceName
+				}
+			default:
+				issues = append(issues, Conflict{Kind: KindRouter, Name: name, Reason: "router has no service and container " + set.Name + " defines several"})
+				delete(cfg.HTTP.Routers, name)
+			}
+		}
+
+		// Routers without rule
+		for name, router := range cfg.HTTP.Routers {
+			if router.Rule == "" {
+				issues = append(issues, Conflict{Kind: KindRouter, Name: name, Reason: "router has no rule; the docker provider default rule is not supported"})
+				delete(cfg.HTTP.Routers, name)
+			}
+		}
+
+		issues = append(issues, combine(combined.HTTP.Routers, cfg.HTTP.Routers, KindRouter, set.Name, origin)...)
+		issues = append(issues, combine(combined.HTTP.Services, cfg.HTTP.Services, KindService, set.Name, origin)...)
+		issues = append(issues, combine(combined.HTTP.Middlewares, cfg.HTTP.Middlewares, KindMiddleware, set.Name, origin)...)
+	}
+
+	return combined, issues
+}
+
+// combine adds the entries of src to dst, reporting names already defined by
+// another container
+func combine[T any](dst, src map[string]T, kind, container string, origin map[string]string) []Conflict {
+	var issues []Conflict
+	for name, value := range src {
+		key := kind + "/" + name
+		if other, exists := origin[key]; exists {
+			issues = append(issues, Conflict{Kind: kind, Name: name, Reason: fmt.Sprintf("defined by containers %s and %s; keeping %s", other, container, other)})
+			continue
+		}
+		origin[key] = container
+		dst[name] = value
+	}
+	return issues
+}
+
+// containerURLs returns a server URL per host, or false if any of them is
+// unknown
+func containerURLs(hosts []string, scheme, port string) ([]string, bool) {
+	if len(hosts) == 0 {
+		return nil, false
+	}
+	urls := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		url, ok := containerURL(host, scheme, port)
+		if !ok {
+			return nil, false
+		}
+		urls = append(urls, url)
+	}
+	return urls, true
+}
+
+func containerURL(host, scheme, port string) (string, bool) {
+	if host == "" {
+		return "", false
+	}
+	if scheme == "" {
+		scheme = "http"
+	}
+	if port != "" {
+		host = strings.Split(host, ":")[0] + ":" + port
+	} else if !strings.Contains(host, ":") {
+		return "", false
+	}
+	return scheme + "://" + host, true
+}
+
+// FromDynamic converts a Traefik dynamic configuration into a document.
+// Constructs TraefikX can't represent are skipped or dropped and reported.
+func FromDynamic(cfg *dynamic.Configuration) (*Document, []Conflict) {
+	doc := &Document{Version: DocumentVersion}
+	var issues []Conflict
+	report := func(kind, name, format string, args ...interface{}) {
+		issues = append(issues, Conflict{Kind: kind, Name: name, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	if cfg.TCP != nil && len(cfg.TCP.Routers)+len(cfg.TCP.Services) > 0 {
+		report("document", "tcp", "TCP routing is not supported")
+	}
+	if cfg.UDP != nil && len(cfg.UDP.Routers)+len(cfg.UDP.Services) > 0 {
+		report("document", "udp", "UDP routing is not supported")
+	}
+	if cfg.TLS != nil && (len(cfg.TLS.Certificates) > 0 || len(cfg.TLS.Options) > 0 || len(cfg.TLS.Stores) > 0) {
+		report("document", "tls", "TLS certificates, options and stores are not supported")
+	}
+	if cfg.HTTP == nil {
+		return doc, issues
+	}
+	if len(cfg.HTTP.ServersTransports) > 0 {
+		report("document", "serversTransports", "servers transports are not supported")
+	}
+
+	// Generated redirect middlewares map back to the router's HTTPS redirect
+	redirects := make(map[string]bool)
+	for name, router := range cfg.HTTP.Routers {
+		redirect := name + "-redirect-https"
+		if m, ok := cfg.HTTP.Middlewares[redirect]; ok && m.RedirectScheme != nil && m.RedirectScheme.Scheme == "https" {
+			for _, ref := range router.Middlewares {
+				if stripProvider(ref) == redirect {
+					redirects[redirect] = true
+				}
+			}
+		}
+	}
+
+	skippedServices := make(map[string]bool)
+	for _, name := range sortedNames(cfg.HTTP.Services) {
+		spec, ok := serviceFromDynamic(name, cfg.HTTP.Services[name], report)
+		if !ok {
+			skippedServices[name] = true
+			continue
+		}
+		doc.Services = append(doc.Services, spec)
+	}
+
+	skippedMiddlewares := make(map[string]bool)
+	for _, name := range sortedNames(cfg.HTTP.Middlewares) {
+		if redirects[name] {
+			continue
+		}
+		spec, ok := middlewareFromDynamic(name, cfg.HTTP.Middlewares[name], report)
+		if !ok {
+			skippedMiddlewares[name] = true
+			continue
+		}
+		doc.Middlewares = append(doc.Middlewares, spec)
+	}
+
+	for _, name := range sortedNames(cfg.HTTP.Routers) {
+		router := cfg.HTTP.Routers[name]
+
+		hostnames, ok := hostnamesFromRule(router.Rule)
+		if !ok {
+			report(KindRouter, name, "skipped: rule can't be represented, only Host matchers are supported: %s", router.Rule)
+			continue
+		}
+
+		service := stripProvider(router.Service)
+		if strings.HasSuffix(router.Service, "@internal") {
+			report(KindRouter, name, "skipped: internal service %s is not supported", router.Service)
+			continue
+		}
+		if skippedServices[service] {
+			report(KindRouter, name, "skipped: service %s could not be imported", service)
+			continue
+		}
+
+		spec := RouterSpec{
+			Name:        name,
+			Hostnames:   hostnames,
+			Service:     service,
+			EntryPoints: router.EntryPoints,
+		}
+
+		skip := false
+		for _, ref := range router.Middlewares {
+			middleware := stripProvider(ref)
+			switch {
+			case redirects[middleware]:
+				spec.RedirectHTTPS = true
+			case strings.HasSuffix(ref, "@internal"):
+				report(KindRouter, name, "skipped: internal middleware %s is not supported", ref)
+				skip = true
+			case skippedMiddlewares[middleware]:
+				report(KindRouter, name, "skipped: middleware %s could not be imported", middleware)
+				skip = true
+			default:
+				spec.Middlewares = append(spec.Middlewares, middleware)
+			}
+		}
+		if skip {
+			continue
+		}
+
+		if router.TLS != nil {
+			spec.TLS = &TLSSpec{CertResolver: router.TLS.CertResolver}
+			if router.TLS.Options != "" || len(router.TLS.Domains) > 0 {
+				report(KindRouter, name, "dropped: TLS options and domains")
+			}
+		}
+		if router.Priority != 0 {
+			report(KindRouter, name, "dropped: priority %d", router.Priority)
+		}
+		if router.Observability != nil || len(router.ParentRefs) > 0 || router.RuleSyntax != "" {
+			report(KindRouter, name, "dropped: observability, parent refs and rule syntax")
+		}
+
+		doc.Routers = append(doc.Routers, spec)
+	}
+
+	return doc, issues
+}
+
+func serviceFromDynamic(name string, service *dynamic.Service, report func(kind, name, format string, args ...interface{})) (ServiceSpec, bool) {
+	lb := service.LoadBalancer
+	if lb == nil {
+		report(KindService, name, "skipped: only load balancer services are supported")
+		return ServiceSpec{}, false
+	}
+	if len(lb.Servers) == 0 {
+		report(KindService, name, "skipped: load balancer has no servers")
+		return ServiceSpec{}, false
+	}
+
+	spec := ServiceSpec{
+		Name:             name,
+		LoadBalancerType: "wrr",
+		PassHostHeader:   lb.PassHostHeader == nil || *lb.PassHostHeader,
+	}
+	for _, server := range lb.Servers {
+		weight := 1
+		if server.Weight != nil {
+			weight = *server.Weight
+		}
+		spec.Servers = append(spec.Servers, ServerSpec{URL: server.URL, Weight: weight})
+		if server.PreservePath {
+			report(KindService, name, "dropped: preservePath on server %s", server.URL)
+		}
+	}
+
+	if lb.HealthCheck != nil {
+		spec.HealthCheck = &HealthCheckSpec{
+			Path:     lb.HealthCheck.Path,
+			Interval: int(time.Duration(lb.HealthCheck.Interval).Seconds()),
+		}
+	}
+
+	if lb.Strategy != "" && lb.Strategy != dynamic.BalancerStrategyWRR {
+		report(KindService, name, "dropped: load balancer strategy %s, using wrr", lb.Strategy)
+	}
+	if lb.Sticky != nil {
+		report(KindService, name, "dropped: sticky sessions")
+	}
+	if lb.PassiveHealthCheck != nil || lb.ServersTransport != "" {
+		report(KindService, name, "dropped: passive health check and servers transport")
+	}
+	// Traefik fills in a 100ms flush interval by default
+	if lb.ResponseForwarding != nil && time.Duration(lb.ResponseForwarding.FlushInterval) != 100*time.Millisecond {
+		report(KindService, name, "dropped: response forwarding")
+	}
+
+	return spec, true
+}
+
+func middlewareFromDynamic(name string, middleware *dynamic.Middleware, report func(kind, name, format string, args ...interface{})) (MiddlewareSpec, bool) {
+	spec := MiddlewareSpec{Name: name}
+
+	switch {
+	case middleware.RedirectScheme != nil:
+		spec.Type = "redirectScheme"
+		spec.Config = models.MiddlewareConfig{
+			Scheme:    middleware.RedirectScheme.Scheme,
+			Port:      middleware.RedirectScheme.Port,
+			Permanent: middleware.RedirectScheme.Permanent,
+		}
+	case middleware.Headers != nil:
+		headers := *middleware.Headers
+		spec.Type = "headers"
+		spec.Config = models.MiddlewareConfig{
+			CustomRequestHeaders:  headers.CustomRequestHeaders,
+			CustomResponseHeaders: headers.CustomResponseHeaders,
+			SSLRedirect:           headers.SSLRedirect != nil && *headers.SSLRedirect,
+		}
+		headers.CustomRequestHeaders = nil
+		headers.CustomResponseHeaders = nil
+		headers.SSLRedirect = nil
+		if !reflect.DeepEqual(headers, dynamic.Headers{}) {
+			report(KindMiddleware, name, "dropped: header options other than custom request/response headers")
+		}
+	case middleware.StripPrefix != nil:
+		spec.Type = "stripPrefix"
+		spec.Config = models.MiddlewareConfig{
+			Prefixes:   middleware.StripPrefix.Prefixes,
+			ForceSlash: middleware.StripPrefix.ForceSlash != nil && *middleware.StripPrefix.ForceSlash,
+		}
+	case middleware.AddPrefix != nil:
+		spec.Type = "addPrefix"
+		spec.Config = models.MiddlewareConfig{Prefix: middleware.AddPrefix.Prefix}
+	default:
+		report(KindMiddleware, name, "skipped: middleware type %s is not supported", middlewareType(middleware))
+		return MiddlewareSpec{}, false
+	}
+
+	return spec, true
+}
+
+// middlewareType returns the name of the configured middleware type
+func middlewareType(middleware *dynamic.Middleware) string {
+	value := reflect.ValueOf(*middleware)
+	for i := 0; i < value.NumField(); i++ {
+		if !value.Field(i).IsZero() {
+			return strings.Split(value.Type().Field(i).Tag.Get("json"), ",")[0]
+		}
+	}
+	return "unknown"
+}
+
+var (
+	hostMatcher = regexp.MustCompile("^Host\\(\\s*((?:[`\"][^`\"]+[`\"]\\s*,?\\s*)+)\\)$")
+	hostValue   = regexp.MustCompile("[`\"]([^`\"]+)[`\"]")
+)
+
+// hostnamesFromRule extracts hostnames from rules made only of Host matchers
+// combined with ||, e.g. Host(`a.example.com`) || Host(`b.example.com`)
+func hostnamesFromRule(rule string) ([]string, bool) {
+	rule = strings.TrimSpace(rule)
+	for strings.HasPrefix(rule, "(") && strings.HasSuffix(rule, ")") {
+		rule = strings.TrimSpace(rule[1 : len(rule)-1])
+	}
+	if rule == "" {
+		return nil, false
+	}
+
+	var hostnames []string
+	for _, term := range strings.Split(rule, "||") {
+		term = strings.Trim(strings.TrimSpace(term), "()")
+		if !strings.HasSuffix(term, ")") {
+			term += ")"
+		}
+		match := hostMatcher.FindStringSubmatch(term)
+		if match == nil {
+			return nil, false
+		}
+		for _, value := range hostValue.FindAllStringSubmatch(match[1], -1) {
+			hostnames = append(hostnames, value[1])
+		}
+	}
+	return hostnames, true
+}
+
+// stripProvider removes the @provider suffix of a reference
+func stripProvider(name string) string {
+	if i := strings.LastIndex(name, "@"); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+func sortedNames[T any](m map[string]T) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
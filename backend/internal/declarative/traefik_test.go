@@ -0,0 +1,288 @@
+package declarative
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHostnamesFromRule(t *testing.T) {
+	tests := []struct {
+		rule      string
+		hostnames []string
+		ok        bool
+	}{
+		{"Host(`app.example.com`)", []string{"app.example.com"}, true},
+		{` Host("app.example.com") `, []string{"app.example.com"}, true},
+		{"Host(`a.example.com`) || Host(`b.example.com`)", []string{"a.example.com", "b.example.com"}, true},
+		{"(Host(`a.example.com`) || Host(`b.example.com`))", []string{"a.example.com", "b.example.com"}, true},
+		{"(Host(`a.example.com`)) || (Host(`b.example.com`))", []string{"a.example.com", "b.example.com"}, true},
+		{"Host(`a.example.com`, `b.example.com`)", []string{"a.example.com", "b.example.com"}, true},
+		{"Host(`a.example.com`,`b.example.com`) || Host(`c.example.com`)", []string{"a.example.com", "b.example.com", "c.example.com"}, true},
+		{"HostRegexp(`^.+\\.example\\.com$`)", nil, false},
+		{"Host(`a.example.com`) || HostRegexp(`^.+\\.example\\.com$`)", nil, false},
+		{"Host(`a.example.com`) && PathPrefix(`/api`)", nil, false},
+		{"Host(`a.example.com`) || PathPrefix(`/api`)", nil, false},
+		{"!Host(`a.example.com`)", nil, false},
+		{"PathPrefix(`/`)", nil, false},
+		{"Host()", nil, false},
+		{"", nil, false},
+	}
+
+	for _, tt := range tests {
+		hostnames, ok := hostnamesFromRule(tt.rule)
+		if ok != tt.ok || !reflect.DeepEqual(hostnames, tt.hostnames) {
+			t.Errorf("hostnamesFromRule(%q) = %q, %v, want %q, %v", tt.rule, hostnames, ok, tt.hostnames, tt.ok)
+		}
+	}
+}
+
+// reasons maps kind/name to the reported reasons, joined by "; "
+func reasons(issues []Conflict) map[string]string {
+	byName := make(map[string]string)
+	for _, issue := range issues {
+		key := issue.Kind + "/" + issue.Name
+		if byName[key] != "" {
+			byName[key] += "; "
+		}
+		byName[key] += issue.Reason
+	}
+	return byName
+}
+
+func TestFromDynamic(t *testing.T) {
+	cfg, err := ParseDynamicConfig([]byte(`
+http:
+  routers:
+    app:
+      rule: Host(`+"`app.example.com`"+`) || Host(`+"`www.example.com`"+`)
+      service: app@file
+      middlewares: [app-redirect-https, strip@file]
+      tls:
+        certResolver: le
+        options: modern
+      priority: 10
+    regexp:
+      rule: HostRegexp(`+"`^.+\\.example\\.com$`"+`)
+      service: app
+    dashboard:
+      rule: Host(`+"`traefik.example.com`"+`)
+      service: api@internal
+    weighted:
+      rule: Host(`+"`canary.example.com`"+`)
+      service: canary
+    authenticated:
+      rule: Host(`+"`admin.example.com`"+`)
+      service: app
+      middlewares: [basic]
+  services:
+    app:
+      loadBalancer:
+        servers:
+          - url: http://app-1:8080
+          - url: http://app-2:8080
+            weight: 3
+        sticky:
+          cookie: {}
+    canary:
+      weighted:
+        services:
+          - name: app
+            weight: 1
+  middlewares:
+    app-redirect-https:
+      redirectScheme:
+        scheme: https
+    strip:
+      stripPrefix:
+        prefixes: [/api]
+    basic:
+      basicAuth:
+        users: ["admin:$apr1$x$y"]
+    secure:
+      headers:
+        customRequestHeaders:
+          X-Team: core
+        stsSeconds: 31536000
+tcp:
+  routers:
+    db:
+      rule: HostSNI(`+"`*`"+`)
+      service: db
+  services:
+    db:
+      loadBalancer:
+        servers:
+          - address: db:5432
+tls:
+  options:
+    modern:
+      minVersion: VersionTLS13
+`), "yaml")
+	if err != nil {
+		t.Fatalf("ParseDynamicConfig: %v", err)
+	}
+
+	doc, issues := FromDynamic(cfg)
+
+	// Every construct that doesn't fit is reported under its name
+	want := map[string]string{
+		"document/tcp":         "TCP routing is not supported",
+		"document/tls":         "TLS certificates, options and stores are not supported",
+		"router/app":           "dropped: TLS options and domains; dropped: priority 10",
+		"router/regexp":        "skipped: rule can't be represented, only Host matchers are supported: HostRegexp(`^.+\\.example\\.com$`)",
+		"router/dashboard":     "skipped: internal service api@internal is not supported",
+		"router/weighted":      "skipped: service canary could not be imported",
+		"router/authenticated": "skipped: middleware basic could not be imported",
+		"service/app":          "dropped: sticky sessions",
+		"service/canary":       "skipped: only load balancer services are supported",
+		"middleware/basic":     "skipped: middleware type basicAuth is not supported",
+		"middleware/secure":    "dropped: header options other than custom request/response headers",
+	}
+	if got := reasons(issues); !reflect.DeepEqual(got, want) {
+		for key, reason := range got {
+			if want[key] != reason {
+				t.Errorf("%s: reported %q, want %q", key, reason, want[key])
+			}
+		}
+		for key, reason := range want {
+			if _, ok := got[key]; !ok {
+				t.Errorf("%s: not reported, want %q", key, reason)
+			}
+		}
+	}
+
+	// The rest is imported, the redirect middleware as the router's HTTPS redirect
+	if len(doc.Routers) != 1 {
+		t.Fatalf("expected only the app router, got %+v", doc.Routers)
+	}
+	router := doc.Routers[0]
+	if router.Name != "app" || router.Service != "app" || !router.RedirectHTTPS ||
+		!reflect.DeepEqual(router.Hostnames, []string{"app.example.com", "www.example.com"}) ||
+		!reflect.DeepEqual(router.Middlewares, []string{"strip"}) ||
+		router.TLS == nil || router.TLS.CertResolver != "le" {
+		t.Errorf("unexpected router %+v", router)
+	}
+	if len(doc.Services) != 1 || !reflect.DeepEqual(doc.Services[0].Servers, []ServerSpec{{URL: "http://app-1:8080", Weight: 1}, {URL: "http://app-2:8080", Weight: 3}}) {
+		t.Errorf("unexpected services %+v", doc.Services)
+	}
+	var middlewares []string
+	for _, m := range doc.Middlewares {
+		middlewares = append(middlewares, m.Name+":"+m.Type)
+	}
+	if strings.Join(middlewares, ",") != "secure:headers,strip:stripPrefix" {
+		t.Errorf("unexpected middlewares %v", middlewares)
+	}
+}
+
+func TestLabelsToDynamic(t *testing.T) {
+	sets := []LabelSet{
+		{
+			// A router without service uses the only service of its container
+			Name: "app",
+			Host: "10.0.0.2",
+			Labels: map[string]string{
+				"traefik.http.routers.app.rule":                      "Host(`app.example.com`)",
+				"traefik.http.services.app.loadbalancer.server.port": "8080",
+			},
+			Hosts: []string{"10.0.0.3"},
+		},
+		{
+			// Or a service named after the container
+			Name: "web",
+			Host: "10.0.0.4:3000",
+			Labels: map[string]string{
+				"traefik.http.routers.web.rule": "Host(`web.example.com`)",
+			},
+		},
+		{
+			Name: "disabled",
+			Labels: map[string]string{
+				"traefik.enable":                     "false",
+				"traefik.http.routers.disabled.rule": "Host(`disabled.example.com`)",
+			},
+		},
+		{
+			Name: "nohost",
+			Labels: map[string]string{
+				"traefik.http.routers.nohost.rule": "Host(`nohost.example.com`)",
+			},
+		},
+		{
+			Name: "several",
+			Host: "10.0.0.7:80",
+			Labels: map[string]string{
+				"traefik.http.routers.several.rule":                  "Host(`several.example.com`)",
+				"traefik.http.services.one.loadbalancer.server.port": "80",
+				"traefik.http.services.two.loadbalancer.server.port": "81",
+			},
+		},
+		{
+			Name: "norule",
+			Host: "10.0.0.5:80",
+			Labels: map[string]string{
+				"traefik.http.routers.norule.service":                  "norule",
+				"traefik.http.services.norule.loadbalancer.server.url": "http://10.0.0.5",
+			},
+		},
+		{
+			// Redefines the app router
+			Name: "copy",
+			Host: "10.0.0.6:8080",
+			Labels: map[string]string{
+				"traefik.http.routers.app.rule": "Host(`copy.example.com`)",
+				"traefik.tcp.routers.db.rule":   "HostSNI(`*`)",
+			},
+		},
+		{
+			Name:   "broken",
+			Labels: map[string]string{"traefik.http.routers.broken.priority": "high"},
+		},
+	}
+
+	cfg, issues := LabelsToDynamic(sets)
+
+	want := map[string]string{
+		"router/nohost":    "router has no service and container nohost has no host with port",
+		"router/several":   "router has no service and container several defines several",
+		"router/norule":    "router has no rule; the docker provider default rule is not supported",
+		"router/app":       "defined by containers app and copy; keeping app",
+		"container/copy":   "TCP routing is not supported",
+		"container/broken": "invalid labels",
+	}
+	got := reasons(issues)
+	for key, reason := range want {
+		if !strings.Contains(got[key], reason) {
+			t.Errorf("%s: reported %q, want %q", key, got[key], reason)
+		}
+	}
+	if _, ok := got["router/disabled"]; ok {
+		t.Error("expected disabled containers to be ignored")
+	}
+
+	if router := cfg.HTTP.Routers["app"]; router == nil || router.Service != "app" || router.Rule != "Host(`app.example.com`)" {
+		t.Errorf("unexpected app router %+v", router)
+	}
+	var urls []string
+	for _, server := range cfg.HTTP.Services["app"].LoadBalancer.Servers {
+		urls = append(urls, server.URL)
+	}
+	if strings.Join(urls, ",") != "http://10.0.0.2:8080,http://10.0.0.3:8080" {
+		t.Errorf("expected a server per instance, got %v", urls)
+	}
+	if web := cfg.HTTP.Routers["web"]; web == nil || web.Service != "web" ||
+		cfg.HTTP.Services["web"].LoadBalancer.Servers[0].URL != "http://10.0.0.4:3000" {
+		t.Errorf("expected the web router to use a service named after its container, got %+v", web)
+	}
+	for _, name := range []string{"disabled", "nohost", "several", "norule"} {
+		if _, ok := cfg.HTTP.Routers[name]; ok {
+			t.Errorf("expected router %s to be left out", name)
+		}
+	}
+
+	// FromLabels carries the issues of both steps
+	_, all := FromLabels(sets)
+	if len(all) < len(issues) {
+		t.Errorf("expected FromLabels to report the label issues, got %+v", all)
+	}
+}
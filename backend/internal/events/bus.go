@@ -0,0 +1,174 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Event types
+const (
+	ConfigChanged     = "config.changed"     // Local routers, services or middlewares were written
+	ProviderUpdated   = "provider.updated"   // An HTTP provider's configuration or state changed
+	ConfigCompiled    = "config.compiled"    // A snapshot with a new configuration is available, data is ConfigVersion
+	ConflictDetected  = "conflict.detected"  // Resources started conflicting, data is []services.ConflictInfo
+	BackendHealth     = "backend.health"     // A service's server started or stopped answering its health check
+	ProviderFetched   = "provider.fetched"   // An HTTP provider was fetched successfully
+	ProviderFailed    = "provider.failed"    // A provider's fetches started failing, or it was dropped from the config
+	ProviderRecovered = "provider.recovered" // A failing provider was fetched again
+	UserCreated       = "user.created"       // An admin created a user
+)
+
+// Resource change actions
+const (
+	ActionCreated   = "created"
+	ActionUpdated   = "updated"
+	ActionDeleted   = "deleted"
+	ActionImported  = "imported"
+	ActionRefreshed = "refreshed" // An HTTP provider fetch was requested
+	ActionRestored  = "restored"  // The database was restored from a backup
+)
+
+// Event is a message published on the bus
+type Event struct {
+	Type   string      `json:"type"`
+	Data   interface{} `json:"data,omitempty"`
+	Time   time.Time   `json:"time"`
+	Origin string      `json:"origin,omitempty"` // Replica that published the event, empty for this one
+
+	// Span that published the event, linked by the work it triggers
+	Span trace.SpanContext `json:"-"`
+}
+
+// ResourceChange is the payload of ConfigChanged events
+type ResourceChange struct {
+	Kind   string `json:"kind"` // router, service, middleware, proxy, http_provider, database
+	ID     uint   `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Action string `json:"action"`
+	UserID uint   `json:"user_id,omitempty"` // Owner of the resource, if any
+}
+
+// ProviderChange is the payload of the ProviderUpdated, ProviderFetched,
+// ProviderFailed and ProviderRecovered events
+type ProviderChange struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Error    string `json:"error,omitempty"`
+	State    string `json:"state,omitempty"`    // healthy, degraded or failed
+	Failures int    `json:"failures,omitempty"` // Consecutive failed fetches
+	Changed  bool   `json:"changed,omitempty"`  // The fetch changed the provider's resources
+}
+
+// ConfigVersion is the payload of ConfigCompiled events. Versions count the
+// compiles of each replica, the hash identifies the content.
+type ConfigVersion struct {
+	Version uint64 `json:"version"`
+	Hash    string `json:"hash"`
+}
+
+// BackendChange is the payload of BackendHealth events
+type BackendChange struct {
+	ServiceID   uint   `json:"service_id"`
+	ServiceName string `json:"service_name"`
+	ServerID    uint   `json:"server_id"`
+	URL         string `json:"url"`
+	Healthy     bool   `json:"healthy"`
+	Error       string `json:"error,omitempty"`
+}
+
+// UserChange is the payload of UserCreated events
+type UserChange struct {
+	ID    uint   `json:"id"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// Bus is an in-process publish/subscribe bus. Delivery never blocks the
+// publisher: subscribers that fall behind miss events.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	ch    chan Event
+	types map[string]bool
+}
+
+// NewBus creates an empty bus
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[*subscriber]struct{})}
+}
+
+// Publish sends an event to all subscribers of its type. Publishing on a nil
+// bus is a no-op so components work without one.
+func (b *Bus) Publish(eventType string, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	b.Forward(Event{Type: eventType, Data: data, Time: time.Now()})
+}
+
+// PublishContext publishes an event on behalf of the span of ctx, e.g. the
+// API request that changed a resource
+func (b *Bus) PublishContext(ctx context.Context, eventType string, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	b.Forward(Event{Type: eventType, Data: data, Time: time.Now(), Span: trace.SpanContextFromContext(ctx)})
+}
+
+// Forward delivers an event published elsewhere, e.g. relayed from another
+// replica, as is
+func (b *Bus) Forward(event Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subscribers {
+		if len(sub.types) > 0 && !sub.types[event.Type] {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving events of the given types, or all
+// events if none are given, and a function that ends the subscription
+func (b *Bus) Subscribe(buffer int, types ...string) (<-chan Event, func()) {
+	if b == nil {
+		return nil, func() {}
+	}
+
+	sub := &subscriber{
+		ch:    make(chan Event, buffer),
+		types: make(map[string]bool, len(types)),
+	}
+	for _, t := range types {
+		sub.types[t] = true
+	}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, sub)
+			b.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+}
@@ -0,0 +1,241 @@
+// Package ha lets several TraefikX replicas share one database. A lease
+// elects the leader that runs the background workers, and a relay carries
+// bus events between replicas.
+package ha
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/traefikx/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// leaderLease is the name of the lease held by the leader
+const leaderLease = "leader"
+
+// Status describes this replica's view of the leadership
+type Status struct {
+	Enabled        bool       `json:"enabled"`
+	Instance       string     `json:"instance"`
+	Leader         bool       `json:"leader"`
+	LeaderInstance string     `json:"leader_instance,omitempty"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+}
+
+// Elector holds the leader lease while it can. The lease is renewed every
+// ttl/3; replicas' clocks must agree to well within ttl.
+type Elector struct {
+	db        *gorm.DB
+	instance  string
+	ttl       time.Duration
+	onElected func()
+	onDeposed func()
+
+	leader    atomic.Bool
+	mu        sync.Mutex
+	holder    string    // Last known holder
+	expires   time.Time // Last known expiry
+	changed   chan struct{}
+	stopChan  chan struct{}
+	done      chan struct{}
+	callbacks chan struct{} // Closed once the callbacks goroutine returned
+}
+
+// NewElector creates an elector for instance. onElected and onDeposed run
+// in order on a goroutine of their own when this replica gains or loses
+// the lease, so slow callbacks don't delay renewals.
+func NewElector(db *gorm.DB, instance string, ttl time.Duration, onElected, onDeposed func()) *Elector {
+	if ttl <= 0 {
+		ttl = 15 * time.Second
+	}
+	return &Elector{
+		db:        db,
+		instance:  instance,
+		ttl:       ttl,
+		onElected: onElected,
+		onDeposed: onDeposed,
+		changed:   make(chan struct{}, 1),
+		stopChan:  make(chan struct{}),
+		done:      make(chan struct{}),
+		callbacks: make(chan struct{}),
+	}
+}
+
+// Start tries to acquire the lease right away, then keeps renewing or
+// acquiring it
+func (e *Elector) Start() {
+	go e.runCallbacks()
+	e.tick()
+
+	go func() {
+		defer close(e.done)
+
+		ticker := time.NewTicker(e.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				e.tick()
+			case <-e.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop steps down, waits for onDeposed and releases the lease so another
+// replica takes over without waiting for it to expire
+func (e *Elector) Stop() {
+	close(e.stopChan)
+	<-e.done
+
+	leader := e.leader.Load()
+	if leader {
+		e.depose()
+	}
+	close(e.changed)
+	<-e.callbacks
+
+	if !leader {
+		return
+	}
+	err := e.db.Model(&models.Lease{}).
+		Where("name = ? AND holder = ?", leaderLease, e.instance).
+		Updates(map[string]interface{}{"holder": "", "expires_at": time.Now().UTC()}).Error
+	if err != nil {
+		slog.Error("Failed to release leader lease", "error", err)
+	}
+}
+
+// IsLeader reports whether this replica holds the lease. Without HA (a nil
+// elector) the only replica is always the leader.
+func (e *Elector) IsLeader() bool {
+	if e == nil {
+		return true
+	}
+	return e.leader.Load()
+}
+
+// Status returns the leadership as last seen by this replica
+func (e *Elector) Status() Status {
+	if e == nil {
+		return Status{Leader: true}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	status := Status{
+		Enabled:        true,
+		Instance:       e.instance,
+		Leader:         e.leader.Load(),
+		LeaderInstance: e.holder,
+	}
+	if !e.expires.IsZero() {
+		expires := e.expires
+		status.LeaseExpiresAt = &expires
+	}
+	return status
+}
+
+// tick renews or acquires the lease. A replica that can't reach the
+// database steps down a full tick before its lease could have been taken
+// over, as the next tick may come late.
+func (e *Elector) tick() {
+	now := time.Now().UTC()
+	acquired, err := e.acquire(now)
+	if err != nil {
+		slog.Error("Leader election failed", "error", err)
+		e.mu.Lock()
+		expired := now.Add(2 * e.ttl / 3).After(e.expires)
+		e.mu.Unlock()
+		if e.leader.Load() && expired {
+			e.depose()
+		}
+		return
+	}
+
+	if acquired && !e.leader.Load() {
+		slog.Info("Elected leader", "instance", e.instance)
+		e.leader.Store(true)
+		e.notify()
+	} else if !acquired && e.leader.Load() {
+		e.depose()
+	}
+}
+
+func (e *Elector) depose() {
+	slog.Warn("No longer the leader", "instance", e.instance)
+	e.leader.Store(false)
+	e.notify()
+}
+
+// notify wakes the callbacks goroutine, a pending wake-up covers any
+// further change
+func (e *Elector) notify() {
+	select {
+	case e.changed <- struct{}{}:
+	default:
+	}
+}
+
+// runCallbacks runs onElected and onDeposed whenever the leadership
+// differs from the one they last ran for. Changes undone before it caught
+// up run no callback.
+func (e *Elector) runCallbacks() {
+	defer close(e.callbacks)
+
+	leader := false
+	for range e.changed {
+		if e.leader.Load() == leader {
+			continue
+		}
+		leader = !leader
+		if leader && e.onElected != nil {
+			e.onElected()
+		} else if !leader && e.onDeposed != nil {
+			e.onDeposed()
+		}
+	}
+}
+
+// acquire takes the lease if this replica holds it or it expired, in a
+// single conditional update so two replicas can't both succeed
+func (e *Elector) acquire(now time.Time) (bool, error) {
+	expires := now.Add(e.ttl)
+	update := func() (int64, error) {
+		result := e.db.Model(&models.Lease{}).
+			Where("name = ? AND (holder = ? OR expires_at < ?)", leaderLease, e.instance, now).
+			Updates(map[string]interface{}{"holder": e.instance, "expires_at": expires})
+		return result.RowsAffected, result.Error
+	}
+
+	updated, err := update()
+	if err != nil {
+		return false, err
+	}
+	if updated == 0 {
+		// The first replica creates the lease, expired
+		lease := models.Lease{Name: leaderLease, ExpiresAt: time.Time{}.UTC()}
+		if err := e.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&lease).Error; err != nil {
+			return false, err
+		}
+		if updated, err = update(); err != nil {
+			return false, err
+		}
+	}
+
+	var lease models.Lease
+	if err := e.db.First(&lease, "name = ?", leaderLease).Error; err != nil {
+		return false, err
+	}
+	e.mu.Lock()
+	e.holder = lease.Holder
+	e.expires = lease.ExpiresAt
+	e.mu.Unlock()
+
+	return updated == 1, nil
+}
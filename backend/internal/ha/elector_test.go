@@ -0,0 +1,133 @@
+package ha
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/traefikx/backend/internal/database"
+	"github.com/traefikx/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB returns a migrated SQLite database in a temporary directory
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// leaseExpiry returns the stored expiry of the leader lease
+func leaseExpiry(t *testing.T, db *gorm.DB) time.Time {
+	t.Helper()
+	var lease models.Lease
+	if err := db.First(&lease, "name = ?", leaderLease).Error; err != nil {
+		t.Fatalf("load lease: %v", err)
+	}
+	return lease.ExpiresAt
+}
+
+// waitFor fails unless ch receives within timeout
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func TestElector_RenewsDuringSlowCallback(t *testing.T) {
+	db := newTestDB(t)
+	elected := make(chan struct{})
+	release := make(chan struct{})
+	deposed := make(chan struct{}, 1)
+	e := NewElector(db, "a", 300*time.Millisecond,
+		func() {
+			close(elected)
+			<-release
+		},
+		func() { deposed <- struct{}{} },
+	)
+	e.Start()
+	waitFor(t, elected, "onElected")
+
+	// The lease is renewed while onElected is still running
+	first := leaseExpiry(t, db)
+	time.Sleep(250 * time.Millisecond)
+	if renewed := leaseExpiry(t, db); !renewed.After(first) {
+		t.Errorf("expected the lease to be renewed during onElected, expiry stayed at %v", first)
+	}
+	if !e.IsLeader() {
+		t.Error("expected to still be the leader")
+	}
+
+	// Stop waits for onElected, then onDeposed
+	close(release)
+	e.Stop()
+	select {
+	case <-deposed:
+	default:
+		t.Error("expected Stop to run onDeposed")
+	}
+	if holder := e.Status().LeaderInstance; holder != "a" {
+		t.Errorf("unexpected holder %q", holder)
+	}
+	var lease models.Lease
+	db.First(&lease, "name = ?", leaderLease)
+	if lease.Holder != "" {
+		t.Errorf("expected the lease to be released, held by %q", lease.Holder)
+	}
+}
+
+func TestElector_StepsDownBeforeExpiry(t *testing.T) {
+	db := newTestDB(t)
+	ttl := 30 * time.Second
+	elected := make(chan struct{})
+	deposed := make(chan struct{})
+	e := NewElector(db, "a", ttl, func() { close(elected) }, func() { close(deposed) })
+	go e.runCallbacks()
+	defer func() {
+		close(e.changed)
+		<-e.callbacks
+	}()
+
+	e.tick()
+	waitFor(t, elected, "onElected")
+
+	// The database becomes unreachable, a fresh lease is kept
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+	e.tick()
+	if !e.IsLeader() {
+		t.Fatal("expected a single failed renewal to keep the lease")
+	}
+
+	// Less than two ticks left: the next tick could come too late
+	e.mu.Lock()
+	e.expires = time.Now().Add(ttl / 2)
+	e.mu.Unlock()
+	e.tick()
+	if e.IsLeader() {
+		t.Error("expected to step down")
+	}
+	waitFor(t, deposed, "onDeposed")
+}
@@ -0,0 +1,143 @@
+package ha
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// relayRetention is how long relayed events are kept for slow replicas
+const relayRetention = 10 * time.Minute
+
+// relayedPayloads maps the relayed event types to their payload types, so
+// subscribers on other replicas receive the same Go types
+var relayedPayloads = map[string]reflect.Type{
+	events.ConfigChanged:     reflect.TypeOf(events.ResourceChange{}),
+	events.ProviderUpdated:   reflect.TypeOf(events.ProviderChange{}),
+	events.BackendHealth:     reflect.TypeOf(events.BackendChange{}),
+	events.ProviderFetched:   reflect.TypeOf(events.ProviderChange{}),
+	events.ProviderFailed:    reflect.TypeOf(events.ProviderChange{}),
+	events.ProviderRecovered: reflect.TypeOf(events.ProviderChange{}),
+	events.UserCreated:       reflect.TypeOf(events.UserChange{}),
+}
+
+// Relay copies this replica's events to the database and forwards the
+// events of other replicas to the local bus, with their origin set. Events
+// with an origin are never relayed again.
+type Relay struct {
+	db       *gorm.DB
+	bus      *events.Bus
+	instance string
+	interval time.Duration
+
+	lastID   uint
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// NewRelay creates a relay polling for other replicas' events every interval
+func NewRelay(db *gorm.DB, bus *events.Bus, instance string, interval time.Duration) *Relay {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	return &Relay{
+		db:       db,
+		bus:      bus,
+		instance: instance,
+		interval: interval,
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start relays events published from now on
+func (r *Relay) Start() {
+	// Earlier events are already reflected in the database
+	r.db.Model(&models.ClusterEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&r.lastID)
+
+	types := make([]string, 0, len(relayedPayloads))
+	for t := range relayedPayloads {
+		types = append(types, t)
+	}
+	local, unsubscribe := r.bus.Subscribe(256, types...)
+
+	go func() {
+		defer close(r.done)
+		defer unsubscribe()
+
+		poll := time.NewTicker(r.interval)
+		defer poll.Stop()
+		prune := time.NewTicker(relayRetention)
+		defer prune.Stop()
+
+		for {
+			select {
+			case event := <-local:
+				if event.Origin == "" {
+					r.send(event)
+				}
+			case <-poll.C:
+				r.receive()
+			case <-prune.C:
+				r.db.Where("created_at < ?", time.Now().Add(-relayRetention)).Delete(&models.ClusterEvent{})
+			case <-r.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends relaying
+func (r *Relay) Stop() {
+	close(r.stopChan)
+	<-r.done
+}
+
+func (r *Relay) send(event events.Event) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		slog.Error("Failed to relay event", "type", event.Type, "error", err)
+		return
+	}
+	record := models.ClusterEvent{Type: event.Type, Data: string(data), Origin: r.instance, CreatedAt: event.Time}
+	if err := r.db.Create(&record).Error; err != nil {
+		slog.Error("Failed to relay event", "type", event.Type, "error", err)
+	}
+}
+
+// receive forwards the events other replicas stored since the last poll.
+// Events are read in ID order; an insert still uncommitted while a later
+// one is read is skipped, the next change to the same resources brings
+// the replica back in line.
+func (r *Relay) receive() {
+	var records []models.ClusterEvent
+	err := r.db.Where("id > ?", r.lastID).Order("id").Limit(500).Find(&records).Error
+	if err != nil {
+		slog.Error("Failed to read relayed events", "error", err)
+		return
+	}
+
+	for _, record := range records {
+		r.lastID = record.ID
+		payloadType, ok := relayedPayloads[record.Type]
+		if !ok || record.Origin == r.instance {
+			continue
+		}
+		payload := reflect.New(payloadType)
+		if err := json.Unmarshal([]byte(record.Data), payload.Interface()); err != nil {
+			slog.Error("Failed to decode relayed event", "type", record.Type, "error", err)
+			continue
+		}
+		r.bus.Forward(events.Event{
+			Type:   record.Type,
+			Data:   payload.Elem().Interface(),
+			Time:   record.CreatedAt,
+			Origin: record.Origin,
+		})
+	}
+}
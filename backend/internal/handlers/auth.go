@@ -1,22 +1,34 @@
 package handlers
 
 import (
+	"context"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/traefikx/backend/internal/auth"
+	"github.com/traefikx/backend/internal/config"
 	"github.com/traefikx/backend/internal/database"
+	"github.com/traefikx/backend/internal/mailer"
+	"github.com/traefikx/backend/internal/metrics"
 	"github.com/traefikx/backend/internal/models"
 	"gorm.io/gorm"
 )
 
+// Time between two prunes of the login history
+const loginHistoryPruneEvery = time.Hour
+
 type AuthHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	throttle *auth.LoginThrottle
+	mailer   mailer.Mailer
 }
 
-func NewAuthHandler(db *gorm.DB) *AuthHandler {
-	return &AuthHandler{db: db}
+func NewAuthHandler(db *gorm.DB, throttle *auth.LoginThrottle, m mailer.Mailer) *AuthHandler {
+	return &AuthHandler{db: db, throttle: throttle, mailer: m}
 }
 
 // Login handles password-based authentication
@@ -27,9 +39,20 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	ip := c.ClientIP()
+	req.Email = models.NormalizeEmail(req.Email)
+
+	// Per-IP and per-account throttling
+	if ok, retryAfter := h.throttle.Allow(ip, req.Email); !ok {
+		tooManyAttempts(c, retryAfter)
+		return
+	}
+
 	// Find user by email
 	var user models.User
 	if err := h.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		h.throttle.Fail(ip, req.Email)
+		h.recordLoginAttempt(c, 0, req.Email, false, "unknown_user")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
@@ -46,12 +69,36 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Verify password
+	// Verify password, before the lock is revealed, so a locked account
+	// answers a wrong password like an unknown one
 	if !database.CheckPassword(req.Password, user.Password) {
+		h.throttle.Fail(ip, req.Email)
+		if user.IsLocked() {
+			h.recordLoginAttempt(c, user.ID, user.Email, false, "account_locked")
+		} else {
+			h.recordFailedLogin(c, &user)
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
+	// Check if account is temporarily locked
+	if user.IsLocked() {
+		h.recordLoginAttempt(c, user.ID, user.Email, false, "account_locked")
+		c.JSON(http.StatusLocked, gin.H{
+			"error":        "Account is temporarily locked due to too many failed login attempts",
+			"locked_until": user.LockedUntil,
+		})
+		return
+	}
+
+	h.throttle.Succeed(req.Email)
+	h.recordLoginAttempt(c, user.ID, user.Email, true, "")
+
+	// Reset failed attempt counters
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+
 	// Update last login time
 	now := time.Now()
 	user.LastLoginAt = &now
@@ -67,7 +114,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	// Create session
 	session := models.Session{
 		UserID:    user.ID,
-		Token:     tokenPair.RefreshToken,
+		TokenHash: models.HashToken(tokenPair.RefreshToken),
 		ExpiresAt: time.Now().Add(7 * 24 * time.Hour),
 	}
 	h.db.Create(&session)
@@ -115,9 +162,18 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
+	ip := c.ClientIP()
+
+	// Per-IP throttling
+	if ok, retryAfter := h.throttle.Allow(ip, ""); !ok {
+		tooManyAttempts(c, retryAfter)
+		return
+	}
+
 	// Find session by refresh token
 	var session models.Session
-	if err := h.db.Where("token = ?", req.RefreshToken).First(&session).Error; err != nil {
+	if err := h.db.Where("token = ?", models.HashToken(req.RefreshToken)).First(&session).Error; err != nil {
+		h.throttle.Fail(ip, "")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
 		return
 	}
@@ -142,6 +198,15 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
+	// Check if account is temporarily locked
+	if user.IsLocked() {
+		c.JSON(http.StatusLocked, gin.H{
+			"error":        "Account is temporarily locked due to too many failed login attempts",
+			"locked_until": user.LockedUntil,
+		})
+		return
+	}
+
 	// Generate new token pair
 	tokenPair, err := auth.GenerateTokenPair(user.ID, user.Email, string(user.Role))
 	if err != nil {
@@ -150,7 +215,7 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 	}
 
 	// Update session with new refresh token
-	session.Token = tokenPair.RefreshToken
+	session.TokenHash = models.HashToken(tokenPair.RefreshToken)
 	session.ExpiresAt = time.Now().Add(7 * 24 * time.Hour)
 	h.db.Save(&session)
 
@@ -283,3 +348,85 @@ func (h *AuthHandler) RemovePassword(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Password removed successfully"})
 }
+
+// recordFailedLogin increments the failed attempt counter and locks the
+// account once the configured limit is reached
+func (h *AuthHandler) recordFailedLogin(c *gin.Context, user *models.User) {
+	cfg := config.AppConfig
+	now := time.Now()
+
+	// Start counting again once a previous lock has expired
+	if user.LockedUntil != nil && !user.IsLocked() {
+		user.FailedLoginAttempts = 0
+		user.LockedUntil = nil
+	}
+
+	user.FailedLoginAttempts++
+	user.LastFailedLoginAt = &now
+
+	reason := "invalid_password"
+	if cfg.LoginMaxAttempts > 0 && user.FailedLoginAttempts >= cfg.LoginMaxAttempts {
+		lockedUntil := now.Add(cfg.LoginLockoutDuration)
+		user.LockedUntil = &lockedUntil
+		reason = "invalid_password_locked"
+		slog.WarnContext(c.Request.Context(), "Account locked after failed login attempts", "email", user.Email,
+			"locked_until", lockedUntil.Format(time.RFC3339), "attempts", user.FailedLoginAttempts)
+	}
+
+	if err := h.db.Model(user).Updates(map[string]interface{}{
+		"failed_login_attempts": user.FailedLoginAttempts,
+		"last_failed_login_at":  user.LastFailedLoginAt,
+		"locked_until":          user.LockedUntil,
+	}).Error; err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to update failed login counters", "email", user.Email, "error", err)
+	}
+
+	h.recordLoginAttempt(c, user.ID, user.Email, false, reason)
+}
+
+// recordLoginAttempt stores an authentication attempt in the login history
+func (h *AuthHandler) recordLoginAttempt(c *gin.Context, userID uint, email string, success bool, reason string) {
+	attempt := models.LoginAttempt{
+		UserID:    userID,
+		Email:     email,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Success:   success,
+		Reason:    reason,
+	}
+	if err := h.db.Create(&attempt).Error; err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to record login attempt", "error", err)
+	}
+	metrics.ObserveLogin("password", success)
+}
+
+// PruneLoginHistory deletes login attempts older than maxAge, right away and
+// then once per loginHistoryPruneEvery, until ctx is done
+func PruneLoginHistory(ctx context.Context, db *gorm.DB, maxAge time.Duration) {
+	ticker := time.NewTicker(loginHistoryPruneEvery)
+	defer ticker.Stop()
+	for {
+		if err := db.WithContext(ctx).Where("created_at < ?", time.Now().Add(-maxAge)).Delete(&models.LoginAttempt{}).Error; err != nil && ctx.Err() == nil {
+			slog.Error("Failed to prune login history", "error", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// tooManyAttempts responds with 429 and a Retry-After header
+func tooManyAttempts(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many attempts, please try again later",
+		"retry_after": seconds,
+	})
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/auth"
+	"github.com/traefikx/backend/internal/config"
+	"github.com/traefikx/backend/internal/database"
+	"github.com/traefikx/backend/internal/ha"
+	"github.com/traefikx/backend/internal/services"
+	"github.com/traefikx/backend/internal/tracing"
+	"github.com/traefikx/backend/internal/version"
+	"gorm.io/gorm"
+)
+
+// readyTimeout bounds the database checks of a readiness probe
+const readyTimeout = 2 * time.Second
+
+type HealthHandler struct {
+	cfg        *config.Config
+	db         *gorm.DB
+	aggregator *services.AggregatorService
+	elector    *ha.Elector
+}
+
+// NewHealthHandler creates a health handler. elector is nil without HA.
+func NewHealthHandler(cfg *config.Config, db *gorm.DB, aggregator *services.AggregatorService, elector *ha.Elector) *HealthHandler {
+	return &HealthHandler{cfg: cfg, db: db, aggregator: aggregator, elector: elector}
+}
+
+// Health reports that the replica is serving and whether it is the leader
+func (h *HealthHandler) Health(c *gin.Context) {
+	status := h.elector.Status()
+	role := "follower"
+	if status.Leader {
+		role = "leader"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"role":   role,
+		"ha":     status,
+	})
+}
+
+// Live is the liveness probe: the process answers HTTP requests
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Ready is the readiness probe. The replica is ready when the database is
+// reachable and migrated, OIDC is initialized if enabled and, on the
+// leader, the aggregator polls providers. Each check is reported, with a
+// 503 status if one fails.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readyTimeout)
+	defer cancel()
+
+	checks := gin.H{}
+	ready := true
+	check := func(name string, err error) {
+		if err != nil {
+			ready = false
+			checks[name] = gin.H{"status": "fail", "error": err.Error()}
+			return
+		}
+		checks[name] = gin.H{"status": "ok"}
+	}
+
+	check("database", h.checkDatabase(ctx))
+	check("migrations", h.checkMigrations(ctx))
+	if h.cfg.OIDCEnabled {
+		check("oidc", h.checkOIDC())
+	}
+	check("aggregator", h.checkAggregator())
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not ready", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{"status": status, "checks": checks})
+}
+
+func (h *HealthHandler) checkDatabase(ctx context.Context) error {
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (h *HealthHandler) checkMigrations(ctx context.Context) error {
+	current, err := database.SchemaVersion(h.db.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	if latest := database.LatestVersion(); current != latest {
+		return fmt.Errorf("schema at version %d, %d required", current, latest)
+	}
+	return nil
+}
+
+func (h *HealthHandler) checkOIDC() error {
+	if !auth.OIDCInitialized() {
+		return fmt.Errorf("OIDC is enabled but failed to initialize")
+	}
+	return nil
+}
+
+// checkAggregator requires the leader's aggregator to run, followers leave
+// polling to the leader
+func (h *HealthHandler) checkAggregator() error {
+	if h.aggregator == nil {
+		return fmt.Errorf("aggregator service not available")
+	}
+	if h.elector.Status().Leader && !h.aggregator.Running() {
+		return fmt.Errorf("aggregator not started")
+	}
+	return nil
+}
+
+// Version returns the build information, the schema version and the
+// optional features enabled in the configuration
+func (h *HealthHandler) Version(c *gin.Context) {
+	schemaVersion, err := database.SchemaVersion(h.db.WithContext(c.Request.Context()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read schema version"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"build":          version.Get(),
+		"schema_version": schemaVersion,
+		"schema_latest":  database.LatestVersion(),
+		"features":       enabledFeatures(h.cfg),
+	})
+}
+
+// enabledFeatures lists the optional features turned on in cfg
+func enabledFeatures(cfg *config.Config) []string {
+	features := []string{}
+	for _, feature := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"oidc", cfg.OIDCEnabled},
+		{"ha", cfg.HAEnabled},
+		{"metrics", cfg.MetricsEnabled},
+		{"tracing", cfg.TracesExporter != "" && !strings.EqualFold(cfg.TracesExporter, tracing.ExporterNone)},
+		{"health_probes", cfg.ProbeEnabled},
+		{"scheduled_backups", cfg.BackupInterval > 0},
+		{"encrypted_backups", cfg.BackupEncryptionKey != ""},
+		{"publish_file", cfg.PublishFileDir != ""},
+		{"publish_redis", cfg.PublishRedisAddr != ""},
+		{"publish_etcd", len(cfg.PublishEtcdEndpoints) > 0},
+	} {
+		if feature.enabled {
+			features = append(features, feature.name)
+		}
+	}
+	return features
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/auth"
+	"github.com/traefikx/backend/internal/config"
+	"github.com/traefikx/backend/internal/database"
+	"github.com/traefikx/backend/internal/models"
+)
+
+// GetInvite validates an invitation link and returns what the user can do with it
+func (h *AuthHandler) GetInvite(c *gin.Context) {
+	tokenString := c.Query("token")
+	if tokenString == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token parameter required"})
+		return
+	}
+
+	token, user, err := lookupUserToken(h.db, tokenString, auth.TokenPurposeInvite)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"email":        user.Email,
+		"expires_at":   token.ExpiresAt,
+		"oidc_enabled": auth.IsOIDCEnabled(),
+	})
+}
+
+// AcceptInvite sets the password of an invited user and logs them in
+func (h *AuthHandler) AcceptInvite(c *gin.Context) {
+	var req models.AcceptInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, user, err := lookupUserToken(h.db, req.Token, auth.TokenPurposeInvite)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !user.IsActive {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is disabled"})
+		return
+	}
+
+	if err := validatePassword(req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := database.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	if err := markUserTokenUsed(h.db, token); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	user.Password = hashedPassword
+	user.PasswordEnabled = true
+	user.LastLoginAt = &now
+	if err := h.db.Save(user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set password"})
+		return
+	}
+
+	h.respondWithSession(c, user)
+}
+
+// InviteOIDC starts the OIDC flow to accept an invitation by linking an identity
+func (h *AuthHandler) InviteOIDC(c *gin.Context) {
+	if !auth.IsOIDCEnabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "OIDC is not configured"})
+		return
+	}
+
+	var req models.InviteOIDCRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, user, err := lookupUserToken(h.db, req.Token, auth.TokenPurposeInvite)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	state := auth.GenerateOIDCInviteState(user.ID, token.ID)
+	authURL := auth.GetOIDCAuthURL(state)
+
+	c.JSON(http.StatusOK, gin.H{
+		"auth_url": authURL,
+		"state":    state,
+	})
+}
+
+// handleOIDCInvite links the OIDC identity to an invited user and logs them in
+func (h *AuthHandler) handleOIDCInvite(c *gin.Context, oidcState *auth.OIDCState, userInfo *auth.OIDCUserInfo) {
+	var token models.UserToken
+	if err := h.db.First(&token, oidcState.InviteTokenID).Error; err != nil ||
+		!token.IsUsable() || token.UserID != oidcState.LinkToUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errTokenUnusable.Error()})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, token.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !user.IsActive {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is disabled"})
+		return
+	}
+
+	// Check if another user is already linked to this OIDC account
+	var existingUser models.User
+	if err := h.db.Where("oidc_subject = ? AND id != ?", userInfo.Subject, user.ID).First(&existingUser).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another account is already linked to this OIDC identity"})
+		return
+	}
+
+	// The identity must belong to the invited email address
+	if !strings.EqualFold(user.Email, userInfo.Email) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Email mismatch",
+			"details": gin.H{
+				"account_email": user.Email,
+				"oidc_email":    userInfo.Email,
+			},
+		})
+		return
+	}
+
+	if err := markUserTokenUsed(h.db, &token); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	user.OIDCProvider = config.AppConfig.OIDCProviderName
+	user.OIDCSubject = userInfo.Subject
+	user.OIDCEnabled = true
+	user.OIDCLinkedAt = &now
+	user.LastLoginAt = &now
+
+	if err := h.db.Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link account"})
+		return
+	}
+
+	h.respondWithSession(c, &user)
+}
+
+// ForgotPassword emails a password reset link. The response never reveals
+// whether the account exists.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Throttled so reset emails can't be used for flooding
+	if ok, retryAfter := h.throttle.AllowPasswordReset(c.ClientIP(), req.Email); !ok {
+		tooManyAttempts(c, retryAfter)
+		return
+	}
+
+	var user models.User
+	if err := h.db.Where("email = ?", models.NormalizeEmail(req.Email)).First(&user).Error; err == nil &&
+		user.IsActive && user.PasswordEnabled {
+		// Send in the background so response timing doesn't leak account existence
+		ctx := c.Request.Context()
+		go func(user models.User) {
+			if err := sendPasswordReset(h.db, h.mailer, &user); err != nil {
+				slog.ErrorContext(ctx, "Failed to send password reset email", "email", user.Email, "error", err)
+			}
+		}(user)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If an account exists for this email, a reset link has been sent"})
+}
+
+// ResetPasswordWithToken sets a new password using a reset link
+func (h *AuthHandler) ResetPasswordWithToken(c *gin.Context) {
+	var req models.ResetPasswordWithTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, user, err := lookupUserToken(h.db, req.Token, auth.TokenPurposePasswordReset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validatePassword(req.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hashedPassword, err := database.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	if err := markUserTokenUsed(h.db, token); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user.Password = hashedPassword
+	user.PasswordEnabled = true
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+	if err := h.db.Save(user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+
+	// Sign out existing sessions
+	h.db.Where("user_id = ?", user.ID).Delete(&models.Session{})
+	h.throttle.ResetAccount(user.Email)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// respondWithSession issues a token pair and session for user
+func (h *AuthHandler) respondWithSession(c *gin.Context, user *models.User) {
+	tokenPair, err := auth.GenerateTokenPair(user.ID, user.Email, string(user.Role))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		return
+	}
+
+	session := models.Session{
+		UserID:    user.ID,
+		TokenHash: models.HashToken(tokenPair.RefreshToken),
+		ExpiresAt: time.Now().Add(7 * 24 * time.Hour),
+	}
+	h.db.Create(&session)
+
+	c.JSON(http.StatusOK, models.AuthResponse{
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    tokenPair.ExpiresIn,
+		User:         *user,
+	})
+}
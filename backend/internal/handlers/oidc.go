@@ -1,14 +1,14 @@
 package handlers
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/traefikx/backend/internal/auth"
 	"github.com/traefikx/backend/internal/config"
+	"github.com/traefikx/backend/internal/metrics"
 	"github.com/traefikx/backend/internal/models"
 )
 
@@ -50,6 +50,11 @@ func (h *AuthHandler) OIDCCallback(c *gin.Context) {
 		return
 	}
 
+	// Logins and invitations count, linking to a signed in account doesn't
+	if oidcState.LinkToUser == 0 {
+		defer func() { metrics.ObserveLogin("oidc", c.Writer.Status() == http.StatusOK) }()
+	}
+
 	// Exchange code for token
 	token, err := auth.ExchangeOIDCCode(code)
 	if err != nil {
@@ -65,34 +70,39 @@ func (h *AuthHandler) OIDCCallback(c *gin.Context) {
 	}
 
 	if userInfo.Email == "" {
-		log.Printf("Error: OIDC provider returned empty email. Subject: %s", userInfo.Subject)
+		slog.WarnContext(c.Request.Context(), "OIDC provider returned no email", "subject", userInfo.Subject)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "OIDC provider did not return an email address"})
 		return
 	}
 
+	// Check if this completes an invitation
+	if oidcState.InviteTokenID > 0 {
+		h.handleOIDCInvite(c, oidcState, userInfo)
+		return
+	}
+
 	// Check if this is a linking flow
 	if oidcState.LinkToUser > 0 {
 		h.handleOIDCLink(c, oidcState.LinkToUser, userInfo)
 		return
 	}
 
-	log.Printf("OIDC Callback: Subject=%s, Email=%s", userInfo.Subject, userInfo.Email)
+	slog.DebugContext(c.Request.Context(), "OIDC callback", "subject", userInfo.Subject, "email", userInfo.Email)
 
 	// Find or create user
 	var user models.User
 	result := h.db.Where("oidc_subject = ? AND oidc_provider = ?", userInfo.Subject, config.AppConfig.OIDCProviderName).First(&user)
 
 	if result.Error != nil {
-		log.Printf("OIDC user not found by subject. Searching by email: %s", userInfo.Email)
+		slog.DebugContext(c.Request.Context(), "OIDC user not found by subject, searching by email", "email", userInfo.Email)
 
 		// User doesn't exist, check if there's a user with same email
-		// Use lowercase comparison to ensure we match regardless of casing
-		emailResult := h.db.Where("lower(email) = ?", strings.ToLower(userInfo.Email)).First(&user)
-		log.Printf("Email search result: ID=%d, Email=%s, Error=%v", user.ID, user.Email, emailResult.Error)
+		emailResult := h.db.Where("email = ?", models.NormalizeEmail(userInfo.Email)).First(&user)
+		slog.DebugContext(c.Request.Context(), "OIDC email search", "user_id", user.ID, "email", user.Email, "error", emailResult.Error)
 
 		if user.ID == 0 {
 			// User does not exist and auto-creation is disabled
-			log.Printf("OIDC login failed: No user found with email %s", userInfo.Email)
+			slog.WarnContext(c.Request.Context(), "OIDC login failed, no user with this email", "email", userInfo.Email)
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error":   "Account not found",
 				"details": "No account exists with this email address. Please contact an administrator.",
@@ -100,7 +110,7 @@ func (h *AuthHandler) OIDCCallback(c *gin.Context) {
 			return
 		} else {
 			// Link existing user with OIDC (email matches)
-			log.Printf("Linking existing user ID=%d with OIDC", user.ID)
+			slog.InfoContext(c.Request.Context(), "Linking existing user with OIDC", "user_id", user.ID)
 
 			user.OIDCProvider = config.AppConfig.OIDCProviderName
 			user.OIDCSubject = userInfo.Subject
@@ -110,11 +120,11 @@ func (h *AuthHandler) OIDCCallback(c *gin.Context) {
 
 			// Ensure email is set (in case DB record was weird)
 			if user.Email == "" {
-				user.Email = userInfo.Email
+				user.Email = models.NormalizeEmail(userInfo.Email)
 			}
 
 			if err := h.db.Save(&user).Error; err != nil {
-				log.Printf("Failed to link user: %v", err)
+				slog.ErrorContext(c.Request.Context(), "Failed to link user", "user_id", user.ID, "error", err)
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link user", "details": err.Error()})
 				return
 			}
@@ -142,7 +152,7 @@ func (h *AuthHandler) OIDCCallback(c *gin.Context) {
 	// Create session
 	session := models.Session{
 		UserID:    user.ID,
-		Token:     tokenPair.RefreshToken,
+		TokenHash: models.HashToken(tokenPair.RefreshToken),
 		ExpiresAt: time.Now().Add(7 * 24 * time.Hour),
 	}
 	h.db.Create(&session)
@@ -230,7 +240,7 @@ func (h *AuthHandler) handleOIDCLink(c *gin.Context, userID uint, userInfo *auth
 	}
 
 	// Check if email matches (if user has email)
-	if user.Email != userInfo.Email {
+	if user.Email != models.NormalizeEmail(userInfo.Email) {
 		c.JSON(http.StatusConflict, gin.H{
 			"error": "Email mismatch",
 			"details": gin.H{
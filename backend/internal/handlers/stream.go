@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/models"
+)
+
+const (
+	streamHeartbeat  = 25 * time.Second // Comment sent on idle streams so proxies keep them open
+	streamRetry      = 3 * time.Second  // Reconnection delay advised to clients
+	streamBufferSize = 64               // Events queued for a slow client before it misses some
+)
+
+// streamedEvents are the bus events sent to clients
+var streamedEvents = []string{
+	events.ProviderFetched,
+	events.ProviderFailed,
+	events.ProviderRecovered,
+	events.ConfigChanged,
+	events.ConfigCompiled,
+	events.BackendHealth,
+}
+
+type StreamHandler struct {
+	bus      *events.Bus
+	shutdown context.Context
+}
+
+// NewStreamHandler creates a stream handler. Streams end when shutdown is
+// done, so they don't hold up the server's graceful shutdown.
+func NewStreamHandler(bus *events.Bus, shutdown context.Context) *StreamHandler {
+	return &StreamHandler{bus: bus, shutdown: shutdown}
+}
+
+// Events streams bus events as Server-Sent Events, named after their type
+// with the event as JSON data. Admins receive every event; users receive
+// the changes to their own proxies and routers and the config versions.
+// The stream ends with a session.expired event when the access token
+// expires, to be reopened with a fresh one.
+func (h *StreamHandler) Events(c *gin.Context) {
+	userID := c.GetUint("userID")
+	isAdmin := c.GetString("role") == string(models.RoleAdmin)
+
+	published, unsubscribe := h.bus.Subscribe(streamBufferSize, streamedEvents...)
+	defer unsubscribe()
+
+	var expired <-chan time.Time
+	if expiresAt, ok := c.Get("tokenExpiresAt"); ok {
+		timer := time.NewTimer(time.Until(expiresAt.(time.Time)))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Unbuffered through nginx
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", streamRetry.Milliseconds())
+	c.Writer.Flush()
+
+	for {
+		select {
+		case event, ok := <-published:
+			if !ok {
+				return
+			}
+			if !visibleTo(event, userID, isAdmin) {
+				continue
+			}
+			c.SSEvent(event.Type, event)
+		case <-heartbeat.C:
+			io.WriteString(c.Writer, ": ping\n\n")
+		case <-expired:
+			c.SSEvent("session.expired", gin.H{})
+			c.Writer.Flush()
+			return
+		case <-c.Request.Context().Done():
+			return
+		case <-h.shutdown.Done():
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// visibleTo reports whether a user may receive event. Users can only see
+// their own resources, other events are for admins.
+func visibleTo(event events.Event, userID uint, isAdmin bool) bool {
+	if isAdmin {
+		return true
+	}
+	switch data := event.Data.(type) {
+	case events.ResourceChange:
+		return data.UserID == userID
+	case events.ConfigVersion:
+		return true
+	}
+	return false
+}
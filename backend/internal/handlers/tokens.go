@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/traefikx/backend/internal/auth"
+	"github.com/traefikx/backend/internal/config"
+	"github.com/traefikx/backend/internal/mailer"
+	"github.com/traefikx/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var errTokenUnusable = errors.New("link is invalid, expired or has already been used")
+
+// issueUserToken signs a single-use token for user and stores its ID.
+// Older unused tokens with the same purpose are revoked.
+func issueUserToken(db *gorm.DB, user *models.User, purpose string, ttl time.Duration) (string, time.Time, error) {
+	tokenString, tokenID, expiresAt, err := auth.GenerateActionToken(user.ID, purpose, ttl)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, purpose).
+			Delete(&models.UserToken{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.UserToken{
+			UserID:    user.ID,
+			Purpose:   purpose,
+			TokenID:   tokenID,
+			ExpiresAt: expiresAt,
+		}).Error
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expiresAt, nil
+}
+
+// lookupUserToken verifies a signed token and returns its record and user
+// without consuming it
+func lookupUserToken(db *gorm.DB, tokenString, purpose string) (*models.UserToken, *models.User, error) {
+	claims, err := auth.ParseActionToken(tokenString, purpose)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var token models.UserToken
+	if err := db.Where("token_id = ? AND purpose = ?", claims.ID, purpose).First(&token).Error; err != nil {
+		return nil, nil, errTokenUnusable
+	}
+
+	if !token.IsUsable() || token.UserID != claims.UserID {
+		return nil, nil, errTokenUnusable
+	}
+
+	var user models.User
+	if err := db.First(&user, token.UserID).Error; err != nil {
+		return nil, nil, errTokenUnusable
+	}
+
+	return &token, &user, nil
+}
+
+// markUserTokenUsed consumes a token. It fails if the token was used concurrently.
+func markUserTokenUsed(db *gorm.DB, token *models.UserToken) error {
+	now := time.Now()
+	result := db.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errTokenUnusable
+	}
+
+	token.UsedAt = &now
+	return nil
+}
+
+// sendInvite emails an invitation link to user
+func sendInvite(db *gorm.DB, m mailer.Mailer, user *models.User) error {
+	cfg := config.AppConfig
+
+	token, expiresAt, err := issueUserToken(db, user, auth.TokenPurposeInvite, cfg.InviteTokenDuration)
+	if err != nil {
+		return err
+	}
+
+	link := cfg.AppBaseURL + "/auth/invite?token=" + url.QueryEscape(token)
+	return m.Send(mailer.InviteMessage(user.Email, link, expiresAt))
+}
+
+// sendPasswordReset emails a password reset link to user
+func sendPasswordReset(db *gorm.DB, m mailer.Mailer, user *models.User) error {
+	cfg := config.AppConfig
+
+	token, expiresAt, err := issueUserToken(db, user, auth.TokenPurposePasswordReset, cfg.PasswordResetTokenDuration)
+	if err != nil {
+		return err
+	}
+
+	link := cfg.AppBaseURL + "/auth/reset-password?token=" + url.QueryEscape(token)
+	return m.Send(mailer.PasswordResetMessage(user.Email, link, expiresAt))
+}
@@ -0,0 +1,181 @@
+package traefik
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/backup"
+	"github.com/traefikx/backend/internal/database"
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/services"
+	"gorm.io/gorm"
+)
+
+// maxBackupUpload limits the size of uploaded backups
+const maxBackupUpload = 512 << 20
+
+type BackupHandler struct {
+	db         *gorm.DB
+	manager    *backup.Manager
+	aggregator *services.AggregatorService
+	bus        *events.Bus
+}
+
+func NewBackupHandler(db *gorm.DB, manager *backup.Manager, aggregator *services.AggregatorService, bus *events.Bus) *BackupHandler {
+	return &BackupHandler{
+		db:         db,
+		manager:    manager,
+		aggregator: aggregator,
+		bus:        bus,
+	}
+}
+
+// ListBackups returns the backups in the backup directory and the schedule
+func (h *BackupHandler) ListBackups(c *gin.Context) {
+	files, err := h.manager.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list backups"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"backups": files,
+		"schedule": gin.H{
+			"enabled":   h.manager.Interval() > 0,
+			"interval":  h.manager.Interval().String(),
+			"retention": h.manager.Retention(),
+			"encrypted": h.manager.Options().Passphrase != "",
+		},
+	})
+}
+
+// CreateBackup takes a backup into the backup directory
+func (h *BackupHandler) CreateBackup(c *gin.Context) {
+	file, err := h.manager.Create()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create backup: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, file)
+}
+
+// DownloadSnapshot takes a backup and sends it without storing it
+func (h *BackupHandler) DownloadSnapshot(c *gin.Context) {
+	var buf bytes.Buffer
+	manifest, err := backup.Write(h.db, &buf, h.manager.Options())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create backup: " + err.Error()})
+		return
+	}
+
+	name := backup.FileName(manifest.CreatedAt, h.manager.Options())
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Data(http.StatusOK, "application/octet-stream", buf.Bytes())
+}
+
+// DownloadBackup sends a backup from the backup directory
+func (h *BackupHandler) DownloadBackup(c *gin.Context) {
+	path, err := h.manager.Path(c.Param("name"))
+	if err != nil {
+		h.fileError(c, err)
+		return
+	}
+	c.FileAttachment(path, c.Param("name"))
+}
+
+// DeleteBackup removes a backup from the backup directory
+func (h *BackupHandler) DeleteBackup(c *gin.Context) {
+	if err := h.manager.Delete(c.Param("name")); err != nil {
+		h.fileError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Backup deleted"})
+}
+
+// RestoreBackup restores an uploaded backup (multipart field "file") or one
+// from the backup directory ("name"). With dry_run=true the backup is only
+// validated.
+func (h *BackupHandler) RestoreBackup(c *gin.Context) {
+	passphrase := h.manager.Options().Passphrase
+	if value := c.PostForm("passphrase"); value != "" {
+		passphrase = value
+	}
+
+	var reader io.Reader
+	if upload, err := c.FormFile("file"); err == nil {
+		if upload.Size > maxBackupUpload {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Backup too large"})
+			return
+		}
+		f, err := upload.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read upload"})
+			return
+		}
+		defer f.Close()
+		reader = f
+	} else if name := c.PostForm("name"); name != "" {
+		path, err := h.manager.Path(name)
+		if err != nil {
+			h.fileError(c, err)
+			return
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read backup"})
+			return
+		}
+		defer f.Close()
+		reader = f
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either file or name is required"})
+		return
+	}
+
+	opened, err := backup.Open(reader, passphrase)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("dry_run") == "true" {
+		if err := opened.Validate(h.db); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "manifest": opened.Manifest})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"valid": true, "manifest": opened.Manifest})
+		return
+	}
+
+	if err := opened.Restore(h.db); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Restore failed: " + err.Error(), "manifest": opened.Manifest})
+		return
+	}
+
+	// Secrets of a backup taken before a key rotation
+	if _, err := database.ReencryptSecrets(h.db); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to re-encrypt restored secrets", "error", err)
+	}
+
+	// Providers are reloaded here; other replicas and the compiler follow the event
+	h.aggregator.Reload()
+	h.bus.PublishContext(c.Request.Context(), events.ConfigChanged, events.ResourceChange{Kind: "database", Action: events.ActionRestored})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Backup restored", "manifest": opened.Manifest})
+}
+
+func (h *BackupHandler) fileError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, backup.ErrInvalidName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid backup name"})
+	case errors.Is(err, os.ErrNotExist):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,192 @@
+package traefik
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/models"
+	"github.com/traefikx/backend/internal/services"
+	"gorm.io/gorm"
+)
+
+type ConflictHandler struct {
+	db  *gorm.DB
+	bus *events.Bus
+}
+
+func NewConflictHandler(db *gorm.DB, bus *events.Bus) *ConflictHandler {
+	return &ConflictHandler{db: db, bus: bus}
+}
+
+// ListConflicts returns recorded conflicts. status selects open (default),
+// resolved or all conflicts.
+func (h *ConflictHandler) ListConflicts(c *gin.Context) {
+	query := h.db.Order("resource_type, resource_name, source")
+	switch c.DefaultQuery("status", "open") {
+	case "open":
+		query = query.Where("resolved_at IS NULL")
+	case "resolved":
+		query = query.Where("resolved_at IS NOT NULL")
+	case "all":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be open, resolved or all"})
+		return
+	}
+
+	var conflicts []models.ConfigConflict
+	if err := query.Find(&conflicts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conflicts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"conflicts": conflicts})
+}
+
+// ListPolicies returns all conflict policies
+func (h *ConflictHandler) ListPolicies(c *gin.Context) {
+	var policies []models.ConflictPolicy
+	if err := h.db.Order("resource_type, resource_name").Find(&policies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch conflict policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policies": policies})
+}
+
+// CreatePolicy adds a pin or merge policy for a resource
+func (h *ConflictHandler) CreatePolicy(c *gin.Context) {
+	var req models.ConflictPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.validatePolicy(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing models.ConflictPolicy
+	if err := h.db.Where("resource_type = ? AND resource_name = ?", req.ResourceType, req.ResourceName).
+		First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A policy for this resource already exists"})
+		return
+	}
+
+	policy := models.ConflictPolicy{
+		ResourceType: req.ResourceType,
+		ResourceName: req.ResourceName,
+		Strategy:     req.Strategy,
+		Source:       req.Source,
+	}
+	if err := h.db.Create(&policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create conflict policy"})
+		return
+	}
+
+	h.publish(c.Request.Context(), &policy, events.ActionCreated)
+	c.JSON(http.StatusCreated, policy)
+}
+
+// UpdatePolicy replaces a conflict policy
+func (h *ConflictHandler) UpdatePolicy(c *gin.Context) {
+	policy, ok := h.findPolicy(c)
+	if !ok {
+		return
+	}
+
+	var req models.ConflictPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.validatePolicy(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing models.ConflictPolicy
+	if err := h.db.Where("resource_type = ? AND resource_name = ? AND id != ?", req.ResourceType, req.ResourceName, policy.ID).
+		First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A policy for this resource already exists"})
+		return
+	}
+
+	policy.ResourceType = req.ResourceType
+	policy.ResourceName = req.ResourceName
+	policy.Strategy = req.Strategy
+	policy.Source = req.Source
+	if err := h.db.Save(policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update conflict policy"})
+		return
+	}
+
+	h.publish(c.Request.Context(), policy, events.ActionUpdated)
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeletePolicy removes a conflict policy, restoring priority order
+func (h *ConflictHandler) DeletePolicy(c *gin.Context) {
+	policy, ok := h.findPolicy(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(policy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete conflict policy"})
+		return
+	}
+
+	h.publish(c.Request.Context(), policy, events.ActionDeleted)
+	c.JSON(http.StatusOK, gin.H{"message": "Conflict policy deleted successfully"})
+}
+
+func (h *ConflictHandler) findPolicy(c *gin.Context) (*models.ConflictPolicy, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid policy ID"})
+		return nil, false
+	}
+
+	var policy models.ConflictPolicy
+	if err := h.db.First(&policy, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conflict policy not found"})
+		return nil, false
+	}
+	return &policy, true
+}
+
+// validatePolicy checks that pins name a known source and that merges
+// target resources that can be merged
+func (h *ConflictHandler) validatePolicy(req *models.ConflictPolicyRequest) error {
+	switch req.Strategy {
+	case models.ConflictStrategyPin:
+		if req.Source == "" {
+			return errors.New("source is required to pin a resource")
+		}
+		if req.Source != services.LocalSource {
+			var provider models.HTTPProvider
+			if err := h.db.Where("name = ?", req.Source).First(&provider).Error; err != nil {
+				return errors.New("source must be local or the name of an HTTP provider")
+			}
+		}
+	case models.ConflictStrategyMerge:
+		if req.ResourceType != "router" && req.ResourceType != "service" {
+			return errors.New("only routers and services can be merged")
+		}
+		req.Source = ""
+	}
+	return nil
+}
+
+func (h *ConflictHandler) publish(ctx context.Context, policy *models.ConflictPolicy, action string) {
+	h.bus.PublishContext(ctx, events.ConfigChanged, events.ResourceChange{
+		Kind:   "conflict_policy",
+		ID:     policy.ID,
+		Name:   policy.ResourceType + "/" + policy.ResourceName,
+		Action: action,
+	})
+}
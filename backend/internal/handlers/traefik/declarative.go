@@ -0,0 +1,258 @@
+package traefik
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/declarative"
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/models"
+	"github.com/traefikx/backend/internal/services"
+	"gorm.io/gorm"
+)
+
+// maxImportSize limits the size of an uploaded document
+const maxImportSize = 10 << 20
+
+type DeclarativeHandler struct {
+	db         *gorm.DB
+	aggregator *services.AggregatorService
+	bus        *events.Bus
+}
+
+func NewDeclarativeHandler(db *gorm.DB, aggregator *services.AggregatorService, bus *events.Bus) *DeclarativeHandler {
+	return &DeclarativeHandler{
+		db:         db,
+		aggregator: aggregator,
+		bus:        bus,
+	}
+}
+
+// Export returns the whole configuration as a declarative document
+func (h *DeclarativeHandler) Export(c *gin.Context) {
+	format, err := declarative.NormalizeFormat(c.DefaultQuery("format", declarative.FormatYAML))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	doc, err := declarative.Export(h.db, c.Query("include_users") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export configuration"})
+		return
+	}
+
+	data, err := declarative.Marshal(doc, format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode configuration"})
+		return
+	}
+
+	filename := fmt.Sprintf("traefikx-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, declarative.ContentType(format), data)
+}
+
+// Import applies a declarative document. With dry_run=true only the plan is
+// returned. Conflicts abort the import unless conflict=skip covers them.
+func (h *DeclarativeHandler) Import(c *gin.Context) {
+	opts, ok := importOptions(c)
+	if !ok {
+		return
+	}
+
+	format, body, ok := readDocument(c)
+	if !ok {
+		return
+	}
+
+	var doc declarative.Document
+	if err := declarative.Unmarshal(body, format, &doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.runImport(c, &doc, opts, nil)
+}
+
+// ImportTraefik imports a Traefik dynamic configuration file (source=file,
+// YAML/TOML/JSON) or a list of docker label sets (source=docker). Constructs
+// that can't be represented are listed as unsupported. Such a file only
+// describes part of the configuration, so only merge mode is accepted.
+func (h *DeclarativeHandler) ImportTraefik(c *gin.Context) {
+	opts, ok := importOptions(c)
+	if !ok {
+		return
+	}
+	if opts.Mode != declarative.ModeMerge {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Traefik configurations can only be imported with mode=merge"})
+		return
+	}
+
+	format, body, ok := readDocument(c)
+	if !ok {
+		return
+	}
+
+	var doc *declarative.Document
+	var unsupported []declarative.Conflict
+
+	switch c.DefaultQuery("source", "file") {
+	case "file":
+		cfg, err := declarative.ParseDynamicConfig(body, format)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		doc, unsupported = declarative.FromDynamic(cfg)
+	case "docker":
+		var sets []declarative.LabelSet
+		if err := declarative.Unmarshal(body, format, &sets); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		doc, unsupported = declarative.FromLabels(sets)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "source must be file or docker"})
+		return
+	}
+
+	h.runImport(c, doc, opts, unsupported)
+}
+
+// runImport plans and, unless it's a dry run, applies a document
+func (h *DeclarativeHandler) runImport(c *gin.Context, doc *declarative.Document, opts declarative.ImportOptions, unsupported []declarative.Conflict) {
+	result, err := declarative.Plan(h.db, doc, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to plan import"})
+		return
+	}
+	result.Unsupported = unsupported
+
+	if opts.DryRun {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	if result.HasBlockingConflicts() {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+
+	if err := declarative.Apply(h.db, doc, result, opts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Import failed: " + err.Error()})
+		return
+	}
+
+	h.syncProviders(c.Request.Context(), result)
+	h.publishCreatedUsers(result)
+	h.bus.PublishContext(c.Request.Context(), events.ConfigChanged, events.ResourceChange{Kind: "import", Action: events.ActionImported})
+
+	c.JSON(http.StatusOK, result)
+}
+
+// importOptions reads the mode, conflict and dry_run query parameters
+func importOptions(c *gin.Context) (declarative.ImportOptions, bool) {
+	mode := c.DefaultQuery("mode", declarative.ModeMerge)
+	if mode != declarative.ModeMerge && mode != declarative.ModeReplace {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be merge or replace"})
+		return declarative.ImportOptions{}, false
+	}
+
+	onConflict := c.DefaultQuery("conflict", declarative.OnConflictOverwrite)
+	if onConflict != declarative.OnConflictOverwrite && onConflict != declarative.OnConflictSkip {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "conflict must be overwrite or skip"})
+		return declarative.ImportOptions{}, false
+	}
+
+	userID, _ := c.Get("userID")
+	return declarative.ImportOptions{
+		Mode:       mode,
+		OnConflict: onConflict,
+		DryRun:     c.Query("dry_run") == "true",
+		ActorID:    userID.(uint),
+		ValidateProvider: func(provider *models.HTTPProvider) error {
+			if err := validateProviderFilters(provider); err != nil {
+				return err
+			}
+			return validateProviderTLS(provider)
+		},
+	}, true
+}
+
+// readDocument reads the request body and its format from ?format or the
+// Content-Type header
+func readDocument(c *gin.Context) (string, []byte, bool) {
+	format := c.Query("format")
+	if format == "" {
+		format = c.ContentType()
+	}
+	format, err := declarative.NormalizeFormat(format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxImportSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return "", nil, false
+	}
+
+	return format, body, true
+}
+
+// publishCreatedUsers announces the users an import created
+func (h *DeclarativeHandler) publishCreatedUsers(result *declarative.ImportResult) {
+	for _, change := range result.Changes {
+		if change.Kind != declarative.KindUser || change.Action != declarative.ActionCreate {
+			continue
+		}
+		var user models.User
+		if err := h.db.First(&user, change.ID).Error; err != nil {
+			continue
+		}
+		h.bus.Publish(events.UserCreated, events.UserChange{ID: user.ID, Email: user.Email, Role: string(user.Role)})
+	}
+}
+
+// syncProviders restarts polling for HTTP providers touched by an import
+func (h *DeclarativeHandler) syncProviders(ctx context.Context, result *declarative.ImportResult) {
+	for _, change := range result.Changes {
+		if change.Kind != declarative.KindHTTPProvider {
+			continue
+		}
+
+		// Lets the HA leader pick up the change when imported on a follower
+		action := events.ActionUpdated
+		switch change.Action {
+		case declarative.ActionCreate:
+			action = events.ActionCreated
+		case declarative.ActionDelete:
+			action = events.ActionDeleted
+		}
+		h.bus.PublishContext(ctx, events.ConfigChanged, events.ResourceChange{Kind: "http_provider", ID: change.ID, Name: change.Name, Action: action})
+
+		if h.aggregator == nil {
+			continue
+		}
+		switch change.Action {
+		case declarative.ActionCreate, declarative.ActionUpdate:
+			var provider models.HTTPProvider
+			if err := h.db.First(&provider, change.ID).Error; err != nil {
+				continue
+			}
+			if provider.IsActive {
+				h.aggregator.UpdateProvider(ctx, &provider)
+			} else {
+				h.aggregator.DeleteProvider(provider.ID)
+			}
+		case declarative.ActionDelete:
+			h.aggregator.DeleteProvider(change.ID)
+		}
+	}
+}
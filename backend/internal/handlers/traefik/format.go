@@ -0,0 +1,76 @@
+package traefik
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/declarative"
+	"github.com/traefikx/backend/internal/services"
+)
+
+// negotiateFormat picks the output format from ?format or the Accept header.
+// JSON is the default since that's what the Traefik HTTP provider expects.
+func negotiateFormat(c *gin.Context) (string, error) {
+	if format := c.Query("format"); format != "" {
+		return declarative.NormalizeFormat(format)
+	}
+
+	accept := strings.ToLower(c.GetHeader("Accept"))
+	switch {
+	case strings.Contains(accept, "yaml"):
+		return declarative.FormatYAML, nil
+	case strings.Contains(accept, "toml"):
+		return declarative.FormatTOML, nil
+	default:
+		return declarative.FormatJSON, nil
+	}
+}
+
+// writeConfig encodes a snapshot in the negotiated format. The ETag is
+// the hash of the canonical JSON encoding, so unchanged configs are answered
+// with 304 Not Modified.
+func writeConfig(c *gin.Context, snapshot *services.ConfigSnapshot) {
+	format, err := negotiateFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	etag := fmt.Sprintf(`"%s-%s"`, snapshot.Hash[:32], format)
+
+	c.Header("ETag", etag)
+	c.Header("Vary", "Accept")
+	c.Header("Cache-Control", "no-cache")
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	data := snapshot.JSON
+	if format != declarative.FormatJSON {
+		data, err = declarative.Marshal(snapshot.Config, format)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode configuration"})
+			return
+		}
+	}
+
+	c.Data(http.StatusOK, declarative.ContentType(format), data)
+}
+
+// etagMatches reports whether an If-None-Match header contains etag
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -1,16 +1,18 @@
 package traefik
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/traefik/traefik/v3/pkg/config/dynamic"
-	"github.com/traefik/traefik/v3/pkg/types"
+	"github.com/traefikx/backend/internal/events"
 	"github.com/traefikx/backend/internal/models"
+	"github.com/traefikx/backend/internal/secrets"
 	"github.com/traefikx/backend/internal/services"
 	"gorm.io/gorm"
 )
@@ -18,15 +20,25 @@ import (
 type HTTPProviderHandler struct {
 	db         *gorm.DB
 	aggregator *services.AggregatorService
+	compiler   *services.ConfigCompiler
+	bus        *events.Bus
 }
 
-func NewHTTPProviderHandler(db *gorm.DB, aggregator *services.AggregatorService) *HTTPProviderHandler {
+func NewHTTPProviderHandler(db *gorm.DB, aggregator *services.AggregatorService, compiler *services.ConfigCompiler, bus *events.Bus) *HTTPProviderHandler {
 	return &HTTPProviderHandler{
 		db:         db,
 		aggregator: aggregator,
+		compiler:   compiler,
+		bus:        bus,
 	}
 }
 
+// publishChange announces a provider change, which the HA leader applies
+// when it was made through a follower
+func (h *HTTPProviderHandler) publishChange(ctx context.Context, provider *models.HTTPProvider, action string) {
+	h.bus.PublishContext(ctx, events.ConfigChanged, events.ResourceChange{Kind: "http_provider", ID: provider.ID, Name: provider.Name, Action: action})
+}
+
 // ListHTTPProviders returns all HTTP providers
 func (h *HTTPProviderHandler) ListHTTPProviders(c *gin.Context) {
 	var providers []models.HTTPProvider
@@ -38,8 +50,8 @@ func (h *HTTPProviderHandler) ListHTTPProviders(c *gin.Context) {
 
 	// Convert to response format
 	responses := make([]map[string]interface{}, len(providers))
-	for i, provider := range providers {
-		responses[i] = provider.ToResponse()
+	for i := range providers {
+		responses[i] = h.providerResponse(&providers[i])
 	}
 
 	c.JSON(http.StatusOK, gin.H{"providers": responses})
@@ -61,7 +73,7 @@ func (h *HTTPProviderHandler) GetHTTPProvider(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, provider.ToResponse())
+	c.JSON(http.StatusOK, h.providerResponse(&provider))
 }
 
 // CreateHTTPProvider creates a new HTTP provider
@@ -86,11 +98,58 @@ func (h *HTTPProviderHandler) CreateHTTPProvider(c *gin.Context) {
 	}
 
 	provider := models.HTTPProvider{
-		Name:            req.Name,
-		URL:             req.URL,
-		Priority:        req.Priority,
-		IsActive:        req.IsActive,
-		RefreshInterval: refreshInterval,
+		Name:                  req.Name,
+		Type:                  req.Type,
+		URL:                   req.URL,
+		Priority:              req.Priority,
+		IsActive:              req.IsActive,
+		RefreshInterval:       refreshInterval,
+		Timeout:               req.Timeout,
+		AuthType:              req.AuthType,
+		AuthUsername:          req.AuthUsername,
+		AuthSecret:            req.AuthSecret,
+		TLSCA:                 req.TLSCA,
+		TLSCert:               req.TLSCert,
+		TLSKey:                req.TLSKey,
+		TLSInsecureSkipVerify: req.TLSInsecureSkipVerify,
+	}
+	provider.SetHeaders(req.Headers)
+	if provider.Type == "" {
+		provider.Type = models.ProviderTypeHTTP
+	}
+	if err := provider.ValidateURL(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if provider.Timeout <= 0 {
+		provider.Timeout = 5
+	}
+	if provider.AuthType == "" {
+		provider.AuthType = models.ProviderAuthNone
+	}
+	provider.FailurePolicy = req.FailurePolicy
+	if provider.FailurePolicy == "" {
+		provider.FailurePolicy = models.ProviderFailureKeep
+	}
+	provider.StaleGracePeriod = 300
+	if req.StaleGracePeriod != nil {
+		provider.StaleGracePeriod = *req.StaleGracePeriod
+	}
+	provider.NamespaceMode = req.NamespaceMode
+	if provider.NamespaceMode == "" {
+		provider.NamespaceMode = models.NamespaceNone
+	}
+	provider.NamespacePrefix = req.NamespacePrefix
+	provider.SetFilters(req.IncludeFilters, req.ExcludeFilters)
+
+	if err := validateProviderFilters(&provider); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validateProviderTLS(&provider); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	if err := h.db.Create(&provider).Error; err != nil {
@@ -98,12 +157,18 @@ func (h *HTTPProviderHandler) CreateHTTPProvider(c *gin.Context) {
 		return
 	}
 
+	// Create skips zero values of columns with a default, 0 means no limit here
+	if req.StaleGracePeriod != nil && *req.StaleGracePeriod == 0 {
+		h.db.Model(&provider).Update("StaleGracePeriod", 0)
+	}
+
 	// Start polling if active
 	if provider.IsActive && h.aggregator != nil {
-		h.aggregator.AddProvider(&provider)
+		h.aggregator.AddProvider(c.Request.Context(), &provider)
 	}
+	h.publishChange(c.Request.Context(), &provider, events.ActionCreated)
 
-	c.JSON(http.StatusCreated, provider.ToResponse())
+	c.JSON(http.StatusCreated, h.providerResponse(&provider))
 }
 
 // UpdateHTTPProvider updates a provider
@@ -139,10 +204,17 @@ func (h *HTTPProviderHandler) UpdateHTTPProvider(c *gin.Context) {
 		provider.Name = *req.Name
 	}
 
-	// Update URL if provided
+	// Update type and URL if provided
+	if req.Type != nil && *req.Type != "" {
+		provider.Type = *req.Type
+	}
 	if req.URL != nil && *req.URL != "" {
 		provider.URL = *req.URL
 	}
+	if err := provider.ValidateURL(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Update priority if provided
 	if req.Priority != nil {
@@ -154,6 +226,77 @@ func (h *HTTPProviderHandler) UpdateHTTPProvider(c *gin.Context) {
 		provider.RefreshInterval = *req.RefreshInterval
 	}
 
+	if req.Timeout != nil && *req.Timeout > 0 {
+		provider.Timeout = *req.Timeout
+	}
+
+	// Update failure handling if provided
+	if req.FailurePolicy != nil {
+		provider.FailurePolicy = *req.FailurePolicy
+	}
+	if req.StaleGracePeriod != nil {
+		provider.StaleGracePeriod = *req.StaleGracePeriod
+	}
+
+	// Update namespacing and filters if provided
+	if req.NamespaceMode != nil {
+		provider.NamespaceMode = *req.NamespaceMode
+	}
+	if req.NamespacePrefix != nil {
+		provider.NamespacePrefix = *req.NamespacePrefix
+	}
+	if req.IncludeFilters != nil || req.ExcludeFilters != nil {
+		include, exclude := provider.GetIncludeFilters(), provider.GetExcludeFilters()
+		if req.IncludeFilters != nil {
+			include = *req.IncludeFilters
+		}
+		if req.ExcludeFilters != nil {
+			exclude = *req.ExcludeFilters
+		}
+		provider.SetFilters(include, exclude)
+	}
+	if err := validateProviderFilters(&provider); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Update auth and headers if provided
+	if req.AuthType != nil {
+		provider.AuthType = *req.AuthType
+	}
+	if req.AuthUsername != nil {
+		provider.AuthUsername = *req.AuthUsername
+	}
+	if req.AuthSecret != nil {
+		provider.AuthSecret = *req.AuthSecret
+	}
+	if req.Headers != nil {
+		provider.SetHeaders(*req.Headers)
+	}
+
+	// Update TLS settings if provided
+	if req.TLSCA != nil {
+		provider.TLSCA = *req.TLSCA
+	}
+	if req.TLSCert != nil {
+		provider.TLSCert = *req.TLSCert
+	}
+	if req.TLSKey != nil {
+		provider.TLSKey = *req.TLSKey
+	}
+	if req.TLSInsecureSkipVerify != nil {
+		provider.TLSInsecureSkipVerify = *req.TLSInsecureSkipVerify
+	}
+
+	if err := validateProviderTLS(&provider); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Cached validators belong to the previous request settings
+	provider.ETag = ""
+	provider.LastModified = ""
+
 	// Update active status if provided
 	if req.IsActive != nil {
 		wasActive := provider.IsActive
@@ -163,7 +306,7 @@ func (h *HTTPProviderHandler) UpdateHTTPProvider(c *gin.Context) {
 		if h.aggregator != nil {
 			if *req.IsActive && !wasActive {
 				// Activating
-				h.aggregator.AddProvider(&provider)
+				h.aggregator.AddProvider(c.Request.Context(), &provider)
 			} else if !*req.IsActive && wasActive {
 				// Deactivating
 				h.aggregator.DeleteProvider(provider.ID)
@@ -178,10 +321,11 @@ func (h *HTTPProviderHandler) UpdateHTTPProvider(c *gin.Context) {
 
 	// Restart polling if config changed and still active
 	if h.aggregator != nil && provider.IsActive {
-		h.aggregator.UpdateProvider(&provider)
+		h.aggregator.UpdateProvider(c.Request.Context(), &provider)
 	}
+	h.publishChange(c.Request.Context(), &provider, events.ActionUpdated)
 
-	c.JSON(http.StatusOK, provider.ToResponse())
+	c.JSON(http.StatusOK, h.providerResponse(&provider))
 }
 
 // DeleteHTTPProvider deletes a provider
@@ -210,6 +354,9 @@ func (h *HTTPProviderHandler) DeleteHTTPProvider(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete provider"})
 		return
 	}
+	h.db.Where("provider_id = ?", provider.ID).Delete(&models.ProviderFetch{})
+	h.db.Where("source = ? OR overridden_by = ?", provider.Name, provider.Name).Delete(&models.ConfigConflict{})
+	h.publishChange(c.Request.Context(), &provider, events.ActionDeleted)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Provider deleted successfully"})
 }
@@ -229,15 +376,17 @@ func (h *HTTPProviderHandler) RefreshHTTPProvider(c *gin.Context) {
 		return
 	}
 
-	if err := h.aggregator.RefreshProvider(uint(providerID)); err != nil {
+	if err := h.aggregator.RefreshProvider(c.Request.Context(), uint(providerID)); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
 		return
 	}
+	h.bus.PublishContext(c.Request.Context(), events.ConfigChanged, events.ResourceChange{Kind: "http_provider", ID: uint(providerID), Action: events.ActionRefreshed})
 
 	c.JSON(http.StatusOK, gin.H{"message": "Refresh triggered"})
 }
 
-// TestHTTPProvider tests the connection to a provider
+// TestHTTPProvider fetches a saved provider synchronously and previews how
+// its configuration would be merged. The provider's status isn't changed.
 func (h *HTTPProviderHandler) TestHTTPProvider(c *gin.Context) {
 	id := c.Param("id")
 
@@ -253,424 +402,308 @@ func (h *HTTPProviderHandler) TestHTTPProvider(c *gin.Context) {
 		return
 	}
 
-	// Trigger a refresh and wait a moment
-	if h.aggregator != nil {
-		h.aggregator.RefreshProvider(uint(providerID))
-	}
-
-	// Reload to get latest status
-	h.db.First(&provider, providerID)
-
-	c.JSON(http.StatusOK, provider.ToResponse())
+	h.testProvider(c, &provider)
 }
 
-// GetMergedConfig returns the merged configuration from all providers
-func (h *HTTPProviderHandler) GetMergedConfig(c *gin.Context) {
-	if h.aggregator == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Aggregator service not available"})
+// TestProviderSettings tests a saved provider given by id, or unsaved
+// provider settings
+func (h *HTTPProviderHandler) TestProviderSettings(c *gin.Context) {
+	var req models.TestHTTPProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Get local config from database
-	var routers []models.Router
-	h.db.Preload("Hostnames").Preload("Service").Preload("Middlewares.Middleware").Find(&routers)
-
-	var services []models.Service
-	h.db.Preload("Servers").Find(&services)
-
-	var middlewares []models.Middleware
-	h.db.Find(&middlewares)
-
-	// Convert to official Traefik types
-	localRouters := make(map[string]*dynamic.Router)
-	for _, router := range routers {
-		if !router.IsActive {
-			continue
+	if req.ID != nil {
+		var provider models.HTTPProvider
+		if err := h.db.First(&provider, *req.ID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
+			return
 		}
-		localRouters[router.Name] = convertRouterModelToDynamic(&router)
+		h.testProvider(c, &provider)
+		return
 	}
 
-	localServices := make(map[string]*dynamic.Service)
-	for _, service := range services {
-		if !service.IsActive {
-			continue
-		}
-		localServices[service.Name] = convertServiceModelToDynamic(&service)
+	if req.URL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either id or url is required"})
+		return
 	}
 
-	localMiddlewares := make(map[string]*dynamic.Middleware)
-	for _, middleware := range middlewares {
-		if !middleware.IsActive {
-			continue
-		}
-		mw := convertMiddlewareModelToDynamic(&middleware)
-		if mw != nil {
-			localMiddlewares[middleware.Name] = mw
-		}
+	provider := models.HTTPProvider{
+		Name:                  req.Name,
+		Type:                  req.Type,
+		URL:                   req.URL,
+		Priority:              req.Priority,
+		Timeout:               req.Timeout,
+		AuthType:              req.AuthType,
+		AuthUsername:          req.AuthUsername,
+		AuthSecret:            req.AuthSecret,
+		TLSCA:                 req.TLSCA,
+		TLSCert:               req.TLSCert,
+		TLSKey:                req.TLSKey,
+		TLSInsecureSkipVerify: req.TLSInsecureSkipVerify,
+		NamespaceMode:         req.NamespaceMode,
+		NamespacePrefix:       req.NamespacePrefix,
+	}
+	provider.SetHeaders(req.Headers)
+	provider.SetFilters(req.IncludeFilters, req.ExcludeFilters)
+	if provider.Name == "" {
+		provider.Name = "test"
+	}
+	if provider.Type == "" {
+		provider.Type = models.ProviderTypeHTTP
+	}
+	if provider.NamespaceMode == "" {
+		provider.NamespaceMode = models.NamespaceNone
+	}
+	if err := provider.ValidateURL(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateProviderFilters(&provider); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	localServersTransports := make(map[string]*dynamic.ServersTransport)
-
-	// Get merged config using official types
-	mergedConfig, conflicts := h.aggregator.GetMergedConfig(
-		localRouters,
-		localServices,
-		localMiddlewares,
-		localServersTransports,
-	)
+	// Previewing changes to an existing provider keeps its identity
+	var existing models.HTTPProvider
+	if err := h.db.Where("name = ?", provider.Name).First(&existing).Error; err == nil {
+		provider.ID = existing.ID
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"config":    mergedConfig,
-		"conflicts": conflicts,
-		"sources":   h.getSourcesInfo(),
-	})
+	h.testProvider(c, &provider)
 }
 
-// getSourcesInfo returns information about all provider sources
-func (h *HTTPProviderHandler) getSourcesInfo() []gin.H {
-	var providers []models.HTTPProvider
-	h.db.Order("priority DESC").Find(&providers)
-
-	// Count local items
-	var localRouterCount, localServiceCount, localMiddlewareCount int64
-	h.db.Model(&models.Router{}).Where("is_active = ?", true).Count(&localRouterCount)
-	h.db.Model(&models.Service{}).Where("is_active = ?", true).Count(&localServiceCount)
-	h.db.Model(&models.Middleware{}).Where("is_active = ?", true).Count(&localMiddlewareCount)
-
-	sources := []gin.H{
-		{
-			"name":             "local",
-			"priority":         9999, // Local always has highest priority
-			"status":           "healthy",
-			"router_count":     localRouterCount,
-			"service_count":    localServiceCount,
-			"middleware_count": localMiddlewareCount,
-		},
+// testProvider runs the test and, when it succeeds, the merge preview. A
+// failed test is reported with 200, the request itself was valid.
+func (h *HTTPProviderHandler) testProvider(c *gin.Context, provider *models.HTTPProvider) {
+	if h.aggregator == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Aggregator service not available"})
+		return
 	}
 
-	for _, provider := range providers {
-		status := "healthy"
-		if provider.LastError != "" {
-			if provider.LastFetched == nil {
-				status = "unhealthy"
-			} else {
-				status = "degraded"
-			}
-		} else if !provider.IsActive {
-			status = "inactive"
+	result, config := h.aggregator.TestProvider(c.Request.Context(), provider)
+	if config != nil && h.compiler != nil {
+		preview, err := h.compiler.PreviewProvider(c.Request.Context(), provider, config)
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "Failed to preview provider", "provider", provider.Name, "error", err)
+		} else {
+			result.Preview = preview
 		}
-
-		sources = append(sources, gin.H{
-			"name":             provider.Name,
-			"priority":         provider.Priority,
-			"status":           status,
-			"last_fetched":     provider.LastFetched,
-			"last_error":       provider.LastError,
-			"router_count":     provider.RouterCount,
-			"service_count":    provider.ServiceCount,
-			"middleware_count": provider.MiddlewareCount,
-		})
 	}
 
-	return sources
+	c.JSON(http.StatusOK, result)
 }
 
-// Helper functions to convert models to Traefik format
-func convertRouterToTraefikFormat(router *models.Router) map[string]interface{} {
-	if len(router.Hostnames) == 0 {
-		return nil
-	}
-
-	// Build rule from hostnames
-	rule := buildRuleFromHostnames(router.Hostnames)
-	entryPoints := splitEntryPoints(router.EntryPoints)
+// providerFetchResponse is a history entry with its decoded diff
+type providerFetchResponse struct {
+	models.ProviderFetch
+	Diff *services.ConfigDiff `json:"diff,omitempty"`
+}
 
-	middlewareNames := []string{}
-	if router.RedirectHTTPS {
-		middlewareNames = append(middlewareNames, fmt.Sprintf("%s-redirect-https", router.Name))
-	}
-	for _, rm := range router.Middlewares {
-		if rm.Middleware.IsActive {
-			middlewareNames = append(middlewareNames, rm.Middleware.Name)
-		}
+// ListProviderFetches returns a provider's fetch history, newest first.
+// Pages are requested with before=<id of the last entry>; changed=true
+// keeps fetches that changed resources and errors=true failed ones.
+func (h *HTTPProviderHandler) ListProviderFetches(c *gin.Context) {
+	providerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid provider ID"})
+		return
 	}
 
-	result := map[string]interface{}{
-		"entryPoints": entryPoints,
-		"rule":        rule,
-		"service":     router.Service.Name,
+	var provider models.HTTPProvider
+	if err := h.db.First(&provider, providerID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider not found"})
+		return
 	}
 
-	if len(middlewareNames) > 0 {
-		result["middlewares"] = middlewareNames
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
 	}
 
-	if router.TLSEnabled {
-		tlsConfig := map[string]interface{}{
-			"certResolver": router.TLSCertResolver,
-		}
-
-		// Add domains from hostnames
-		if len(router.Hostnames) > 0 {
-			hostnames := make([]string, len(router.Hostnames))
-			for i, h := range router.Hostnames {
-				hostnames[i] = h.Hostname
-			}
-
-			if len(hostnames) > 0 {
-				mainDomain := hostnames[0]
-				sans := []string{}
-
-				// Add wildcard for the main domain
-				parts := strings.Split(mainDomain, ".")
-				if len(parts) >= 2 {
-					wildcard := "*." + strings.Join(parts[1:], ".")
-					sans = append(sans, wildcard)
-				}
-
-				// Add any additional hostnames as SANs with wildcards
-				for i := 1; i < len(hostnames); i++ {
-					sans = append(sans, hostnames[i])
-					parts := strings.Split(hostnames[i], ".")
-					if len(parts) >= 2 {
-						wildcard := "*." + strings.Join(parts[1:], ".")
-						sans = append(sans, wildcard)
-					}
-				}
-
-				tlsConfig["domains"] = []map[string]interface{}{
-					{
-						"main": mainDomain,
-						"sans": sans,
-					},
-				}
-			}
+	query := h.db.Where("provider_id = ?", provider.ID).Order("id DESC").Limit(limit)
+	if before := c.Query("before"); before != "" {
+		beforeID, err := strconv.ParseUint(before, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before cursor"})
+			return
 		}
-
-		result["tls"] = tlsConfig
+		query = query.Where("id < ?", beforeID)
 	}
-
-	return result
-}
-
-func buildRuleFromHostnames(hostnames []models.RouterHostname) string {
-	if len(hostnames) == 0 {
-		return ""
+	if c.Query("changed") == "true" {
+		query = query.Where("changed = ?", true)
 	}
-	if len(hostnames) == 1 {
-		return fmt.Sprintf("Host(`%s`)", hostnames[0].Hostname)
-	}
-	hosts := make([]string, len(hostnames))
-	for i, h := range hostnames {
-		hosts[i] = fmt.Sprintf("`%s`", h.Hostname)
-	}
-	return fmt.Sprintf("Host(%s)", strings.Join(hosts, ", "))
-}
-
-func convertServiceToTraefikFormat(service *models.Service) map[string]interface{} {
-	servers := make([]map[string]string, len(service.Servers))
-	for i, s := range service.Servers {
-		servers[i] = map[string]string{"url": s.URL}
+	if c.Query("errors") == "true" {
+		query = query.Where("success = ?", false)
 	}
 
-	lbConfig := map[string]interface{}{
-		"servers":        servers,
-		"passHostHeader": service.PassHostHeader,
+	var fetches []models.ProviderFetch
+	if err := query.Find(&fetches).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch provider history"})
+		return
 	}
 
-	if service.HealthCheckEnabled && service.HealthCheckPath != "" {
-		lbConfig["healthCheck"] = map[string]interface{}{
-			"path":     service.HealthCheckPath,
-			"interval": fmt.Sprintf("%ds", service.HealthCheckInterval),
+	responses := make([]providerFetchResponse, len(fetches))
+	for i, fetch := range fetches {
+		responses[i].ProviderFetch = fetch
+		if fetch.Diff != "" {
+			var diff services.ConfigDiff
+			if json.Unmarshal([]byte(fetch.Diff), &diff) == nil {
+				responses[i].Diff = &diff
+			}
 		}
 	}
 
-	return map[string]interface{}{
-		"loadBalancer": lbConfig,
+	result := gin.H{"fetches": responses}
+	if len(fetches) == limit {
+		result["next_before"] = fetches[len(fetches)-1].ID
 	}
+	c.JSON(http.StatusOK, result)
 }
 
-func convertMiddlewareToTraefikFormat(middleware *models.Middleware) map[string]interface{} {
-	var config models.MiddlewareConfig
-	if err := json.Unmarshal([]byte(middleware.Config), &config); err != nil {
-		return nil
+// providerResponse adds the live fetch state from the aggregator
+func (h *HTTPProviderHandler) providerResponse(provider *models.HTTPProvider) map[string]interface{} {
+	response := provider.ToResponse()
+	response["state"] = "inactive"
+	response["consecutive_failures"] = 0
+	if !provider.IsActive || h.aggregator == nil {
+		return response
 	}
 
-	switch middleware.Type {
-	case "redirectScheme":
-		return map[string]interface{}{
-			"redirectScheme": map[string]interface{}{
-				"scheme":    config.Scheme,
-				"port":      config.Port,
-				"permanent": config.Permanent,
-			},
-		}
-	case "headers":
-		result := map[string]interface{}{}
-		if len(config.CustomRequestHeaders) > 0 {
-			result["customRequestHeaders"] = config.CustomRequestHeaders
-		}
-		if len(config.CustomResponseHeaders) > 0 {
-			result["customResponseHeaders"] = config.CustomResponseHeaders
-		}
-		if config.SSLRedirect {
-			result["sslRedirect"] = true
-		}
-		return map[string]interface{}{"headers": result}
-	case "stripPrefix":
-		return map[string]interface{}{
-			"stripPrefix": map[string]interface{}{
-				"prefixes":   config.Prefixes,
-				"forceSlash": config.ForceSlash,
-			},
-		}
-	case "addPrefix":
-		return map[string]interface{}{
-			"addPrefix": map[string]interface{}{
-				"prefix": config.Prefix,
-			},
-		}
-	default:
-		return nil
+	response["state"] = "pending"
+	if status, ok := h.aggregator.GetStatus(provider.ID); ok {
+		response["state"] = status.State
+		response["consecutive_failures"] = status.ConsecutiveFailures
+		response["next_fetch"] = status.NextFetch
+		response["warnings"] = status.Warnings
 	}
+	return response
 }
 
-// New conversion functions using official Traefik types
+// validateProviderTLS checks that the CA and client certificate parse
+func validateProviderTLS(provider *models.HTTPProvider) error {
+	_, err := services.NewProviderClient(provider)
+	return err
+}
 
-func convertRouterModelToDynamic(router *models.Router) *dynamic.Router {
-	if len(router.Hostnames) == 0 {
-		return nil
+// validateProviderFilters checks the include and exclude patterns
+func validateProviderFilters(provider *models.HTTPProvider) error {
+	if err := services.ValidatePatterns(provider.GetIncludeFilters()); err != nil {
+		return err
 	}
+	return services.ValidatePatterns(provider.GetExcludeFilters())
+}
 
-	// Build rule from hostnames
-	rule := buildRuleFromHostnames(router.Hostnames)
-	entryPoints := splitEntryPoints(router.EntryPoints)
-
-	middlewareNames := []string{}
-	if router.RedirectHTTPS {
-		middlewareNames = append(middlewareNames, fmt.Sprintf("%s-redirect-https", router.Name))
-	}
-	for _, rm := range router.Middlewares {
-		if rm.Middleware.IsActive {
-			middlewareNames = append(middlewareNames, rm.Middleware.Name)
-		}
+// GetMergedConfig returns the merged configuration from all providers
+func (h *HTTPProviderHandler) GetMergedConfig(c *gin.Context) {
+	if h.aggregator == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Aggregator service not available"})
+		return
 	}
 
-	dynRouter := &dynamic.Router{
-		EntryPoints: entryPoints,
-		Rule:        rule,
-		Service:     router.Service.Name,
-		Middlewares: middlewareNames,
+	snapshot, err := h.compiler.Snapshot(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build configuration"})
+		return
 	}
 
-	if router.TLSEnabled {
-		tlsConfig := &dynamic.RouterTLSConfig{
-			CertResolver: router.TLSCertResolver,
-		}
-
-		// Add domains from hostnames
-		if len(router.Hostnames) > 0 {
-			hostnames := make([]string, len(router.Hostnames))
-			for i, h := range router.Hostnames {
-				hostnames[i] = h.Hostname
-			}
+	c.JSON(http.StatusOK, gin.H{
+		"config":    redactConfig(snapshot.Config),
+		"conflicts": snapshot.Conflicts,
+		"sources":   h.getSourcesInfo(snapshot),
+		"version":   snapshot.Version,
+		"built_at":  snapshot.BuiltAt,
+	})
+}
 
-			if len(hostnames) > 0 {
-				mainDomain := hostnames[0]
-				sans := []string{}
-
-				// Add wildcard for the main domain
-				parts := strings.Split(mainDomain, ".")
-				if len(parts) >= 2 {
-					wildcard := "*." + strings.Join(parts[1:], ".")
-					sans = append(sans, wildcard)
-				}
-
-				// Add any additional hostnames as SANs with wildcards
-				for i := 1; i < len(hostnames); i++ {
-					sans = append(sans, hostnames[i])
-					parts := strings.Split(hostnames[i], ".")
-					if len(parts) >= 2 {
-						wildcard := "*." + strings.Join(parts[1:], ".")
-						sans = append(sans, wildcard)
-					}
-				}
-
-				tlsConfig.Domains = []types.Domain{
-					{
-						Main: mainDomain,
-						SANs: sans,
-					},
-				}
-			}
+// redactConfig hides credentials in a configuration shown to admins:
+// sensitive header values and basic or digest auth password hashes. The
+// snapshot itself is not modified.
+func redactConfig(config *dynamic.Configuration) *dynamic.Configuration {
+	if config == nil || config.HTTP == nil || len(config.HTTP.Middlewares) == 0 {
+		return config
+	}
+
+	redacted := *config
+	httpConfig := *config.HTTP
+	redacted.HTTP = &httpConfig
+	httpConfig.Middlewares = make(map[string]*dynamic.Middleware, len(config.HTTP.Middlewares))
+	for name, middleware := range config.HTTP.Middlewares {
+		if middleware == nil {
+			httpConfig.Middlewares[name] = middleware
+			continue
 		}
-
-		dynRouter.TLS = tlsConfig
+		copied := *middleware
+		if middleware.Headers != nil {
+			headers := *middleware.Headers
+			headers.CustomRequestHeaders = models.RedactHeaders(headers.CustomRequestHeaders)
+			headers.CustomResponseHeaders = models.RedactHeaders(headers.CustomResponseHeaders)
+			copied.Headers = &headers
+		}
+		if middleware.BasicAuth != nil {
+			basicAuth := *middleware.BasicAuth
+			basicAuth.Users = redactUsers(basicAuth.Users)
+			copied.BasicAuth = &basicAuth
+		}
+		if middleware.DigestAuth != nil {
+			digestAuth := *middleware.DigestAuth
+			digestAuth.Users = redactUsers(digestAuth.Users)
+			copied.DigestAuth = &digestAuth
+		}
+		httpConfig.Middlewares[name] = &copied
 	}
-
-	return dynRouter
+	return &redacted
 }
 
-func convertServiceModelToDynamic(service *models.Service) *dynamic.Service {
-	servers := make([]dynamic.Server, len(service.Servers))
-	for i, s := range service.Servers {
-		servers[i] = dynamic.Server{
-			URL: s.URL,
-		}
+// redactUsers keeps the user names of "user:hash" entries
+func redactUsers(users dynamic.Users) dynamic.Users {
+	redacted := make(dynamic.Users, len(users))
+	for i, user := range users {
+		name, _, _ := strings.Cut(user, ":")
+		redacted[i] = name + ":" + secrets.Redacted
 	}
+	return redacted
+}
+
+// getSourcesInfo returns information about all provider sources
+func (h *HTTPProviderHandler) getSourcesInfo(snapshot *services.ConfigSnapshot) []gin.H {
+	var providers []models.HTTPProvider
+	h.db.Order("priority DESC").Find(&providers)
 
-	passHostHeader := service.PassHostHeader
-	dynService := &dynamic.Service{
-		LoadBalancer: &dynamic.ServersLoadBalancer{
-			Servers:        servers,
-			PassHostHeader: &passHostHeader,
+	sources := []gin.H{
+		{
+			"name":             "local",
+			"priority":         9999, // Local always has highest priority
+			"status":           "healthy",
+			"router_count":     snapshot.LocalRouterCount,
+			"service_count":    snapshot.LocalServiceCount,
+			"middleware_count": snapshot.LocalMiddlewareCount,
 		},
 	}
 
-	if service.HealthCheckEnabled && service.HealthCheckPath != "" {
-		dynService.LoadBalancer.HealthCheck = &dynamic.ServerHealthCheck{
-			Path: service.HealthCheckPath,
+	for _, provider := range providers {
+		status := "inactive"
+		if provider.IsActive {
+			status = "pending"
+			if current, ok := h.aggregator.GetStatus(provider.ID); ok {
+				status = current.State
+			}
 		}
-	}
 
-	return dynService
-}
-
-func convertMiddlewareModelToDynamic(middleware *models.Middleware) *dynamic.Middleware {
-	var config models.MiddlewareConfig
-	if err := json.Unmarshal([]byte(middleware.Config), &config); err != nil {
-		return nil
+		sources = append(sources, gin.H{
+			"name":             provider.Name,
+			"type":             provider.Type,
+			"priority":         provider.Priority,
+			"status":           status,
+			"last_fetched":     provider.LastFetched,
+			"last_error":       provider.LastError,
+			"router_count":     provider.RouterCount,
+			"service_count":    provider.ServiceCount,
+			"middleware_count": provider.MiddlewareCount,
+		})
 	}
 
-	switch middleware.Type {
-	case "redirectScheme":
-		return &dynamic.Middleware{
-			RedirectScheme: &dynamic.RedirectScheme{
-				Scheme:    config.Scheme,
-				Port:      config.Port,
-				Permanent: config.Permanent,
-			},
-		}
-	case "headers":
-		return &dynamic.Middleware{
-			Headers: &dynamic.Headers{
-				CustomRequestHeaders:  config.CustomRequestHeaders,
-				CustomResponseHeaders: config.CustomResponseHeaders,
-			},
-		}
-	case "stripPrefix":
-		return &dynamic.Middleware{
-			StripPrefix: &dynamic.StripPrefix{
-				Prefixes: config.Prefixes,
-			},
-		}
-	case "addPrefix":
-		return &dynamic.Middleware{
-			AddPrefix: &dynamic.AddPrefix{
-				Prefix: config.Prefix,
-			},
-		}
-	default:
-		return nil
-	}
+	return sources
 }
@@ -0,0 +1,277 @@
+package traefik
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/models"
+	"github.com/traefikx/backend/internal/notify"
+	"gorm.io/gorm"
+)
+
+type NotificationHandler struct {
+	db         *gorm.DB
+	dispatcher *notify.Dispatcher
+}
+
+func NewNotificationHandler(db *gorm.DB, dispatcher *notify.Dispatcher) *NotificationHandler {
+	return &NotificationHandler{db: db, dispatcher: dispatcher}
+}
+
+// ListEvents returns the events channels can subscribe to
+func (h *NotificationHandler) ListEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"events": notify.Events})
+}
+
+// ListChannels returns all notification channels
+func (h *NotificationHandler) ListChannels(c *gin.Context) {
+	var channels []models.NotificationChannel
+	if err := h.db.Order("name").Find(&channels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification channels"})
+		return
+	}
+
+	responses := make([]map[string]interface{}, len(channels))
+	for i := range channels {
+		responses[i] = channels[i].ToResponse()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"channels": responses})
+}
+
+// GetChannel returns a notification channel
+func (h *NotificationHandler) GetChannel(c *gin.Context) {
+	channel, ok := h.findChannel(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, channel.ToResponse())
+}
+
+// CreateChannel adds a notification channel
+func (h *NotificationHandler) CreateChannel(c *gin.Context) {
+	var req models.CreateNotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing models.NotificationChannel
+	if err := h.db.Where("name = ?", req.Name).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Notification channel with this name already exists"})
+		return
+	}
+
+	isActive := req.IsActive == nil || *req.IsActive
+	channel := models.NotificationChannel{
+		Name:     req.Name,
+		Type:     req.Type,
+		URL:      req.URL,
+		Secret:   req.Secret,
+		IsActive: isActive,
+	}
+	channel.SetRecipients(req.Recipients)
+	channel.SetEvents(req.Events)
+
+	if err := notify.ValidateChannel(&channel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Create(&channel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notification channel"})
+		return
+	}
+
+	// Create skips false for columns defaulting to true
+	if !isActive {
+		h.db.Model(&channel).Update("IsActive", false)
+	}
+
+	c.JSON(http.StatusCreated, channel.ToResponse())
+}
+
+// UpdateChannel changes the fields sent of a notification channel
+func (h *NotificationHandler) UpdateChannel(c *gin.Context) {
+	channel, ok := h.findChannel(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateNotificationChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name != nil && *req.Name != channel.Name {
+		var existing models.NotificationChannel
+		if err := h.db.Where("name = ? AND id != ?", *req.Name, channel.ID).First(&existing).Error; err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Notification channel with this name already exists"})
+			return
+		}
+		channel.Name = *req.Name
+	}
+	if req.Type != nil {
+		channel.Type = *req.Type
+	}
+	if req.URL != nil {
+		channel.URL = *req.URL
+	}
+	if req.Secret != nil {
+		channel.Secret = *req.Secret
+	}
+	if req.Recipients != nil {
+		channel.SetRecipients(*req.Recipients)
+	}
+	if req.Events != nil {
+		channel.SetEvents(*req.Events)
+	}
+	if req.IsActive != nil {
+		channel.IsActive = *req.IsActive
+	}
+
+	if err := notify.ValidateChannel(channel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Save(channel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification channel"})
+		return
+	}
+
+	c.JSON(http.StatusOK, channel.ToResponse())
+}
+
+// DeleteChannel removes a notification channel and its delivery log
+func (h *NotificationHandler) DeleteChannel(c *gin.Context) {
+	channel, ok := h.findChannel(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(channel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notification channel"})
+		return
+	}
+	h.db.Where("channel_id = ?", channel.ID).Delete(&models.NotificationDelivery{})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification channel deleted successfully"})
+}
+
+// TestChannel sends a test notification to a channel and returns the
+// recorded delivery, whether it succeeded or not
+func (h *NotificationHandler) TestChannel(c *gin.Context) {
+	channel, ok := h.findChannel(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.dispatcher.Test(c.Request.Context(), channel)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send test notification"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  delivery.Status == models.DeliveryDelivered,
+		"delivery": delivery,
+	})
+}
+
+// ListDeliveries returns the delivery log, newest first. Pages are
+// requested with before=<id of the last entry>; channel_id and status
+// (pending, delivered or failed) filter it.
+func (h *NotificationHandler) ListDeliveries(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	query := h.db.Order("id DESC").Limit(limit)
+	if before := c.Query("before"); before != "" {
+		beforeID, err := strconv.ParseUint(before, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before cursor"})
+			return
+		}
+		query = query.Where("id < ?", beforeID)
+	}
+	if channelID := c.Query("channel_id"); channelID != "" {
+		id, err := strconv.ParseUint(channelID, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel ID"})
+			return
+		}
+		query = query.Where("channel_id = ?", id)
+	}
+	switch status := c.Query("status"); status {
+	case "":
+	case models.DeliveryPending, models.DeliveryDelivered, models.DeliveryFailed:
+		query = query.Where("status = ?", status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, delivered or failed"})
+		return
+	}
+
+	var deliveries []models.NotificationDelivery
+	if err := query.Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification deliveries"})
+		return
+	}
+
+	result := gin.H{"deliveries": deliveries}
+	if len(deliveries) == limit {
+		result["next_before"] = deliveries[len(deliveries)-1].ID
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// RetryDelivery queues a failed delivery again
+func (h *NotificationHandler) RetryDelivery(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+
+	var delivery models.NotificationDelivery
+	if err := h.db.First(&delivery, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification delivery not found"})
+		return
+	}
+	if delivery.Status != models.DeliveryFailed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only failed deliveries can be retried"})
+		return
+	}
+	if delivery.Event == notify.EventTest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Test notifications are not retried, test the channel again"})
+		return
+	}
+
+	if err := h.dispatcher.Retry(c.Request.Context(), &delivery); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry notification delivery"})
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+func (h *NotificationHandler) findChannel(c *gin.Context) (*models.NotificationChannel, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel ID"})
+		return nil, false
+	}
+
+	var channel models.NotificationChannel
+	if err := h.db.First(&channel, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification channel not found"})
+		return nil, false
+	}
+	return &channel, true
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/events"
 	"github.com/traefikx/backend/internal/models"
 	"gorm.io/gorm"
 )
@@ -48,11 +49,12 @@ type UpdateProxyHostRequest struct {
 }
 
 type ProxyHandler struct {
-	db *gorm.DB
+	db  *gorm.DB
+	bus *events.Bus
 }
 
-func NewProxyHandler(db *gorm.DB) *ProxyHandler {
-	return &ProxyHandler{db: db}
+func NewProxyHandler(db *gorm.DB, bus *events.Bus) *ProxyHandler {
+	return &ProxyHandler{db: db, bus: bus}
 }
 
 // ListProxyHosts returns all proxy hosts (combined router + service view)
@@ -204,6 +206,8 @@ func (h *ProxyHandler) CreateProxyHost(c *gin.Context) {
 	// Reload with associations
 	h.db.Preload("Hostnames").Preload("Service.Servers").First(&router, router.ID)
 
+	h.bus.PublishContext(c.Request.Context(), events.ConfigChanged, events.ResourceChange{Kind: "proxy", ID: router.ID, Name: router.Name, Action: events.ActionCreated, UserID: router.UserID})
+
 	proxy := h.routerToProxyHost(&router)
 	c.JSON(http.StatusCreated, proxy)
 }
@@ -328,6 +332,7 @@ func (h *ProxyHandler) UpdateProxyHost(c *gin.Context) {
 
 	// Reload
 	h.db.Preload("Hostnames").Preload("Service.Servers").First(&router, router.ID)
+	h.bus.PublishContext(c.Request.Context(), events.ConfigChanged, events.ResourceChange{Kind: "proxy", ID: router.ID, Name: router.Name, Action: events.ActionUpdated, UserID: router.UserID})
 	proxy := h.routerToProxyHost(&router)
 	c.JSON(http.StatusOK, proxy)
 }
@@ -370,6 +375,8 @@ func (h *ProxyHandler) DeleteProxyHost(c *gin.Context) {
 	h.db.Where("service_id = ?", serviceID).Delete(&models.ServiceServer{})
 	h.db.Delete(&models.Service{}, serviceID)
 
+	h.bus.PublishContext(c.Request.Context(), events.ConfigChanged, events.ResourceChange{Kind: "proxy", ID: router.ID, Name: router.Name, Action: events.ActionDeleted, UserID: router.UserID})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Proxy host deleted successfully"})
 }
 
@@ -0,0 +1,27 @@
+package traefik
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/publisher"
+)
+
+type PublisherHandler struct {
+	manager *publisher.Manager
+}
+
+func NewPublisherHandler(manager *publisher.Manager) *PublisherHandler {
+	return &PublisherHandler{manager: manager}
+}
+
+// ListPublishers returns the publish status of each configured publisher
+func (h *PublisherHandler) ListPublishers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"publishers": h.manager.Statuses()})
+}
+
+// SyncPublishers republishes the current configuration to all publishers
+func (h *PublisherHandler) SyncPublishers(c *gin.Context) {
+	h.manager.Sync()
+	c.JSON(http.StatusAccepted, gin.H{"message": "Publish scheduled"})
+}
@@ -6,16 +6,18 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/events"
 	"github.com/traefikx/backend/internal/models"
 	"gorm.io/gorm"
 )
 
 type RouterHandler struct {
-	db *gorm.DB
+	db  *gorm.DB
+	bus *events.Bus
 }
 
-func NewRouterHandler(db *gorm.DB) *RouterHandler {
-	return &RouterHandler{db: db}
+func NewRouterHandler(db *gorm.DB, bus *events.Bus) *RouterHandler {
+	return &RouterHandler{db: db, bus: bus}
 }
 
 // ListRouters returns list of all routers
@@ -158,6 +160,8 @@ func (h *RouterHandler) CreateRouter(c *gin.Context) {
 		Preload("Middlewares.Middleware").
 		First(&router, router.ID)
 
+	h.bus.PublishContext(c.Request.Context(), events.ConfigChanged, events.ResourceChange{Kind: "router", ID: router.ID, Name: router.Name, Action: events.ActionCreated, UserID: router.UserID})
+
 	c.JSON(http.StatusCreated, router.ToResponse())
 }
 
@@ -288,6 +292,8 @@ func (h *RouterHandler) UpdateRouter(c *gin.Context) {
 		Preload("Middlewares.Middleware").
 		First(&router, router.ID)
 
+	h.bus.PublishContext(c.Request.Context(), events.ConfigChanged, events.ResourceChange{Kind: "router", ID: router.ID, Name: router.Name, Action: events.ActionUpdated, UserID: router.UserID})
+
 	c.JSON(http.StatusOK, router.ToResponse())
 }
 
@@ -319,6 +325,8 @@ func (h *RouterHandler) DeleteRouter(c *gin.Context) {
 		return
 	}
 
+	h.bus.PublishContext(c.Request.Context(), events.ConfigChanged, events.ResourceChange{Kind: "router", ID: router.ID, Name: router.Name, Action: events.ActionDeleted, UserID: router.UserID})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Router deleted successfully"})
 }
 
@@ -328,13 +336,6 @@ func isValidHostname(hostname string) bool {
 	if hostname == "" {
 		return false
 	}
-	// Basic hostname validation
-	if strings.Contains(hostname, " ") {
-		return false
-	}
-	// Must contain at least one dot (domain.tld)
-	if !strings.Contains(hostname, ".") {
-		return false
-	}
-	return true
+	// Basic hostname validation, single labels such as localhost are fine
+	return !strings.Contains(hostname, " ")
 }
@@ -7,16 +7,18 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/events"
 	"github.com/traefikx/backend/internal/models"
 	"gorm.io/gorm"
 )
 
 type ServiceHandler struct {
-	db *gorm.DB
+	db  *gorm.DB
+	bus *events.Bus
 }
 
-func NewServiceHandler(db *gorm.DB) *ServiceHandler {
-	return &ServiceHandler{db: db}
+func NewServiceHandler(db *gorm.DB, bus *events.Bus) *ServiceHandler {
+	return &ServiceHandler{db: db, bus: bus}
 }
 
 // ListServices returns list of all services
@@ -120,6 +122,8 @@ func (h *ServiceHandler) CreateService(c *gin.Context) {
 	// Reload with servers
 	h.db.Preload("Servers").First(&service, service.ID)
 
+	h.bus.PublishContext(c.Request.Context(), events.ConfigChanged, events.ResourceChange{Kind: "service", ID: service.ID, Name: service.Name, Action: events.ActionCreated})
+
 	c.JSON(http.StatusCreated, service.ToResponse())
 }
 
@@ -209,6 +213,8 @@ func (h *ServiceHandler) UpdateService(c *gin.Context) {
 	// Reload with servers
 	h.db.Preload("Servers").First(&service, service.ID)
 
+	h.bus.PublishContext(c.Request.Context(), events.ConfigChanged, events.ResourceChange{Kind: "service", ID: service.ID, Name: service.Name, Action: events.ActionUpdated})
+
 	c.JSON(http.StatusOK, service.ToResponse())
 }
 
@@ -245,6 +251,8 @@ func (h *ServiceHandler) DeleteService(c *gin.Context) {
 		return
 	}
 
+	h.bus.PublishContext(c.Request.Context(), events.ConfigChanged, events.ResourceChange{Kind: "service", ID: service.ID, Name: service.Name, Action: events.ActionDeleted})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Service deleted successfully"})
 }
 
@@ -262,11 +270,12 @@ func isValidServerURL(url string) bool {
 }
 
 type MiddlewareHandler struct {
-	db *gorm.DB
+	db  *gorm.DB
+	bus *events.Bus
 }
 
-func NewMiddlewareHandler(db *gorm.DB) *MiddlewareHandler {
-	return &MiddlewareHandler{db: db}
+func NewMiddlewareHandler(db *gorm.DB, bus *events.Bus) *MiddlewareHandler {
+	return &MiddlewareHandler{db: db, bus: bus}
 }
 
 // ListMiddlewares returns list of all middlewares
@@ -341,6 +350,8 @@ func (h *MiddlewareHandler) CreateMiddleware(c *gin.Context) {
 		return
 	}
 
+	h.bus.PublishContext(c.Request.Context(), events.ConfigChanged, events.ResourceChange{Kind: "middleware", ID: middleware.ID, Name: middleware.Name, Action: events.ActionCreated})
+
 	c.JSON(http.StatusCreated, middleware.ToResponse())
 }
 
@@ -384,6 +395,11 @@ func (h *MiddlewareHandler) UpdateMiddleware(c *gin.Context) {
 
 	// Update config if provided
 	if req.Config != nil {
+		// Redacted values are kept as stored
+		var stored models.MiddlewareConfig
+		if json.Unmarshal([]byte(middleware.Config), &stored) == nil {
+			req.Config.RestoreRedacted(stored)
+		}
 		configJSON, err := json.Marshal(req.Config)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid middleware configuration"})
@@ -402,6 +418,8 @@ func (h *MiddlewareHandler) UpdateMiddleware(c *gin.Context) {
 		return
 	}
 
+	h.bus.PublishContext(c.Request.Context(), events.ConfigChanged, events.ResourceChange{Kind: "middleware", ID: middleware.ID, Name: middleware.Name, Action: events.ActionUpdated})
+
 	c.JSON(http.StatusOK, middleware.ToResponse())
 }
 
@@ -435,5 +453,7 @@ func (h *MiddlewareHandler) DeleteMiddleware(c *gin.Context) {
 		return
 	}
 
+	h.bus.PublishContext(c.Request.Context(), events.ConfigChanged, events.ResourceChange{Kind: "middleware", ID: middleware.ID, Name: middleware.Name, Action: events.ActionDeleted})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Middleware deleted successfully"})
 }
@@ -1,25 +1,34 @@
 package handlers
 
 import (
+	"log/slog"
 	"net/http"
 	"strconv"
-	"strings"
 	"unicode"
 
 	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/auth"
 	"github.com/traefikx/backend/internal/database"
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/mailer"
 	"github.com/traefikx/backend/internal/models"
 	"gorm.io/gorm"
 )
 
 type UserHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	throttle *auth.LoginThrottle
+	mailer   mailer.Mailer
+	bus      *events.Bus
 }
 
-func NewUserHandler(db *gorm.DB) *UserHandler {
-	return &UserHandler{db: db}
+func NewUserHandler(db *gorm.DB, throttle *auth.LoginThrottle, m mailer.Mailer, bus *events.Bus) *UserHandler {
+	return &UserHandler{db: db, throttle: throttle, mailer: m, bus: bus}
 }
 
+// failedLoginHistoryLimit is the number of failed attempts returned with a user
+const failedLoginHistoryLimit = 20
+
 // ListUsers returns list of all users (admin only)
 func (h *UserHandler) ListUsers(c *gin.Context) {
 	var users []models.User
@@ -48,6 +57,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 
 	// Check if email already exists
 	var existingUser models.User
+	req.Email = models.NormalizeEmail(req.Email)
 	if err := h.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "User with this email already exists"})
 		return
@@ -84,10 +94,44 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
+	h.bus.Publish(events.UserCreated, events.UserChange{ID: user.ID, Email: user.Email, Role: string(user.Role)})
+
+	// Let the user choose their own password or link OIDC
+	if req.SendInvite {
+		if err := sendInvite(h.db, h.mailer, &user); err != nil {
+			// The user exists now; the invitation can be resent
+			slog.ErrorContext(c.Request.Context(), "Failed to send invitation", "email", user.Email, "error", err)
+		}
+	}
 
 	c.JSON(http.StatusCreated, user.ToResponse())
 }
 
+// InviteUser (re)sends an invitation email to a user (admin only)
+func (h *UserHandler) InviteUser(c *gin.Context) {
+	id := c.Param("id")
+
+	userID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := sendInvite(h.db, h.mailer, &user); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to send invitation", "email", user.Email, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send invitation email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation sent"})
+}
+
 // GetUser returns a specific user (admin only or own profile)
 func (h *UserHandler) GetUser(c *gin.Context) {
 	id := c.Param("id")
@@ -119,7 +163,19 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user.ToResponse())
+	response := user.ToResponse()
+
+	// Admins also get the recent failed login history
+	if userRole == string(models.RoleAdmin) {
+		var attempts []models.LoginAttempt
+		h.db.Where("user_id = ? AND success = ?", user.ID, false).
+			Order("created_at DESC").
+			Limit(failedLoginHistoryLimit).
+			Find(&attempts)
+		response.FailedLoginHistory = attempts
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // UpdateUser updates a user (admin only)
@@ -145,7 +201,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	}
 
 	// Update fields
-	if req.Email != "" {
+	if req.Email = models.NormalizeEmail(req.Email); req.Email != "" {
 		// Check if email is taken by another user
 		var existingUser models.User
 		if err := h.db.Where("email = ? AND id != ?", req.Email, userID).First(&existingUser).Error; err == nil {
@@ -224,7 +280,8 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 	}
 
 	type ResetPasswordRequest struct {
-		NewPassword string `json:"new_password" binding:"required"`
+		NewPassword string `json:"new_password"`
+		SendEmail   bool   `json:"send_email"` // Email a reset link instead of setting a password
 	}
 
 	var req ResetPasswordRequest
@@ -233,6 +290,21 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
+	if req.SendEmail {
+		if err := sendPasswordReset(h.db, h.mailer, &user); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Failed to send password reset email", "email", user.Email, "error", err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send password reset email"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Password reset email sent"})
+		return
+	}
+
+	if req.NewPassword == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "new_password is required"})
+		return
+	}
+
 	// Validate password
 	if err := validatePassword(req.NewPassword); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -257,6 +329,38 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
 }
 
+// UnlockUser clears a temporary lockout and the failed login counters (admin only)
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	id := c.Param("id")
+
+	userID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+
+	if err := h.db.Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
+		return
+	}
+
+	// Clear the per-account backoff as well
+	if h.throttle != nil {
+		h.throttle.ResetAccount(user.Email)
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse())
+}
+
 // validatePassword checks password complexity
 func validatePassword(password string) error {
 	if len(password) < 12 {
@@ -408,7 +512,3 @@ func (h *UserHandler) ToggleUserOIDC(c *gin.Context) {
 		"enabled": req.Enabled,
 	})
 }
-
-func normalizeEmail(email string) string {
-	return strings.ToLower(strings.TrimSpace(email))
-}
@@ -0,0 +1,82 @@
+package logging
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID, taken from the client or a
+// proxy in front if it sends one
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the request IDs accepted from clients
+const maxRequestIDLength = 128
+
+// quietRoutes are polled by monitoring and logged at debug level
+var quietRoutes = map[string]bool{
+	"/api/health": true,
+	"/healthz":    true,
+	"/readyz":     true,
+	"/metrics":    true,
+}
+
+// Middleware gives each request an ID, stored in the request's context and
+// returned in the X-Request-ID header, and writes an access log record
+// once the request is handled. Secrets in the query string are redacted.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = NewRequestID()
+		}
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case quietRoutes[c.FullPath()]:
+			level = slog.LevelDebug
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", RedactURL(c.Request.URL.RequestURI())),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("size", max(c.Writer.Size(), 0)),
+		}
+		if route := c.FullPath(); route != "" {
+			attrs = append(attrs, slog.String("route", route))
+		}
+		if errs := c.Errors.ByType(gin.ErrorTypePrivate).String(); errs != "" {
+			attrs = append(attrs, slog.String("error", errs))
+		}
+		slog.LogAttrs(c.Request.Context(), level, "Request", attrs...)
+	}
+}
+
+// validRequestID accepts IDs made of letters, digits and -_.:
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/traefikx/backend/internal/secrets"
+)
+
+// RedactURL hides the password and the values of query parameters named
+// like secrets, e.g. ?token=, in a URL or request URI before it is logged
+func RedactURL(raw string) string {
+	base, query, hasQuery := strings.Cut(raw, "?")
+
+	if u, err := url.Parse(base); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			// Replaced in place, url.URL would escape the placeholder
+			redacted := url.User(u.User.Username()).String() + ":" + secrets.Redacted
+			if replaced := strings.Replace(base, u.User.String()+"@", redacted+"@", 1); replaced != base {
+				base = replaced
+			} else {
+				u.User = url.UserPassword(u.User.Username(), secrets.Redacted)
+				base = u.String()
+			}
+		}
+	}
+	if !hasQuery {
+		return base
+	}
+
+	// Rebuilt by hand, url.Values would reorder and escape the parameters
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		name, _, hasValue := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if hasValue && secrets.IsSensitiveName(name) {
+			params[i] = param[:strings.IndexByte(param, '=')+1] + secrets.Redacted
+		}
+	}
+	return base + "?" + strings.Join(params, "&")
+}
+
+// RedactError redacts the URL in the message of a failed HTTP request
+func RedactError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = RedactURL(urlErr.URL)
+	}
+	return err
+}
@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes each email as an .eml file into a directory
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("failed to name email file: %w", err)
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405"), hex.EncodeToString(b))
+
+	return os.WriteFile(filepath.Join(m.dir, name), buildMessage(m.from, msg), 0600)
+}
@@ -0,0 +1,69 @@
+package mailer
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/traefikx/backend/internal/config"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails. Implementations: SMTP, log and file.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New creates a mailer based on the MAIL_DRIVER configuration
+func New(cfg *config.Config) (Mailer, error) {
+	switch strings.ToLower(cfg.MailDriver) {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the smtp mail driver")
+		}
+		return NewSMTPMailer(cfg), nil
+	case "file":
+		return NewFileMailer(cfg.MailFileDir, cfg.MailFrom)
+	case "log", "":
+		return NewLogMailer(cfg.MailFrom), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver: %s", cfg.MailDriver)
+	}
+}
+
+// LogMailer logs that emails would be sent, without their body: it holds
+// the single-use links of invitations and password resets, which must not
+// end up in logs. Use the file driver to read the emails.
+type LogMailer struct {
+	from string
+}
+
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	slog.Info("Email", "from", m.from, "to", strings.Join(msg.To, ", "), "subject", msg.Subject)
+	return nil
+}
+
+// buildMessage renders an RFC 5322 message
+func buildMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(msg.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,223 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/traefikx/backend/internal/config"
+)
+
+// smtpStub is a minimal SMTP server recording what it receives
+type smtpStub struct {
+	listener   net.Listener
+	rejectRcpt string // Recipient answered with 550
+
+	mu       sync.Mutex
+	auth     string
+	from     string
+	rcpts    []string
+	data     string
+	commands []string
+}
+
+func startSMTPStub(t *testing.T, rejectRcpt string) *smtpStub {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &smtpStub{listener: listener, rejectRcpt: rejectRcpt}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 stub ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		s.mu.Lock()
+		s.commands = append(s.commands, verb)
+		s.mu.Unlock()
+
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250-stub")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			s.mu.Lock()
+			s.auth = line
+			s.mu.Unlock()
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			s.mu.Lock()
+			s.from = line
+			s.mu.Unlock()
+			reply("250 OK")
+		case "RCPT":
+			if s.rejectRcpt != "" && strings.Contains(line, s.rejectRcpt) {
+				reply("550 No such user")
+				continue
+			}
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, line)
+			s.mu.Unlock()
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 OK queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *smtpStub) mailer(username string) *SMTPMailer {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return NewSMTPMailer(&config.Config{
+		SMTPHost:     host,
+		SMTPPort:     portNumber,
+		SMTPUsername: username,
+		SMTPPassword: "secret",
+		MailFrom:     "TraefikX <noreply@traefikx.local>",
+	})
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	stub := startSMTPStub(t, "")
+
+	err := stub.mailer("mailer").Send(Message{
+		To:      []string{"alice@example.com", "bob@example.com"},
+		Subject: "Welcome",
+		Body:    "Hello\nworld",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if !strings.HasPrefix(stub.auth, "AUTH PLAIN") {
+		t.Errorf("expected AUTH PLAIN, got %q", stub.auth)
+	}
+	if stub.from != "MAIL FROM:<noreply@traefikx.local>" {
+		t.Errorf("unexpected envelope sender %q", stub.from)
+	}
+	if len(stub.rcpts) != 2 || !strings.Contains(stub.rcpts[0], "alice@example.com") || !strings.Contains(stub.rcpts[1], "bob@example.com") {
+		t.Errorf("unexpected recipients %v", stub.rcpts)
+	}
+	for _, want := range []string{
+		"From: TraefikX <noreply@traefikx.local>\r\n",
+		"To: alice@example.com, bob@example.com\r\n",
+		"Subject: Welcome\r\n",
+		"\r\n\r\nHello\r\nworld",
+	} {
+		if !strings.Contains(stub.data, want) {
+			t.Errorf("message lacks %q:\n%s", want, stub.data)
+		}
+	}
+	if last := stub.commands[len(stub.commands)-1]; last != "QUIT" {
+		t.Errorf("expected the session to end with QUIT, got %s", last)
+	}
+}
+
+func TestSMTPMailer_SendWithoutAuth(t *testing.T) {
+	stub := startSMTPStub(t, "")
+
+	if err := stub.mailer("").Send(Message{To: []string{"alice@example.com"}, Subject: "Hi", Body: "Hi"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if stub.auth != "" {
+		t.Errorf("expected no authentication, got %q", stub.auth)
+	}
+}
+
+func TestSMTPMailer_RejectedRecipient(t *testing.T) {
+	stub := startSMTPStub(t, "nobody@example.com")
+
+	err := stub.mailer("").Send(Message{To: []string{"alice@example.com", "nobody@example.com"}, Subject: "Hi", Body: "Hi"})
+	if err == nil || !strings.Contains(err.Error(), "RCPT TO nobody@example.com failed") {
+		t.Fatalf("expected the rejected recipient to fail the send, got %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if stub.data != "" {
+		t.Errorf("expected no message to be sent, got %q", stub.data)
+	}
+}
+
+func TestSMTPMailer_Timeout(t *testing.T) {
+	// Accepts connections but never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	m := NewSMTPMailer(&config.Config{SMTPHost: host, SMTPPort: portNumber, SMTPTimeout: 100 * time.Millisecond})
+
+	start := time.Now()
+	if err := m.Send(Message{To: []string{"alice@example.com"}, Subject: "Hi", Body: "Hi"}); err == nil {
+		t.Fatal("expected a silent server to fail the send")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the send to give up after the timeout, took %v", elapsed)
+	}
+}
@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"fmt"
+	"time"
+)
+
+// InviteMessage builds the invitation email for a new user
+func InviteMessage(to, link string, expiresAt time.Time) Message {
+	return Message{
+		To:      []string{to},
+		Subject: "You have been invited to TraefikX",
+		Body: fmt.Sprintf(`Hello,
+
+An administrator has created a TraefikX account for %s.
+
+Open the link below to set your password or sign in with your identity provider:
+
+%s
+
+This link can only be used once and expires on %s.
+`, to, link, expiresAt.Format(time.RFC1123)),
+	}
+}
+
+// PasswordResetMessage builds the password reset email
+func PasswordResetMessage(to, link string, expiresAt time.Time) Message {
+	return Message{
+		To:      []string{to},
+		Subject: "Reset your TraefikX password",
+		Body: fmt.Sprintf(`Hello,
+
+A password reset was requested for your TraefikX account (%s).
+
+Open the link below to choose a new password:
+
+%s
+
+This link can only be used once and expires on %s.
+If you did not request a reset, you can ignore this email.
+`, to, link, expiresAt.Format(time.RFC1123)),
+	}
+}
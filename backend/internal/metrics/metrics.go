@@ -0,0 +1,176 @@
+// Package metrics exposes Prometheus metrics. Events such as requests and
+// fetches are counted here as they happen; state such as provider health
+// is collected from the services when scraped.
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes all metric names
+const namespace = "traefikx"
+
+// Registry holds the TraefikX metrics, with the Go runtime and process ones
+var Registry = prometheus.NewRegistry()
+
+// Fetch results
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "API requests by route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "API request latency by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	providerFetches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_fetches_total",
+		Help:      "HTTP provider fetches by result.",
+	}, []string{"provider", "result"})
+
+	providerFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_fetch_duration_seconds",
+		Help:      "Time to fetch and parse an HTTP provider's configuration.",
+		Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"provider"})
+
+	compileDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "config_compile_duration_seconds",
+		Help:      "Time to compile the merged Traefik configuration.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by method (password, oidc) and result.",
+	}, []string{"method", "result"})
+
+	probes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "backend_probes_total",
+		Help:      "Health probes of service servers by result.",
+	}, []string{"service", "result"})
+
+	probeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "backend_probe_duration_seconds",
+		Help:      "Health probe latency of service servers.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		providerFetches,
+		providerFetchDuration,
+		compileDuration,
+		logins,
+		probes,
+		probeDuration,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format. With a token,
+// scrapers must send it as a bearer token.
+func Handler(token string) gin.HandlerFunc {
+	handler := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	expected := []byte("Bearer " + token)
+	return func(c *gin.Context) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), expected) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid metrics token"})
+			return
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// Middleware counts API requests and their latency by route pattern, so
+// IDs in paths don't create a series per resource
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched" // 404s and the static UI
+		}
+		method := c.Request.Method
+		httpRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveFetch records a provider fetch
+func ObserveFetch(provider string, duration time.Duration, success bool) {
+	result := ResultSuccess
+	if !success {
+		result = ResultFailure
+	}
+	providerFetches.WithLabelValues(provider, result).Inc()
+	providerFetchDuration.WithLabelValues(provider).Observe(duration.Seconds())
+}
+
+// ForgetProvider removes the series of a deleted or renamed provider
+func ForgetProvider(provider string) {
+	labels := prometheus.Labels{"provider": provider}
+	providerFetches.DeletePartialMatch(labels)
+	providerFetchDuration.DeletePartialMatch(labels)
+}
+
+// ObserveCompile records a configuration compile
+func ObserveCompile(duration time.Duration) {
+	compileDuration.Observe(duration.Seconds())
+}
+
+// ObserveLogin records a login attempt
+func ObserveLogin(method string, success bool) {
+	result := ResultSuccess
+	if !success {
+		result = ResultFailure
+	}
+	logins.WithLabelValues(method, result).Inc()
+}
+
+// ObserveProbe records a health probe of one of a service's servers
+func ObserveProbe(service string, duration time.Duration, healthy bool) {
+	result := "healthy"
+	if !healthy {
+		result = "unhealthy"
+	}
+	probes.WithLabelValues(service, result).Inc()
+	probeDuration.WithLabelValues(service).Observe(duration.Seconds())
+}
+
+// ForgetService removes the probe series of a deleted or renamed service
+func ForgetService(service string) {
+	labels := prometheus.Labels{"service": service}
+	probes.DeletePartialMatch(labels)
+	probeDuration.DeletePartialMatch(labels)
+}
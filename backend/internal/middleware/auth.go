@@ -26,20 +26,44 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := auth.ParseToken(parts[1])
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-			c.Abort()
+		authenticate(c, parts[1])
+	}
+}
+
+// StreamAuthMiddleware authenticates event streams. Browsers can't set
+// headers on an EventSource, so the access token may also be sent in an
+// access_token query parameter.
+func StreamAuthMiddleware() gin.HandlerFunc {
+	header := AuthMiddleware()
+	return func(c *gin.Context) {
+		token := c.Query("access_token")
+		if token == "" || c.GetHeader("Authorization") != "" {
+			header(c)
 			return
 		}
+		authenticate(c, token)
+	}
+}
 
-		// Set user info in context
-		c.Set("userID", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("role", claims.Role)
+// authenticate sets the user of a valid access token, and its expiry as
+// tokenExpiresAt, in the context
+func authenticate(c *gin.Context, token string) {
+	claims, err := auth.ParseToken(token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.Abort()
+		return
+	}
 
-		c.Next()
+	// Set user info in context
+	c.Set("userID", claims.UserID)
+	c.Set("email", claims.Email)
+	c.Set("role", claims.Role)
+	if claims.ExpiresAt != nil {
+		c.Set("tokenExpiresAt", claims.ExpiresAt.Time)
 	}
+
+	c.Next()
 }
 
 func AdminMiddleware() gin.HandlerFunc {
@@ -79,7 +103,8 @@ func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
 		}
 
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/auth"
+	"github.com/traefikx/backend/internal/config"
+)
+
+func TestAuthMiddleware_AcceptsOnlyAccessTokens(t *testing.T) {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{
+		JWTSecret:            "middleware-test-secret-of-at-least-32-chars",
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+	}
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/me", AuthMiddleware(), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("email"))
+	})
+	get := func(token string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/me", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	pair, err := auth.GenerateTokenPair(1, "admin@example.com", "admin")
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+	if code := get(pair.AccessToken); code != http.StatusOK {
+		t.Errorf("access token: got %d", code)
+	}
+	if code := get(pair.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("refresh token: got %d, want 401", code)
+	}
+
+	for _, purpose := range []string{auth.TokenPurposeInvite, auth.TokenPurposePasswordReset} {
+		token, _, _, err := auth.GenerateActionToken(1, purpose, time.Hour)
+		if err != nil {
+			t.Fatalf("GenerateActionToken: %v", err)
+		}
+		if code := get(token); code != http.StatusUnauthorized {
+			t.Errorf("%s token: got %d, want 401", purpose, code)
+		}
+		if _, err := auth.ParseActionToken(token, purpose); err != nil {
+			t.Errorf("%s token: %v", purpose, err)
+		}
+	}
+
+	// Nor are access tokens accepted as links
+	if _, err := auth.ParseActionToken(pair.AccessToken, auth.TokenPurposeInvite); err == nil {
+		t.Error("expected an access token to be rejected as an invite")
+	}
+}
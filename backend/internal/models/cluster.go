@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Lease is a named lock held by one instance until it expires. Replicas
+// elect their leader by holding a lease.
+type Lease struct {
+	Name      string    `gorm:"primaryKey;size:64" json:"name"`
+	Holder    string    `gorm:"size:255" json:"holder"` // Instance ID, empty once released
+	ExpiresAt time.Time `json:"expires_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ClusterEvent is an event relayed between replicas through the database
+type ClusterEvent struct {
+	ID        uint      `gorm:"primaryKey"`
+	Type      string    `gorm:"size:64;not null"`
+	Data      string    `gorm:"type:text"`         // JSON payload
+	Origin    string    `gorm:"size:255;not null"` // Instance that published the event
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// OIDCLoginState is a pending OIDC login, stored in the database so the
+// callback can be handled by any replica
+type OIDCLoginState struct {
+	State         string    `gorm:"primaryKey;size:255"`
+	LinkToUser    uint      // If > 0, link to existing user instead of creating new
+	InviteTokenID uint      // If > 0, the link completes this invitation
+	ExpiresAt     time.Time `gorm:"index"`
+}
@@ -0,0 +1,124 @@
+package models
+
+import "time"
+
+// NotificationChannel is a target notified of the events it subscribes to
+type NotificationChannel struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	Name       string `gorm:"uniqueIndex;size:255;not null" json:"name"`
+	Type       string `gorm:"size:32;not null" json:"type"`            // webhook, slack, ntfy, gotify or email
+	URL        string `gorm:"type:text;serializer:encrypted" json:"-"` // Endpoint, which may embed a token as Slack's do
+	Secret     string `gorm:"type:text;serializer:encrypted" json:"-"` // Webhook signing key, ntfy or Gotify token
+	Recipients string `gorm:"type:text" json:"-"`                      // JSON array of email addresses
+	Events     string `gorm:"type:text" json:"-"`                      // JSON array of subscribed events, empty = all
+	IsActive   bool   `gorm:"default:true" json:"is_active"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Notification channel types
+const (
+	ChannelWebhook = "webhook" // JSON POST, signed with HMAC-SHA256 when a secret is set
+	ChannelSlack   = "slack"   // Slack-compatible incoming webhook
+	ChannelNtfy    = "ntfy"    // ntfy topic URL
+	ChannelGotify  = "gotify"  // Gotify server URL
+	ChannelEmail   = "email"   // Sent with the configured mailer
+)
+
+// GetRecipients returns the email addresses of an email channel
+func (n *NotificationChannel) GetRecipients() []string {
+	return decodeStringList(n.Recipients)
+}
+
+// GetEvents returns the subscribed events, empty for all
+func (n *NotificationChannel) GetEvents() []string {
+	return decodeStringList(n.Events)
+}
+
+// SetRecipients stores the email addresses of an email channel
+func (n *NotificationChannel) SetRecipients(recipients []string) {
+	n.Recipients = encodeStringList(recipients)
+}
+
+// SetEvents stores the subscribed events
+func (n *NotificationChannel) SetEvents(events []string) {
+	n.Events = encodeStringList(events)
+}
+
+// Subscribed reports whether the channel is notified of event
+func (n *NotificationChannel) Subscribed(event string) bool {
+	events := n.GetEvents()
+	if len(events) == 0 {
+		return true
+	}
+	for _, subscribed := range events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ToResponse converts NotificationChannel to a response for admins. The URL
+// is returned so it can be edited, the secret never is.
+func (n *NotificationChannel) ToResponse() map[string]interface{} {
+	return map[string]interface{}{
+		"id":         n.ID,
+		"name":       n.Name,
+		"type":       n.Type,
+		"url":        n.URL,
+		"has_secret": n.Secret != "",
+		"recipients": n.GetRecipients(),
+		"events":     n.GetEvents(),
+		"is_active":  n.IsActive,
+		"created_at": n.CreatedAt.Format(time.RFC3339),
+		"updated_at": n.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+type CreateNotificationChannelRequest struct {
+	Name       string   `json:"name" binding:"required"`
+	Type       string   `json:"type" binding:"required,oneof=webhook slack ntfy gotify email"`
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	Recipients []string `json:"recipients"`
+	Events     []string `json:"events"`
+	IsActive   *bool    `json:"is_active"`
+}
+
+// UpdateNotificationChannelRequest leaves omitted fields unchanged. The
+// secret is only replaced when sent; send an empty string to clear it.
+type UpdateNotificationChannelRequest struct {
+	Name       *string   `json:"name,omitempty"`
+	Type       *string   `json:"type,omitempty" binding:"omitempty,oneof=webhook slack ntfy gotify email"`
+	URL        *string   `json:"url,omitempty"`
+	Secret     *string   `json:"secret,omitempty"`
+	Recipients *[]string `json:"recipients,omitempty"`
+	Events     *[]string `json:"events,omitempty"`
+	IsActive   *bool     `json:"is_active,omitempty"`
+}
+
+// NotificationDelivery records the delivery of a notification to a
+// channel, retried with backoff until it succeeds or attempts run out
+type NotificationDelivery struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	ChannelID     uint       `gorm:"index;not null" json:"channel_id"`
+	Event         string     `gorm:"size:64;index" json:"event"`
+	Payload       string     `gorm:"type:text" json:"-"` // JSON notification
+	Status        string     `gorm:"size:16;index" json:"status"`
+	Attempts      int        `json:"attempts"`
+	StatusCode    int        `json:"status_code,omitempty"` // HTTP status of the last attempt
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt *time.Time `gorm:"index" json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Notification delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
@@ -1,13 +1,21 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/traefikx/backend/internal/secrets"
 )
 
 // Router represents a Traefik router configuration
 type Router struct {
 	ID        uint             `gorm:"primaryKey" json:"id"`
-	Name      string           `gorm:"uniqueIndex;not null" json:"name"` // Unique router name
+	Name      string           `gorm:"size:255;uniqueIndex;not null" json:"name"` // Unique router name
 	Hostnames []RouterHostname `gorm:"foreignKey:RouterID;constraint:OnDelete:CASCADE" json:"hostnames"`
 	ServiceID uint             `gorm:"not null" json:"service_id"`
 	Service   Service          `gorm:"foreignKey:ServiceID" json:"service,omitempty"`
@@ -52,8 +60,8 @@ type RouterMiddleware struct {
 // Service represents a Traefik service configuration
 type Service struct {
 	ID   uint   `gorm:"primaryKey" json:"id"`
-	Name string `gorm:"uniqueIndex;not null" json:"name"` // Unique service name
-	Type string `gorm:"default:http" json:"type"`         // http (for now)
+	Name string `gorm:"size:255;uniqueIndex;not null" json:"name"` // Unique service name
+	Type string `gorm:"default:http" json:"type"`                  // http (for now)
 
 	// Load Balancing
 	Servers          []ServiceServer `gorm:"foreignKey:ServiceID;constraint:OnDelete:CASCADE" json:"servers"`
@@ -78,17 +86,18 @@ type ServiceServer struct {
 	ServiceID uint   `gorm:"not null;index" json:"service_id"`
 	URL       string `gorm:"not null" json:"url"` // e.g., http://192.168.1.100:8080
 	Weight    int    `gorm:"default:1" json:"weight"`
-	IsHealthy *bool  `json:"is_healthy,omitempty"` // Traefik tracks this
+	IsHealthy *bool  `json:"is_healthy,omitempty"` // Last health probe result, nil until probed
 }
 
 // Middleware represents a Traefik middleware configuration
 type Middleware struct {
 	ID   uint   `gorm:"primaryKey" json:"id"`
-	Name string `gorm:"uniqueIndex;not null" json:"name"` // Unique middleware name
-	Type string `gorm:"not null" json:"type"`             // redirectScheme, headers, stripPrefix, etc.
+	Name string `gorm:"size:255;uniqueIndex;not null" json:"name"` // Unique middleware name
+	Type string `gorm:"not null" json:"type"`                      // redirectScheme, headers, stripPrefix, etc.
 
-	// Type-specific configuration stored as JSON
-	Config string `gorm:"type:text" json:"config"` // JSON configuration based on type
+	// Type-specific configuration stored as JSON, encrypted as it may hold
+	// credentials
+	Config string `gorm:"type:text;serializer:encrypted" json:"config"` // JSON configuration based on type
 
 	IsActive  bool      `gorm:"default:true" json:"is_active"`
 	CreatedAt time.Time `json:"created_at"`
@@ -174,6 +183,57 @@ type MiddlewareConfig struct {
 	Prefix string `json:"prefix,omitempty"`
 }
 
+// Redact hides the values of sensitive headers such as Authorization
+func (c *MiddlewareConfig) Redact() {
+	c.CustomRequestHeaders = RedactHeaders(c.CustomRequestHeaders)
+	c.CustomResponseHeaders = RedactHeaders(c.CustomResponseHeaders)
+}
+
+// RestoreRedacted puts back the stored values of headers that are still
+// redacted, so a redacted config can be edited and saved
+func (c *MiddlewareConfig) RestoreRedacted(stored MiddlewareConfig) {
+	restoreHeaders(c.CustomRequestHeaders, stored.CustomRequestHeaders)
+	restoreHeaders(c.CustomResponseHeaders, stored.CustomResponseHeaders)
+}
+
+// RedactHeaders returns a copy of headers with sensitive values redacted
+func RedactHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if value != "" && secrets.IsSensitiveName(name) {
+			value = secrets.Redacted
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+func restoreHeaders(headers, stored map[string]string) {
+	for name, value := range headers {
+		if value == secrets.Redacted {
+			headers[name] = stored[name]
+		}
+	}
+}
+
+// RedactMiddlewareConfig redacts a stored JSON config. Invalid config is
+// returned unchanged.
+func RedactMiddlewareConfig(config string) string {
+	var parsed MiddlewareConfig
+	if err := json.Unmarshal([]byte(config), &parsed); err != nil {
+		return config
+	}
+	parsed.Redact()
+	redacted, err := json.Marshal(parsed)
+	if err != nil {
+		return config
+	}
+	return string(redacted)
+}
+
 type RouterResponse struct {
 	ID              uint             `json:"id"`
 	Name            string           `json:"name"`
@@ -296,9 +356,10 @@ type ServiceResponse struct {
 }
 
 type ServerResponse struct {
-	ID     uint   `json:"id"`
-	URL    string `json:"url"`
-	Weight int    `json:"weight"`
+	ID        uint   `json:"id"`
+	URL       string `json:"url"`
+	Weight    int    `json:"weight"`
+	IsHealthy *bool  `json:"is_healthy,omitempty"`
 }
 
 // ToResponse converts Service to ServiceResponse
@@ -306,9 +367,10 @@ func (s *Service) ToResponse() ServiceResponse {
 	servers := make([]ServerResponse, len(s.Servers))
 	for i, srv := range s.Servers {
 		servers[i] = ServerResponse{
-			ID:     srv.ID,
-			URL:    srv.URL,
-			Weight: srv.Weight,
+			ID:        srv.ID,
+			URL:       srv.URL,
+			Weight:    srv.Weight,
+			IsHealthy: srv.IsHealthy,
 		}
 	}
 
@@ -344,7 +406,7 @@ func (m *Middleware) ToResponse() MiddlewareResponse {
 		ID:        m.ID,
 		Name:      m.Name,
 		Type:      m.Type,
-		Config:    m.Config,
+		Config:    RedactMiddlewareConfig(m.Config),
 		IsActive:  m.IsActive,
 		CreatedAt: m.CreatedAt,
 		UpdatedAt: m.UpdatedAt,
@@ -354,19 +416,158 @@ func (m *Middleware) ToResponse() MiddlewareResponse {
 // HTTPProvider represents an external Traefik HTTP Provider to aggregate
 type HTTPProvider struct {
 	ID              uint       `gorm:"primaryKey" json:"id"`
-	Name            string     `gorm:"uniqueIndex;not null" json:"name"`
-	URL             string     `gorm:"not null" json:"url"`
+	Name            string     `gorm:"size:255;uniqueIndex;not null" json:"name"`
+	Type            string     `gorm:"default:http" json:"type"`        // http, docker, file or consul
+	URL             string     `gorm:"not null" json:"url"`             // Endpoint, Docker socket or directory, depending on Type
 	Priority        int        `gorm:"default:0;index" json:"priority"` // Higher = higher priority
 	IsActive        bool       `gorm:"default:true" json:"is_active"`
 	RefreshInterval int        `gorm:"default:30" json:"refresh_interval"` // seconds
+	Timeout         int        `gorm:"default:5" json:"timeout"`           // seconds
 	LastFetched     *time.Time `json:"last_fetched"`
-	LastResponse    []byte     `gorm:"type:text" json:"-"`
+	LastResponse    []byte     `gorm:"type:text;serializer:encrypted" json:"-"` // May hold credentials of the provider's middlewares
 	LastError       string     `json:"last_error"`
+	State           string     `json:"state"` // healthy, degraded or failed, as last decided by the aggregator
 	RouterCount     int        `json:"router_count"`
 	ServiceCount    int        `json:"service_count"`
 	MiddlewareCount int        `json:"middleware_count"`
-	CreatedAt       time.Time  `json:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// Behaviour while fetches fail
+	FailurePolicy    string `gorm:"default:keep" json:"failure_policy"`    // keep (last known good) or drop
+	StaleGracePeriod int    `gorm:"default:300" json:"stale_grace_period"` // seconds a stale config is kept, 0 = no limit
+
+	// Namespacing and filtering of the provider's resources
+	NamespaceMode   string `gorm:"default:none" json:"namespace_mode"` // none, suffix or prefix
+	NamespacePrefix string `json:"namespace_prefix"`                   // Defaults to "<name>-"
+	IncludeFilters  string `gorm:"type:text" json:"-"`                 // JSON array of patterns
+	ExcludeFilters  string `gorm:"type:text" json:"-"`
+
+	// Authentication sent with every request
+	AuthType     string `gorm:"default:none" json:"auth_type"` // none, bearer or basic
+	AuthUsername string `json:"auth_username"`
+	AuthSecret   string `gorm:"type:text;serializer:encrypted" json:"-"` // Bearer token or basic auth password
+	Headers      string `gorm:"type:text;serializer:encrypted" json:"-"` // JSON object of custom headers
+
+	// TLS settings for https URLs
+	TLSCA                 string `gorm:"type:text" json:"tls_ca"` // PEM bundle trusted in addition to system roots
+	TLSCert               string `gorm:"type:text" json:"tls_cert"`
+	TLSKey                string `gorm:"type:text;serializer:encrypted" json:"-"`
+	TLSInsecureSkipVerify bool   `json:"tls_insecure_skip_verify"`
+
+	// Validators of the last response, for conditional requests
+	ETag         string `json:"-"`
+	LastModified string `json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Provider types
+const (
+	ProviderTypeHTTP   = "http"   // Traefik HTTP provider endpoint
+	ProviderTypeDocker = "docker" // Container labels from a Docker Engine API
+	ProviderTypeFile   = "file"   // Directory of Traefik dynamic configuration files
+	ProviderTypeConsul = "consul" // Service tags from a Consul catalog API
+)
+
+// Provider failure policies
+const (
+	ProviderFailureKeep = "keep"
+	ProviderFailureDrop = "drop"
+)
+
+// Provider namespace modes
+const (
+	NamespaceNone   = "none"
+	NamespaceSuffix = "suffix"
+	NamespacePrefix = "prefix"
+)
+
+// Provider auth types
+const (
+	ProviderAuthNone   = "none"
+	ProviderAuthBearer = "bearer"
+	ProviderAuthBasic  = "basic"
+)
+
+// ValidateURL checks that the URL suits the provider type: an http(s)
+// endpoint, a Docker socket (unix://, tcp:// or http(s)://) or an absolute
+// directory path (optionally file://)
+func (e *HTTPProvider) ValidateURL() error {
+	switch e.Type {
+	case "", ProviderTypeHTTP, ProviderTypeConsul:
+		if u, err := url.Parse(e.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an http or https URL")
+		}
+	case ProviderTypeDocker:
+		u, err := url.Parse(e.URL)
+		if err != nil {
+			return fmt.Errorf("invalid Docker endpoint: %w", err)
+		}
+		switch {
+		case u.Scheme == "unix" && u.Path != "":
+		case (u.Scheme == "tcp" || u.Scheme == "http" || u.Scheme == "https") && u.Host != "":
+		default:
+			return fmt.Errorf("url must be a unix://, tcp://, http:// or https:// Docker endpoint")
+		}
+	case ProviderTypeFile:
+		if !filepath.IsAbs(strings.TrimPrefix(e.URL, "file://")) {
+			return fmt.Errorf("url must be an absolute directory path")
+		}
+	default:
+		return fmt.Errorf("unsupported provider type %q", e.Type)
+	}
+	return nil
+}
+
+// GetHeaders returns the custom request headers
+func (e *HTTPProvider) GetHeaders() map[string]string {
+	headers := map[string]string{}
+	if e.Headers != "" {
+		json.Unmarshal([]byte(e.Headers), &headers)
+	}
+	return headers
+}
+
+// GetIncludeFilters returns the patterns selecting imported routers
+func (e *HTTPProvider) GetIncludeFilters() []string {
+	return decodeStringList(e.IncludeFilters)
+}
+
+// GetExcludeFilters returns the patterns of resources never imported
+func (e *HTTPProvider) GetExcludeFilters() []string {
+	return decodeStringList(e.ExcludeFilters)
+}
+
+// SetFilters stores the include and exclude patterns
+func (e *HTTPProvider) SetFilters(include, exclude []string) {
+	e.IncludeFilters = encodeStringList(include)
+	e.ExcludeFilters = encodeStringList(exclude)
+}
+
+func decodeStringList(value string) []string {
+	list := []string{}
+	if value != "" {
+		json.Unmarshal([]byte(value), &list)
+	}
+	return list
+}
+
+func encodeStringList(list []string) string {
+	if len(list) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(list)
+	return string(data)
+}
+
+// SetHeaders stores custom request headers
+func (e *HTTPProvider) SetHeaders(headers map[string]string) {
+	if len(headers) == 0 {
+		e.Headers = ""
+		return
+	}
+	data, _ := json.Marshal(headers)
+	e.Headers = string(data)
 }
 
 // ToResponse converts HTTPProvider to a safe response
@@ -375,37 +576,187 @@ func (e *HTTPProvider) ToResponse() map[string]interface{} {
 	if e.LastFetched != nil {
 		lastFetched = e.LastFetched.Format(time.RFC3339)
 	}
+	authType := e.AuthType
+	if authType == "" {
+		authType = ProviderAuthNone
+	}
+	providerType := e.Type
+	if providerType == "" {
+		providerType = ProviderTypeHTTP
+	}
+	namespaceMode := e.NamespaceMode
+	if namespaceMode == "" {
+		namespaceMode = NamespaceNone
+	}
+	// Header values may hold credentials, only names are returned
+	headerNames := []string{}
+	for name := range e.GetHeaders() {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+
 	return map[string]interface{}{
-		"id":               e.ID,
-		"name":             e.Name,
-		"url":              e.URL,
-		"priority":         e.Priority,
-		"is_active":        e.IsActive,
-		"refresh_interval": e.RefreshInterval,
-		"last_fetched":     lastFetched,
-		"last_error":       e.LastError,
-		"router_count":     e.RouterCount,
-		"service_count":    e.ServiceCount,
-		"middleware_count": e.MiddlewareCount,
-		"created_at":       e.CreatedAt.Format(time.RFC3339),
-		"updated_at":       e.UpdatedAt.Format(time.RFC3339),
+		"id":                       e.ID,
+		"name":                     e.Name,
+		"type":                     providerType,
+		"url":                      e.URL,
+		"priority":                 e.Priority,
+		"is_active":                e.IsActive,
+		"refresh_interval":         e.RefreshInterval,
+		"timeout":                  e.Timeout,
+		"failure_policy":           e.FailurePolicy,
+		"namespace_mode":           namespaceMode,
+		"namespace_prefix":         e.NamespacePrefix,
+		"include_filters":          e.GetIncludeFilters(),
+		"exclude_filters":          e.GetExcludeFilters(),
+		"stale_grace_period":       e.StaleGracePeriod,
+		"last_fetched":             lastFetched,
+		"last_error":               e.LastError,
+		"router_count":             e.RouterCount,
+		"service_count":            e.ServiceCount,
+		"middleware_count":         e.MiddlewareCount,
+		"auth_type":                authType,
+		"auth_username":            e.AuthUsername,
+		"has_auth_secret":          e.AuthSecret != "",
+		"header_names":             headerNames,
+		"tls_ca":                   e.TLSCA,
+		"tls_cert":                 e.TLSCert,
+		"has_tls_key":              e.TLSKey != "",
+		"tls_insecure_skip_verify": e.TLSInsecureSkipVerify,
+		"created_at":               e.CreatedAt.Format(time.RFC3339),
+		"updated_at":               e.UpdatedAt.Format(time.RFC3339),
 	}
 }
 
 // Request/Response structures for HTTPProvider API
 
 type CreateHTTPProviderRequest struct {
-	Name            string `json:"name" binding:"required"`
-	URL             string `json:"url" binding:"required,url"`
-	Priority        int    `json:"priority"`
-	RefreshInterval int    `json:"refresh_interval"`
-	IsActive        bool   `json:"is_active"`
+	Name                  string            `json:"name" binding:"required"`
+	Type                  string            `json:"type" binding:"omitempty,oneof=http docker file consul"`
+	URL                   string            `json:"url" binding:"required"`
+	Priority              int               `json:"priority"`
+	RefreshInterval       int               `json:"refresh_interval"`
+	Timeout               int               `json:"timeout"`
+	IsActive              bool              `json:"is_active"`
+	FailurePolicy         string            `json:"failure_policy" binding:"omitempty,oneof=keep drop"`
+	NamespaceMode         string            `json:"namespace_mode" binding:"omitempty,oneof=none suffix prefix"`
+	NamespacePrefix       string            `json:"namespace_prefix"`
+	IncludeFilters        []string          `json:"include_filters"`
+	ExcludeFilters        []string          `json:"exclude_filters"`
+	StaleGracePeriod      *int              `json:"stale_grace_period" binding:"omitempty,min=0"`
+	AuthType              string            `json:"auth_type" binding:"omitempty,oneof=none bearer basic"`
+	AuthUsername          string            `json:"auth_username"`
+	AuthSecret            string            `json:"auth_secret"`
+	Headers               map[string]string `json:"headers"`
+	TLSCA                 string            `json:"tls_ca"`
+	TLSCert               string            `json:"tls_cert"`
+	TLSKey                string            `json:"tls_key"`
+	TLSInsecureSkipVerify bool              `json:"tls_insecure_skip_verify"`
 }
 
+// UpdateHTTPProviderRequest leaves omitted fields unchanged. Secrets can't be
+// read back, so auth_secret and tls_key are only replaced when sent; send
+// an empty string to clear them.
 type UpdateHTTPProviderRequest struct {
-	Name            *string `json:"name,omitempty"`
-	URL             *string `json:"url,omitempty" binding:"omitempty,url"`
-	Priority        *int    `json:"priority,omitempty"`
-	RefreshInterval *int    `json:"refresh_interval,omitempty"`
-	IsActive        *bool   `json:"is_active,omitempty"`
+	Name                  *string            `json:"name,omitempty"`
+	Type                  *string            `json:"type,omitempty" binding:"omitempty,oneof=http docker file consul"`
+	URL                   *string            `json:"url,omitempty"`
+	Priority              *int               `json:"priority,omitempty"`
+	RefreshInterval       *int               `json:"refresh_interval,omitempty"`
+	Timeout               *int               `json:"timeout,omitempty"`
+	IsActive              *bool              `json:"is_active,omitempty"`
+	FailurePolicy         *string            `json:"failure_policy,omitempty" binding:"omitempty,oneof=keep drop"`
+	NamespaceMode         *string            `json:"namespace_mode,omitempty" binding:"omitempty,oneof=none suffix prefix"`
+	NamespacePrefix       *string            `json:"namespace_prefix,omitempty"`
+	IncludeFilters        *[]string          `json:"include_filters,omitempty"`
+	ExcludeFilters        *[]string          `json:"exclude_filters,omitempty"`
+	StaleGracePeriod      *int               `json:"stale_grace_period,omitempty" binding:"omitempty,min=0"`
+	AuthType              *string            `json:"auth_type,omitempty" binding:"omitempty,oneof=none bearer basic"`
+	AuthUsername          *string            `json:"auth_username,omitempty"`
+	AuthSecret            *string            `json:"auth_secret,omitempty"`
+	Headers               *map[string]string `json:"headers,omitempty"`
+	TLSCA                 *string            `json:"tls_ca,omitempty"`
+	TLSCert               *string            `json:"tls_cert,omitempty"`
+	TLSKey                *string            `json:"tls_key,omitempty"`
+	TLSInsecureSkipVerify *bool              `json:"tls_insecure_skip_verify,omitempty"`
+}
+
+// TestHTTPProviderRequest tests either a saved provider, by ID, or unsaved
+// settings. The name of an existing provider previews replacing it.
+type TestHTTPProviderRequest struct {
+	ID                    *uint             `json:"id,omitempty"`
+	Name                  string            `json:"name"`
+	Type                  string            `json:"type" binding:"omitempty,oneof=http docker file consul"`
+	URL                   string            `json:"url"`
+	Priority              int               `json:"priority"`
+	Timeout               int               `json:"timeout"`
+	NamespaceMode         string            `json:"namespace_mode" binding:"omitempty,oneof=none suffix prefix"`
+	NamespacePrefix       string            `json:"namespace_prefix"`
+	IncludeFilters        []string          `json:"include_filters"`
+	ExcludeFilters        []string          `json:"exclude_filters"`
+	AuthType              string            `json:"auth_type" binding:"omitempty,oneof=none bearer basic"`
+	AuthUsername          string            `json:"auth_username"`
+	AuthSecret            string            `json:"auth_secret"`
+	Headers               map[string]string `json:"headers"`
+	TLSCA                 string            `json:"tls_ca"`
+	TLSCert               string            `json:"tls_cert"`
+	TLSKey                string            `json:"tls_key"`
+	TLSInsecureSkipVerify bool              `json:"tls_insecure_skip_verify"`
+}
+
+// ProviderFetch records one fetch of a provider
+type ProviderFetch struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	ProviderID      uint      `gorm:"index;not null" json:"provider_id"`
+	FetchedAt       time.Time `gorm:"index" json:"fetched_at"`
+	DurationMs      int64     `json:"duration_ms"`
+	StatusCode      int       `json:"status_code,omitempty"` // HTTP status, 0 for other sources or without response
+	Size            int       `json:"size"`                  // Response body bytes
+	Success         bool      `json:"success"`
+	Error           string    `json:"error,omitempty"`
+	Changed         bool      `gorm:"index" json:"changed"`                    // Resources differ from the previous good fetch
+	Diff            string    `gorm:"type:text;serializer:encrypted" json:"-"` // JSON diff against the previous good fetch
+	RouterCount     int       `json:"router_count"`
+	ServiceCount    int       `json:"service_count"`
+	MiddlewareCount int       `json:"middleware_count"`
+}
+
+// ConflictPolicy decides which source wins when several define a resource
+// with the same name
+type ConflictPolicy struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ResourceType string    `gorm:"size:64;uniqueIndex:idx_conflict_policy_resource;not null" json:"resource_type"` // router, service, middleware or serversTransport
+	ResourceName string    `gorm:"size:255;uniqueIndex:idx_conflict_policy_resource;not null" json:"resource_name"`
+	Strategy     string    `gorm:"not null" json:"strategy"` // pin or merge
+	Source       string    `json:"source"`                   // Pinned source: "local" or a provider name
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Conflict policy strategies
+const (
+	ConflictStrategyPin   = "pin"   // Always take the resource from Source
+	ConflictStrategyMerge = "merge" // Union router middlewares or load balancer servers
+)
+
+// ConfigConflict records a resource defined by more than one source. Rows
+// are kept once the conflict disappears, with ResolvedAt set.
+type ConfigConflict struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ResourceType   string     `gorm:"size:64;uniqueIndex:idx_config_conflict;not null" json:"resource_type"`
+	ResourceName   string     `gorm:"size:255;uniqueIndex:idx_config_conflict;not null" json:"resource_name"`
+	Source         string     `gorm:"size:255;uniqueIndex:idx_config_conflict;not null" json:"source"` // Source whose definition was dropped
+	OverriddenBy   string     `json:"overridden_by"`
+	SourcePriority int        `json:"source_priority"`
+	Resolution     string     `json:"resolution"` // priority or pin
+	FirstSeen      time.Time  `json:"first_seen"`
+	LastSeen       time.Time  `json:"last_seen"`
+	ResolvedAt     *time.Time `gorm:"index" json:"resolved_at"`
+}
+
+type ConflictPolicyRequest struct {
+	ResourceType string `json:"resource_type" binding:"required,oneof=router service middleware serversTransport"`
+	ResourceName string `json:"resource_name" binding:"required"`
+	Strategy     string `json:"strategy" binding:"required,oneof=pin merge"`
+	Source       string `json:"source"`
 }
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
 	"time"
 )
 
@@ -13,7 +16,7 @@ const (
 
 type User struct {
 	ID       uint     `gorm:"primaryKey" json:"id"`
-	Email    string   `gorm:"uniqueIndex;not null" json:"email"`
+	Email    string   `gorm:"size:255;uniqueIndex;not null" json:"email"`
 	Password string   `json:"-"` // Never expose password in JSON
 	Role     UserRole `gorm:"default:user" json:"role"`
 	IsActive bool     `gorm:"default:true" json:"is_active"`
@@ -23,16 +26,26 @@ type User struct {
 
 	// OIDC fields
 	OIDCProvider string     `json:"oidc_provider,omitempty"`
-	OIDCSubject  string     `gorm:"index" json:"oidc_subject,omitempty"`
+	OIDCSubject  string     `gorm:"size:255;index" json:"oidc_subject,omitempty"`
 	OIDCLinkedAt *time.Time `json:"oidc_linked_at,omitempty"`
 	OIDCEnabled  bool       `gorm:"default:false" json:"oidc_enabled"`
 
+	// Brute-force protection
+	FailedLoginAttempts int        `gorm:"default:0" json:"failed_login_attempts"`
+	LastFailedLoginAt   *time.Time `json:"last_failed_login_at,omitempty"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty"`
+
 	// Timestamps
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
 }
 
+// NormalizeEmail returns the form emails are stored and looked up in
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 // CanLoginWithPassword checks if user can login with password
 func (u *User) CanLoginWithPassword() bool {
 	return u.PasswordEnabled && u.Password != ""
@@ -48,10 +61,43 @@ func (u *User) IsLinkedToOIDC() bool {
 	return u.OIDCProvider != "" && u.OIDCSubject != ""
 }
 
+// IsLocked checks if the account is temporarily locked after failed logins
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
+
+// LoginAttempt records an authentication attempt for auditing
+type LoginAttempt struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index" json:"user_id,omitempty"` // 0 if the email is unknown
+	Email     string    `gorm:"size:255;index" json:"email"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason,omitempty"` // invalid_password, account_locked, unknown_user, ...
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// UserToken tracks signed single-use invitation and password reset tokens
+type UserToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Purpose   string     `gorm:"size:64;not null;index" json:"purpose"`  // invite, password_reset
+	TokenID   string     `gorm:"size:255;uniqueIndex;not null" json:"-"` // JWT ID (jti) of the signed token
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IsUsable checks if the token has neither been used nor expired
+func (t *UserToken) IsUsable() bool {
+	return t.UsedAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
 type Session struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	UserID    uint      `gorm:"not null;index" json:"user_id"`
-	Token     string    `gorm:"uniqueIndex;not null" json:"token"`
+	TokenHash string    `gorm:"column:token;size:512;uniqueIndex;not null" json:"-"` // SHA-256 of the refresh token, see HashToken
 	ExpiresAt time.Time `json:"expires_at"`
 	CreatedAt time.Time `json:"created_at"`
 
@@ -64,11 +110,19 @@ func (s *Session) IsExpired() bool {
 	return time.Now().After(s.ExpiresAt)
 }
 
+// HashToken returns the form refresh tokens are stored and looked up in, so
+// a leaked database doesn't hand out sessions
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 type CreateUserRequest struct {
 	Email       string   `json:"email" binding:"required,email"`
 	Password    string   `json:"password,omitempty"`
 	Role        UserRole `json:"role" binding:"required,oneof=admin user"`
 	OIDCEnabled bool     `json:"oidc_enabled"`
+	SendInvite  bool     `json:"send_invite"` // Email an invitation link instead of setting a password
 }
 
 type UpdateUserRequest struct {
@@ -83,6 +137,24 @@ type ChangePasswordRequest struct {
 	NewPassword     string `json:"new_password" binding:"required,min=12"`
 }
 
+type AcceptInviteRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type InviteOIDCRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordWithTokenRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
@@ -120,6 +192,13 @@ type UserResponse struct {
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 	LastLoginAt     *time.Time `json:"last_login_at,omitempty"`
+
+	// Brute-force protection
+	FailedLoginAttempts int            `json:"failed_login_attempts"`
+	LastFailedLoginAt   *time.Time     `json:"last_failed_login_at,omitempty"`
+	LockedUntil         *time.Time     `json:"locked_until,omitempty"`
+	IsLocked            bool           `json:"is_locked"`
+	FailedLoginHistory  []LoginAttempt `json:"failed_login_history,omitempty"`
 }
 
 // ToResponse converts User to UserResponse
@@ -136,5 +215,10 @@ func (u *User) ToResponse() UserResponse {
 		CreatedAt:       u.CreatedAt,
 		UpdatedAt:       u.UpdatedAt,
 		LastLoginAt:     u.LastLoginAt,
+
+		FailedLoginAttempts: u.FailedLoginAttempts,
+		LastFailedLoginAt:   u.LastFailedLoginAt,
+		LockedUntil:         u.LockedUntil,
+		IsLocked:            u.IsLocked(),
 	}
 }
@@ -0,0 +1,208 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/traefikx/backend/internal/mailer"
+	"github.com/traefikx/backend/internal/models"
+)
+
+// Headers of webhook requests. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the channel's secret, prefixed with
+// "sha256=", so receivers can reject forged and replayed requests.
+const (
+	HeaderEvent     = "X-TraefikX-Event"
+	HeaderDelivery  = "X-TraefikX-Delivery"
+	HeaderTimestamp = "X-TraefikX-Timestamp"
+	HeaderSignature = "X-TraefikX-Signature"
+)
+
+// ValidateChannel checks that a channel has what its type needs to send
+func ValidateChannel(channel *models.NotificationChannel) error {
+	switch channel.Type {
+	case models.ChannelWebhook, models.ChannelSlack, models.ChannelNtfy, models.ChannelGotify:
+		if u, err := url.Parse(channel.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an http or https URL")
+		}
+		if channel.Type == models.ChannelGotify && channel.Secret == "" {
+			return fmt.Errorf("secret must be set to a Gotify application token")
+		}
+	case models.ChannelEmail:
+		recipients := channel.GetRecipients()
+		if len(recipients) == 0 {
+			return fmt.Errorf("recipients are required for email channels")
+		}
+		for _, recipient := range recipients {
+			if _, err := mail.ParseAddress(recipient); err != nil {
+				return fmt.Errorf("invalid recipient %q", recipient)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported channel type %q", channel.Type)
+	}
+	for _, event := range channel.GetEvents() {
+		if !ValidEvent(event) {
+			return fmt.Errorf("unknown event %q", event)
+		}
+	}
+	return nil
+}
+
+// attempt is the outcome of one delivery attempt
+type attempt struct {
+	statusCode int // HTTP status, 0 without response
+	err        error
+}
+
+// send delivers n to channel once
+func (d *Dispatcher) send(ctx context.Context, channel *models.NotificationChannel, deliveryID uint, n *Notification) attempt {
+	ctx, cancel := context.WithTimeout(ctx, d.opts.Timeout)
+	defer cancel()
+
+	switch channel.Type {
+	case models.ChannelWebhook:
+		body, err := json.Marshal(n)
+		if err != nil {
+			return attempt{err: err}
+		}
+		headers := map[string]string{
+			"Content-Type":  "application/json",
+			HeaderEvent:     n.Event,
+			HeaderDelivery:  strconv.FormatUint(uint64(deliveryID), 10),
+			HeaderTimestamp: strconv.FormatInt(time.Now().Unix(), 10),
+		}
+		if channel.Secret != "" {
+			headers[HeaderSignature] = Sign(channel.Secret, headers[HeaderTimestamp], body)
+		}
+		return d.post(ctx, channel.URL, headers, body)
+
+	case models.ChannelSlack:
+		body, err := json.Marshal(map[string]string{"text": fmt.Sprintf("*%s*\n%s", n.Title, n.Message)})
+		if err != nil {
+			return attempt{err: err}
+		}
+		return d.post(ctx, channel.URL, map[string]string{"Content-Type": "application/json"}, body)
+
+	case models.ChannelNtfy:
+		headers := map[string]string{
+			"Title":    n.Title,
+			"Priority": ntfyPriority(n.Severity),
+			"Tags":     ntfyTag(n.Severity),
+		}
+		if channel.Secret != "" {
+			headers["Authorization"] = "Bearer " + channel.Secret
+		}
+		return d.post(ctx, channel.URL, headers, []byte(n.Message))
+
+	case models.ChannelGotify:
+		body, err := json.Marshal(map[string]interface{}{
+			"title":    n.Title,
+			"message":  n.Message,
+			"priority": gotifyPriority(n.Severity),
+		})
+		if err != nil {
+			return attempt{err: err}
+		}
+		endpoint := strings.TrimRight(channel.URL, "/")
+		if !strings.HasSuffix(endpoint, "/message") {
+			endpoint += "/message"
+		}
+		return d.post(ctx, endpoint, map[string]string{
+			"Content-Type": "application/json",
+			"X-Gotify-Key": channel.Secret,
+		}, body)
+
+	case models.ChannelEmail:
+		if d.mailer == nil {
+			return attempt{err: errors.New("no mailer configured")}
+		}
+		return attempt{err: d.mailer.Send(mailer.Message{
+			To:      channel.GetRecipients(),
+			Subject: "[TraefikX] " + n.Title,
+			Body:    fmt.Sprintf("%s\n\nEvent: %s\nTime: %s\n", n.Message, n.Event, n.Time.Format(time.RFC1123)),
+		})}
+	}
+	return attempt{err: fmt.Errorf("unsupported channel type %q", channel.Type)}
+}
+
+// post sends body to endpoint, any 2xx status is a success
+func (d *Dispatcher) post(ctx context.Context, endpoint string, headers map[string]string, body []byte) attempt {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return attempt{err: errors.New("invalid request")}
+	}
+	req.Header.Set("User-Agent", "TraefikX")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		// The URL may embed a token, as Slack's do, and is left out
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return attempt{err: err}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return attempt{statusCode: resp.StatusCode, err: fmt.Errorf("unexpected status %s", resp.Status)}
+	}
+	return attempt{statusCode: resp.StatusCode}
+}
+
+// Sign returns the signature of a webhook request sent at timestamp
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func ntfyPriority(severity string) string {
+	switch severity {
+	case SeverityCritical:
+		return "high"
+	case SeverityWarning:
+		return "default"
+	}
+	return "low"
+}
+
+func ntfyTag(severity string) string {
+	switch severity {
+	case SeverityCritical:
+		return "rotating_light"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "information_source"
+}
+
+func gotifyPriority(severity string) int {
+	switch severity {
+	case SeverityCritical:
+		return 8
+	case SeverityWarning:
+		return 5
+	}
+	return 2
+}
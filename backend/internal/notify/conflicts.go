@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"fmt"
+
+	"github.com/traefikx/backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConflictWebhookChannel names the channel CONFLICT_WEBHOOK_URL is kept as
+const ConflictWebhookChannel = "conflict-webhook"
+
+// EnsureConflictWebhook keeps the URL of CONFLICT_WEBHOOK_URL, which
+// predates notification channels, as a webhook channel subscribed to
+// conflict.detected. Its notifications are then sent once, by the leader,
+// and retried and logged like any channel's. Admins can sign them by
+// setting a secret on the channel; startup only updates its URL.
+func EnsureConflictWebhook(db *gorm.DB, url string) error {
+	channel := models.NotificationChannel{
+		Name:     ConflictWebhookChannel,
+		Type:     models.ChannelWebhook,
+		URL:      url,
+		IsActive: true,
+	}
+	channel.SetEvents([]string{EventConflictDetected})
+	if err := ValidateChannel(&channel); err != nil {
+		return fmt.Errorf("CONFLICT_WEBHOOK_URL: %w", err)
+	}
+
+	// Replicas starting together create it once
+	result := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&channel)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	var existing models.NotificationChannel
+	if err := db.Where("name = ?", ConflictWebhookChannel).First(&existing).Error; err != nil {
+		return err
+	}
+	if existing.URL == url {
+		return nil
+	}
+	existing.URL = url
+	return db.Model(&existing).Select("URL", "UpdatedAt").Updates(&existing).Error
+}
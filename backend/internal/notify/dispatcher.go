@@ -0,0 +1,375 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/mailer"
+	"github.com/traefikx/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	deliveryTick     = time.Second // How often due deliveries are looked for
+	deliveryBatch    = 32          // Deliveries started per tick at most
+	maxRetryDelay    = time.Hour   // Cap of the doubling retry delay
+	deliveryPruneGap = time.Hour   // Time between two prunes of the delivery log
+)
+
+// Options tunes deliveries
+type Options struct {
+	Timeout      time.Duration // Per attempt
+	MaxAttempts  int
+	RetryBackoff time.Duration // Delay after the first failed attempt, doubled for each further one
+	MaxAge       time.Duration // Age after which finished deliveries are deleted, 0 = never
+}
+
+// Dispatcher notifies channels of the events they subscribe to. Every
+// replica queues a delivery per subscribed channel for the events it
+// publishes itself, in the database; the deliveries are sent, and retried
+// with backoff, by the replica running the delivery loop, the leader in HA
+// mode. Each attempt is recorded in the delivery log.
+type Dispatcher struct {
+	db     *gorm.DB
+	bus    *events.Bus
+	mailer mailer.Mailer
+	client *http.Client
+	opts   Options
+
+	wake        chan struct{} // Signals new deliveries to the loop
+	unsubscribe func()
+	listening   chan struct{} // Closed once the listener has returned
+
+	mu      sync.Mutex
+	running bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+	sending map[uint]bool // Deliveries being attempted
+	pruned  time.Time
+}
+
+// NewDispatcher creates a dispatcher sending email notifications with m,
+// which may be nil
+func NewDispatcher(db *gorm.DB, bus *events.Bus, m mailer.Mailer, opts Options) *Dispatcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 30 * time.Second
+	}
+	return &Dispatcher{
+		db:     db,
+		bus:    bus,
+		mailer: m,
+		client: &http.Client{
+			// Notifications are sent where configured, not where redirected
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		opts: opts,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Listen queues deliveries for the events published on this replica until
+// Close. Events relayed from other replicas were queued by their origin.
+func (d *Dispatcher) Listen() {
+	published, unsubscribe := d.bus.Subscribe(64,
+		events.ConfigChanged,
+		events.ProviderFailed,
+		events.ProviderRecovered,
+		events.ConflictDetected,
+		events.BackendHealth,
+		events.UserCreated,
+	)
+	d.unsubscribe = unsubscribe
+	d.listening = make(chan struct{})
+
+	go func() {
+		defer close(d.listening)
+		for event := range published {
+			if event.Origin != "" {
+				continue
+			}
+			if n, ok := fromEvent(event); ok {
+				d.enqueue(n)
+			}
+		}
+	}()
+}
+
+// Close stops listening and sending
+func (d *Dispatcher) Close() {
+	if d.unsubscribe != nil {
+		d.unsubscribe()
+		<-d.listening
+	}
+	d.Stop()
+}
+
+// enqueue stores a pending delivery of n for every active channel subscribed
+// to its event
+func (d *Dispatcher) enqueue(n *Notification) {
+	var channels []models.NotificationChannel
+	if err := d.db.Where("is_active = ?", true).Find(&channels).Error; err != nil {
+		slog.Error("Failed to load notification channels", "event", n.Event, "error", err)
+		return
+	}
+
+	payload, err := json.Marshal(n)
+	if err != nil {
+		slog.Error("Failed to encode notification", "event", n.Event, "error", err)
+		return
+	}
+
+	now := time.Now()
+	queued := 0
+	for i := range channels {
+		if !channels[i].Subscribed(n.Event) {
+			continue
+		}
+		delivery := models.NotificationDelivery{
+			ChannelID:     channels[i].ID,
+			Event:         n.Event,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+		}
+		if err := d.db.Create(&delivery).Error; err != nil {
+			slog.Error("Failed to queue notification", "channel", channels[i].Name, "event", n.Event, "error", err)
+			continue
+		}
+		queued++
+	}
+	if queued > 0 {
+		d.signal()
+	}
+}
+
+// signal wakes the delivery loop, pending signals are coalesced
+func (d *Dispatcher) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start begins sending queued deliveries. It can be called again after
+// Stop, in HA mode the leader sends. A nil dispatcher does nothing.
+func (d *Dispatcher) Start() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.running {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d.running = true
+	d.cancel = cancel
+	d.done = make(chan struct{})
+	d.sending = make(map[uint]bool)
+	go d.run(ctx, d.done)
+}
+
+// Stop ends sending, waiting for the attempts in progress. Deliveries left
+// pending are sent once started again, by this replica or the next leader.
+func (d *Dispatcher) Stop() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	if !d.running {
+		d.mu.Unlock()
+		return
+	}
+	d.running = false
+	d.cancel()
+	done := d.done
+	d.mu.Unlock()
+	<-done
+}
+
+func (d *Dispatcher) run(ctx context.Context, done chan struct{}) {
+	var wg sync.WaitGroup
+	defer close(done)
+	defer wg.Wait()
+
+	ticker := time.NewTicker(deliveryTick)
+	defer ticker.Stop()
+	for {
+		d.deliverDue(ctx, &wg)
+		d.prune()
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deliverDue starts the attempts of the pending deliveries that are due
+func (d *Dispatcher) deliverDue(ctx context.Context, wg *sync.WaitGroup) {
+	var due []models.NotificationDelivery
+	if err := d.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, time.Now()).
+		Order("next_attempt_at").Limit(deliveryBatch).
+		Find(&due).Error; err != nil {
+		if ctx.Err() == nil {
+			slog.Error("Failed to load pending notifications", "error", err)
+		}
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range due {
+		delivery := &due[i]
+		if d.sending[delivery.ID] {
+			continue
+		}
+		d.sending[delivery.ID] = true
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(ctx, delivery)
+			d.mu.Lock()
+			delete(d.sending, delivery.ID)
+			d.mu.Unlock()
+		}()
+	}
+}
+
+// deliver makes one attempt at a pending delivery
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.NotificationDelivery) {
+	var channel models.NotificationChannel
+	if err := d.db.First(&channel, delivery.ChannelID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			d.finish(delivery, attempt{err: errors.New("channel deleted")}, true)
+		}
+		return
+	}
+	if !channel.IsActive {
+		d.finish(delivery, attempt{err: errors.New("channel disabled")}, true)
+		return
+	}
+
+	var n Notification
+	if err := json.Unmarshal([]byte(delivery.Payload), &n); err != nil {
+		d.finish(delivery, attempt{err: errors.New("invalid payload")}, true)
+		return
+	}
+
+	result := d.send(ctx, &channel, delivery.ID, &n)
+	if ctx.Err() != nil {
+		return // Stopped, retried once started again
+	}
+	d.finish(delivery, result, false)
+	if result.err != nil && delivery.Status == models.DeliveryFailed {
+		slog.Warn("Notification not delivered", "channel", channel.Name, "event", delivery.Event,
+			"attempts", delivery.Attempts, "error", result.err)
+	} else if result.err != nil {
+		slog.Debug("Notification attempt failed", "channel", channel.Name, "event", delivery.Event,
+			"attempts", delivery.Attempts, "retry_at", delivery.NextAttemptAt, "error", result.err)
+	}
+}
+
+// finish records an attempt, scheduling the next one unless it succeeded,
+// was the last or final is set
+func (d *Dispatcher) finish(delivery *models.NotificationDelivery, result attempt, final bool) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.StatusCode = result.statusCode
+	delivery.NextAttemptAt = nil
+
+	switch {
+	case result.err == nil:
+		delivery.Status = models.DeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	case final || delivery.Attempts >= d.opts.MaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = result.err.Error()
+	default:
+		delivery.Status = models.DeliveryPending
+		delivery.LastError = result.err.Error()
+		next := now.Add(d.retryDelay(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+
+	if err := d.db.Select("status", "attempts", "status_code", "last_error", "next_attempt_at", "delivered_at", "updated_at").
+		Save(delivery).Error; err != nil {
+		slog.Error("Failed to record notification delivery", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// retryDelay is the backoff after attempts failed attempts
+func (d *Dispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.opts.RetryBackoff
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// prune deletes finished deliveries older than the maximum age, at most
+// once per deliveryPruneGap
+func (d *Dispatcher) prune() {
+	if d.opts.MaxAge <= 0 || time.Since(d.pruned) < deliveryPruneGap {
+		return
+	}
+	d.pruned = time.Now()
+	if err := d.db.Where("status <> ? AND created_at < ?", models.DeliveryPending, time.Now().Add(-d.opts.MaxAge)).
+		Delete(&models.NotificationDelivery{}).Error; err != nil {
+		slog.Error("Failed to prune notification deliveries", "error", err)
+	}
+}
+
+// Test sends a test notification to channel right away, without retries,
+// and returns its recorded delivery
+func (d *Dispatcher) Test(ctx context.Context, channel *models.NotificationChannel) (*models.NotificationDelivery, error) {
+	n := testNotification()
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+	delivery := &models.NotificationDelivery{
+		ChannelID: channel.ID,
+		Event:     n.Event,
+		Payload:   string(payload),
+		Status:    models.DeliveryPending,
+	}
+	if err := d.db.WithContext(ctx).Create(delivery).Error; err != nil {
+		return nil, err
+	}
+
+	d.finish(delivery, d.send(ctx, channel, delivery.ID, n), true)
+	return delivery, nil
+}
+
+// Retry queues a finished delivery again, with a fresh set of attempts
+func (d *Dispatcher) Retry(ctx context.Context, delivery *models.NotificationDelivery) error {
+	now := time.Now()
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+	if err := d.db.WithContext(ctx).Select("status", "attempts", "next_attempt_at", "updated_at").
+		Save(delivery).Error; err != nil {
+		return err
+	}
+	d.signal()
+	return nil
+}
@@ -0,0 +1,265 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/traefikx/backend/internal/database"
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/models"
+	"github.com/traefikx/backend/internal/secrets"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB returns a migrated SQLite database in a temporary directory
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	if err := secrets.Init("notify-test-key"); err != nil {
+		t.Fatalf("secrets: %v", err)
+	}
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// sinkRequest is a request received by a webhookSink
+type sinkRequest struct {
+	header http.Header
+	body   []byte
+	at     time.Time
+}
+
+// webhookSink answers webhooks with the next of its statuses, 200 once
+// they run out, and records the requests
+type webhookSink struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []sinkRequest
+}
+
+func startWebhookSink(t *testing.T, statuses ...int) *webhookSink {
+	t.Helper()
+	sink := &webhookSink{statuses: statuses}
+	sink.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		sink.requests = append(sink.requests, sinkRequest{header: r.Header.Clone(), body: body, at: time.Now()})
+		status := http.StatusOK
+		if len(sink.statuses) > 0 {
+			status, sink.statuses = sink.statuses[0], sink.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(sink.Close)
+	return sink
+}
+
+// waitForRequests returns the requests once n were received
+func (s *webhookSink) waitForRequests(t *testing.T, n int) []sinkRequest {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		s.mu.Lock()
+		requests := append([]sinkRequest(nil), s.requests...)
+		s.mu.Unlock()
+		if len(requests) >= n {
+			return requests
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d requests, got %d", n, len(requests))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// createWebhook stores an active webhook channel for all events
+func createWebhook(t *testing.T, db *gorm.DB, url, secret string) *models.NotificationChannel {
+	t.Helper()
+	channel := &models.NotificationChannel{Name: "sink", Type: models.ChannelWebhook, URL: url, Secret: secret, IsActive: true}
+	if err := db.Create(channel).Error; err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	return channel
+}
+
+// waitForDelivery returns the only delivery once it is no longer pending
+func waitForDelivery(t *testing.T, db *gorm.DB) models.NotificationDelivery {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var deliveries []models.NotificationDelivery
+		db.Find(&deliveries)
+		if len(deliveries) == 1 && deliveries[0].Status != models.DeliveryPending {
+			return deliveries[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the delivery, got %+v", deliveries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// startDispatcher listens and sends until the test ends
+func startDispatcher(t *testing.T, db *gorm.DB, bus *events.Bus, opts Options) *Dispatcher {
+	t.Helper()
+	d := NewDispatcher(db, bus, nil, opts)
+	d.Listen()
+	d.Start()
+	t.Cleanup(d.Close)
+	return d
+}
+
+func TestDispatcher_SignsWebhooks(t *testing.T) {
+	db := newTestDB(t)
+	sink := startWebhookSink(t)
+	createWebhook(t, db, sink.URL, "webhook-secret")
+	bus := events.NewBus()
+	startDispatcher(t, db, bus, Options{})
+
+	bus.Publish(events.ConfigChanged, events.ResourceChange{Kind: "router", Name: "app", Action: events.ActionDeleted})
+	request := sink.waitForRequests(t, 1)[0]
+
+	timestamp := request.header.Get(HeaderTimestamp)
+	if sent, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+		t.Errorf("unexpected timestamp %q", timestamp)
+	}
+	mac := hmac.New(sha256.New, []byte("webhook-secret"))
+	mac.Write([]byte(timestamp + "." + string(request.body)))
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); request.header.Get(HeaderSignature) != want {
+		t.Errorf("signature %q, want %q", request.header.Get(HeaderSignature), want)
+	}
+	if request.header.Get(HeaderEvent) != EventConfigChanged || request.header.Get(HeaderDelivery) == "" {
+		t.Errorf("unexpected headers %v", request.header)
+	}
+
+	var n Notification
+	if err := json.Unmarshal(request.body, &n); err != nil {
+		t.Fatalf("decode notification: %v", err)
+	}
+	if n.Event != EventConfigChanged || n.Title != `Router "app" deleted` {
+		t.Errorf("unexpected notification %+v", n)
+	}
+
+	delivery := waitForDelivery(t, db)
+	if delivery.Status != models.DeliveryDelivered || delivery.Attempts != 1 || request.header.Get(HeaderDelivery) != strconv.FormatUint(uint64(delivery.ID), 10) {
+		t.Errorf("unexpected delivery %+v", delivery)
+	}
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	db := newTestDB(t)
+	sink := startWebhookSink(t, http.StatusServiceUnavailable, http.StatusInternalServerError)
+	createWebhook(t, db, sink.URL, "")
+	bus := events.NewBus()
+	backoff := 200 * time.Millisecond
+	startDispatcher(t, db, bus, Options{MaxAttempts: 5, RetryBackoff: backoff})
+
+	bus.Publish(events.UserCreated, events.UserChange{Email: "new@example.com"})
+	requests := sink.waitForRequests(t, 3)
+
+	// Retried after the backoff, then twice as long
+	if gap := requests[1].at.Sub(requests[0].at); gap < backoff {
+		t.Errorf("second attempt after %v, expected at least %v", gap, backoff)
+	}
+	if gap := requests[2].at.Sub(requests[1].at); gap < 2*backoff {
+		t.Errorf("third attempt after %v, expected at least %v", gap, 2*backoff)
+	}
+	if requests[0].header.Get(HeaderSignature) != "" {
+		t.Error("expected requests of a channel without secret to be unsigned")
+	}
+
+	delivery := waitForDelivery(t, db)
+	if delivery.Status != models.DeliveryDelivered || delivery.Attempts != 3 || delivery.LastError != "" {
+		t.Errorf("unexpected delivery %+v", delivery)
+	}
+}
+
+func TestDispatcher_GivesUp(t *testing.T) {
+	db := newTestDB(t)
+	sink := startWebhookSink(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	createWebhook(t, db, sink.URL, "")
+	bus := events.NewBus()
+	startDispatcher(t, db, bus, Options{MaxAttempts: 2, RetryBackoff: 10 * time.Millisecond})
+
+	bus.Publish(events.UserCreated, events.UserChange{Email: "new@example.com"})
+
+	delivery := waitForDelivery(t, db)
+	if delivery.Status != models.DeliveryFailed || delivery.Attempts != 2 || delivery.StatusCode != http.StatusBadGateway || delivery.LastError == "" {
+		t.Errorf("unexpected delivery %+v", delivery)
+	}
+	if requests := sink.waitForRequests(t, 2); len(requests) != 2 {
+		t.Errorf("expected 2 attempts, got %d", len(requests))
+	}
+}
+
+func TestDispatcher_RetryDelay(t *testing.T) {
+	d := NewDispatcher(nil, nil, nil, Options{RetryBackoff: 30 * time.Second})
+	for attempts, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		20: maxRetryDelay,
+	} {
+		if got := d.retryDelay(attempts); got != want {
+			t.Errorf("retryDelay(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestEnsureConflictWebhook(t *testing.T) {
+	db := newTestDB(t)
+	if err := EnsureConflictWebhook(db, "https://hooks.example.com/conflicts"); err != nil {
+		t.Fatalf("EnsureConflictWebhook: %v", err)
+	}
+
+	var channel models.NotificationChannel
+	if err := db.Where("name = ?", ConflictWebhookChannel).First(&channel).Error; err != nil {
+		t.Fatalf("load channel: %v", err)
+	}
+	if channel.Type != models.ChannelWebhook || !channel.IsActive || !channel.Subscribed(EventConflictDetected) || channel.Subscribed(EventConfigChanged) {
+		t.Errorf("unexpected channel %+v", channel)
+	}
+
+	// Restarts update the URL, settings made by admins are kept
+	db.Model(&channel).Select("Secret").Updates(&models.NotificationChannel{Secret: "signing-key"})
+	if err := EnsureConflictWebhook(db, "https://hooks.example.com/v2"); err != nil {
+		t.Fatalf("EnsureConflictWebhook: %v", err)
+	}
+	var channels []models.NotificationChannel
+	db.Find(&channels)
+	if len(channels) != 1 || channels[0].URL != "https://hooks.example.com/v2" || channels[0].Secret != "signing-key" {
+		t.Errorf("unexpected channels %+v", channels)
+	}
+
+	if err := EnsureConflictWebhook(db, "ftp://hooks.example.com"); err == nil {
+		t.Error("expected an invalid URL to be rejected")
+	}
+}
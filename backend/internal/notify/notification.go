@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/services"
+)
+
+// Notification events channels subscribe to
+const (
+	EventConfigChanged     = "config.changed"
+	EventProviderFailed    = "provider.failed"
+	EventProviderRecovered = "provider.recovered"
+	EventConflictDetected  = "conflict.detected"
+	EventBackendDown       = "backend.down"
+	EventBackendUp         = "backend.up"
+	EventUserCreated       = "user.created"
+	EventTest              = "test" // Sent on request to every channel tested, never subscribed to
+)
+
+// Events lists the events channels can subscribe to
+var Events = []string{
+	EventConfigChanged,
+	EventProviderFailed,
+	EventProviderRecovered,
+	EventConflictDetected,
+	EventBackendDown,
+	EventBackendUp,
+	EventUserCreated,
+}
+
+// ValidEvent reports whether channels can subscribe to event
+func ValidEvent(event string) bool {
+	for _, known := range Events {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Notification is what channels are sent, as JSON by webhooks
+type Notification struct {
+	Event    string      `json:"event"`
+	Time     time.Time   `json:"time"`
+	Severity string      `json:"severity"`
+	Title    string      `json:"title"`
+	Message  string      `json:"message"`
+	Data     interface{} `json:"data,omitempty"` // Payload of the bus event
+}
+
+// fromEvent describes a bus event, false for events nobody is notified of
+func fromEvent(event events.Event) (*Notification, bool) {
+	n := &Notification{Time: event.Time, Severity: SeverityInfo, Data: event.Data}
+
+	switch data := event.Data.(type) {
+	case events.ResourceChange:
+		if event.Type != events.ConfigChanged || data.Action == events.ActionRefreshed {
+			return nil, false
+		}
+		n.Event = EventConfigChanged
+		kind := strings.ReplaceAll(data.Kind, "_", " ")
+		if data.Action == events.ActionImported {
+			kind = "configuration"
+		}
+		if data.Name != "" {
+			n.Title = fmt.Sprintf("%s %q %s", capitalize(kind), data.Name, data.Action)
+		} else {
+			n.Title = fmt.Sprintf("%s %s", capitalize(kind), data.Action)
+		}
+		n.Message = n.Title + ", the configuration served to Traefik changed."
+
+	case events.ProviderChange:
+		switch event.Type {
+		case events.ProviderFailed:
+			n.Event = EventProviderFailed
+			n.Severity = SeverityWarning
+			n.Title = fmt.Sprintf("Provider %q is failing", data.Name)
+			n.Message = fmt.Sprintf("Fetching provider %q failed %d time(s): %s. Its last known good configuration is still served.", data.Name, data.Failures, data.Error)
+			if data.State == services.ProviderFailed {
+				n.Severity = SeverityCritical
+				n.Title = fmt.Sprintf("Provider %q failed", data.Name)
+				n.Message = fmt.Sprintf("Fetching provider %q failed %d time(s): %s. Its resources were removed from the configuration.", data.Name, data.Failures, data.Error)
+			}
+		case events.ProviderRecovered:
+			n.Event = EventProviderRecovered
+			n.Title = fmt.Sprintf("Provider %q recovered", data.Name)
+			n.Message = fmt.Sprintf("Provider %q was fetched again after %d failure(s).", data.Name, data.Failures)
+		default:
+			return nil, false
+		}
+
+	case []services.ConflictInfo:
+		if len(data) == 0 {
+			return nil, false
+		}
+		n.Event = EventConflictDetected
+		n.Severity = SeverityWarning
+		n.Title = fmt.Sprintf("%d new config conflict(s)", len(data))
+		lines := make([]string, len(data))
+		for i, conflict := range data {
+			lines[i] = fmt.Sprintf("- %s %q from %s is overridden by %s", conflict.Type, conflict.Name, conflict.Source, conflict.OverriddenBy)
+		}
+		n.Message = strings.Join(lines, "\n")
+
+	case events.BackendChange:
+		if data.Healthy {
+			n.Event = EventBackendUp
+			n.Title = fmt.Sprintf("Server of %q is up", data.ServiceName)
+			n.Message = fmt.Sprintf("Server %s of service %q answers its health check again.", data.URL, data.ServiceName)
+		} else {
+			n.Event = EventBackendDown
+			n.Severity = SeverityCritical
+			n.Title = fmt.Sprintf("Server of %q is down", data.ServiceName)
+			n.Message = fmt.Sprintf("Server %s of service %q fails its health check: %s.", data.URL, data.ServiceName, data.Error)
+		}
+
+	case events.UserChange:
+		n.Event = EventUserCreated
+		n.Title = fmt.Sprintf("User %s created", data.Email)
+		n.Message = fmt.Sprintf("A %s account was created for %s.", data.Role, data.Email)
+
+	default:
+		return nil, false
+	}
+	return n, true
+}
+
+// testNotification is sent by Dispatcher.Test
+func testNotification() *Notification {
+	return &Notification{
+		Event:    EventTest,
+		Time:     time.Now(),
+		Severity: SeverityInfo,
+		Title:    "Test notification",
+		Message:  "This channel receives TraefikX notifications.",
+	}
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
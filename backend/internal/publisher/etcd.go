@@ -0,0 +1,69 @@
+package publisher
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// etcd rejects transactions with more operations than --max-txn-ops (128 by
+// default), so larger changes are split into several transactions
+const etcdMaxTxnOps = 128
+
+// EtcdStore stores keys in etcd v3, which is what Traefik's etcd provider reads
+type EtcdStore struct {
+	client    *clientv3.Client
+	endpoints []string
+}
+
+func NewEtcdStore(endpoints []string, username, password string, dialTimeout time.Duration) (*EtcdStore, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		Username:    username,
+		Password:    password,
+		DialTimeout: dialTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &EtcdStore{client: client, endpoints: endpoints}, nil
+}
+
+func (s *EtcdStore) Target() string { return "etcd://" + strings.Join(s.endpoints, ",") }
+
+func (s *EtcdStore) List(ctx context.Context, prefix string) (map[string]string, error) {
+	resp, err := s.client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		result[string(kv.Key)] = string(kv.Value)
+	}
+	return result, nil
+}
+
+// Apply writes puts before deletes, so a partially applied change never
+// leaves a router without the keys it had
+func (s *EtcdStore) Apply(ctx context.Context, puts map[string]string, deletes []string) error {
+	ops := make([]clientv3.Op, 0, len(puts)+len(deletes))
+	for key, value := range puts {
+		ops = append(ops, clientv3.OpPut(key, value))
+	}
+	for _, key := range deletes {
+		ops = append(ops, clientv3.OpDelete(key))
+	}
+
+	for start := 0; start < len(ops); start += etcdMaxTxnOps {
+		end := min(start+etcdMaxTxnOps, len(ops))
+		if _, err := s.client.Txn(ctx).Then(ops[start:end]...).Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *EtcdStore) Close() error { return s.client.Close() }
@@ -0,0 +1,89 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/traefikx/backend/internal/declarative"
+	"github.com/traefikx/backend/internal/services"
+)
+
+// FilePublisher writes the configuration into a directory watched by
+// Traefik's file provider (providers.file.directory). Files are replaced
+// atomically so Traefik never reads a partial write.
+type FilePublisher struct {
+	dir    string
+	name   string
+	format string
+}
+
+// NewFilePublisher creates a file publisher. Traefik's file provider reads
+// YAML and TOML only.
+func NewFilePublisher(dir, name, format string) (*FilePublisher, error) {
+	format, err := declarative.NormalizeFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	if format == declarative.FormatJSON {
+		return nil, fmt.Errorf("the file provider does not read JSON, use yaml or toml")
+	}
+	if name == "" || filepath.Base(name) != name {
+		return nil, fmt.Errorf("invalid publish file name: %q", name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create publish directory: %w", err)
+	}
+	return &FilePublisher{dir: dir, name: name, format: format}, nil
+}
+
+func (p *FilePublisher) Name() string { return "file" }
+
+func (p *FilePublisher) Type() string { return "file" }
+
+func (p *FilePublisher) Target() string { return p.path() }
+
+func (p *FilePublisher) path() string {
+	ext := ".yml"
+	if p.format == declarative.FormatTOML {
+		ext = ".toml"
+	}
+	return filepath.Join(p.dir, p.name+ext)
+}
+
+// Publish writes to a temporary file in the same directory, syncs it and
+// renames it over the previous file
+func (p *FilePublisher) Publish(_ context.Context, snapshot *services.ConfigSnapshot) error {
+	data, err := declarative.Marshal(snapshot.Config, p.format)
+	if err != nil {
+		return fmt.Errorf("failed to encode configuration: %w", err)
+	}
+
+	// The file provider only loads .yml/.yaml/.toml files, so it skips this one
+	tmp, err := os.CreateTemp(p.dir, "."+p.name+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), p.path())
+}
+
+func (p *FilePublisher) Close() error { return nil }
@@ -0,0 +1,113 @@
+package publisher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"github.com/traefikx/backend/internal/services"
+)
+
+// testSnapshot returns a snapshot serving one router per name, each with
+// its own service
+func testSnapshot(t testing.TB, names ...string) *services.ConfigSnapshot {
+	t.Helper()
+	config := &dynamic.Configuration{HTTP: &dynamic.HTTPConfiguration{
+		Routers:  map[string]*dynamic.Router{},
+		Services: map[string]*dynamic.Service{},
+	}}
+	for _, name := range names {
+		config.HTTP.Routers[name] = &dynamic.Router{
+			Rule:        "Host(`" + name + ".example.com`)",
+			Service:     name,
+			EntryPoints: []string{"websecure"},
+		}
+		config.HTTP.Services[name] = &dynamic.Service{LoadBalancer: &dynamic.ServersLoadBalancer{
+			Servers: []dynamic.Server{{URL: "http://" + name + ":8080"}},
+		}}
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("encode config: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return &services.ConfigSnapshot{Config: config, JSON: data, Hash: hex.EncodeToString(sum[:])}
+}
+
+func TestFilePublisher_Publish(t *testing.T) {
+	for _, tc := range []struct {
+		format string
+		file   string
+		want   string
+	}{
+		{"yaml", "traefikx.yml", "rule: Host(`app.example.com`)"},
+		{"toml", "traefikx.toml", "rule = 'Host(`app.example.com`)'"},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			dir := t.TempDir()
+			p, err := NewFilePublisher(dir, "traefikx", tc.format)
+			if err != nil {
+				t.Fatalf("NewFilePublisher: %v", err)
+			}
+			if p.Target() != filepath.Join(dir, tc.file) {
+				t.Errorf("unexpected target %s", p.Target())
+			}
+
+			if err := p.Publish(context.Background(), testSnapshot(t, "app", "old")); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+			if err := p.Publish(context.Background(), testSnapshot(t, "app")); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, tc.file))
+			if err != nil {
+				t.Fatalf("read published file: %v", err)
+			}
+			if !strings.Contains(string(data), tc.want) {
+				t.Errorf("published file lacks %q:\n%s", tc.want, data)
+			}
+			if strings.Contains(string(data), "old") {
+				t.Errorf("published file still holds the previous config:\n%s", data)
+			}
+
+			// Temporary files are renamed or removed
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != 1 {
+				t.Errorf("expected only the published file, got %v", entries)
+			}
+		})
+	}
+}
+
+func TestFilePublisher_UnwritableDirectory(t *testing.T) {
+	dir := t.TempDir()
+	p, err := NewFilePublisher(dir, "traefikx", "yaml")
+	if err != nil {
+		t.Fatalf("NewFilePublisher: %v", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Publish(context.Background(), testSnapshot(t, "app")); err == nil {
+		t.Fatal("expected publishing into a missing directory to fail")
+	}
+}
+
+func TestNewFilePublisher_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewFilePublisher(dir, "traefikx", "json"); err == nil {
+		t.Error("expected JSON to be rejected")
+	}
+	if _, err := NewFilePublisher(dir, "../traefikx", "yaml"); err == nil {
+		t.Error("expected a file name with a path to be rejected")
+	}
+}
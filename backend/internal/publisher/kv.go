@@ -0,0 +1,120 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/traefikx/backend/internal/services"
+)
+
+// KVStore is the minimal key/value access the KV publisher needs
+type KVStore interface {
+	// List returns every key under prefix with its value
+	List(ctx context.Context, prefix string) (map[string]string, error)
+	// Apply writes puts and removes deletes, atomically where the backend allows
+	Apply(ctx context.Context, puts map[string]string, deletes []string) error
+	Target() string
+	Close() error
+}
+
+// KVPublisher writes the configuration in the layout read by Traefik's KV
+// providers (providers.redis, providers.etcd), for example
+// traefik/http/routers/<name>/rule. Only changed keys are written and keys
+// no longer in the configuration are removed.
+type KVPublisher struct {
+	name    string
+	rootKey string
+	store   KVStore
+}
+
+func NewKVPublisher(name, rootKey string, store KVStore) *KVPublisher {
+	return &KVPublisher{name: name, rootKey: strings.Trim(rootKey, "/"), store: store}
+}
+
+func (p *KVPublisher) Name() string { return p.name }
+
+func (p *KVPublisher) Type() string { return p.name }
+
+func (p *KVPublisher) Target() string { return p.store.Target() + "/" + p.rootKey }
+
+func (p *KVPublisher) Publish(ctx context.Context, snapshot *services.ConfigSnapshot) error {
+	pairs, err := FlattenKV(p.rootKey, snapshot.JSON)
+	if err != nil {
+		return fmt.Errorf("failed to flatten configuration: %w", err)
+	}
+
+	existing, err := p.store.List(ctx, p.rootKey+"/")
+	if err != nil {
+		return err
+	}
+
+	puts := make(map[string]string)
+	for key, value := range pairs {
+		if current, ok := existing[key]; !ok || current != value {
+			puts[key] = value
+		}
+	}
+
+	deletes := []string{}
+	for key := range existing {
+		if _, ok := pairs[key]; !ok {
+			deletes = append(deletes, key)
+		}
+	}
+	sort.Strings(deletes)
+
+	if len(puts) == 0 && len(deletes) == 0 {
+		return nil
+	}
+	return p.store.Apply(ctx, puts, deletes)
+}
+
+func (p *KVPublisher) Close() error { return p.store.Close() }
+
+// FlattenKV converts a JSON encoded dynamic configuration to KV pairs.
+// Objects and arrays become path segments (array items by index), empty
+// objects such as "tls": {} become "true" as in Traefik's label syntax.
+func FlattenKV(rootKey string, data []byte) (map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var root interface{}
+	if err := decoder.Decode(&root); err != nil {
+		return nil, err
+	}
+
+	pairs := make(map[string]string)
+	flatten(pairs, rootKey, root)
+	return pairs, nil
+}
+
+func flatten(pairs map[string]string, key string, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+	case map[string]interface{}:
+		if len(v) == 0 {
+			pairs[key] = "true"
+			return
+		}
+		for k, child := range v {
+			flatten(pairs, key+"/"+k, child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flatten(pairs, key+"/"+strconv.Itoa(i), child)
+		}
+	case string:
+		pairs[key] = v
+	case json.Number:
+		pairs[key] = v.String()
+	case bool:
+		pairs[key] = strconv.FormatBool(v)
+	default:
+		pairs[key] = fmt.Sprint(v)
+	}
+}
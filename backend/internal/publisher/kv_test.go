@@ -0,0 +1,209 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"google.golang.org/grpc"
+)
+
+func TestFlattenKV(t *testing.T) {
+	pairs, err := FlattenKV("traefik", []byte(`{"http":{"routers":{"app":{"rule":"Host(`+"`a`"+`)","entryPoints":["web","websecure"],"priority":10,"tls":{}}}}}`))
+	if err != nil {
+		t.Fatalf("FlattenKV: %v", err)
+	}
+
+	want := map[string]string{
+		"traefik/http/routers/app/rule":          "Host(`a`)",
+		"traefik/http/routers/app/entryPoints/0": "web",
+		"traefik/http/routers/app/entryPoints/1": "websecure",
+		"traefik/http/routers/app/priority":      "10",
+		"traefik/http/routers/app/tls":           "true",
+	}
+	if len(pairs) != len(want) {
+		t.Fatalf("expected %d pairs, got %v", len(want), pairs)
+	}
+	for key, value := range want {
+		if pairs[key] != value {
+			t.Errorf("%s = %q, want %q", key, pairs[key], value)
+		}
+	}
+}
+
+func TestKVPublisher_Redis(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.Set("other/key", "kept") // Outside the root key
+	p := NewKVPublisher("redis", "/traefik/", NewRedisStore(server.Addr(), "", "", 0))
+	defer p.Close()
+	ctx := context.Background()
+
+	if err := p.Publish(ctx, testSnapshot(t, "app", "old")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if value, _ := server.Get("traefik/http/routers/old/service"); value != "old" {
+		t.Fatalf("expected the old router to be published, got %q", value)
+	}
+
+	if err := p.Publish(ctx, testSnapshot(t, "app")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if value, _ := server.Get("traefik/http/routers/app/rule"); value != "Host(`app.example.com`)" {
+		t.Errorf("unexpected rule %q", value)
+	}
+	for _, key := range server.Keys() {
+		if strings.Contains(key, "/old") {
+			t.Errorf("expected %s to be deleted", key)
+		}
+	}
+	if value, _ := server.Get("other/key"); value != "kept" {
+		t.Error("expected keys outside the root key to be left alone")
+	}
+}
+
+func TestKVPublisher_RedisError(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.SetError("LOADING Redis is loading the dataset in memory")
+	p := NewKVPublisher("redis", "traefik", NewRedisStore(server.Addr(), "", "", 0))
+	defer p.Close()
+
+	err := p.Publish(context.Background(), testSnapshot(t, "app"))
+	if err == nil || !strings.Contains(err.Error(), "LOADING") {
+		t.Fatalf("expected the Redis error, got %v", err)
+	}
+}
+
+// fakeEtcd is an etcd KV API keeping keys in memory, recording the number
+// of operations of each transaction
+type fakeEtcd struct {
+	etcdserverpb.UnimplementedKVServer
+
+	mu   sync.Mutex
+	kvs  map[string]string
+	txns []int
+}
+
+func startFakeEtcd(t *testing.T) (*fakeEtcd, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	fake := &fakeEtcd{kvs: map[string]string{}}
+	server := grpc.NewServer()
+	etcdserverpb.RegisterKVServer(server, fake)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return fake, listener.Addr().String()
+}
+
+func (f *fakeEtcd) Range(_ context.Context, req *etcdserverpb.RangeRequest) (*etcdserverpb.RangeResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &etcdserverpb.RangeResponse{}
+	for key, value := range f.kvs {
+		if inRange(key, req.Key, req.RangeEnd) {
+			resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value)})
+		}
+	}
+	sort.Slice(resp.Kvs, func(i, j int) bool { return bytes.Compare(resp.Kvs[i].Key, resp.Kvs[j].Key) < 0 })
+	resp.Count = int64(len(resp.Kvs))
+	return resp, nil
+}
+
+func (f *fakeEtcd) Txn(_ context.Context, req *etcdserverpb.TxnRequest) (*etcdserverpb.TxnResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(req.Success) > etcdMaxTxnOps {
+		return nil, fmt.Errorf("etcdserver: too many operations in txn request")
+	}
+	f.txns = append(f.txns, len(req.Success))
+	for _, op := range req.Success {
+		switch {
+		case op.GetRequestPut() != nil:
+			put := op.GetRequestPut()
+			f.kvs[string(put.Key)] = string(put.Value)
+		case op.GetRequestDeleteRange() != nil:
+			del := op.GetRequestDeleteRange()
+			for key := range f.kvs {
+				if inRange(key, del.Key, del.RangeEnd) {
+					delete(f.kvs, key)
+				}
+			}
+		}
+	}
+	return &etcdserverpb.TxnResponse{Succeeded: true}, nil
+}
+
+// state returns a copy of the keys and the transactions so far
+func (f *fakeEtcd) state() (map[string]string, []int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	kvs := make(map[string]string, len(f.kvs))
+	for key, value := range f.kvs {
+		kvs[key] = value
+	}
+	return kvs, append([]int(nil), f.txns...)
+}
+
+// inRange matches a key like etcd: key alone, or [key, rangeEnd)
+func inRange(key string, start, end []byte) bool {
+	if len(end) == 0 {
+		return key == string(start)
+	}
+	return key >= string(start) && key < string(end)
+}
+
+func TestKVPublisher_Etcd(t *testing.T) {
+	fake, addr := startFakeEtcd(t)
+	fake.kvs["other/key"] = "kept"
+	store, err := NewEtcdStore([]string{addr}, "", "", 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewEtcdStore: %v", err)
+	}
+	p := NewKVPublisher("etcd", "traefik", store)
+	defer p.Close()
+	ctx := context.Background()
+
+	// Enough routers to need several transactions
+	names := make([]string, 60)
+	for i := range names {
+		names[i] = fmt.Sprintf("app%d", i)
+	}
+	if err := p.Publish(ctx, testSnapshot(t, names...)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	_, txns := fake.state()
+	if len(txns) < 2 {
+		t.Errorf("expected the change to be split into several transactions, got %v", txns)
+	}
+
+	if err := p.Publish(ctx, testSnapshot(t, names[1:]...)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	// Only the 4 keys of the removed router and service change
+	kvs, txns := fake.state()
+	if len(txns) != 3 || txns[2] != 4 {
+		t.Errorf("expected a transaction deleting 4 keys, got %v", txns)
+	}
+	for key := range kvs {
+		if strings.Contains(key, "/app0/") {
+			t.Errorf("expected %s to be deleted", key)
+		}
+	}
+	if kvs["traefik/http/routers/app1/service"] != "app1" {
+		t.Error("expected the remaining routers to be kept")
+	}
+	if kvs["other/key"] != "kept" {
+		t.Error("expected keys outside the root key to be left alone")
+	}
+}
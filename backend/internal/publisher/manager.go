@@ -0,0 +1,239 @@
+package publisher
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/logging"
+	"github.com/traefikx/backend/internal/services"
+)
+
+// Status is the publish state of one publisher
+type Status struct {
+	Name            string     `json:"name"`
+	Type            string     `json:"type"`
+	Target          string     `json:"target"`
+	Status          string     `json:"status"` // pending, healthy or error
+	Version         uint64     `json:"version,omitempty"`
+	Hash            string     `json:"hash,omitempty"`
+	LastPublishedAt *time.Time `json:"last_published_at,omitempty"`
+	LastAttemptAt   *time.Time `json:"last_attempt_at,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	ErrorCount      int        `json:"error_count"` // Consecutive failures
+}
+
+// Manager pushes each new config snapshot to all publishers. Every publisher
+// runs in its own goroutine so a slow backend never delays the others, and
+// failed publishes are retried until they succeed.
+type Manager struct {
+	compiler      *services.ConfigCompiler
+	bus           *events.Bus
+	workers       []*worker
+	timeout       time.Duration
+	retryInterval time.Duration
+
+	mu       sync.Mutex
+	running  bool
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+type worker struct {
+	publisher Publisher
+	notify    chan struct{}
+
+	mu     sync.RWMutex
+	status Status
+}
+
+func NewManager(compiler *services.ConfigCompiler, bus *events.Bus, publishers []Publisher, timeout, retryInterval time.Duration) *Manager {
+	if retryInterval <= 0 {
+		retryInterval = 30 * time.Second
+	}
+	m := &Manager{
+		compiler:      compiler,
+		bus:           bus,
+		timeout:       timeout,
+		retryInterval: retryInterval,
+	}
+	for _, p := range publishers {
+		m.workers = append(m.workers, &worker{
+			publisher: p,
+			notify:    make(chan struct{}, 1),
+			status: Status{
+				Name:   p.Name(),
+				Type:   p.Type(),
+				Target: p.Target(),
+				Status: "pending",
+			},
+		})
+	}
+	return m
+}
+
+// Start publishes the current snapshot and then every new one. It can be
+// called again after Stop, in HA mode publishers run on the leader only.
+func (m *Manager) Start() {
+	if len(m.workers) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running {
+		return
+	}
+	m.running = true
+	m.stopChan = make(chan struct{})
+	stop := m.stopChan
+
+	compiled, unsubscribe := m.bus.Subscribe(16, events.ConfigCompiled)
+
+	for _, w := range m.workers {
+		slog.Info("Publishing config", "publisher", w.status.Name, "target", logging.RedactURL(w.status.Target))
+		m.wg.Add(1)
+		go m.run(w, stop)
+		w.trigger()
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer unsubscribe()
+		for {
+			select {
+			case <-compiled:
+				for _, w := range m.workers {
+					w.trigger()
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends all workers
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	if !m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.running = false
+	close(m.stopChan)
+	m.mu.Unlock()
+
+	m.wg.Wait()
+}
+
+// Close stops the workers and closes the publishers
+func (m *Manager) Close() {
+	m.Stop()
+	for _, w := range m.workers {
+		if err := w.publisher.Close(); err != nil {
+			slog.Error("Failed to close publisher", "publisher", w.status.Name, "error", err)
+		}
+	}
+}
+
+// Statuses returns the state of every publisher
+func (m *Manager) Statuses() []Status {
+	statuses := make([]Status, 0, len(m.workers))
+	for _, w := range m.workers {
+		w.mu.RLock()
+		statuses = append(statuses, w.status)
+		w.mu.RUnlock()
+	}
+	return statuses
+}
+
+// Sync republishes the current snapshot to every publisher, even those
+// that are up to date, e.g. after the target was wiped
+func (m *Manager) Sync() {
+	for _, w := range m.workers {
+		w.mu.Lock()
+		w.status.Hash = ""
+		w.mu.Unlock()
+		w.trigger()
+	}
+}
+
+// trigger schedules a publish; pending triggers are coalesced
+func (w *worker) trigger() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Manager) run(w *worker, stop <-chan struct{}) {
+	defer m.wg.Done()
+
+	retry := time.NewTicker(m.retryInterval)
+	defer retry.Stop()
+
+	for {
+		select {
+		case <-w.notify:
+			m.publish(w)
+		case <-retry.C:
+			w.mu.RLock()
+			failed := w.status.Status == "error"
+			w.mu.RUnlock()
+			if failed {
+				m.publish(w)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (m *Manager) publish(w *worker) {
+	snapshot, err := m.compiler.Snapshot(context.Background())
+	if err != nil {
+		slog.Error("Failed to get config snapshot", "publisher", w.status.Name, "error", err)
+		return
+	}
+
+	// Nothing to do if this snapshot content is already published
+	w.mu.RLock()
+	upToDate := w.status.Status == "healthy" && w.status.Hash == snapshot.Hash
+	w.mu.RUnlock()
+	if upToDate {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	err = w.publisher.Publish(ctx, snapshot)
+	cancel()
+
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.status.LastAttemptAt = &now
+	if err != nil {
+		if w.status.ErrorCount == 0 {
+			slog.Error("Failed to publish config", "publisher", w.status.Name, "error", err)
+		}
+		w.status.Status = "error"
+		w.status.LastError = err.Error()
+		w.status.ErrorCount++
+		return
+	}
+
+	if w.status.ErrorCount > 0 {
+		slog.Info("Publishing config recovered", "publisher", w.status.Name, "failures", w.status.ErrorCount)
+	}
+	w.status.Status = "healthy"
+	w.status.Version = snapshot.Version
+	w.status.Hash = snapshot.Hash
+	w.status.LastPublishedAt = &now
+	w.status.LastError = ""
+	w.status.ErrorCount = 0
+}
@@ -0,0 +1,130 @@
+package publisher
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/traefikx/backend/internal/database"
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/services"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestCompiler returns a compiler over an empty, migrated database
+func newTestCompiler(t *testing.T, bus *events.Bus) *services.ConfigCompiler {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return services.NewConfigCompiler(db, nil, bus)
+}
+
+// waitForStatus polls the first publisher's status until check accepts it
+func waitForStatus(t *testing.T, m *Manager, check func(Status) bool) Status {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := m.Statuses()[0]
+		if check(status) {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out, last status %+v", status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestManager_RetriesRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.SetError("READONLY You can't write against a read only replica")
+
+	bus := events.NewBus()
+	compiler := newTestCompiler(t, bus)
+	m := NewManager(compiler, bus, []Publisher{
+		NewKVPublisher("redis", "traefik", NewRedisStore(server.Addr(), "", "", 0)),
+	}, time.Second, 20*time.Millisecond)
+	m.Start()
+	defer m.Close()
+
+	failed := waitForStatus(t, m, func(s Status) bool { return s.ErrorCount >= 2 })
+	if failed.Status != "error" || failed.LastError == "" {
+		t.Fatalf("expected failed attempts to be recorded, got %+v", failed)
+	}
+
+	server.SetError("")
+	published := waitForStatus(t, m, func(s Status) bool { return s.Status == "healthy" })
+
+	snapshot, err := compiler.Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if published.Hash != snapshot.Hash || published.ErrorCount != 0 || published.LastError != "" {
+		t.Errorf("unexpected status after recovery %+v", published)
+	}
+	if len(server.Keys()) == 0 {
+		t.Error("expected the config to be written to Redis")
+	}
+}
+
+// countingPublisher counts its publishes
+type countingPublisher struct {
+	publishes atomic.Int32
+}
+
+func (p *countingPublisher) Name() string   { return "counting" }
+func (p *countingPublisher) Type() string   { return "counting" }
+func (p *countingPublisher) Target() string { return "memory" }
+func (p *countingPublisher) Close() error   { return nil }
+
+func (p *countingPublisher) Publish(context.Context, *services.ConfigSnapshot) error {
+	p.publishes.Add(1)
+	return nil
+}
+
+func TestManager_SkipsPublishedSnapshots(t *testing.T) {
+	bus := events.NewBus()
+	compiler := newTestCompiler(t, bus)
+	publisher := &countingPublisher{}
+	m := NewManager(compiler, bus, []Publisher{publisher}, time.Second, 20*time.Millisecond)
+	m.Start()
+	defer m.Close()
+
+	waitForStatus(t, m, func(s Status) bool { return s.Status == "healthy" })
+
+	// Compiling the same config again, or retry ticks, don't republish
+	if _, err := compiler.Rebuild(context.Background()); err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := publisher.publishes.Load(); n != 1 {
+		t.Fatalf("expected 1 publish, got %d", n)
+	}
+
+	// Sync forces a republish
+	m.Sync()
+	deadline := time.Now().Add(5 * time.Second)
+	for publisher.publishes.Load() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected Sync to republish, got %d publishes", publisher.publishes.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
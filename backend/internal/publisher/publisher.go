@@ -0,0 +1,62 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/traefikx/backend/internal/config"
+	"github.com/traefikx/backend/internal/services"
+)
+
+// Publisher pushes compiled configuration to a place Traefik reads from.
+// Implementations: file provider directory, Redis and etcd.
+type Publisher interface {
+	Name() string
+	Type() string
+	Target() string // Human readable destination, without credentials
+	Publish(ctx context.Context, snapshot *services.ConfigSnapshot) error
+	Close() error
+}
+
+// New creates the publishers enabled in the configuration
+func New(cfg *config.Config) ([]Publisher, error) {
+	publishers := []Publisher{}
+
+	if cfg.PublishFileDir != "" {
+		p, err := NewFilePublisher(cfg.PublishFileDir, cfg.PublishFileName, cfg.PublishFileFormat)
+		if err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, p)
+	}
+
+	if cfg.PublishRedisAddr != "" {
+		publishers = append(publishers, NewKVPublisher("redis", cfg.PublishKVRootKey, NewRedisStore(
+			cfg.PublishRedisAddr,
+			cfg.PublishRedisUsername,
+			cfg.PublishRedisPassword,
+			cfg.PublishRedisDB,
+		)))
+	}
+
+	if endpoints := trimEmpty(cfg.PublishEtcdEndpoints); len(endpoints) > 0 {
+		store, err := NewEtcdStore(endpoints, cfg.PublishEtcdUsername, cfg.PublishEtcdPassword, cfg.PublishTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create etcd client: %w", err)
+		}
+		publishers = append(publishers, NewKVPublisher("etcd", cfg.PublishKVRootKey, store))
+	}
+
+	return publishers, nil
+}
+
+func trimEmpty(values []string) []string {
+	result := []string{}
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
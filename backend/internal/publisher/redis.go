@@ -0,0 +1,89 @@
+package publisher
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore stores keys as plain Redis strings, which is what Traefik's
+// Redis provider reads
+type RedisStore struct {
+	client *redis.Client
+	addr   string
+}
+
+func NewRedisStore(addr, username, password string, db int) *RedisStore {
+	return &RedisStore{
+		client: redis.NewClient(&redis.Options{
+			Addr:     addr,
+			Username: username,
+			Password: password,
+			DB:       db,
+		}),
+		addr: addr,
+	}
+}
+
+func (s *RedisStore) Target() string { return "redis://" + s.addr }
+
+func (s *RedisStore) List(ctx context.Context, prefix string) (map[string]string, error) {
+	keys := []string{}
+	iter := s.client.Scan(ctx, 0, escapeGlob(prefix)+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		// Keys deleted since the scan come back as nil
+		if str, ok := value.(string); ok {
+			result[keys[i]] = str
+		}
+	}
+	return result, nil
+}
+
+// Apply runs all writes in a MULTI/EXEC transaction
+func (s *RedisStore) Apply(ctx context.Context, puts map[string]string, deletes []string) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(deletes) > 0 {
+			pipe.Del(ctx, deletes...)
+		}
+		if len(puts) > 0 {
+			values := make([]interface{}, 0, len(puts)*2)
+			for key, value := range puts {
+				values = append(values, key, value)
+			}
+			pipe.MSet(ctx, values...)
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) Close() error { return s.client.Close() }
+
+// escapeGlob escapes characters that have a meaning in SCAN MATCH patterns
+func escapeGlob(s string) string {
+	result := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '*', '?', '[', ']', '\\':
+			result = append(result, '\\')
+		}
+		result = append(result, s[i])
+	}
+	return string(result)
+}
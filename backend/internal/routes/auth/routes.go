@@ -13,6 +13,11 @@ func RegisterRoutes(api *gin.RouterGroup, handler *handlers.AuthHandler) {
 	api.GET("/auth/oidc", handler.OIDCLogin)
 	api.GET("/auth/oidc/callback", handler.OIDCCallback)
 	api.GET("/auth/oidc/status", handler.GetOIDCStatus)
+	api.GET("/auth/invite", handler.GetInvite)
+	api.POST("/auth/invite/accept", handler.AcceptInvite)
+	api.POST("/auth/invite/oidc", handler.InviteOIDC)
+	api.POST("/auth/password/forgot", handler.ForgotPassword)
+	api.POST("/auth/password/reset", handler.ResetPasswordWithToken)
 
 	// Protected routes
 	protected := api.Group("")
@@ -1,25 +1,59 @@
 package routes
 
 import (
+	"context"
+
 	"github.com/gin-gonic/gin"
+	authService "github.com/traefikx/backend/internal/auth"
+	"github.com/traefikx/backend/internal/backup"
 	"github.com/traefikx/backend/internal/config"
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/ha"
 	"github.com/traefikx/backend/internal/handlers"
+	"github.com/traefikx/backend/internal/logging"
+	"github.com/traefikx/backend/internal/mailer"
+	"github.com/traefikx/backend/internal/metrics"
 	"github.com/traefikx/backend/internal/middleware"
+	"github.com/traefikx/backend/internal/notify"
+	"github.com/traefikx/backend/internal/publisher"
 	"github.com/traefikx/backend/internal/routes/auth"
 	"github.com/traefikx/backend/internal/routes/static"
 	traefikRoutes "github.com/traefikx/backend/internal/routes/traefik"
 	"github.com/traefikx/backend/internal/routes/user"
 	"github.com/traefikx/backend/internal/services"
+	"github.com/traefikx/backend/internal/tracing"
 	"gorm.io/gorm"
 )
 
-func SetupRouter(cfg *config.Config, db *gorm.DB, aggregator *services.AggregatorService) *gin.Engine {
+// SetupRouter creates the router. Event streams end when shutdown is done.
+func SetupRouter(shutdown context.Context, cfg *config.Config, db *gorm.DB, aggregator *services.AggregatorService, compiler *services.ConfigCompiler, publishers *publisher.Manager, backups *backup.Manager, bus *events.Bus, m mailer.Mailer, elector *ha.Elector, dispatcher *notify.Dispatcher) *gin.Engine {
+	// Login throttling shared by auth and user handlers
+	rateLimits := authService.NewMemoryRateLimitStore()
+	context.AfterFunc(shutdown, rateLimits.Close)
+	loginThrottle := authService.NewLoginThrottle(cfg, rateLimits)
+	if cfg.LoginHistoryMaxAge > 0 {
+		go handlers.PruneLoginHistory(shutdown, db, cfg.LoginHistoryMaxAge)
+	}
+
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db)
-	userHandler := handlers.NewUserHandler(db)
+	authHandler := handlers.NewAuthHandler(db, loginThrottle, m)
+	userHandler := handlers.NewUserHandler(db, loginThrottle, m, bus)
+	healthHandler := handlers.NewHealthHandler(cfg, db, aggregator, elector)
+	streamHandler := handlers.NewStreamHandler(bus, shutdown)
+
+	// Setup router, with a span, a request ID and an access log for every request
+	r := gin.New()
+	r.Use(tracing.Middleware(), logging.Middleware(), gin.Recovery())
 
-	// Setup router
-	r := gin.Default()
+	// Prometheus metrics, outside /api so scrapers need no session
+	if cfg.MetricsEnabled {
+		r.Use(metrics.Middleware())
+		r.GET("/metrics", metrics.Handler(cfg.MetricsToken))
+	}
+
+	// Liveness and readiness probes for orchestrators
+	r.GET("/healthz", healthHandler.Live)
+	r.GET("/readyz", healthHandler.Ready)
 
 	// CORS middleware
 	r.Use(middleware.CORSMiddleware(cfg.CORSAllowedOrigins))
@@ -27,11 +61,17 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, aggregator *services.Aggregato
 	// API routes
 	api := r.Group("/api")
 	{
+		api.GET("/health", healthHandler.Health)
+		api.GET("/version", healthHandler.Version)
+
+		// Live updates for the UI
+		api.GET("/events", middleware.StreamAuthMiddleware(), streamHandler.Events)
+
 		auth.RegisterRoutes(api, authHandler)
 		user.RegisterRoutes(api, userHandler)
 
 		// Traefik routes
-		traefikRoutes.RegisterRoutes(api, cfg, db, aggregator)
+		traefikRoutes.RegisterRoutes(api, cfg, db, aggregator, compiler, publishers, backups, bus, dispatcher)
 	}
 
 	// Static routes
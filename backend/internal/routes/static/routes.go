@@ -1,7 +1,7 @@
 package static
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -12,7 +12,7 @@ import (
 func RegisterRoutes(r *gin.Engine) {
 	staticPath := getStaticPath()
 	if staticPath != "" {
-		log.Printf("Serving static files from: %s", staticPath)
+		slog.Info("Serving static files", "path", staticPath)
 		// Check if static directory exists
 		if _, err := os.Stat(staticPath); !os.IsNotExist(err) {
 
@@ -59,10 +59,10 @@ func RegisterRoutes(r *gin.Engine) {
 				c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
 			})
 		} else {
-			log.Printf("Warning: Static path not found: %s", staticPath)
+			slog.Warn("Static path not found", "path", staticPath)
 		}
 	} else {
-		log.Println("Warning: No static files path configured")
+		slog.Warn("No static files path configured")
 	}
 }
 
@@ -2,21 +2,30 @@ package traefik
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/backup"
 	"github.com/traefikx/backend/internal/config"
+	"github.com/traefikx/backend/internal/events"
 	"github.com/traefikx/backend/internal/handlers/traefik"
 	"github.com/traefikx/backend/internal/middleware"
+	"github.com/traefikx/backend/internal/notify"
+	"github.com/traefikx/backend/internal/publisher"
 	"github.com/traefikx/backend/internal/services"
 	"gorm.io/gorm"
 )
 
-func RegisterRoutes(api *gin.RouterGroup, cfg *config.Config, db *gorm.DB, aggregator *services.AggregatorService) {
+func RegisterRoutes(api *gin.RouterGroup, cfg *config.Config, db *gorm.DB, aggregator *services.AggregatorService, compiler *services.ConfigCompiler, publishers *publisher.Manager, backups *backup.Manager, bus *events.Bus, dispatcher *notify.Dispatcher) {
 	// Initialize handlers
-	serviceHandler := traefik.NewServiceHandler(db)
-	routerHandler := traefik.NewRouterHandler(db)
-	middlewareHandler := traefik.NewMiddlewareHandler(db)
-	providerHandler := traefik.NewTraefikProviderHandler(db, aggregator)
-	proxyHandler := traefik.NewProxyHandler(db)
-	httpProviderHandler := traefik.NewHTTPProviderHandler(db, aggregator)
+	serviceHandler := traefik.NewServiceHandler(db, bus)
+	routerHandler := traefik.NewRouterHandler(db, bus)
+	middlewareHandler := traefik.NewMiddlewareHandler(db, bus)
+	providerHandler := traefik.NewTraefikProviderHandler(compiler)
+	proxyHandler := traefik.NewProxyHandler(db, bus)
+	httpProviderHandler := traefik.NewHTTPProviderHandler(db, aggregator, compiler, bus)
+	declarativeHandler := traefik.NewDeclarativeHandler(db, aggregator, bus)
+	publisherHandler := traefik.NewPublisherHandler(publishers)
+	conflictHandler := traefik.NewConflictHandler(db, bus)
+	backupHandler := traefik.NewBackupHandler(db, backups, aggregator, bus)
+	notificationHandler := traefik.NewNotificationHandler(db, dispatcher)
 
 	// Traefik management routes (protected)
 	traefikGroup := api.Group("/traefik")
@@ -53,14 +62,51 @@ func RegisterRoutes(api *gin.RouterGroup, cfg *config.Config, db *gorm.DB, aggre
 		// HTTP Provider management (admin only)
 		traefikGroup.GET("/http-providers", middleware.AdminMiddleware(), httpProviderHandler.ListHTTPProviders)
 		traefikGroup.POST("/http-providers", middleware.AdminMiddleware(), httpProviderHandler.CreateHTTPProvider)
+		traefikGroup.POST("/http-providers/test", middleware.AdminMiddleware(), httpProviderHandler.TestProviderSettings)
 		traefikGroup.GET("/http-providers/:id", middleware.AdminMiddleware(), httpProviderHandler.GetHTTPProvider)
 		traefikGroup.PUT("/http-providers/:id", middleware.AdminMiddleware(), httpProviderHandler.UpdateHTTPProvider)
 		traefikGroup.DELETE("/http-providers/:id", middleware.AdminMiddleware(), httpProviderHandler.DeleteHTTPProvider)
 		traefikGroup.POST("/http-providers/:id/refresh", middleware.AdminMiddleware(), httpProviderHandler.RefreshHTTPProvider)
 		traefikGroup.POST("/http-providers/:id/test", middleware.AdminMiddleware(), httpProviderHandler.TestHTTPProvider)
+		traefikGroup.GET("/http-providers/:id/fetches", middleware.AdminMiddleware(), httpProviderHandler.ListProviderFetches)
 
 		// Merged config viewer (admin only)
 		traefikGroup.GET("/merged-config", middleware.AdminMiddleware(), httpProviderHandler.GetMergedConfig)
+
+		// Conflicts between sources and their resolution policies (admin only)
+		traefikGroup.GET("/conflicts", middleware.AdminMiddleware(), conflictHandler.ListConflicts)
+		traefikGroup.GET("/conflict-policies", middleware.AdminMiddleware(), conflictHandler.ListPolicies)
+		traefikGroup.POST("/conflict-policies", middleware.AdminMiddleware(), conflictHandler.CreatePolicy)
+		traefikGroup.PUT("/conflict-policies/:id", middleware.AdminMiddleware(), conflictHandler.UpdatePolicy)
+		traefikGroup.DELETE("/conflict-policies/:id", middleware.AdminMiddleware(), conflictHandler.DeletePolicy)
+
+		// Declarative config import/export (admin only)
+		traefikGroup.GET("/export", middleware.AdminMiddleware(), declarativeHandler.Export)
+		traefikGroup.POST("/import", middleware.AdminMiddleware(), declarativeHandler.Import)
+		traefikGroup.POST("/import/traefik", middleware.AdminMiddleware(), declarativeHandler.ImportTraefik)
+
+		// Config publishers (admin only)
+		traefikGroup.GET("/publishers", middleware.AdminMiddleware(), publisherHandler.ListPublishers)
+		traefikGroup.POST("/publishers/sync", middleware.AdminMiddleware(), publisherHandler.SyncPublishers)
+
+		// Database backups (admin only)
+		traefikGroup.GET("/backups", middleware.AdminMiddleware(), backupHandler.ListBackups)
+		traefikGroup.POST("/backups", middleware.AdminMiddleware(), backupHandler.CreateBackup)
+		traefikGroup.GET("/backups/snapshot", middleware.AdminMiddleware(), backupHandler.DownloadSnapshot)
+		traefikGroup.POST("/backups/restore", middleware.AdminMiddleware(), backupHandler.RestoreBackup)
+		traefikGroup.GET("/backups/:name", middleware.AdminMiddleware(), backupHandler.DownloadBackup)
+		traefikGroup.DELETE("/backups/:name", middleware.AdminMiddleware(), backupHandler.DeleteBackup)
+
+		// Notification channels and their delivery log (admin only)
+		traefikGroup.GET("/notifications/events", middleware.AdminMiddleware(), notificationHandler.ListEvents)
+		traefikGroup.GET("/notifications/channels", middleware.AdminMiddleware(), notificationHandler.ListChannels)
+		traefikGroup.POST("/notifications/channels", middleware.AdminMiddleware(), notificationHandler.CreateChannel)
+		traefikGroup.GET("/notifications/channels/:id", middleware.AdminMiddleware(), notificationHandler.GetChannel)
+		traefikGroup.PUT("/notifications/channels/:id", middleware.AdminMiddleware(), notificationHandler.UpdateChannel)
+		traefikGroup.DELETE("/notifications/channels/:id", middleware.AdminMiddleware(), notificationHandler.DeleteChannel)
+		traefikGroup.POST("/notifications/channels/:id/test", middleware.AdminMiddleware(), notificationHandler.TestChannel)
+		traefikGroup.GET("/notifications/deliveries", middleware.AdminMiddleware(), notificationHandler.ListDeliveries)
+		traefikGroup.POST("/notifications/deliveries/:id/retry", middleware.AdminMiddleware(), notificationHandler.RetryDelivery)
 	}
 
 	// Traefik provider endpoint (public but token-protected)
@@ -18,6 +18,8 @@ func RegisterRoutes(api *gin.RouterGroup, handler *handlers.UserHandler) {
 		protected.POST("/users/:id/reset-password", middleware.AdminMiddleware(), handler.ResetPassword)
 		protected.POST("/users/:id/password/toggle", middleware.AdminMiddleware(), handler.ToggleUserPasswordLogin)
 		protected.POST("/users/:id/oidc/toggle", middleware.AdminMiddleware(), handler.ToggleUserOIDC)
+		protected.POST("/users/:id/unlock", middleware.AdminMiddleware(), handler.UnlockUser)
+		protected.POST("/users/:id/invite", middleware.AdminMiddleware(), handler.InviteUser)
 
 		// This route is accessible by authenticated users (handler might have its own checks)
 		protected.GET("/users/:id", handler.GetUser)
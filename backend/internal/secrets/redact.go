@@ -0,0 +1,32 @@
+package secrets
+
+import "strings"
+
+// Redacted replaces secret values in API responses. Sending it back in an
+// update keeps the stored value.
+const Redacted = "********"
+
+// sensitiveNames are parts of header and field names whose values are
+// secrets
+var sensitiveNames = []string{
+	"authorization",
+	"cookie",
+	"token",
+	"secret",
+	"password",
+	"api-key",
+	"api_key",
+	"apikey",
+}
+
+// IsSensitiveName reports whether the value of a header or field with this
+// name should be redacted
+func IsSensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range sensitiveNames {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,299 @@
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// Stored formats. Values without a prefix are legacy plaintext and are
+// returned unchanged.
+//
+// v1 values are sealed directly with the master key. v2 values use
+// envelope encryption: each value has its own random data key, sealed
+// with the master key identified by the key ID:
+//
+//	enc:v2:<key id>:<sealed data key>:<sealed value>
+const (
+	prefixV1 = "enc:v1:"
+	prefixV2 = "enc:v2:"
+)
+
+// masterKey encrypts data keys
+type masterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+var (
+	mu      sync.RWMutex
+	current *masterKey
+	keys    map[string]*masterKey // Current and previous keys by ID
+)
+
+// ErrNotInitialized is returned when Init has not been called
+var ErrNotInitialized = errors.New("secrets: encryption key not initialized")
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// Init sets the master key used to encrypt secrets, and previous keys that
+// can still decrypt them until they are re-encrypted. AES-256 keys are
+// derived from the keys with SHA-256.
+func Init(key string, previous ...string) error {
+	if key == "" {
+		return errors.New("secrets: empty encryption key")
+	}
+
+	ring := make(map[string]*masterKey, len(previous)+1)
+	var first *masterKey
+	for _, k := range append([]string{key}, previous...) {
+		if k == "" {
+			continue
+		}
+		master, err := newMasterKey(k)
+		if err != nil {
+			return err
+		}
+		if first == nil {
+			first = master
+		}
+		ring[master.id] = master
+	}
+
+	mu.Lock()
+	current, keys = first, ring
+	mu.Unlock()
+	return nil
+}
+
+func newMasterKey(key string) (*masterKey, error) {
+	sum := sha256.Sum256([]byte(key))
+	gcm, err := newGCM(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	// The ID identifies the key without revealing it
+	id := sha256.Sum256(sum[:])
+	return &masterKey{id: hex.EncodeToString(id[:4]), aead: gcm}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// KeyID returns the ID of the current master key
+func KeyID() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if current == nil {
+		return ""
+	}
+	return current.id
+}
+
+// Encrypt encrypts plaintext with a new data key. Empty strings stay empty.
+func Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	mu.RLock()
+	master := current
+	mu.RUnlock()
+	if master == nil {
+		return "", ErrNotInitialized
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealedKey, err := seal(master.aead, dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealedValue, err := seal(gcm, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return prefixV2 + master.id + ":" + base64.StdEncoding.EncodeToString(sealedKey) + ":" + base64.StdEncoding.EncodeToString(sealedValue), nil
+}
+
+// Decrypt reverses Encrypt. It also reads v1 values and plaintext.
+func Decrypt(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, prefixV2):
+		return decryptV2(strings.TrimPrefix(value, prefixV2))
+	case strings.HasPrefix(value, prefixV1):
+		return decryptV1(strings.TrimPrefix(value, prefixV1))
+	default:
+		return value, nil
+	}
+}
+
+func decryptV2(value string) (string, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return "", errors.New("secrets: invalid encoding")
+	}
+
+	mu.RLock()
+	master, ok := keys[parts[0]]
+	initialized := keys != nil
+	mu.RUnlock()
+	if !initialized {
+		return "", ErrNotInitialized
+	}
+	if !ok {
+		return "", fmt.Errorf("secrets: encrypted with unknown key %s", parts[0])
+	}
+
+	sealedKey, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("secrets: invalid encoding: %w", err)
+	}
+	dataKey, err := open(master.aead, sealedKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	sealedValue, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("secrets: invalid encoding: %w", err)
+	}
+	plaintext, err := open(gcm, sealedValue)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// decryptV1 tries each master key, v1 values don't say which one sealed them
+func decryptV1(value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("secrets: invalid encoding: %w", err)
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	if keys == nil {
+		return "", ErrNotInitialized
+	}
+	for _, master := range keys {
+		if plaintext, err := open(master.aead, sealed); err == nil {
+			return string(plaintext), nil
+		}
+	}
+	return "", errors.New("secrets: decryption failed, wrong key?")
+}
+
+func seal(gcm cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(gcm cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("secrets: ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("secrets: decryption failed, wrong key?")
+	}
+	return plaintext, nil
+}
+
+// StoredKeyID returns the ID of the master key of a stored value: "" for
+// empty values, "plaintext" and "v1" for values not yet under envelope
+// encryption
+func StoredKeyID(value string) string {
+	switch {
+	case value == "":
+		return ""
+	case strings.HasPrefix(value, prefixV2):
+		id, _, _ := strings.Cut(strings.TrimPrefix(value, prefixV2), ":")
+		return id
+	case strings.HasPrefix(value, prefixV1):
+		return "v1"
+	default:
+		return "plaintext"
+	}
+}
+
+// NeedsRotation reports whether a stored value should be re-encrypted
+// with the current master key
+func NeedsRotation(value string) bool {
+	id := StoredKeyID(value)
+	return id != "" && id != KeyID()
+}
+
+// EncryptedSerializer encrypts string and []byte fields at rest. Use it
+// with the `gorm:"serializer:encrypted"` tag.
+type EncryptedSerializer struct{}
+
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var stored string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		stored = v
+	case []byte:
+		stored = string(v)
+	default:
+		return fmt.Errorf("secrets: unsupported column value %T", dbValue)
+	}
+
+	plaintext, err := Decrypt(stored)
+	if err != nil {
+		return err
+	}
+	value := field.ReflectValueOf(ctx, dst)
+	if value.Kind() == reflect.Slice {
+		value.SetBytes([]byte(plaintext))
+	} else {
+		value.SetString(plaintext)
+	}
+	return nil
+}
+
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	switch plaintext := fieldValue.(type) {
+	case string:
+		return Encrypt(plaintext)
+	case []byte:
+		return Encrypt(string(plaintext))
+	default:
+		return nil, fmt.Errorf("secrets: unsupported field type %T", fieldValue)
+	}
+}
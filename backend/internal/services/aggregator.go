@@ -1,17 +1,27 @@
 package services
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"sort"
 	"sync"
 	"time"
 
 	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/logging"
+	"github.com/traefikx/backend/internal/metrics"
 	"github.com/traefikx/backend/internal/models"
+	"github.com/traefikx/backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 )
 
@@ -28,100 +38,418 @@ type ProviderStatus struct {
 	RouterCount     int
 	ServiceCount    int
 	MiddlewareCount int
+
+	State               string // healthy, degraded or failed
+	FailurePolicy       string
+	ConsecutiveFailures int
+	NextFetch           *time.Time
+	Warnings            []string // Resources a docker, file or consul source skipped
+
+	body      []byte    // Last response, used to detect changes
+	rulesKey  string    // Filters and namespacing applied to Config
+	updatedAt time.Time // Of the provider row a follower built the status from
 }
 
+// Provider circuit states
+const (
+	ProviderHealthy  = "healthy"  // Last fetch succeeded
+	ProviderDegraded = "degraded" // Failing, the last good config is still served
+	ProviderFailed   = "failed"   // Failing, the provider is left out of the config
+)
+
+// maxProviderBackoff caps the retry delay of failing providers
+const maxProviderBackoff = 5 * time.Minute
+
+// defaultMaxResponseSize limits provider responses until SetMaxResponseSize
+const defaultMaxResponseSize = 10 << 20
+
 // AggregatorService manages polling and caching of external HTTP providers
 type AggregatorService struct {
 	db         *gorm.DB
-	client     *http.Client
+	bus        *events.Bus
+	clients    map[uint]*providerClient
+	clientsMu  sync.Mutex
 	statuses   map[uint]*ProviderStatus
 	statusesMu sync.RWMutex
-	tickers    map[uint]*time.Ticker
-	tickersMu  sync.Mutex
-	stopChan   chan struct{}
+	pollers    map[uint]*poller
+	pollersMu  sync.Mutex
+	ctx        context.Context // Parent of the pollers' contexts, cancelled until Start and by Stop
+	cancel     context.CancelFunc
+	running    bool          // Between Start and Stop, guarded by pollersMu
+	fetchSlots chan struct{} // Bounds concurrent fetches
+	wg         sync.WaitGroup
+
+	historyLimit  int           // Fetches kept per provider, 0 = no limit
+	historyMaxAge time.Duration // Age after which fetches are deleted, 0 = no limit
+
+	maxResponseSize int64 // Bytes read from a provider, larger responses fail the fetch
+}
+
+// providerClient is a cached HTTP client, reused while the provider's TLS
+// and timeout settings are unchanged so connections are kept alive
+type providerClient struct {
+	fingerprint string
+	client      *http.Client
+}
+
+// poller is the poll loop of one provider. Cancelling its context stops the
+// loop and aborts an in-flight fetch.
+type poller struct {
+	cancel  context.CancelFunc
+	refresh chan fetchOrigin // Asks for a fetch before the next one is due, from the request asking
+	done    chan struct{}    // Closed once the loop has returned
+}
+
+// trigger schedules an immediate fetch, coalescing pending requests. The
+// fetch is logged and traced as part of origin, if any.
+func (p *poller) trigger(origin fetchOrigin) {
+	select {
+	case p.refresh <- origin:
+	default:
+	}
+}
+
+// fetchOrigin is the API request a fetch runs for, after the request
+// has been answered: its ID is logged with the fetch and the fetch's span
+// joins its trace
+type fetchOrigin struct {
+	requestID string
+	span      trace.SpanContext
+}
+
+func originOf(ctx context.Context) fetchOrigin {
+	return fetchOrigin{requestID: logging.RequestID(ctx), span: trace.SpanContextFromContext(ctx)}
 }
 
-// NewAggregatorService creates a new aggregator service
-func NewAggregatorService(db *gorm.DB) *AggregatorService {
+// context returns ctx carrying the origin's request ID and span
+func (o fetchOrigin) context(ctx context.Context) context.Context {
+	ctx = logging.WithRequestID(ctx, o.requestID)
+	if o.span.IsValid() {
+		ctx = trace.ContextWithSpanContext(ctx, o.span)
+	}
+	return ctx
+}
+
+// NewAggregatorService creates a new aggregator service. Changes to a
+// provider's configuration are published on bus. At most
+// maxConcurrentFetches providers are fetched at the same time. Every fetch
+// is recorded, keeping historyLimit fetches per provider for up to
+// historyMaxAge.
+func NewAggregatorService(db *gorm.DB, bus *events.Bus, maxConcurrentFetches, historyLimit int, historyMaxAge time.Duration) *AggregatorService {
+	if maxConcurrentFetches <= 0 {
+		maxConcurrentFetches = 4
+	}
+	// Nothing polls until Start
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
 	return &AggregatorService{
-		db:       db,
-		client:   &http.Client{Timeout: 5 * time.Second},
-		statuses: make(map[uint]*ProviderStatus),
-		tickers:  make(map[uint]*time.Ticker),
-		stopChan: make(chan struct{}),
+		db:         db,
+		bus:        bus,
+		clients:    make(map[uint]*providerClient),
+		statuses:   make(map[uint]*ProviderStatus),
+		pollers:    make(map[uint]*poller),
+		ctx:        ctx,
+		cancel:     cancel,
+		fetchSlots: make(chan struct{}, maxConcurrentFetches),
+
+		historyLimit:  historyLimit,
+		historyMaxAge: historyMaxAge,
+
+		maxResponseSize: defaultMaxResponseSize,
 	}
 }
 
-// Start begins the aggregator service
+// SetMaxResponseSize limits the size of provider responses. Larger ones
+// fail the fetch instead of being read into memory. Call it before Start.
+func (a *AggregatorService) SetMaxResponseSize(size int64) {
+	if size > 0 {
+		a.maxResponseSize = size
+	}
+}
+
+// Start polls the active providers. It can be called again after Stop, in
+// HA mode the aggregator runs while this replica is the leader. Calls while
+// it runs do nothing.
 func (a *AggregatorService) Start() {
-	log.Println("Starting HTTP provider aggregator service...")
+	a.pollersMu.Lock()
+	if a.running {
+		a.pollersMu.Unlock()
+		return
+	}
+	a.running = true
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+	ctx := a.ctx
+	a.pollersMu.Unlock()
+
+	slog.Info("Starting HTTP provider aggregator service")
+
+	// Provider changes made through other replicas
+	changes, unsubscribe := a.bus.Subscribe(64, events.ConfigChanged)
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		defer unsubscribe()
+		for {
+			select {
+			case event := <-changes:
+				a.applyRemoteChange(event)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 
 	// Load all providers and start polling
 	var providers []models.HTTPProvider
 	if err := a.db.Find(&providers).Error; err != nil {
-		log.Printf("Failed to load HTTP providers: %v", err)
+		slog.Error("Failed to load HTTP providers", "error", err)
 		return
 	}
 
 	for _, provider := range providers {
-		a.startPolling(&provider)
+		a.startPolling(&provider, fetchOrigin{})
 	}
 
-	log.Printf("Aggregator service started with %d providers", len(providers))
+	slog.Info("Aggregator service started", "providers", len(providers))
 }
 
-// Stop stops all polling
+// applyRemoteChange starts, restarts or stops polling a provider that was
+// changed through another replica. Changes made through this one are
+// applied by the handlers directly.
+func (a *AggregatorService) applyRemoteChange(event events.Event) {
+	change, ok := event.Data.(events.ResourceChange)
+	if event.Origin == "" || !ok {
+		return
+	}
+	if change.Kind == "database" && change.Action == events.ActionRestored {
+		a.Reload()
+		return
+	}
+	if change.Kind != "http_provider" {
+		return
+	}
+
+	switch change.Action {
+	case events.ActionDeleted:
+		a.DeleteProvider(change.ID)
+	case events.ActionRefreshed:
+		if err := a.RefreshProvider(context.Background(), change.ID); err != nil {
+			slog.Error("Failed to refresh provider", "provider_id", change.ID, "error", err)
+		}
+	default:
+		var provider models.HTTPProvider
+		if err := a.db.First(&provider, change.ID).Error; err != nil {
+			slog.Error("Failed to load provider", "provider_id", change.ID, "error", err)
+			return
+		}
+		if provider.IsActive {
+			a.UpdateProvider(context.Background(), &provider)
+		} else {
+			a.DeleteProvider(provider.ID)
+		}
+	}
+}
+
+// Running reports whether the aggregator polls providers
+func (a *AggregatorService) Running() bool {
+	a.pollersMu.Lock()
+	defer a.pollersMu.Unlock()
+	return a.running
+}
+
+// SyncStatuses rebuilds the statuses of a stopped aggregator from the
+// database, where the leader records each fetch. It does nothing while the
+// aggregator runs.
+func (a *AggregatorService) SyncStatuses(ctx context.Context) error {
+	if a.Running() {
+		return nil
+	}
+
+	var providers []models.HTTPProvider
+	if err := a.db.WithContext(ctx).Find(&providers).Error; err != nil {
+		return err
+	}
+
+	a.statusesMu.Lock()
+	defer a.statusesMu.Unlock()
+
+	statuses := make(map[uint]*ProviderStatus, len(providers))
+	for i := range providers {
+		provider := &providers[i]
+		if status, ok := a.statuses[provider.ID]; ok && status.updatedAt.Equal(provider.UpdatedAt) {
+			statuses[provider.ID] = status
+			continue
+		}
+
+		status := restoreStatus(provider)
+		status.LastError = provider.LastError
+		status.FailurePolicy = provider.FailurePolicy
+		status.State = provider.State
+		if status.State == "" && status.Config != nil {
+			status.State = ProviderHealthy
+		}
+		status.updatedAt = provider.UpdatedAt
+		statuses[provider.ID] = status
+	}
+	a.statuses = statuses
+	return nil
+}
+
+// Stop cancels all poll loops and in-flight fetches, and waits for them
+// to return
 func (a *AggregatorService) Stop() {
-	close(a.stopChan)
-	a.tickersMu.Lock()
-	for _, ticker := range a.tickers {
-		ticker.Stop()
+	a.pollersMu.Lock()
+	if !a.running {
+		a.pollersMu.Unlock()
+		return
 	}
-	a.tickers = make(map[uint]*time.Ticker)
-	a.tickersMu.Unlock()
+	a.running = false
+	a.cancel()
+	a.pollers = make(map[uint]*poller)
+	a.pollersMu.Unlock()
+
+	a.wg.Wait()
+	slog.Info("Aggregator service stopped")
 }
 
-// startPolling starts polling for a specific provider
-func (a *AggregatorService) startPolling(provider *models.HTTPProvider) {
-	a.tickersMu.Lock()
-	defer a.tickersMu.Unlock()
+// startPolling (re)starts the poll loop of a provider. A running loop is
+// stopped first, so a restart never overlaps with the previous fetch. The
+// first fetch is logged and traced as part of origin.
+func (a *AggregatorService) startPolling(provider *models.HTTPProvider, origin fetchOrigin) {
+	a.pollersMu.Lock()
+	previous := a.pollers[provider.ID]
+	delete(a.pollers, provider.ID)
 
-	// Stop existing ticker if any
-	if ticker, exists := a.tickers[provider.ID]; exists {
-		ticker.Stop()
+	var p *poller
+	var ctx context.Context
+	if provider.IsActive && a.ctx.Err() == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(a.ctx)
+		p = &poller{
+			cancel:  cancel,
+			refresh: make(chan fetchOrigin, 1),
+			done:    make(chan struct{}),
+		}
+		a.pollers[provider.ID] = p
+		a.wg.Add(1)
 	}
+	a.pollersMu.Unlock()
+
+	if previous != nil {
+		previous.cancel()
+		<-previous.done
+	}
+	if p != nil {
+		go a.poll(ctx, provider.ID, p, origin)
+		a.watch(ctx, provider, p)
+	}
+}
 
-	if !provider.IsActive {
+// watch fetches providers whose source reports changes as soon as they
+// happen, the poll loop remaining as a fallback
+func (a *AggregatorService) watch(ctx context.Context, provider *models.HTTPProvider, p *poller) {
+	if isHTTPProvider(provider) {
+		return
+	}
+	source, err := NewSource(provider, nil)
+	if err != nil {
+		return
+	}
+	watcher, ok := source.(Watcher)
+	if !ok {
 		return
 	}
 
-	// Do initial fetch
-	a.fetchProvider(provider)
+	if err := watcher.Watch(ctx, func() { p.trigger(fetchOrigin{}) }); err != nil {
+		slog.Warn("Failed to watch provider, changes are picked up by polling", "provider", provider.Name, "error", err)
+	}
+}
+
+// stopPolling ends the poll loop of a provider and waits for it to return
+func (a *AggregatorService) stopPolling(providerID uint) {
+	a.pollersMu.Lock()
+	p, exists := a.pollers[providerID]
+	delete(a.pollers, providerID)
+	a.pollersMu.Unlock()
+
+	if exists {
+		p.cancel()
+		<-p.done
+	}
+}
+
+// poll fetches a provider right away, then whenever its next fetch is due
+// or a refresh is requested. The delay is recomputed after every fetch,
+// which is how failing providers back off.
+func (a *AggregatorService) poll(ctx context.Context, providerID uint, p *poller, origin fetchOrigin) {
+	defer a.wg.Done()
+	defer close(p.done)
+
+	for {
+		var provider models.HTTPProvider
+		if err := a.db.WithContext(ctx).First(&provider, providerID).Error; err != nil {
+			if ctx.Err() == nil {
+				slog.Warn("Provider not found, stopping polling", "provider_id", providerID)
+			}
+			return
+		}
+		a.fetchProvider(origin.context(ctx), &provider)
+
+		timer := time.NewTimer(a.nextFetchDelay(providerID))
+		select {
+		case <-timer.C:
+			origin = fetchOrigin{}
+		case origin = <-p.refresh:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
 
-	// Start ticker
+func (a *AggregatorService) nextFetchDelay(providerID uint) time.Duration {
+	a.statusesMu.RLock()
+	defer a.statusesMu.RUnlock()
+
+	if status, exists := a.statuses[providerID]; exists && status.NextFetch != nil {
+		return max(time.Until(*status.NextFetch), 0)
+	}
+	return 30 * time.Second
+}
+
+// fetchDelay is the provider's refresh interval, doubled for each consecutive
+// failure up to maxProviderBackoff. Retries get ±20% jitter so providers
+// that fail together don't retry in lockstep.
+func fetchDelay(provider *models.HTTPProvider, failures int) time.Duration {
 	interval := time.Duration(provider.RefreshInterval) * time.Second
 	if interval < 5*time.Second {
 		interval = 5 * time.Second // Minimum 5 seconds
 	}
+	if failures == 0 {
+		return interval
+	}
 
-	ticker := time.NewTicker(interval)
-	a.tickers[provider.ID] = ticker
+	delay := interval
+	for i := 0; i < failures && delay < maxProviderBackoff; i++ {
+		delay *= 2
+	}
+	delay = max(min(delay, maxProviderBackoff), interval)
 
-	go func(providerID uint) {
-		for {
-			select {
-			case <-ticker.C:
-				var p models.HTTPProvider
-				if err := a.db.First(&p, providerID).Error; err != nil {
-					log.Printf("Provider %d not found, stopping polling", providerID)
-					return
-				}
-				a.fetchProvider(&p)
-			case <-a.stopChan:
-				return
-			}
-		}
-	}(provider.ID)
+	return time.Duration(float64(delay) * (0.8 + rand.Float64()*0.4))
+}
+
+// failureState decides whether a failing provider's last good config is
+// still served. The grace period is checked on every failed fetch.
+func failureState(provider *models.HTTPProvider, status *ProviderStatus, now time.Time) string {
+	if status.Config == nil || status.LastFetched == nil || provider.FailurePolicy == models.ProviderFailureDrop {
+		return ProviderFailed
+	}
+	grace := time.Duration(provider.StaleGracePeriod) * time.Second
+	if grace > 0 && now.Sub(*status.LastFetched) > grace {
+		return ProviderFailed
+	}
+	return ProviderDegraded
 }
 
 // DynamicConfig represents the full Traefik dynamic configuration
@@ -129,44 +457,80 @@ type DynamicConfig struct {
 	HTTP *dynamic.HTTPConfiguration `json:"http,omitempty"`
 }
 
-// fetchProvider fetches configuration from a provider
-func (a *AggregatorService) fetchProvider(provider *models.HTTPProvider) {
-	log.Printf("Fetching from provider %s (%s)", provider.Name, provider.URL)
-
-	resp, err := a.client.Get(provider.URL)
-	if err != nil {
-		a.updateProviderError(provider, fmt.Sprintf("Connection error: %v", err))
+// fetchProvider fetches configuration from a provider. Nothing is recorded
+// when ctx is cancelled, the provider was then updated or deleted.
+func (a *AggregatorService) fetchProvider(ctx context.Context, provider *models.HTTPProvider) {
+	select {
+	case a.fetchSlots <- struct{}{}:
+	case <-ctx.Done():
 		return
 	}
-	defer resp.Body.Close()
+	defer func() { <-a.fetchSlots }()
 
-	if resp.StatusCode != http.StatusOK {
-		a.updateProviderError(provider, fmt.Sprintf("HTTP %d: %s", resp.StatusCode, resp.Status))
-		return
+	ctx, span := tracing.Start(ctx, "provider.fetch", trace.WithAttributes(
+		attribute.Int("provider.id", int(provider.ID)),
+		attribute.String("provider.name", provider.Name),
+		attribute.String("provider.type", provider.Type),
+	))
+	defer span.End()
+
+	slog.DebugContext(ctx, "Fetching from provider", "provider", provider.Name, "url", logging.RedactURL(provider.URL))
+
+	fetch := &models.ProviderFetch{ProviderID: provider.ID, FetchedAt: time.Now()}
+	defer func() {
+		if ctx.Err() == nil {
+			duration := time.Since(fetch.FetchedAt)
+			fetch.DurationMs = duration.Milliseconds()
+			a.recordFetch(ctx, fetch)
+			metrics.ObserveFetch(provider.Name, duration, fetch.Success)
+		}
+		span.SetAttributes(attribute.Bool("provider.changed", fetch.Changed))
+	}()
+	fail := func(errMsg string) {
+		fetch.Error = errMsg
+		tracing.Fail(span, errors.New(errMsg))
+		a.updateProviderError(ctx, provider, errMsg)
+	}
+
+	var body []byte
+	var warnings []string
+	if isHTTPProvider(provider) {
+		var err error
+		if body, fetch.StatusCode, err = a.fetchHTTP(ctx, provider); err != nil {
+			fail(err.Error())
+			return
+		}
+	} else {
+		result, err := a.fetchSource(ctx, provider)
+		if err != nil {
+			fail(err.Error())
+			return
+		}
+		body, warnings = result.Body, result.Warnings
 	}
+	fetch.Size = len(body)
 
-	body, err := io.ReadAll(resp.Body)
+	// Parse into official Traefik types
+	httpConfig, err := parseProviderConfig(body)
 	if err != nil {
-		a.updateProviderError(provider, fmt.Sprintf("Read error: %v", err))
+		fail(fmt.Sprintf("JSON parse error: %v", err))
 		return
 	}
 
-	// Parse into official Traefik types
-	var config DynamicConfig
-	if err := json.Unmarshal(body, &config); err != nil {
-		a.updateProviderError(provider, fmt.Sprintf("JSON parse error: %v", err))
+	rules := RulesForProvider(provider)
+	httpConfig, err = ApplyProviderRules(httpConfig, rules)
+	if err != nil {
+		fail(fmt.Sprintf("Filter error: %v", err))
 		return
 	}
 
 	// Count items
-	routerCount := 0
-	serviceCount := 0
-	middlewareCount := 0
+	routerCount := len(httpConfig.Routers)
+	serviceCount := len(httpConfig.Services)
+	middlewareCount := len(httpConfig.Middlewares)
 
-	if config.HTTP != nil {
-		routerCount = len(config.HTTP.Routers)
-		serviceCount = len(config.HTTP.Services)
-		middlewareCount = len(config.HTTP.Middlewares)
+	if ctx.Err() != nil {
+		return
 	}
 
 	// Update database
@@ -174,20 +538,47 @@ func (a *AggregatorService) fetchProvider(provider *models.HTTPProvider) {
 	provider.LastFetched = &now
 	provider.LastResponse = body
 	provider.LastError = ""
+	provider.State = ProviderHealthy
 	provider.RouterCount = routerCount
 	provider.ServiceCount = serviceCount
 	provider.MiddlewareCount = middlewareCount
 
-	if err := a.db.Save(provider).Error; err != nil {
-		log.Printf("Failed to save provider %s: %v", provider.Name, err)
+	// Only the fetch state, an admin may have edited the provider meanwhile
+	if err := a.db.WithContext(context.WithoutCancel(ctx)).Model(provider).
+		Select("LastFetched", "LastResponse", "LastError", "State", "RouterCount", "ServiceCount", "MiddlewareCount", "ETag", "LastModified").
+		Updates(provider).Error; err != nil {
+		slog.ErrorContext(ctx, "Failed to save provider", "provider", provider.Name, "error", err)
 	}
 
 	// Update in-memory status with official types
 	a.statusesMu.Lock()
-	httpConfig := &dynamic.HTTPConfiguration{}
-	if config.HTTP != nil {
-		httpConfig = config.HTTP
+	previous, existed := a.statuses[provider.ID]
+	changed := !existed || previous.State != ProviderHealthy || !bytes.Equal(previous.body, body) ||
+		previous.rulesKey != rules.Key() || previous.Priority != provider.Priority
+
+	// Compare with the last good config, from the database after a restart
+	var previousConfig *dynamic.HTTPConfiguration
+	if existed {
+		previousConfig = previous.Config
+	} else {
+		previousConfig = restoreStatus(provider).Config
+	}
+	fetch.Success = true
+	fetch.RouterCount = routerCount
+	fetch.ServiceCount = serviceCount
+	fetch.MiddlewareCount = middlewareCount
+	if !existed || !bytes.Equal(previous.body, body) || previous.rulesKey != rules.Key() {
+		if diff := DiffConfigs(previousConfig, httpConfig); !diff.Empty() {
+			data, _ := json.Marshal(diff)
+			fetch.Changed = true
+			fetch.Diff = string(data)
+		}
 	}
+	recovered := existed && previous.ConsecutiveFailures > 0
+	if recovered {
+		slog.InfoContext(ctx, "Provider recovered", "provider", provider.Name, "failures", previous.ConsecutiveFailures)
+	}
+	next := now.Add(fetchDelay(provider, 0))
 	a.statuses[provider.ID] = &ProviderStatus{
 		ID:              provider.ID,
 		Name:            provider.Name,
@@ -200,62 +591,353 @@ func (a *AggregatorService) fetchProvider(provider *models.HTTPProvider) {
 		RouterCount:     routerCount,
 		ServiceCount:    serviceCount,
 		MiddlewareCount: middlewareCount,
+		State:           ProviderHealthy,
+		FailurePolicy:   provider.FailurePolicy,
+		NextFetch:       &next,
+		Warnings:        warnings,
+		body:            body,
+		rulesKey:        rules.Key(),
 	}
 	a.statusesMu.Unlock()
 
-	log.Printf("Successfully fetched from %s: %d routers, %d services, %d middlewares",
-		provider.Name, routerCount, serviceCount, middlewareCount)
+	a.bus.Publish(events.ProviderFetched, events.ProviderChange{
+		ID: provider.ID, Name: provider.Name, State: ProviderHealthy, Changed: changed,
+	})
+	if changed {
+		a.bus.PublishContext(ctx, events.ProviderUpdated, events.ProviderChange{ID: provider.ID, Name: provider.Name})
+	}
+	if recovered {
+		a.bus.Publish(events.ProviderRecovered, events.ProviderChange{
+			ID: provider.ID, Name: provider.Name, State: ProviderHealthy, Failures: previous.ConsecutiveFailures,
+		})
+	}
+
+	slog.InfoContext(ctx, "Fetched provider", "provider", provider.Name,
+		"routers", routerCount, "services", serviceCount, "middlewares", middlewareCount, "changed", fetch.Changed)
 }
 
-// updateProviderError updates provider with error status
-func (a *AggregatorService) updateProviderError(provider *models.HTTPProvider, errMsg string) {
-	log.Printf("Provider %s error: %s", provider.Name, errMsg)
+// fetchHTTP requests a Traefik HTTP provider endpoint, conditionally when
+// a previous response is cached
+func (a *AggregatorService) fetchHTTP(ctx context.Context, provider *models.HTTPProvider) ([]byte, int, error) {
+	client, err := a.clientFor(provider)
+	if err != nil {
+		return nil, 0, fmt.Errorf("TLS configuration error: %v", err)
+	}
 
-	provider.LastError = errMsg
-	if err := a.db.Model(provider).Update("last_error", errMsg).Error; err != nil {
-		log.Printf("Failed to update provider error: %v", err)
+	req, err := NewProviderRequest(ctx, provider)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Invalid request: %v", err)
+	}
+
+	// Conditional request, only when there is a cached response to fall back on
+	cached := a.cachedBody(provider)
+	if cached != nil {
+		if provider.ETag != "" {
+			req.Header.Set("If-None-Match", provider.ETag)
+		}
+		if provider.LastModified != "" {
+			req.Header.Set("If-Modified-Since", provider.LastModified)
+		}
 	}
 
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Connection error: %v", logging.RedactError(err))
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		body = cached
+	case resp.StatusCode == http.StatusOK:
+		body, err = a.readResponse(resp.Body)
+		if err != nil {
+			return nil, resp.StatusCode, err
+		}
+	default:
+		return nil, resp.StatusCode, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	// A 304 may omit validators, keep the previous ones then
+	if etag := resp.Header.Get("ETag"); etag != "" || resp.StatusCode == http.StatusOK {
+		provider.ETag = etag
+	}
+	if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" || resp.StatusCode == http.StatusOK {
+		provider.LastModified = lastModified
+	}
+
+	return body, resp.StatusCode, nil
+}
+
+// readResponse reads a provider's response body, up to maxResponseSize
+func (a *AggregatorService) readResponse(r io.Reader) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, a.maxResponseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("Read error: %v", err)
+	}
+	if int64(len(body)) > a.maxResponseSize {
+		return nil, fmt.Errorf("Response too large: more than %d bytes", a.maxResponseSize)
+	}
+	return body, nil
+}
+
+// recordFetch stores a fetch in the provider's history and prunes entries
+// beyond the retention limits
+func (a *AggregatorService) recordFetch(ctx context.Context, fetch *models.ProviderFetch) {
+	db := a.db.WithContext(context.WithoutCancel(ctx))
+	if err := db.Create(fetch).Error; err != nil {
+		slog.ErrorContext(ctx, "Failed to record fetch", "provider_id", fetch.ProviderID, "error", err)
+		return
+	}
+
+	if a.historyMaxAge > 0 {
+		db.Where("provider_id = ? AND fetched_at < ?", fetch.ProviderID, time.Now().Add(-a.historyMaxAge)).
+			Delete(&models.ProviderFetch{})
+	}
+	if a.historyLimit > 0 {
+		var oldest []uint
+		db.Model(&models.ProviderFetch{}).Where("provider_id = ?", fetch.ProviderID).
+			Order("id DESC").Offset(a.historyLimit).Limit(1).Pluck("id", &oldest)
+		if len(oldest) > 0 {
+			db.Where("provider_id = ? AND id <= ?", fetch.ProviderID, oldest[0]).Delete(&models.ProviderFetch{})
+		}
+	}
+}
+
+// fetchSource reads a docker, file or consul provider
+func (a *AggregatorService) fetchSource(ctx context.Context, provider *models.HTTPProvider) (*SourceResult, error) {
+	client, err := a.clientFor(provider)
+	if err != nil {
+		return nil, fmt.Errorf("TLS configuration error: %v", err)
+	}
+
+	source, err := NewSource(provider, client)
+	if err != nil {
+		return nil, err
+	}
+	return source.Fetch(ctx)
+}
+
+// clientFor returns the cached client for a provider, rebuilding it when its
+// settings changed
+func (a *AggregatorService) clientFor(provider *models.HTTPProvider) (*http.Client, error) {
+	fingerprint := clientFingerprint(provider)
+
+	a.clientsMu.Lock()
+	defer a.clientsMu.Unlock()
+
+	if cached, ok := a.clients[provider.ID]; ok && cached.fingerprint == fingerprint {
+		return cached.client, nil
+	}
+
+	client, err := NewProviderClient(provider)
+	if err != nil {
+		return nil, err
+	}
+	a.clients[provider.ID] = &providerClient{fingerprint: fingerprint, client: client}
+	return client, nil
+}
+
+// cachedBody returns the last successful response of a provider, from
+// memory or from the database after a restart
+func (a *AggregatorService) cachedBody(provider *models.HTTPProvider) []byte {
+	a.statusesMu.RLock()
+	status, exists := a.statuses[provider.ID]
+	a.statusesMu.RUnlock()
+
+	if exists && status.body != nil {
+		return status.body
+	}
+	if len(provider.LastResponse) > 0 {
+		return provider.LastResponse
+	}
+	return nil
+}
+
+// updateProviderError updates provider with error status
+func (a *AggregatorService) updateProviderError(ctx context.Context, provider *models.HTTPProvider, errMsg string) {
+	if ctx.Err() != nil {
+		return // Cancelled, not a provider failure
+	}
+	slog.WarnContext(ctx, "Provider fetch failed", "provider", provider.Name, "error", errMsg)
+
+	now := time.Now()
 	a.statusesMu.Lock()
-	if status, exists := a.statuses[provider.ID]; exists {
-		status.LastError = errMsg
+	status, exists := a.statuses[provider.ID]
+	if !exists {
+		status = restoreStatus(provider)
+		a.statuses[provider.ID] = status
 	}
+	previousState := status.State
+
+	status.Name = provider.Name
+	status.URL = provider.URL
+	status.Priority = provider.Priority
+	status.IsActive = provider.IsActive
+	status.FailurePolicy = provider.FailurePolicy
+	status.LastError = errMsg
+	status.ConsecutiveFailures++
+	status.State = failureState(provider, status, now)
+	next := now.Add(fetchDelay(provider, status.ConsecutiveFailures))
+	status.NextFetch = &next
+	state := status.State
+	failures := status.ConsecutiveFailures
 	a.statusesMu.Unlock()
+
+	provider.LastError = errMsg
+	provider.State = state
+	if err := a.db.WithContext(context.WithoutCancel(ctx)).Model(provider).Updates(map[string]interface{}{"last_error": errMsg, "state": state}).Error; err != nil {
+		slog.ErrorContext(ctx, "Failed to update provider error", "provider", provider.Name, "error", err)
+	}
+
+	if state != previousState {
+		if state == ProviderDegraded {
+			slog.WarnContext(ctx, "Provider is failing, serving its last known good config", "provider", provider.Name)
+		} else {
+			slog.ErrorContext(ctx, "Provider failed, removing it from the config", "provider", provider.Name)
+		}
+		change := events.ProviderChange{ID: provider.ID, Name: provider.Name, Error: errMsg, State: state, Failures: failures}
+		a.bus.PublishContext(ctx, events.ProviderUpdated, change)
+		a.bus.Publish(events.ProviderFailed, change)
+	}
 }
 
-// RefreshProvider manually refreshes a specific provider
-func (a *AggregatorService) RefreshProvider(providerID uint) error {
+// restoreStatus creates the status of a provider whose first fetch since
+// startup failed, from the last good response stored in the database
+func restoreStatus(provider *models.HTTPProvider) *ProviderStatus {
+	status := &ProviderStatus{
+		ID:          provider.ID,
+		Name:        provider.Name,
+		URL:         provider.URL,
+		Priority:    provider.Priority,
+		IsActive:    provider.IsActive,
+		LastFetched: provider.LastFetched,
+	}
+
+	if len(provider.LastResponse) == 0 || provider.LastFetched == nil {
+		return status
+	}
+	config, err := parseProviderConfig(provider.LastResponse)
+	if err != nil {
+		return status
+	}
+	rules := RulesForProvider(provider)
+	if config, err = ApplyProviderRules(config, rules); err != nil {
+		return status
+	}
+
+	status.Config = config
+	status.RouterCount = len(config.Routers)
+	status.ServiceCount = len(config.Services)
+	status.MiddlewareCount = len(config.Middlewares)
+	status.body = provider.LastResponse
+	status.rulesKey = rules.Key()
+	return status
+}
+
+// parseProviderConfig decodes a provider response into Traefik types
+func parseProviderConfig(body []byte) (*dynamic.HTTPConfiguration, error) {
+	var config DynamicConfig
+	if err := json.Unmarshal(body, &config); err != nil {
+		return nil, err
+	}
+	if config.HTTP == nil {
+		return &dynamic.HTTPConfiguration{}, nil
+	}
+	return config.HTTP, nil
+}
+
+// RefreshProvider manually refreshes a specific provider. The fetch is
+// logged and traced as part of the request of ctx.
+func (a *AggregatorService) RefreshProvider(ctx context.Context, providerID uint) error {
 	var provider models.HTTPProvider
-	if err := a.db.First(&provider, providerID).Error; err != nil {
+	if err := a.db.WithContext(ctx).First(&provider, providerID).Error; err != nil {
 		return err
 	}
 
-	go a.fetchProvider(&provider)
+	a.pollersMu.Lock()
+	defer a.pollersMu.Unlock()
+
+	if p, polling := a.pollers[providerID]; polling {
+		p.trigger(originOf(ctx))
+		return nil
+	}
+
+	// A stopped aggregator belongs to a follower, the leader fetches
+	if a.ctx.Err() != nil {
+		return nil
+	}
+
+	// Inactive providers are fetched once
+	fetchCtx := originOf(ctx).context(a.ctx)
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.fetchProvider(fetchCtx, &provider)
+	}()
 	return nil
 }
 
-// AddProvider adds a new provider and starts polling
-func (a *AggregatorService) AddProvider(provider *models.HTTPProvider) {
-	a.startPolling(provider)
+// Reload forgets all providers and loads them from the database again,
+// after it was replaced by a backup
+func (a *AggregatorService) Reload() {
+	ids := map[uint]bool{}
+	a.statusesMu.RLock()
+	for id := range a.statuses {
+		ids[id] = true
+	}
+	a.statusesMu.RUnlock()
+	a.pollersMu.Lock()
+	for id := range a.pollers {
+		ids[id] = true
+	}
+	a.pollersMu.Unlock()
+	for id := range ids {
+		a.DeleteProvider(id)
+	}
+
+	var providers []models.HTTPProvider
+	if err := a.db.Find(&providers).Error; err != nil {
+		slog.Error("Failed to load HTTP providers", "error", err)
+		return
+	}
+	for _, provider := range providers {
+		a.startPolling(&provider, fetchOrigin{})
+	}
+}
+
+// AddProvider adds a new provider and starts polling, logging and tracing
+// the first fetch as part of the request of ctx
+func (a *AggregatorService) AddProvider(ctx context.Context, provider *models.HTTPProvider) {
+	a.startPolling(provider, originOf(ctx))
 }
 
 // UpdateProvider updates a provider and restarts polling
-func (a *AggregatorService) UpdateProvider(provider *models.HTTPProvider) {
-	a.startPolling(provider)
+func (a *AggregatorService) UpdateProvider(ctx context.Context, provider *models.HTTPProvider) {
+	a.startPolling(provider, originOf(ctx))
 }
 
 // DeleteProvider stops polling for a provider
 func (a *AggregatorService) DeleteProvider(providerID uint) {
-	a.tickersMu.Lock()
-	if ticker, exists := a.tickers[providerID]; exists {
-		ticker.Stop()
-		delete(a.tickers, providerID)
-	}
-	a.tickersMu.Unlock()
+	a.stopPolling(providerID)
 
 	a.statusesMu.Lock()
+	status, existed := a.statuses[providerID]
 	delete(a.statuses, providerID)
 	a.statusesMu.Unlock()
+
+	a.clientsMu.Lock()
+	if cached, ok := a.clients[providerID]; ok {
+		cached.client.CloseIdleConnections()
+		delete(a.clients, providerID)
+	}
+	a.clientsMu.Unlock()
+
+	if existed {
+		metrics.ForgetProvider(status.Name)
+		a.bus.Publish(events.ProviderUpdated, events.ProviderChange{ID: providerID, Name: status.Name})
+	}
 }
 
 // GetStatuses returns all provider statuses
@@ -276,6 +958,18 @@ func (a *AggregatorService) GetStatuses() []ProviderStatus {
 	return statuses
 }
 
+// GetStatus returns the status of one provider
+func (a *AggregatorService) GetStatus(providerID uint) (ProviderStatus, bool) {
+	a.statusesMu.RLock()
+	defer a.statusesMu.RUnlock()
+
+	status, exists := a.statuses[providerID]
+	if !exists {
+		return ProviderStatus{}, false
+	}
+	return *status, true
+}
+
 // MergedConfig represents the merged configuration from all sources
 type MergedConfig struct {
 	HTTP *dynamic.HTTPConfiguration `json:"http,omitempty"`
@@ -288,143 +982,98 @@ type ConflictInfo struct {
 	Source         string `json:"source"`
 	OverriddenBy   string `json:"overridden_by"`
 	SourcePriority int    `json:"source_priority"`
+	Resolution     string `json:"resolution"` // priority or pin
 }
 
-// GetMergedConfig returns the merged configuration from all active providers
-// Priority: Local DB > Provider (by priority, higher first)
+// Conflict resolutions
+const (
+	ResolutionPriority = "priority" // The highest priority source won
+	ResolutionPin      = "pin"      // A pin policy chose the source
+)
+
+// GetMergedConfig returns the merged configuration from all active providers.
+// By default local resources win, then providers by priority (higher
+// first, ties broken by name). policies pin a resource to a source or merge
+// the definitions of all sources.
 func (a *AggregatorService) GetMergedConfig(
 	localRouters map[string]*dynamic.Router,
 	localServices map[string]*dynamic.Service,
 	localMiddlewares map[string]*dynamic.Middleware,
 	localServersTransports map[string]*dynamic.ServersTransport,
+	policies []models.ConflictPolicy,
+) (*MergedConfig, []ConflictInfo) {
+	return a.mergeConfig(nil, localRouters, localServices, localMiddlewares, localServersTransports, policies)
+}
+
+// mergeConfig merges the providers' configurations with the local ones.
+// candidate, when set, replaces the provider with the same ID or name.
+func (a *AggregatorService) mergeConfig(
+	candidate *ProviderStatus,
+	localRouters map[string]*dynamic.Router,
+	localServices map[string]*dynamic.Service,
+	localMiddlewares map[string]*dynamic.Middleware,
+	localServersTransports map[string]*dynamic.ServersTransport,
+	policies []models.ConflictPolicy,
 ) (*MergedConfig, []ConflictInfo) {
 	a.statusesMu.RLock()
 	defer a.statusesMu.RUnlock()
 
-	merged := &MergedConfig{
-		HTTP: &dynamic.HTTPConfiguration{
-			Routers:           make(map[string]*dynamic.Router),
-			Services:          make(map[string]*dynamic.Service),
-			Middlewares:       make(map[string]*dynamic.Middleware),
-			Models:            make(map[string]*dynamic.Model),
-			ServersTransports: make(map[string]*dynamic.ServersTransport),
-		},
-	}
-	conflicts := []ConflictInfo{}
-
-	// Track which items came from which source for conflict detection
-	routerSources := make(map[string]string)
-	serviceSources := make(map[string]string)
-	middlewareSources := make(map[string]string)
-	serversTransportSources := make(map[string]string)
-
-	// Add local config first (highest priority)
-	for name, router := range localRouters {
-		merged.HTTP.Routers[name] = router
-		routerSources[name] = "local"
-	}
-	for name, service := range localServices {
-		merged.HTTP.Services[name] = service
-		serviceSources[name] = "local"
-	}
-	for name, middleware := range localMiddlewares {
-		merged.HTTP.Middlewares[name] = middleware
-		middlewareSources[name] = "local"
-	}
-	for name, st := range localServersTransports {
-		merged.HTTP.ServersTransports[name] = st
-		serversTransportSources[name] = "local"
-	}
-
 	// Get sorted statuses by priority (higher first)
-	statuses := make([]*ProviderStatus, 0, len(a.statuses))
+	statuses := make([]*ProviderStatus, 0, len(a.statuses)+1)
 	for _, status := range a.statuses {
-		if status.IsActive && status.Config != nil && status.LastError == "" {
+		if candidate != nil && ((candidate.ID != 0 && status.ID == candidate.ID) || status.Name == candidate.Name) {
+			continue
+		}
+		if status.IsActive && status.Config != nil && status.State != ProviderFailed {
 			statuses = append(statuses, status)
 		}
 	}
+	if candidate != nil {
+		statuses = append(statuses, candidate)
+	}
 	sort.Slice(statuses, func(i, j int) bool {
-		return statuses[i].Priority > statuses[j].Priority
+		if statuses[i].Priority != statuses[j].Priority {
+			return statuses[i].Priority > statuses[j].Priority
+		}
+		return statuses[i].Name < statuses[j].Name
 	})
 
-	// Merge from providers
+	routers := newCandidates(localRouters)
+	services := newCandidates(localServices)
+	middlewares := newCandidates(localMiddlewares)
+	transports := newCandidates(localServersTransports)
 	for _, status := range statuses {
-		if status.Config == nil {
-			continue
-		}
+		routers.add(status, status.Config.Routers)
+		services.add(status, status.Config.Services)
+		middlewares.add(status, status.Config.Middlewares)
+		transports.add(status, status.Config.ServersTransports)
+	}
 
-		// Merge routers
-		for name, router := range status.Config.Routers {
-			if existingSource, exists := routerSources[name]; exists {
-				if existingSource != status.Name {
-					conflicts = append(conflicts, ConflictInfo{
-						Type:           "router",
-						Name:           name,
-						Source:         status.Name,
-						OverriddenBy:   existingSource,
-						SourcePriority: status.Priority,
-					})
-				}
-				continue // Skip, higher priority already has it
-			}
-			merged.HTTP.Routers[name] = router
-			routerSources[name] = status.Name
-		}
+	byKey := make(map[string]models.ConflictPolicy, len(policies))
+	for _, policy := range policies {
+		byKey[policyKey(policy.ResourceType, policy.ResourceName)] = policy
+	}
 
-		// Merge services
-		for name, service := range status.Config.Services {
-			if existingSource, exists := serviceSources[name]; exists {
-				if existingSource != status.Name {
-					conflicts = append(conflicts, ConflictInfo{
-						Type:           "service",
-						Name:           name,
-						Source:         status.Name,
-						OverriddenBy:   existingSource,
-						SourcePriority: status.Priority,
-					})
-				}
-				continue
-			}
-			merged.HTTP.Services[name] = service
-			serviceSources[name] = status.Name
-		}
+	conflicts := []ConflictInfo{}
+	merged := &MergedConfig{
+		HTTP: &dynamic.HTTPConfiguration{
+			Routers:           routers.merge("router", byKey, mergeRouters, &conflicts),
+			Services:          services.merge("service", byKey, mergeServices, &conflicts),
+			Middlewares:       middlewares.merge("middleware", byKey, nil, &conflicts),
+			Models:            make(map[string]*dynamic.Model),
+			ServersTransports: transports.merge("serversTransport", byKey, nil, &conflicts),
+		},
+	}
 
-		// Merge middlewares
-		for name, middleware := range status.Config.Middlewares {
-			if existingSource, exists := middlewareSources[name]; exists {
-				if existingSource != status.Name {
-					conflicts = append(conflicts, ConflictInfo{
-						Type:           "middleware",
-						Name:           name,
-						Source:         status.Name,
-						OverriddenBy:   existingSource,
-						SourcePriority: status.Priority,
-					})
-				}
-				continue
-			}
-			merged.HTTP.Middlewares[name] = middleware
-			middlewareSources[name] = status.Name
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Type != conflicts[j].Type {
+			return conflicts[i].Type < conflicts[j].Type
 		}
-
-		// Merge servers transports
-		for name, st := range status.Config.ServersTransports {
-			if existingSource, exists := serversTransportSources[name]; exists {
-				if existingSource != status.Name {
-					conflicts = append(conflicts, ConflictInfo{
-						Type:           "serversTransport",
-						Name:           name,
-						Source:         status.Name,
-						OverriddenBy:   existingSource,
-						SourcePriority: status.Priority,
-					})
-				}
-				continue
-			}
-			merged.HTTP.ServersTransports[name] = st
-			serversTransportSources[name] = status.Name
+		if conflicts[i].Name != conflicts[j].Name {
+			return conflicts[i].Name < conflicts[j].Name
 		}
-	}
+		return conflicts[i].Source < conflicts[j].Source
+	})
 
 	return merged, conflicts
 }
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/models"
+	"github.com/traefikx/backend/internal/secrets"
+	"go.uber.org/goleak"
+	"gorm.io/gorm"
+)
+
+// startFakeProvider serves a provider config with a single router
+func startFakeProvider(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"http":{"routers":{"app":{"rule":"Host(` + "`app.example.com`" + `)","service":"app"}},` +
+			`"services":{"app":{"loadBalancer":{"servers":[{"url":"http://app:8080"}]}}}}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// createProvider stores an active HTTP provider
+func createProvider(t *testing.T, db *gorm.DB, name, url string) *models.HTTPProvider {
+	t.Helper()
+	provider := &models.HTTPProvider{Name: name, URL: url, IsActive: true, RefreshInterval: 30, Timeout: 5}
+	if err := db.Create(provider).Error; err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+	return provider
+}
+
+// waitForState polls a provider's status until it reaches state
+func waitForState(t *testing.T, a *AggregatorService, id uint, state string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if status, ok := a.GetStatus(id); ok && status.State == state {
+			return
+		}
+		if time.Now().After(deadline) {
+			status, _ := a.GetStatus(id)
+			t.Fatalf("timed out waiting for provider %d to be %s, last status %+v", id, state, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAggregator_StopEndsPollers(t *testing.T) {
+	db := newTestDB(t)
+	server := startFakeProvider(t)
+	kept := createProvider(t, db, "kept", server.URL)
+	deleted := createProvider(t, db, "deleted", server.URL)
+
+	// Goroutines of the database and test server outlive the test body
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	defer server.CloseClientConnections()
+
+	a := NewAggregatorService(db, events.NewBus(), 4, 10, 0)
+	a.Start()
+	waitForState(t, a, kept.ID, ProviderHealthy)
+	waitForState(t, a, deleted.ID, ProviderHealthy)
+
+	deleted.RefreshInterval = 60
+	a.UpdateProvider(context.Background(), deleted)
+	if err := db.Delete(deleted).Error; err != nil {
+		t.Fatalf("delete provider: %v", err)
+	}
+	a.DeleteProvider(deleted.ID)
+	a.Stop()
+
+	a.Start()
+	waitForState(t, a, kept.ID, ProviderHealthy)
+	if _, ok := a.GetStatus(deleted.ID); ok {
+		t.Error("expected the deleted provider not to be polled after a restart")
+	}
+	a.Stop()
+
+	a.pollersMu.Lock()
+	defer a.pollersMu.Unlock()
+	if len(a.pollers) != 0 {
+		t.Errorf("expected no pollers after Stop, got %d", len(a.pollers))
+	}
+}
+
+func TestAggregator_StartTwice(t *testing.T) {
+	db := newTestDB(t)
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte(`{"http":{}}`))
+	}))
+	defer server.Close()
+	provider := createProvider(t, db, "app", server.URL)
+
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	defer server.CloseClientConnections()
+
+	a := NewAggregatorService(db, events.NewBus(), 4, 10, 0)
+	a.Start()
+	waitForState(t, a, provider.ID, ProviderHealthy)
+
+	// A second Start neither restarts the pollers nor listens twice
+	a.Start()
+	time.Sleep(100 * time.Millisecond)
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("expected a single fetch, got %d", n)
+	}
+
+	a.Stop()
+	if a.Running() {
+		t.Error("expected a single Stop to stop the aggregator")
+	}
+	a.Stop()
+}
+
+func TestAggregator_FetchKeepsConcurrentEdits(t *testing.T) {
+	db := newTestDB(t)
+	server := startFakeProvider(t)
+	provider := createProvider(t, db, "app", server.URL)
+
+	// Edited by an admin while the fetch is in flight
+	if err := db.Model(&models.HTTPProvider{ID: provider.ID}).Updates(map[string]interface{}{"name": "renamed", "priority": 5}).Error; err != nil {
+		t.Fatalf("update provider: %v", err)
+	}
+	a := NewAggregatorService(db, events.NewBus(), 4, 10, 0)
+	a.fetchProvider(context.Background(), provider)
+
+	var stored models.HTTPProvider
+	if err := db.First(&stored, provider.ID).Error; err != nil {
+		t.Fatalf("load provider: %v", err)
+	}
+	if stored.Name != "renamed" || stored.Priority != 5 {
+		t.Errorf("expected the edit to be kept, got name %q priority %d", stored.Name, stored.Priority)
+	}
+	if stored.State != ProviderHealthy || stored.RouterCount != 1 || stored.LastFetched == nil || len(stored.LastResponse) == 0 {
+		t.Errorf("expected the fetch state to be stored, got %+v", stored)
+	}
+
+	// The response may hold credentials, it is stored encrypted
+	var raw string
+	db.Table("http_providers").Select("last_response").Where("id = ?", provider.ID).Scan(&raw)
+	if secrets.StoredKeyID(raw) != secrets.KeyID() {
+		t.Errorf("expected the response to be encrypted, stored %q", raw)
+	}
+}
+
+func TestAggregator_LimitsResponseSize(t *testing.T) {
+	db := newTestDB(t)
+	server := startFakeProvider(t)
+	provider := createProvider(t, db, "app", server.URL)
+	a := NewAggregatorService(db, events.NewBus(), 4, 10, 0)
+
+	// The fake provider's response is about 150 bytes
+	a.SetMaxResponseSize(64)
+	a.fetchProvider(context.Background(), provider)
+	status, _ := a.GetStatus(provider.ID)
+	if status.State == ProviderHealthy || !strings.Contains(status.LastError, "Response too large") {
+		t.Errorf("expected the fetch to fail, got state %s error %q", status.State, status.LastError)
+	}
+	var fetch models.ProviderFetch
+	if err := db.Last(&fetch, "provider_id = ?", provider.ID).Error; err != nil || fetch.Success || !strings.Contains(fetch.Error, "Response too large") {
+		t.Errorf("expected a failed fetch in the history, got %+v, %v", fetch, err)
+	}
+
+	a.SetMaxResponseSize(1024)
+	a.fetchProvider(context.Background(), provider)
+	if status, _ := a.GetStatus(provider.ID); status.State != ProviderHealthy {
+		t.Errorf("expected the fetch to succeed within the limit, got %s: %s", status.State, status.LastError)
+	}
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/metrics"
+	"github.com/traefikx/backend/internal/models"
+	"github.com/traefikx/backend/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// ConfigSnapshot is a compiled, immutable view of the configuration served
+// to Traefik. Callers must not modify it.
+type ConfigSnapshot struct {
+	Config    *dynamic.Configuration
+	JSON      []byte // Canonical JSON encoding of Config
+	Hash      string // SHA-256 of JSON
+	Conflicts []ConflictInfo
+	Version   uint64
+	BuiltAt   time.Time
+	BuildTime time.Duration
+
+	LocalRouterCount     int
+	LocalServiceCount    int
+	LocalMiddlewareCount int
+}
+
+// ConfigCompiler keeps the merged Traefik configuration in memory and
+// rebuilds it when local resources or HTTP providers change
+type ConfigCompiler struct {
+	db         *gorm.DB
+	aggregator *AggregatorService
+	bus        *events.Bus
+
+	isLeader func() bool // Whether this replica records conflicts, nil outside HA mode
+
+	snapshot  atomic.Pointer[ConfigSnapshot]
+	version   atomic.Uint64
+	compileMu sync.Mutex
+	stopChan  chan struct{}
+	done      chan struct{}
+}
+
+// NewConfigCompiler creates a compiler. aggregator may be nil.
+func NewConfigCompiler(db *gorm.DB, aggregator *AggregatorService, bus *events.Bus) *ConfigCompiler {
+	return &ConfigCompiler{
+		db:         db,
+		aggregator: aggregator,
+		bus:        bus,
+		stopChan:   make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// SetLeaderCheck makes only the replica for which isLeader returns true
+// record conflicts, in HA mode. Call it before Start.
+func (c *ConfigCompiler) SetLeaderCheck(isLeader func() bool) {
+	c.isLeader = isLeader
+}
+
+// Start compiles the initial snapshot and rebuilds it on change events
+func (c *ConfigCompiler) Start() {
+	changes, unsubscribe := c.bus.Subscribe(64, events.ConfigChanged, events.ProviderUpdated)
+
+	if _, err := c.Rebuild(context.Background()); err != nil {
+		slog.Error("Failed to compile initial config", "error", err)
+	}
+
+	go func() {
+		defer close(c.done)
+		defer unsubscribe()
+
+		for {
+			select {
+			case event := <-changes:
+				// Coalesce bursts of changes into one rebuild, linked to
+				// the requests and fetches that made them
+				links := c.drain(changes, appendLink(nil, event))
+				if _, err := c.rebuild(context.Background(), trace.WithLinks(links...)); err != nil {
+					slog.Error("Failed to compile config", "error", err)
+				}
+			case <-c.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the rebuild loop
+func (c *ConfigCompiler) Stop() {
+	close(c.stopChan)
+	<-c.done
+}
+
+// drain empties changes, adding links to the spans that published them
+func (c *ConfigCompiler) drain(changes <-chan events.Event, links []trace.Link) []trace.Link {
+	for {
+		select {
+		case event := <-changes:
+			links = appendLink(links, event)
+		default:
+			return links
+		}
+	}
+}
+
+func appendLink(links []trace.Link, event events.Event) []trace.Link {
+	if event.Span.IsValid() {
+		links = append(links, trace.Link{SpanContext: event.Span})
+	}
+	return links
+}
+
+// Snapshot returns the current snapshot, compiling one if none exists yet
+func (c *ConfigCompiler) Snapshot(ctx context.Context) (*ConfigSnapshot, error) {
+	if snapshot := c.snapshot.Load(); snapshot != nil {
+		return snapshot, nil
+	}
+	return c.Rebuild(ctx)
+}
+
+// Rebuild compiles a new snapshot from the database and the aggregator
+func (c *ConfigCompiler) Rebuild(ctx context.Context) (*ConfigSnapshot, error) {
+	return c.rebuild(ctx)
+}
+
+func (c *ConfigCompiler) rebuild(ctx context.Context, opts ...trace.SpanStartOption) (*ConfigSnapshot, error) {
+	ctx, span := tracing.Start(ctx, "config.compile", opts...)
+	defer span.End()
+
+	c.compileMu.Lock()
+	defer c.compileMu.Unlock()
+
+	start := time.Now()
+
+	routers, middlewares, policies, err := c.load(ctx)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, err
+	}
+
+	snapshot, err := c.compile(ctx, routers, middlewares, policies)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	snapshot.BuildTime = time.Since(start)
+	metrics.ObserveCompile(snapshot.BuildTime)
+	span.SetAttributes(
+		attribute.Int64("config.version", int64(snapshot.Version)),
+		attribute.String("config.hash", snapshot.Hash),
+		attribute.Int("config.size", len(snapshot.JSON)),
+		attribute.Int("config.conflicts", len(snapshot.Conflicts)),
+	)
+
+	// Rebuilds that produce the same config aren't announced
+	previous := c.snapshot.Swap(snapshot)
+	if previous == nil || previous.Hash != snapshot.Hash {
+		c.bus.PublishContext(ctx, events.ConfigCompiled, events.ConfigVersion{Version: snapshot.Version, Hash: snapshot.Hash})
+	}
+
+	if c.isLeader != nil && !c.isLeader() {
+		return snapshot, nil
+	}
+
+	// A failure to record conflicts doesn't invalidate the snapshot
+	detected, err := RecordConflicts(c.db.WithContext(ctx), snapshot.Conflicts, snapshot.BuiltAt)
+	if err != nil {
+		slog.Error("Failed to record config conflicts", "error", err)
+	} else if len(detected) > 0 {
+		for _, conflict := range detected {
+			slog.Warn("Config conflict", "type", conflict.Type, "name", conflict.Name, "source", conflict.Source,
+				"overridden_by", conflict.OverriddenBy, "priority", conflict.SourcePriority, "resolution", conflict.Resolution)
+		}
+		c.bus.PublishContext(ctx, events.ConflictDetected, detected)
+	}
+
+	return snapshot, nil
+}
+
+// load reads the active local resources and the conflict policies
+func (c *ConfigCompiler) load(ctx context.Context) ([]models.Router, []models.Middleware, []models.ConflictPolicy, error) {
+	db := c.db.WithContext(ctx)
+
+	var routers []models.Router
+	if err := db.Where("is_active = ?", true).
+		Preload("Hostnames").
+		Preload("Service.Servers").
+		Preload("Middlewares.Middleware").
+		Find(&routers).Error; err != nil {
+		return nil, nil, nil, err
+	}
+
+	var middlewares []models.Middleware
+	if err := db.Where("is_active = ?", true).Find(&middlewares).Error; err != nil {
+		return nil, nil, nil, err
+	}
+
+	var policies []models.ConflictPolicy
+	if err := db.Find(&policies).Error; err != nil {
+		return nil, nil, nil, err
+	}
+
+	return routers, middlewares, policies, nil
+}
+
+// MergePreview shows how a provider's configuration would be merged,
+// without changing the served configuration
+type MergePreview struct {
+	Served    ResourceNames  `json:"served"`    // The provider's resources that would be served
+	Conflicts []ConflictInfo `json:"conflicts"` // Conflicts the provider would take part in
+	Changes   ConfigDiff     `json:"changes"`   // Differences with the served configuration
+}
+
+// ResourceNames lists resources by type
+type ResourceNames struct {
+	Routers     []string `json:"routers"`
+	Services    []string `json:"services"`
+	Middlewares []string `json:"middlewares"`
+}
+
+// PreviewProvider merges config as provider's configuration, in place of
+// the provider's current one or of a provider with the same name. Inactive
+// or unsaved providers are previewed as if they were active.
+func (c *ConfigCompiler) PreviewProvider(ctx context.Context, provider *models.HTTPProvider, config *dynamic.HTTPConfiguration) (*MergePreview, error) {
+	if c.aggregator == nil {
+		return nil, fmt.Errorf("aggregator service not available")
+	}
+
+	current, err := c.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	routers, middlewares, policies, err := c.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	local := CompileLocal(routers, middlewares)
+	candidate := &ProviderStatus{
+		ID:       provider.ID,
+		Name:     provider.Name,
+		Priority: provider.Priority,
+		IsActive: true,
+		Config:   config,
+	}
+	merged, conflicts := c.aggregator.mergeConfig(candidate, local.Routers, local.Services, local.Middlewares, local.ServersTransports, policies)
+
+	preview := &MergePreview{Conflicts: []ConflictInfo{}}
+	lost := map[string]bool{}
+	for _, conflict := range conflicts {
+		if conflict.Source == provider.Name {
+			lost[policyKey(conflict.Type, conflict.Name)] = true
+		}
+		if conflict.Source == provider.Name || conflict.OverriddenBy == provider.Name {
+			preview.Conflicts = append(preview.Conflicts, conflict)
+		}
+	}
+	preview.Served.Routers = servedNames("router", config.Routers, lost)
+	preview.Served.Services = servedNames("service", config.Services, lost)
+	preview.Served.Middlewares = servedNames("middleware", config.Middlewares, lost)
+
+	var served *dynamic.HTTPConfiguration
+	if current.Config != nil {
+		served = current.Config.HTTP
+	}
+	preview.Changes = DiffConfigs(served, merged.HTTP)
+
+	return preview, nil
+}
+
+// servedNames returns the sorted names of resources not overridden by
+// another source
+func servedNames[T any](resourceType string, resources map[string]T, lost map[string]bool) []string {
+	names := []string{}
+	for name := range resources {
+		if !lost[policyKey(resourceType, name)] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// compile merges local resources with the providers' configurations
+func (c *ConfigCompiler) compile(ctx context.Context, routers []models.Router, middlewares []models.Middleware, policies []models.ConflictPolicy) (*ConfigSnapshot, error) {
+	local := CompileLocal(routers, middlewares)
+
+	config := &dynamic.Configuration{HTTP: local}
+	conflicts := []ConflictInfo{}
+	if c.aggregator != nil {
+		// Followers merge the providers as last fetched by the leader
+		if err := c.aggregator.SyncStatuses(ctx); err != nil {
+			return nil, err
+		}
+		_, span := tracing.Start(ctx, "config.merge")
+		merged, mergeConflicts := c.aggregator.GetMergedConfig(
+			local.Routers,
+			local.Services,
+			local.Middlewares,
+			local.ServersTransports,
+			policies,
+		)
+		if merged != nil && merged.HTTP != nil {
+			config.HTTP = merged.HTTP
+		}
+		conflicts = mergeConflicts
+		span.SetAttributes(attribute.Int("config.conflicts", len(conflicts)))
+		span.End()
+	}
+
+	// Map keys are sorted by encoding/json, which keeps the hash stable
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+
+	return &ConfigSnapshot{
+		Config:               config,
+		JSON:                 data,
+		Hash:                 hex.EncodeToString(sum[:]),
+		Conflicts:            conflicts,
+		Version:              c.version.Add(1),
+		BuiltAt:              time.Now(),
+		LocalRouterCount:     len(local.Routers),
+		LocalServiceCount:    len(local.Services),
+		LocalMiddlewareCount: len(local.Middlewares),
+	}, nil
+}
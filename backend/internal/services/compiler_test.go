@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// benchmarkResources is the number of routers, services and middlewares
+// the benchmarks compile
+const benchmarkResources = 5000
+
+// seedResources creates n services with one server each, n headers
+// middlewares and n routers using them
+func seedResources(tb testing.TB, db *gorm.DB, n int) {
+	tb.Helper()
+
+	services := make([]models.Service, n)
+	for i := range services {
+		services[i] = models.Service{
+			Name:    fmt.Sprintf("service-%d", i),
+			Servers: []models.ServiceServer{{URL: fmt.Sprintf("http://10.0.%d.%d:8080", i/256, i%256)}},
+		}
+	}
+	if err := db.CreateInBatches(services, 500).Error; err != nil {
+		tb.Fatalf("create services: %v", err)
+	}
+
+	middlewares := make([]models.Middleware, n)
+	for i := range middlewares {
+		middlewares[i] = models.Middleware{
+			Name:   fmt.Sprintf("middleware-%d", i),
+			Type:   "headers",
+			Config: fmt.Sprintf(`{"customRequestHeaders":{"X-Index":"%d"}}`, i),
+		}
+	}
+	if err := db.CreateInBatches(middlewares, 500).Error; err != nil {
+		tb.Fatalf("create middlewares: %v", err)
+	}
+
+	routers := make([]models.Router, n)
+	for i := range routers {
+		routers[i] = models.Router{
+			Name:        fmt.Sprintf("router-%d", i),
+			Hostnames:   []models.RouterHostname{{Hostname: fmt.Sprintf("app-%d.example.com", i)}},
+			ServiceID:   services[i].ID,
+			UserID:      1,
+			Middlewares: []models.RouterMiddleware{{MiddlewareID: middlewares[i].ID}},
+		}
+	}
+	if err := db.CreateInBatches(routers, 500).Error; err != nil {
+		tb.Fatalf("create routers: %v", err)
+	}
+}
+
+func TestConfigCompiler_PublishesChangedConfig(t *testing.T) {
+	db := newTestDB(t)
+	seedResources(t, db, 3)
+
+	bus := events.NewBus()
+	compiled, unsubscribe := bus.Subscribe(16, events.ConfigCompiled)
+	defer unsubscribe()
+	compiler := NewConfigCompiler(db, nil, bus)
+	ctx := context.Background()
+
+	first, err := compiler.Rebuild(ctx)
+	if err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	if _, err := compiler.Rebuild(ctx); err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	if err := db.Model(&models.Router{}).Where("name = ?", "router-0").Update("entry_points", "websecure").Error; err != nil {
+		t.Fatalf("update router: %v", err)
+	}
+	changed, err := compiler.Rebuild(ctx)
+	if err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	if changed.Hash == first.Hash {
+		t.Fatal("expected the router change to change the hash")
+	}
+
+	var published []events.ConfigVersion
+	for len(compiled) > 0 {
+		published = append(published, (<-compiled).Data.(events.ConfigVersion))
+	}
+	if len(published) != 2 {
+		t.Fatalf("expected 2 config.compiled events, got %d: %+v", len(published), published)
+	}
+	if published[0].Hash != first.Hash || published[1].Hash != changed.Hash {
+		t.Errorf("unexpected published versions %+v", published)
+	}
+}
+
+func BenchmarkConfigCompiler_Rebuild(b *testing.B) {
+	db := newTestDB(b)
+	seedResources(b, db, benchmarkResources)
+	compiler := NewConfigCompiler(db, nil, events.NewBus())
+	ctx := context.Background()
+
+	for b.Loop() {
+		if _, err := compiler.Rebuild(ctx); err != nil {
+			b.Fatalf("Rebuild: %v", err)
+		}
+	}
+}
+
+func BenchmarkCompileLocal(b *testing.B) {
+	db := newTestDB(b)
+	seedResources(b, db, benchmarkResources)
+	routers, middlewares, _, err := NewConfigCompiler(db, nil, events.NewBus()).load(context.Background())
+	if err != nil {
+		b.Fatalf("load: %v", err)
+	}
+
+	for b.Loop() {
+		CompileLocal(routers, middlewares)
+	}
+}
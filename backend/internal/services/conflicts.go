@@ -0,0 +1,98 @@
+package services
+
+import (
+	"time"
+
+	"github.com/traefikx/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// RecordConflicts persists the conflicts of a compiled configuration and
+// returns those that are new, or back after being resolved. Recorded
+// conflicts that are no longer present are marked resolved.
+func RecordConflicts(db *gorm.DB, conflicts []ConflictInfo, now time.Time) ([]ConflictInfo, error) {
+	detected := []ConflictInfo{}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing []models.ConfigConflict
+		if err := tx.Find(&existing).Error; err != nil {
+			return err
+		}
+		byKey := make(map[string]*models.ConfigConflict, len(existing))
+		for i := range existing {
+			byKey[conflictKey(existing[i].ResourceType, existing[i].ResourceName, existing[i].Source)] = &existing[i]
+		}
+
+		current := make(map[string]bool, len(conflicts))
+		seen := []uint{}
+		for _, conflict := range conflicts {
+			key := conflictKey(conflict.Type, conflict.Name, conflict.Source)
+			current[key] = true
+
+			record, exists := byKey[key]
+			if !exists {
+				detected = append(detected, conflict)
+				if err := tx.Create(&models.ConfigConflict{
+					ResourceType:   conflict.Type,
+					ResourceName:   conflict.Name,
+					Source:         conflict.Source,
+					OverriddenBy:   conflict.OverriddenBy,
+					SourcePriority: conflict.SourcePriority,
+					Resolution:     conflict.Resolution,
+					FirstSeen:      now,
+					LastSeen:       now,
+				}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			if record.ResolvedAt != nil {
+				detected = append(detected, conflict)
+				record.FirstSeen = now
+			} else if record.OverriddenBy == conflict.OverriddenBy &&
+				record.SourcePriority == conflict.SourcePriority &&
+				record.Resolution == conflict.Resolution {
+				seen = append(seen, record.ID)
+				continue
+			}
+			if err := tx.Model(record).Updates(map[string]interface{}{
+				"overridden_by":   conflict.OverriddenBy,
+				"source_priority": conflict.SourcePriority,
+				"resolution":      conflict.Resolution,
+				"first_seen":      record.FirstSeen,
+				"last_seen":       now,
+				"resolved_at":     nil,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(seen) > 0 {
+			if err := tx.Model(&models.ConfigConflict{}).Where("id IN ?", seen).
+				Update("last_seen", now).Error; err != nil {
+				return err
+			}
+		}
+
+		resolved := []uint{}
+		for key, record := range byKey {
+			if record.ResolvedAt == nil && !current[key] {
+				resolved = append(resolved, record.ID)
+			}
+		}
+		if len(resolved) > 0 {
+			return tx.Model(&models.ConfigConflict{}).Where("id IN ?", resolved).
+				Update("resolved_at", now).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return detected, nil
+}
+
+func conflictKey(resourceType, name, source string) string {
+	return resourceType + "/" + name + "/" + source
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/models"
+)
+
+func TestRecordConflicts(t *testing.T) {
+	db := newTestDB(t)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	app := ConflictInfo{Type: "router", Name: "app", Source: "edge", OverriddenBy: LocalSource, SourcePriority: 1, Resolution: ResolutionPriority}
+	api := ConflictInfo{Type: "service", Name: "api", Source: "edge", OverriddenBy: "core", SourcePriority: 1, Resolution: ResolutionPriority}
+
+	record := func(at time.Duration, conflicts ...ConflictInfo) []ConflictInfo {
+		t.Helper()
+		detected, err := RecordConflicts(db, conflicts, start.Add(at))
+		if err != nil {
+			t.Fatalf("RecordConflicts: %v", err)
+		}
+		return detected
+	}
+	stored := func(name string) models.ConfigConflict {
+		t.Helper()
+		var conflict models.ConfigConflict
+		if err := db.Where("resource_name = ?", name).First(&conflict).Error; err != nil {
+			t.Fatalf("load conflict %s: %v", name, err)
+		}
+		return conflict
+	}
+
+	if detected := record(0, app, api); len(detected) != 2 {
+		t.Fatalf("expected both conflicts to be new, got %+v", detected)
+	}
+
+	// Conflicts already recorded are only seen again
+	if detected := record(time.Minute, app, api); len(detected) != 0 {
+		t.Errorf("expected no new conflicts, got %+v", detected)
+	}
+	if conflict := stored("app"); !conflict.FirstSeen.Equal(start) || !conflict.LastSeen.Equal(start.Add(time.Minute)) {
+		t.Errorf("unexpected first/last seen %v/%v", conflict.FirstSeen, conflict.LastSeen)
+	}
+
+	// Nor are they new when another source wins
+	pinned := app
+	pinned.OverriddenBy, pinned.Resolution = "core", ResolutionPin
+	if detected := record(2*time.Minute, pinned, api); len(detected) != 0 {
+		t.Errorf("expected a changed winner not to be new, got %+v", detected)
+	}
+	if conflict := stored("app"); conflict.OverriddenBy != "core" || conflict.Resolution != ResolutionPin {
+		t.Errorf("expected the winner to be updated, got %+v", conflict)
+	}
+
+	// Conflicts that are gone are resolved, and new again when they're back
+	if detected := record(3*time.Minute, api); len(detected) != 0 {
+		t.Errorf("expected no new conflicts, got %+v", detected)
+	}
+	if conflict := stored("app"); conflict.ResolvedAt == nil || !conflict.ResolvedAt.Equal(start.Add(3*time.Minute)) {
+		t.Errorf("expected app to be resolved, got %+v", conflict)
+	}
+	if conflict := stored("api"); conflict.ResolvedAt != nil {
+		t.Errorf("expected api to stay unresolved, got %+v", conflict)
+	}
+
+	detected := record(4*time.Minute, app, api)
+	if len(detected) != 1 || detected[0] != app {
+		t.Errorf("expected app to be new again, got %+v", detected)
+	}
+	if conflict := stored("app"); conflict.ResolvedAt != nil || !conflict.FirstSeen.Equal(start.Add(4*time.Minute)) {
+		t.Errorf("expected app to be reopened, got %+v", conflict)
+	}
+
+	var count int64
+	db.Model(&models.ConfigConflict{}).Count(&count)
+	if count != 2 {
+		t.Errorf("expected one record per conflict, got %d", count)
+	}
+}
+
+func TestConfigCompiler_NotifiesConflictsOnce(t *testing.T) {
+	db := newTestDB(t)
+	server := startFakeProvider(t)
+	provider := createProvider(t, db, "edge", server.URL)
+
+	// The provider serves a router and a service named app, like the local ones
+	service := models.Service{Name: "app", Servers: []models.ServiceServer{{URL: "http://local:8080"}}}
+	if err := db.Create(&service).Error; err != nil {
+		t.Fatalf("create service: %v", err)
+	}
+	router := models.Router{
+		Name:      "app",
+		Hostnames: []models.RouterHostname{{Hostname: "app.example.com"}},
+		ServiceID: service.ID,
+		UserID:    1,
+	}
+	if err := db.Create(&router).Error; err != nil {
+		t.Fatalf("create router: %v", err)
+	}
+
+	a := NewAggregatorService(db, nil, 1, 0, 0)
+	a.fetchProvider(context.Background(), provider)
+
+	bus := events.NewBus()
+	detected, unsubscribe := bus.Subscribe(16, events.ConflictDetected)
+	defer unsubscribe()
+	compiler := NewConfigCompiler(db, a, bus)
+
+	for range 3 {
+		if _, err := compiler.Rebuild(context.Background()); err != nil {
+			t.Fatalf("Rebuild: %v", err)
+		}
+	}
+
+	if len(detected) != 1 {
+		t.Fatalf("expected a single conflict.detected event, got %d", len(detected))
+	}
+	conflicts := (<-detected).Data.([]ConflictInfo)
+	if len(conflicts) != 2 || !hasConflictFrom(conflicts, "router", "app", "edge") || !hasConflictFrom(conflicts, "service", "app", "edge") {
+		t.Errorf("unexpected conflicts %+v", conflicts)
+	}
+
+	var count int64
+	db.Model(&models.ConfigConflict{}).Where("resolved_at IS NULL").Count(&count)
+	if count != 2 {
+		t.Errorf("expected 2 recorded conflicts, got %d", count)
+	}
+}
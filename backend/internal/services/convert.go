@@ -0,0 +1,185 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"github.com/traefikx/backend/internal/models"
+)
+
+// CompileLocal converts active routers and middlewares from the database into
+// Traefik dynamic configuration. Routers need Hostnames, Service.Servers and
+// Middlewares.Middleware preloaded. Only services used by a router are included.
+func CompileLocal(routers []models.Router, middlewares []models.Middleware) *dynamic.HTTPConfiguration {
+	config := &dynamic.HTTPConfiguration{
+		Routers:           make(map[string]*dynamic.Router),
+		Services:          make(map[string]*dynamic.Service),
+		Middlewares:       make(map[string]*dynamic.Middleware),
+		Models:            make(map[string]*dynamic.Model),
+		ServersTransports: make(map[string]*dynamic.ServersTransport),
+	}
+
+	for i := range routers {
+		router := &routers[i]
+		if !router.IsActive || len(router.Hostnames) == 0 {
+			continue
+		}
+
+		config.Routers[router.Name] = BuildRouterConfig(router)
+
+		if _, exists := config.Services[router.Service.Name]; !exists {
+			config.Services[router.Service.Name] = BuildServiceConfig(&router.Service)
+		}
+
+		// Redirect-to-https middleware generated for the router
+		if router.RedirectHTTPS {
+			config.Middlewares[redirectMiddlewareName(router.Name)] = &dynamic.Middleware{
+				RedirectScheme: &dynamic.RedirectScheme{
+					Scheme:    "https",
+					Port:      "443",
+					Permanent: true,
+				},
+			}
+		}
+	}
+
+	for i := range middlewares {
+		if !middlewares[i].IsActive {
+			continue
+		}
+		if middlewareConfig := BuildMiddlewareConfig(&middlewares[i]); middlewareConfig != nil {
+			config.Middlewares[middlewares[i].Name] = middlewareConfig
+		}
+	}
+
+	return config
+}
+
+func redirectMiddlewareName(routerName string) string {
+	return fmt.Sprintf("%s-redirect-https", routerName)
+}
+
+// BuildRouterConfig converts a router model. TLS is not emitted yet; routers
+// are served on their entry points and TLS is configured on the Traefik side.
+func BuildRouterConfig(router *models.Router) *dynamic.Router {
+	middlewareNames := []string{}
+	if router.RedirectHTTPS {
+		middlewareNames = append(middlewareNames, redirectMiddlewareName(router.Name))
+	}
+
+	// Keep execution order
+	routerMiddlewares := append([]models.RouterMiddleware(nil), router.Middlewares...)
+	sort.SliceStable(routerMiddlewares, func(i, j int) bool {
+		return routerMiddlewares[i].Priority < routerMiddlewares[j].Priority
+	})
+	for _, rm := range routerMiddlewares {
+		if rm.Middleware.IsActive {
+			middlewareNames = append(middlewareNames, rm.Middleware.Name)
+		}
+	}
+
+	return &dynamic.Router{
+		EntryPoints: splitEntryPoints(router.EntryPoints),
+		Rule:        buildRule(router.Hostnames),
+		Service:     router.Service.Name,
+		Middlewares: middlewareNames,
+	}
+}
+
+// BuildServiceConfig converts a service model to a load balancer service
+func BuildServiceConfig(service *models.Service) *dynamic.Service {
+	servers := make([]dynamic.Server, 0, len(service.Servers))
+	for _, s := range service.Servers {
+		servers = append(servers, dynamic.Server{
+			URL: s.URL,
+		})
+	}
+
+	passHostHeader := service.PassHostHeader
+	config := &dynamic.Service{
+		LoadBalancer: &dynamic.ServersLoadBalancer{
+			Servers:        servers,
+			PassHostHeader: &passHostHeader,
+		},
+	}
+
+	if service.HealthCheckEnabled && service.HealthCheckPath != "" {
+		config.LoadBalancer.HealthCheck = &dynamic.ServerHealthCheck{
+			Path: service.HealthCheckPath,
+		}
+	}
+
+	return config
+}
+
+// BuildMiddlewareConfig converts a middleware model. It returns nil for
+// unknown types or invalid stored config.
+func BuildMiddlewareConfig(middleware *models.Middleware) *dynamic.Middleware {
+	var config models.MiddlewareConfig
+	if err := json.Unmarshal([]byte(middleware.Config), &config); err != nil {
+		return nil
+	}
+
+	switch middleware.Type {
+	case "redirectScheme":
+		return &dynamic.Middleware{
+			RedirectScheme: &dynamic.RedirectScheme{
+				Scheme:    config.Scheme,
+				Port:      config.Port,
+				Permanent: config.Permanent,
+			},
+		}
+	case "headers":
+		return &dynamic.Middleware{
+			Headers: &dynamic.Headers{
+				CustomRequestHeaders:  config.CustomRequestHeaders,
+				CustomResponseHeaders: config.CustomResponseHeaders,
+			},
+		}
+	case "stripPrefix":
+		return &dynamic.Middleware{
+			StripPrefix: &dynamic.StripPrefix{
+				Prefixes: config.Prefixes,
+			},
+		}
+	case "addPrefix":
+		return &dynamic.Middleware{
+			AddPrefix: &dynamic.AddPrefix{
+				Prefix: config.Prefix,
+			},
+		}
+	default:
+		return nil
+	}
+}
+
+func buildRule(hostnames []models.RouterHostname) string {
+	if len(hostnames) == 0 {
+		return ""
+	}
+	if len(hostnames) == 1 {
+		return fmt.Sprintf("Host(`%s`)", hostnames[0].Hostname)
+	}
+	// Multiple hostnames - use Host()
+	hosts := make([]string, len(hostnames))
+	for i, h := range hostnames {
+		hosts[i] = fmt.Sprintf("`%s`", h.Hostname)
+	}
+	return fmt.Sprintf("Host(%s)", strings.Join(hosts, ", "))
+}
+
+func splitEntryPoints(ep string) []string {
+	parts := []string{}
+	for _, p := range strings.Split(ep, ",") {
+		if trimmed := strings.TrimSpace(p); trimmed != "" {
+			parts = append(parts, trimmed)
+		}
+	}
+	if len(parts) == 0 {
+		return []string{"web", "websecure"}
+	}
+	return parts
+}
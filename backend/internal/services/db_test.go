@@ -0,0 +1,36 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/traefikx/backend/internal/database"
+	"github.com/traefikx/backend/internal/secrets"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB returns a migrated SQLite database in a temporary directory
+func newTestDB(tb testing.TB) *gorm.DB {
+	tb.Helper()
+	if err := secrets.Init("services-test-key"); err != nil {
+		tb.Fatalf("secrets: %v", err)
+	}
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(tb.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		tb.Fatalf("open database: %v", err)
+	}
+	if _, err := database.MigrateUp(db); err != nil {
+		tb.Fatalf("migrate: %v", err)
+	}
+	tb.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
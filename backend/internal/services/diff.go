@@ -0,0 +1,69 @@
+package services
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// ConfigDiff lists the resources that differ between two configurations
+type ConfigDiff struct {
+	Routers     ResourceDiff `json:"routers"`
+	Services    ResourceDiff `json:"services"`
+	Middlewares ResourceDiff `json:"middlewares"`
+}
+
+// ResourceDiff holds the names of added, removed and changed resources
+type ResourceDiff struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+}
+
+// Empty reports whether both configurations define the same resources
+func (d ConfigDiff) Empty() bool {
+	return d.Routers.empty() && d.Services.empty() && d.Middlewares.empty()
+}
+
+func (d ResourceDiff) empty() bool {
+	return len(d.Added)+len(d.Removed)+len(d.Changed) == 0
+}
+
+// DiffConfigs compares two configurations. A nil previous configuration
+// makes every resource added.
+func DiffConfigs(previous, current *dynamic.HTTPConfiguration) ConfigDiff {
+	if previous == nil {
+		previous = &dynamic.HTTPConfiguration{}
+	}
+	if current == nil {
+		current = &dynamic.HTTPConfiguration{}
+	}
+	return ConfigDiff{
+		Routers:     diffResources(previous.Routers, current.Routers),
+		Services:    diffResources(previous.Services, current.Services),
+		Middlewares: diffResources(previous.Middlewares, current.Middlewares),
+	}
+}
+
+func diffResources[T any](previous, current map[string]T) ResourceDiff {
+	diff := ResourceDiff{}
+	for name, value := range current {
+		old, existed := previous[name]
+		switch {
+		case !existed:
+			diff.Added = append(diff.Added, name)
+		case !reflect.DeepEqual(old, value):
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range previous {
+		if _, exists := current[name]; !exists {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/traefikx/backend/internal/models"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const defaultProviderTimeout = 5 * time.Second
+
+// NewProviderClient builds an HTTP client from a provider's TLS and timeout
+// settings
+func NewProviderClient(provider *models.HTTPProvider) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: provider.TLSInsecureSkipVerify,
+	}
+
+	if provider.TLSCA != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(provider.TLSCA)) {
+			return nil, fmt.Errorf("invalid CA certificate: no PEM certificates found")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if provider.TLSCert != "" || provider.TLSKey != "" {
+		cert, err := tls.X509KeyPair([]byte(provider.TLSCert), []byte(provider.TLSKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Timeout: providerTimeout(provider),
+		// Spans for requests, whose trace context is sent to the provider
+		Transport: otelhttp.NewTransport(transport),
+	}, nil
+}
+
+// NewProviderRequest builds a GET request carrying the provider's auth and
+// custom headers
+func NewProviderRequest(ctx context.Context, provider *models.HTTPProvider) (*http.Request, error) {
+	return newSourceRequest(ctx, provider, provider.URL)
+}
+
+// newSourceRequest builds a GET request to url with the provider's auth and
+// custom headers, for sources that query several endpoints
+func newSourceRequest(ctx context.Context, provider *models.HTTPProvider, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	for name, value := range provider.GetHeaders() {
+		req.Header.Set(name, value)
+	}
+
+	switch provider.AuthType {
+	case models.ProviderAuthBearer:
+		req.Header.Set("Authorization", "Bearer "+provider.AuthSecret)
+	case models.ProviderAuthBasic:
+		req.SetBasicAuth(provider.AuthUsername, provider.AuthSecret)
+	}
+
+	return req, nil
+}
+
+func providerTimeout(provider *models.HTTPProvider) time.Duration {
+	if provider.Timeout <= 0 {
+		return defaultProviderTimeout
+	}
+	return time.Duration(provider.Timeout) * time.Second
+}
+
+// clientFingerprint identifies the settings a provider client was built
+// from, so clients are rebuilt only when those settings change
+func clientFingerprint(provider *models.HTTPProvider) string {
+	h := sha256.New()
+	for _, part := range []string{
+		provider.TLSCA,
+		provider.TLSCert,
+		provider.TLSKey,
+		strconv.FormatBool(provider.TLSInsecureSkipVerify),
+		strconv.Itoa(provider.Timeout),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"github.com/traefikx/backend/internal/models"
+)
+
+// LocalSource names the database as a source in conflicts and pins
+const LocalSource = "local"
+
+// candidate is one source's definition of a resource
+type candidate[T any] struct {
+	source   string
+	priority int
+	value    T
+}
+
+// candidates collects the definitions of each resource name, in order of
+// precedence
+type candidates[T any] map[string][]candidate[T]
+
+func newCandidates[T any](local map[string]T) candidates[T] {
+	c := make(candidates[T], len(local))
+	for name, value := range local {
+		c[name] = []candidate[T]{{source: LocalSource, value: value}}
+	}
+	return c
+}
+
+func (c candidates[T]) add(status *ProviderStatus, resources map[string]T) {
+	for name, value := range resources {
+		c[name] = append(c[name], candidate[T]{source: status.Name, priority: status.Priority, value: value})
+	}
+}
+
+// mergeFunc combines the definitions of a resource, the first one taking
+// precedence. It returns false when they can't be combined.
+type mergeFunc[T any] func(values []T) (T, bool)
+
+// merge picks a definition for each name and appends the dropped ones to
+// conflicts. A pin policy whose source doesn't define the resource falls
+// back to priority order.
+func (c candidates[T]) merge(resourceType string, policies map[string]models.ConflictPolicy, combine mergeFunc[T], conflicts *[]ConflictInfo) map[string]T {
+	result := make(map[string]T, len(c))
+	for name, defs := range c {
+		winner := 0
+		resolution := ResolutionPriority
+
+		if policy, ok := policies[policyKey(resourceType, name)]; ok && len(defs) > 1 {
+			switch policy.Strategy {
+			case models.ConflictStrategyPin:
+				for i, def := range defs {
+					if def.source == policy.Source {
+						winner = i
+						resolution = ResolutionPin
+						break
+					}
+				}
+			case models.ConflictStrategyMerge:
+				if combine != nil {
+					values := make([]T, len(defs))
+					for i, def := range defs {
+						values[i] = def.value
+					}
+					if value, ok := combine(values); ok {
+						result[name] = value
+						continue
+					}
+				}
+			}
+		}
+
+		result[name] = defs[winner].value
+		for i, def := range defs {
+			if i == winner {
+				continue
+			}
+			*conflicts = append(*conflicts, ConflictInfo{
+				Type:           resourceType,
+				Name:           name,
+				Source:         def.source,
+				OverriddenBy:   defs[winner].source,
+				SourcePriority: def.priority,
+				Resolution:     resolution,
+			})
+		}
+	}
+	return result
+}
+
+func policyKey(resourceType, name string) string {
+	return resourceType + "/" + name
+}
+
+// mergeRouters keeps the first router and appends the middlewares of the
+// others it doesn't already use
+func mergeRouters(routers []*dynamic.Router) (*dynamic.Router, bool) {
+	if routers[0] == nil {
+		return nil, false
+	}
+	merged := routers[0].DeepCopy()
+	seen := map[string]bool{}
+	for _, middleware := range merged.Middlewares {
+		seen[middleware] = true
+	}
+	for _, router := range routers[1:] {
+		if router == nil {
+			continue
+		}
+		for _, middleware := range router.Middlewares {
+			if !seen[middleware] {
+				seen[middleware] = true
+				merged.Middlewares = append(merged.Middlewares, middleware)
+			}
+		}
+	}
+	return merged, true
+}
+
+// mergeServices keeps the first service and adds the load balancer servers
+// of the others, by URL. Only load balancer services can be merged.
+func mergeServices(services []*dynamic.Service) (*dynamic.Service, bool) {
+	if services[0] == nil || services[0].LoadBalancer == nil {
+		return nil, false
+	}
+	merged := services[0].DeepCopy()
+	seen := map[string]bool{}
+	for _, server := range merged.LoadBalancer.Servers {
+		seen[server.URL] = true
+	}
+	for _, service := range services[1:] {
+		if service == nil || service.LoadBalancer == nil {
+			continue
+		}
+		for _, server := range service.LoadBalancer.Servers {
+			if !seen[server.URL] {
+				seen[server.URL] = true
+				merged.LoadBalancer.Servers = append(merged.LoadBalancer.Servers, server)
+			}
+		}
+	}
+	return merged, true
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"github.com/traefikx/backend/internal/models"
+)
+
+// newMergeAggregator returns an aggregator serving the given providers'
+// configurations, as if they had just been fetched
+func newMergeAggregator(providers ...*ProviderStatus) *AggregatorService {
+	a := NewAggregatorService(nil, nil, 1, 0, 0)
+	for i, status := range providers {
+		status.ID = uint(i + 1)
+		status.IsActive = true
+		if status.State == "" {
+			status.State = ProviderHealthy
+		}
+		a.statuses[status.ID] = status
+	}
+	return a
+}
+
+func routerWith(service string, middlewares ...string) *dynamic.Router {
+	return &dynamic.Router{Rule: "Host(`app.example.com`)", Service: service, Middlewares: middlewares}
+}
+
+func serviceWith(urls ...string) *dynamic.Service {
+	servers := make([]dynamic.Server, len(urls))
+	for i, url := range urls {
+		servers[i] = dynamic.Server{URL: url}
+	}
+	return &dynamic.Service{LoadBalancer: &dynamic.ServersLoadBalancer{Servers: servers}}
+}
+
+func providerWith(name string, priority int, routers map[string]*dynamic.Router, services map[string]*dynamic.Service) *ProviderStatus {
+	return &ProviderStatus{
+		Name:     name,
+		Priority: priority,
+		Config:   &dynamic.HTTPConfiguration{Routers: routers, Services: services},
+	}
+}
+
+func TestGetMergedConfig_Precedence(t *testing.T) {
+	a := newMergeAggregator(
+		providerWith("low", 1, map[string]*dynamic.Router{"app": routerWith("low"), "shared": routerWith("low")}, nil),
+		providerWith("high", 10, map[string]*dynamic.Router{"app": routerWith("high"), "shared": routerWith("high")}, nil),
+		providerWith("also-high", 10, map[string]*dynamic.Router{"shared": routerWith("also-high")}, nil),
+		&ProviderStatus{Name: "failed", Priority: 100, State: ProviderFailed,
+			Config: &dynamic.HTTPConfiguration{Routers: map[string]*dynamic.Router{"shared": routerWith("failed")}}},
+	)
+
+	merged, conflicts := a.GetMergedConfig(map[string]*dynamic.Router{"app": routerWith("local")}, nil, nil, nil, nil)
+
+	// Local resources win, then providers by priority, ties broken by name.
+	// Failed providers are left out.
+	if got := merged.HTTP.Routers["app"].Service; got != "local" {
+		t.Errorf("app: got the router of %s, want local", got)
+	}
+	if got := merged.HTTP.Routers["shared"].Service; got != "also-high" {
+		t.Errorf("shared: got the router of %s, want also-high", got)
+	}
+
+	want := []ConflictInfo{
+		{Type: "router", Name: "app", Source: "high", OverriddenBy: LocalSource, SourcePriority: 10, Resolution: ResolutionPriority},
+		{Type: "router", Name: "app", Source: "low", OverriddenBy: LocalSource, SourcePriority: 1, Resolution: ResolutionPriority},
+		{Type: "router", Name: "shared", Source: "high", OverriddenBy: "also-high", SourcePriority: 10, Resolution: ResolutionPriority},
+		{Type: "router", Name: "shared", Source: "low", OverriddenBy: "also-high", SourcePriority: 1, Resolution: ResolutionPriority},
+	}
+	if !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("conflicts = %+v, want %+v", conflicts, want)
+	}
+}
+
+func TestGetMergedConfig_Policies(t *testing.T) {
+	local := map[string]*dynamic.Router{"app": routerWith("app", "local-auth", "compress")}
+	localServices := map[string]*dynamic.Service{
+		"app":    serviceWith("http://local:8080"),
+		"canary": {Weighted: &dynamic.WeightedRoundRobin{Services: []dynamic.WRRService{{Name: "app"}}}},
+	}
+	providers := func() *AggregatorService {
+		return newMergeAggregator(
+			providerWith("low", 1,
+				map[string]*dynamic.Router{"app": routerWith("app", "low-auth", "compress")},
+				map[string]*dynamic.Service{
+					"app":    serviceWith("http://low:8080", "http://local:8080"),
+					"canary": serviceWith("http://canary:8080"),
+				}),
+			providerWith("high", 10,
+				map[string]*dynamic.Router{"app": routerWith("app", "high-auth", "local-auth")},
+				map[string]*dynamic.Service{"app": serviceWith("http://high:8080", "http://low:8080")}),
+		)
+	}
+
+	tests := []struct {
+		name        string
+		policy      models.ConflictPolicy
+		middlewares []string // Of the merged app router
+		servers     []string // Of the merged app service
+		conflicts   []string // Type/name/source overridden by winner (resolution)
+	}{
+		{
+			name:        "pin a router to a provider",
+			policy:      models.ConflictPolicy{ResourceType: "router", ResourceName: "app", Strategy: models.ConflictStrategyPin, Source: "low"},
+			middlewares: []string{"low-auth", "compress"},
+			servers:     []string{"http://local:8080"},
+			conflicts: []string{
+				"router/app/high by low (pin)",
+				"router/app/local by low (pin)",
+				"service/app/high by local (priority)",
+				"service/app/low by local (priority)",
+				"service/canary/low by local (priority)",
+			},
+		},
+		{
+			name:        "a pin to a source that doesn't define the resource falls back to priority",
+			policy:      models.ConflictPolicy{ResourceType: "router", ResourceName: "app", Strategy: models.ConflictStrategyPin, Source: "gone"},
+			middlewares: []string{"local-auth", "compress"},
+			servers:     []string{"http://local:8080"},
+			conflicts: []string{
+				"router/app/high by local (priority)",
+				"router/app/low by local (priority)",
+				"service/app/high by local (priority)",
+				"service/app/low by local (priority)",
+				"service/canary/low by local (priority)",
+			},
+		},
+		{
+			name:        "merge router middlewares in order of precedence",
+			policy:      models.ConflictPolicy{ResourceType: "router", ResourceName: "app", Strategy: models.ConflictStrategyMerge},
+			middlewares: []string{"local-auth", "compress", "high-auth", "low-auth"},
+			servers:     []string{"http://local:8080"},
+			conflicts: []string{
+				"service/app/high by local (priority)",
+				"service/app/low by local (priority)",
+				"service/canary/low by local (priority)",
+			},
+		},
+		{
+			name:        "union load balancer servers by URL",
+			policy:      models.ConflictPolicy{ResourceType: "service", ResourceName: "app", Strategy: models.ConflictStrategyMerge},
+			middlewares: []string{"local-auth", "compress"},
+			servers:     []string{"http://local:8080", "http://high:8080", "http://low:8080"},
+			conflicts: []string{
+				"router/app/high by local (priority)",
+				"router/app/low by local (priority)",
+				"service/canary/low by local (priority)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflicts := providers().GetMergedConfig(local, localServices, nil, nil, []models.ConflictPolicy{tt.policy})
+
+			if got := merged.HTTP.Routers["app"].Middlewares; !reflect.DeepEqual(got, tt.middlewares) {
+				t.Errorf("router middlewares = %v, want %v", got, tt.middlewares)
+			}
+			var servers []string
+			for _, server := range merged.HTTP.Services["app"].LoadBalancer.Servers {
+				servers = append(servers, server.URL)
+			}
+			if !reflect.DeepEqual(servers, tt.servers) {
+				t.Errorf("service servers = %v, want %v", servers, tt.servers)
+			}
+
+			var got []string
+			for _, c := range conflicts {
+				got = append(got, c.Type+"/"+c.Name+"/"+c.Source+" by "+c.OverriddenBy+" ("+c.Resolution+")")
+			}
+			if !reflect.DeepEqual(got, tt.conflicts) {
+				t.Errorf("conflicts = %q, want %q", got, tt.conflicts)
+			}
+		})
+	}
+
+	// Services other than load balancers can't be merged and fall back to
+	// priority
+	merged, conflicts := providers().GetMergedConfig(local, localServices, nil, nil, []models.ConflictPolicy{
+		{ResourceType: "service", ResourceName: "canary", Strategy: models.ConflictStrategyMerge},
+	})
+	if merged.HTTP.Services["canary"].Weighted == nil {
+		t.Errorf("expected the local weighted canary to win, got %+v", merged.HTTP.Services["canary"])
+	}
+	if !hasConflictFrom(conflicts, "service", "canary", "low") {
+		t.Errorf("expected the low canary to conflict, got %+v", conflicts)
+	}
+
+	// The inputs are left alone
+	if got := local["app"].Middlewares; !reflect.DeepEqual(got, []string{"local-auth", "compress"}) {
+		t.Errorf("local router modified: %v", got)
+	}
+	if got := localServices["app"].LoadBalancer.Servers; len(got) != 1 {
+		t.Errorf("local service modified: %+v", got)
+	}
+}
+
+func hasConflictFrom(conflicts []ConflictInfo, resourceType, name, source string) bool {
+	for _, c := range conflicts {
+		if c.Type == resourceType && c.Name == name && c.Source == source {
+			return true
+		}
+	}
+	return false
+}
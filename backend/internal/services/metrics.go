@@ -0,0 +1,128 @@
+package services
+
+import (
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/traefikx/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	providerUpDesc = prometheus.NewDesc("traefikx_provider_up",
+		"Whether the provider's last fetch succeeded.", []string{"provider"}, nil)
+	providerFailuresDesc = prometheus.NewDesc("traefikx_provider_consecutive_failures",
+		"Failed fetches since the provider's last success.", []string{"provider"}, nil)
+	providerLastSuccessDesc = prometheus.NewDesc("traefikx_provider_last_success_timestamp_seconds",
+		"Time of the provider's last successful fetch.", []string{"provider"}, nil)
+	resourcesDesc = prometheus.NewDesc("traefikx_config_resources",
+		"Routers, services and middlewares by source, before merging.", []string{"source", "type"}, nil)
+	conflictsDesc = prometheus.NewDesc("traefikx_config_conflicts",
+		"Resources currently overridden by another source.", []string{"type"}, nil)
+	configSizeDesc = prometheus.NewDesc("traefikx_config_size_bytes",
+		"Size of the compiled configuration as JSON.", nil, nil)
+	configVersionDesc = prometheus.NewDesc("traefikx_config_version",
+		"Version of the compiled configuration, reset on restart.", nil, nil)
+	configBuiltDesc = prometheus.NewDesc("traefikx_config_last_compile_timestamp_seconds",
+		"Time the configuration was last compiled.", nil, nil)
+	backendUpDesc = prometheus.NewDesc("traefikx_backend_up",
+		"Whether the server answered its service's last health probe.", []string{"service", "server"}, nil)
+)
+
+// StateCollector reports the state of providers, the compiled config and
+// probed servers when scraped. Followers report what the leader last stored.
+type StateCollector struct {
+	db         *gorm.DB
+	aggregator *AggregatorService
+	compiler   *ConfigCompiler
+}
+
+func NewStateCollector(db *gorm.DB, aggregator *AggregatorService, compiler *ConfigCompiler) *StateCollector {
+	return &StateCollector{db: db, aggregator: aggregator, compiler: compiler}
+}
+
+// Describe implements prometheus.Collector
+func (s *StateCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		providerUpDesc, providerFailuresDesc, providerLastSuccessDesc,
+		resourcesDesc, conflictsDesc, configSizeDesc, configVersionDesc, configBuiltDesc,
+		backendUpDesc,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector
+func (s *StateCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, status := range s.aggregator.GetStatuses() {
+		if !status.IsActive {
+			continue
+		}
+		up := 0.0
+		if status.State == ProviderHealthy && status.LastFetched != nil {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(providerUpDesc, prometheus.GaugeValue, up, status.Name)
+		ch <- prometheus.MustNewConstMetric(providerFailuresDesc, prometheus.GaugeValue, float64(status.ConsecutiveFailures), status.Name)
+		if status.LastFetched != nil {
+			ch <- prometheus.MustNewConstMetric(providerLastSuccessDesc, prometheus.GaugeValue, float64(status.LastFetched.Unix()), status.Name)
+		}
+		if status.Config != nil && status.State != ProviderFailed {
+			s.collectResources(ch, status.Name, status.RouterCount, status.ServiceCount, status.MiddlewareCount)
+		}
+	}
+
+	// Not compiled on scrape, a fresh replica reports once it has compiled
+	if snapshot := s.compiler.snapshot.Load(); snapshot != nil {
+		s.collectResources(ch, LocalSource, snapshot.LocalRouterCount, snapshot.LocalServiceCount, snapshot.LocalMiddlewareCount)
+
+		conflicts := map[string]int{"router": 0, "service": 0, "middleware": 0}
+		for _, conflict := range snapshot.Conflicts {
+			conflicts[conflict.Type]++
+		}
+		for resourceType, count := range conflicts {
+			ch <- prometheus.MustNewConstMetric(conflictsDesc, prometheus.GaugeValue, float64(count), resourceType)
+		}
+
+		ch <- prometheus.MustNewConstMetric(configSizeDesc, prometheus.GaugeValue, float64(len(snapshot.JSON)))
+		ch <- prometheus.MustNewConstMetric(configVersionDesc, prometheus.GaugeValue, float64(snapshot.Version))
+		ch <- prometheus.MustNewConstMetric(configBuiltDesc, prometheus.GaugeValue, float64(snapshot.BuiltAt.Unix()))
+	}
+
+	s.collectBackends(ch)
+}
+
+func (s *StateCollector) collectResources(ch chan<- prometheus.Metric, source string, routers, services, middlewares int) {
+	ch <- prometheus.MustNewConstMetric(resourcesDesc, prometheus.GaugeValue, float64(routers), source, "router")
+	ch <- prometheus.MustNewConstMetric(resourcesDesc, prometheus.GaugeValue, float64(services), source, "service")
+	ch <- prometheus.MustNewConstMetric(resourcesDesc, prometheus.GaugeValue, float64(middlewares), source, "middleware")
+}
+
+// collectBackends reports the servers probed so far
+func (s *StateCollector) collectBackends(ch chan<- prometheus.Metric) {
+	var services []models.Service
+	if err := s.db.Where("is_active = ? AND health_check_enabled = ?", true, true).
+		Preload("Servers", "is_healthy IS NOT NULL").
+		Find(&services).Error; err != nil {
+		slog.Error("Failed to load service health for metrics", "error", err)
+		return
+	}
+
+	seen := make(map[[2]string]bool)
+	for _, service := range services {
+		for _, server := range service.Servers {
+			// Duplicate URLs would fail the scrape
+			key := [2]string{service.Name, server.URL}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			up := 0.0
+			if *server.IsHealthy {
+				up = 1
+			}
+			ch <- prometheus.MustNewConstMetric(backendUpDesc, prometheus.GaugeValue, up, service.Name, server.URL)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"github.com/traefikx/backend/internal/models"
+)
+
+// ProviderRules are the filters and namespacing applied to a provider's
+// configuration before it is merged
+type ProviderRules struct {
+	Include []string
+	Exclude []string
+	Mode    string // none, suffix or prefix
+	Prefix  string
+	Suffix  string
+}
+
+// RulesForProvider returns the rules configured on a provider. Traefik
+// reserves "@" for its own provider namespaces (everything served by the
+// HTTP provider already becomes name@http), so suffixes use "-".
+func RulesForProvider(provider *models.HTTPProvider) ProviderRules {
+	rules := ProviderRules{
+		Include: provider.GetIncludeFilters(),
+		Exclude: provider.GetExcludeFilters(),
+		Mode:    provider.NamespaceMode,
+	}
+	switch provider.NamespaceMode {
+	case models.NamespacePrefix:
+		rules.Prefix = provider.NamespacePrefix
+		if rules.Prefix == "" {
+			rules.Prefix = provider.Name + "-"
+		}
+	case models.NamespaceSuffix:
+		rules.Suffix = "-" + provider.Name
+	}
+	return rules
+}
+
+// Key identifies the rules, to detect changes
+func (r ProviderRules) Key() string {
+	return fmt.Sprintf("%q|%q|%s|%s|%s", r.Include, r.Exclude, r.Mode, r.Prefix, r.Suffix)
+}
+
+// ValidatePatterns checks filter patterns
+func ValidatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := compilePattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyProviderRules filters and namespaces a provider configuration. The
+// input is not modified.
+//
+// Filters select routers, by name or, with a "rule:" prefix, by rule. Glob
+// patterns use * and ?. When filters are set, services, middlewares and
+// servers transports are kept only if a selected router uses them, directly
+// or through other services and chain middlewares, and their name isn't
+// excluded.
+//
+// Namespacing renames every resource and rewrites the references between
+// them. References to names the provider doesn't define, or qualified with
+// @ (auth@file, api@internal), are left alone.
+func ApplyProviderRules(config *dynamic.HTTPConfiguration, rules ProviderRules) (*dynamic.HTTPConfiguration, error) {
+	include, err := compilePatterns(rules.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compilePatterns(rules.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	config = config.DeepCopy()
+	if len(include) > 0 || len(exclude) > 0 {
+		filterConfig(config, include, exclude)
+	}
+	if rules.Prefix != "" || rules.Suffix != "" {
+		config = namespaceConfig(config, func(name string) string {
+			return rules.Prefix + name + rules.Suffix
+		})
+	}
+	return config, nil
+}
+
+// pattern matches resource names, or router rules when rule is set
+type pattern struct {
+	rule bool
+	re   *regexp.Regexp
+}
+
+func compilePatterns(patterns []string) ([]pattern, error) {
+	compiled := make([]pattern, 0, len(patterns))
+	for _, p := range patterns {
+		c, err := compilePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+func compilePattern(p string) (pattern, error) {
+	result := pattern{}
+	if strings.HasPrefix(p, "rule:") {
+		result.rule = true
+		p = strings.TrimPrefix(p, "rule:")
+	}
+	if strings.TrimSpace(p) == "" {
+		return result, fmt.Errorf("empty filter pattern")
+	}
+
+	expr := regexp.QuoteMeta(p)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return result, fmt.Errorf("invalid filter pattern %q: %w", p, err)
+	}
+	result.re = re
+	return result, nil
+}
+
+func matchesAny(patterns []pattern, name, rule string) bool {
+	for _, p := range patterns {
+		if p.rule {
+			if rule != "" && p.re.MatchString(rule) {
+				return true
+			}
+		} else if p.re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func filterConfig(config *dynamic.HTTPConfiguration, include, exclude []pattern) {
+	for name, router := range config.Routers {
+		if router == nil ||
+			(len(include) > 0 && !matchesAny(include, name, router.Rule)) ||
+			matchesAny(exclude, name, router.Rule) {
+			delete(config.Routers, name)
+		}
+	}
+
+	// Collect what the remaining routers use
+	services := map[string]bool{}
+	middlewares := map[string]bool{}
+	transports := map[string]bool{}
+
+	var useMiddleware func(name string)
+	var useService func(name string)
+
+	useMiddleware = func(name string) {
+		if middlewares[name] || matchesAny(exclude, name, "") {
+			return
+		}
+		middleware, exists := config.Middlewares[name]
+		if !exists || middleware == nil {
+			return
+		}
+		middlewares[name] = true
+		if middleware.Chain != nil {
+			for _, m := range middleware.Chain.Middlewares {
+				useMiddleware(m)
+			}
+		}
+		if middleware.Errors != nil {
+			useService(middleware.Errors.Service)
+		}
+	}
+
+	useService = func(name string) {
+		if services[name] || matchesAny(exclude, name, "") {
+			return
+		}
+		service, exists := config.Services[name]
+		if !exists || service == nil {
+			return
+		}
+		services[name] = true
+		for _, child := range serviceChildren(service) {
+			useService(child)
+		}
+		if service.LoadBalancer != nil && service.LoadBalancer.ServersTransport != "" {
+			transports[service.LoadBalancer.ServersTransport] = true
+		}
+	}
+
+	for _, router := range config.Routers {
+		useService(router.Service)
+		for _, m := range router.Middlewares {
+			useMiddleware(m)
+		}
+	}
+
+	for name := range config.Services {
+		if !services[name] {
+			delete(config.Services, name)
+		}
+	}
+	for name := range config.Middlewares {
+		if !middlewares[name] {
+			delete(config.Middlewares, name)
+		}
+	}
+	for name := range config.ServersTransports {
+		if !transports[name] || matchesAny(exclude, name, "") {
+			delete(config.ServersTransports, name)
+		}
+	}
+}
+
+// serviceChildren returns the services a service delegates to
+func serviceChildren(service *dynamic.Service) []string {
+	children := []string{}
+	if service.Weighted != nil {
+		for _, s := range service.Weighted.Services {
+			children = append(children, s.Name)
+		}
+	}
+	if service.HighestRandomWeight != nil {
+		for _, s := range service.HighestRandomWeight.Services {
+			children = append(children, s.Name)
+		}
+	}
+	if service.Mirroring != nil {
+		children = append(children, service.Mirroring.Service)
+		for _, m := range service.Mirroring.Mirrors {
+			children = append(children, m.Name)
+		}
+	}
+	if service.Failover != nil {
+		children = append(children, service.Failover.Service, service.Failover.Fallback)
+	}
+	return children
+}
+
+func namespaceConfig(config *dynamic.HTTPConfiguration, rename func(string) string) *dynamic.HTTPConfiguration {
+	// Only names defined by this provider are rewritten
+	ref := func(defined map[string]bool) func(string) string {
+		return func(name string) string {
+			if name == "" || strings.Contains(name, "@") || !defined[name] {
+				return name
+			}
+			return rename(name)
+		}
+	}
+	routerRef := ref(keys(config.Routers))
+	serviceRef := ref(keys(config.Services))
+	middlewareRef := ref(keys(config.Middlewares))
+	transportRef := ref(keys(config.ServersTransports))
+
+	result := &dynamic.HTTPConfiguration{
+		Routers:           make(map[string]*dynamic.Router, len(config.Routers)),
+		Services:          make(map[string]*dynamic.Service, len(config.Services)),
+		Middlewares:       make(map[string]*dynamic.Middleware, len(config.Middlewares)),
+		Models:            config.Models,
+		ServersTransports: make(map[string]*dynamic.ServersTransport, len(config.ServersTransports)),
+	}
+
+	for name, router := range config.Routers {
+		if router == nil {
+			continue
+		}
+		router.Service = serviceRef(router.Service)
+		for i := range router.Middlewares {
+			router.Middlewares[i] = middlewareRef(router.Middlewares[i])
+		}
+		for i := range router.ParentRefs {
+			router.ParentRefs[i] = routerRef(router.ParentRefs[i])
+		}
+		result.Routers[rename(name)] = router
+	}
+
+	for name, service := range config.Services {
+		if service == nil {
+			continue
+		}
+		if service.LoadBalancer != nil {
+			service.LoadBalancer.ServersTransport = transportRef(service.LoadBalancer.ServersTransport)
+		}
+		if service.Weighted != nil {
+			for i := range service.Weighted.Services {
+				service.Weighted.Services[i].Name = serviceRef(service.Weighted.Services[i].Name)
+			}
+		}
+		if service.HighestRandomWeight != nil {
+			for i := range service.HighestRandomWeight.Services {
+				service.HighestRandomWeight.Services[i].Name = serviceRef(service.HighestRandomWeight.Services[i].Name)
+			}
+		}
+		if service.Mirroring != nil {
+			service.Mirroring.Service = serviceRef(service.Mirroring.Service)
+			for i := range service.Mirroring.Mirrors {
+				service.Mirroring.Mirrors[i].Name = serviceRef(service.Mirroring.Mirrors[i].Name)
+			}
+		}
+		if service.Failover != nil {
+			service.Failover.Service = serviceRef(service.Failover.Service)
+			service.Failover.Fallback = serviceRef(service.Failover.Fallback)
+		}
+		result.Services[rename(name)] = service
+	}
+
+	for name, middleware := range config.Middlewares {
+		if middleware == nil {
+			continue
+		}
+		if middleware.Chain != nil {
+			for i := range middleware.Chain.Middlewares {
+				middleware.Chain.Middlewares[i] = middlewareRef(middleware.Chain.Middlewares[i])
+			}
+		}
+		if middleware.Errors != nil {
+			middleware.Errors.Service = serviceRef(middleware.Errors.Service)
+		}
+		result.Middlewares[rename(name)] = middleware
+	}
+
+	for name, transport := range config.ServersTransports {
+		result.ServersTransports[rename(name)] = transport
+	}
+
+	return result
+}
+
+func keys[V any](m map[string]V) map[string]bool {
+	result := make(map[string]bool, len(m))
+	for k := range m {
+		result[k] = true
+	}
+	return result
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"github.com/traefikx/backend/internal/models"
+)
+
+// namespaceFixture is a provider configuration whose resources reference
+// each other in every way the rules have to follow
+const namespaceFixture = `{"http": {
+	"routers": {
+		"app": {"rule": "Host(` + "`app.example.com`" + `)", "service": "app", "middlewares": ["secure", "auth@file"],
+			"tls": {"options": "modern", "certResolver": "le"}},
+		"api": {"rule": "Host(` + "`api.example.com`" + `) && PathPrefix(` + "`/v1`" + `)", "service": "canary", "middlewares": ["chain"]},
+		"admin": {"rule": "Host(` + "`admin.internal`" + `)", "service": "admin", "middlewares": ["adminonly"]},
+		"dashboard": {"rule": "Host(` + "`traefik.example.com`" + `)", "service": "api@internal"},
+		"orphan": {"rule": "Host(` + "`orphan.example.com`" + `)", "service": "missing"}
+	},
+	"services": {
+		"app": {"loadBalancer": {"servers": [{"url": "http://app:8080"}], "serversTransport": "insecure"}},
+		"v1": {"loadBalancer": {"servers": [{"url": "http://v1:8080"}]}},
+		"v2": {"loadBalancer": {"servers": [{"url": "http://v2:8080"}]}},
+		"canary": {"weighted": {"services": [{"name": "v1", "weight": 9}, {"name": "v2", "weight": 1}]}},
+		"admin": {"loadBalancer": {"servers": [{"url": "http://admin:8080"}]}},
+		"backup": {"failover": {"service": "app", "fallback": "errorpages"}},
+		"errorpages": {"loadBalancer": {"servers": [{"url": "http://errorpages:8080"}]}}
+	},
+	"middlewares": {
+		"secure": {"headers": {"customRequestHeaders": {"X-Forwarded-Proto": "https"}}},
+		"chain": {"chain": {"middlewares": ["secure", "errors", "strip@file"]}},
+		"errors": {"errors": {"status": ["500-599"], "service": "errorpages", "query": "/{status}.html"}},
+		"adminonly": {"ipAllowList": {"sourceRange": ["10.0.0.0/8"]}}
+	},
+	"serversTransports": {
+		"insecure": {"insecureSkipVerify": true}
+	}
+}}`
+
+func parseNamespaceFixture(t *testing.T) *dynamic.HTTPConfiguration {
+	t.Helper()
+	config, err := parseProviderConfig([]byte(namespaceFixture))
+	if err != nil {
+		t.Fatalf("parseProviderConfig: %v", err)
+	}
+	return config
+}
+
+// sortedKeys returns the names of a map, sorted and joined by ","
+func sortedKeys[V any](m map[string]V) string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ",")
+}
+
+func TestApplyProviderRules_Filters(t *testing.T) {
+	tests := []struct {
+		name        string
+		include     []string
+		exclude     []string
+		routers     string
+		services    string
+		middlewares string
+		transports  string
+	}{
+		{
+			name:        "no filters keep everything",
+			routers:     "admin,api,app,dashboard,orphan",
+			services:    "admin,app,backup,canary,errorpages,v1,v2",
+			middlewares: "adminonly,chain,errors,secure",
+			transports:  "insecure",
+		},
+		{
+			name:        "include by name keeps what the router uses",
+			include:     []string{"app"},
+			routers:     "app",
+			services:    "app",
+			middlewares: "secure",
+			transports:  "insecure",
+		},
+		{
+			name:        "include by glob",
+			include:     []string{"a??"},
+			routers:     "api,app",
+			services:    "app,canary,errorpages,v1,v2",
+			middlewares: "chain,errors,secure",
+			transports:  "insecure",
+		},
+		{
+			name:        "include by rule follows weighted services and chains",
+			include:     []string{"rule:*PathPrefix(`/v1`)*"},
+			routers:     "api",
+			services:    "canary,errorpages,v1,v2",
+			middlewares: "chain,errors,secure",
+		},
+		{
+			name:        "exclude by rule",
+			exclude:     []string{"rule:Host(`*.internal`)"},
+			routers:     "api,app,dashboard,orphan",
+			services:    "app,canary,errorpages,v1,v2",
+			middlewares: "chain,errors,secure",
+			transports:  "insecure",
+		},
+		{
+			name:        "excluded dependencies are dropped",
+			include:     []string{"*"},
+			exclude:     []string{"v2", "errors", "insecure"},
+			routers:     "admin,api,app,dashboard,orphan",
+			services:    "admin,app,canary,v1",
+			middlewares: "adminonly,chain,secure",
+		},
+		{
+			name:        "exclude wins over include",
+			include:     []string{"app", "admin"},
+			exclude:     []string{"adm*"},
+			routers:     "app",
+			services:    "app",
+			middlewares: "secure",
+			transports:  "insecure",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ApplyProviderRules(parseNamespaceFixture(t), ProviderRules{Include: tt.include, Exclude: tt.exclude})
+			if err != nil {
+				t.Fatalf("ApplyProviderRules: %v", err)
+			}
+			if got := sortedKeys(config.Routers); got != tt.routers {
+				t.Errorf("routers = %s, want %s", got, tt.routers)
+			}
+			if got := sortedKeys(config.Services); got != tt.services {
+				t.Errorf("services = %s, want %s", got, tt.services)
+			}
+			if got := sortedKeys(config.Middlewares); got != tt.middlewares {
+				t.Errorf("middlewares = %s, want %s", got, tt.middlewares)
+			}
+			if got := sortedKeys(config.ServersTransports); got != tt.transports {
+				t.Errorf("servers transports = %s, want %s", got, tt.transports)
+			}
+		})
+	}
+}
+
+func TestApplyProviderRules_InvalidPatterns(t *testing.T) {
+	for _, patterns := range [][]string{{""}, {"rule:"}, {"app", " "}} {
+		if err := ValidatePatterns(patterns); err == nil {
+			t.Errorf("ValidatePatterns(%q): expected an error", patterns)
+		}
+		if _, err := ApplyProviderRules(parseNamespaceFixture(t), ProviderRules{Exclude: patterns}); err == nil {
+			t.Errorf("ApplyProviderRules(%q): expected an error", patterns)
+		}
+	}
+	if err := ValidatePatterns([]string{"app-*", "rule:Host(`?.example.com`)"}); err != nil {
+		t.Errorf("ValidatePatterns: %v", err)
+	}
+}
+
+func TestRulesForProvider(t *testing.T) {
+	tests := []struct {
+		mode, prefix   string
+		wantPrefix     string
+		wantSuffix     string
+		wantRenamedApp string
+	}{
+		{models.NamespaceNone, "", "", "", "app"},
+		{models.NamespacePrefix, "", "edge-", "", "edge-app"},
+		{models.NamespacePrefix, "eu.", "eu.", "", "eu.app"},
+		{models.NamespaceSuffix, "ignored-", "", "-edge", "app-edge"},
+	}
+
+	for _, tt := range tests {
+		provider := &models.HTTPProvider{Name: "edge", NamespaceMode: tt.mode, NamespacePrefix: tt.prefix}
+		provider.SetFilters([]string{"app"}, []string{"rule:*.internal*"})
+		rules := RulesForProvider(provider)
+		if rules.Prefix != tt.wantPrefix || rules.Suffix != tt.wantSuffix ||
+			!reflect.DeepEqual(rules.Include, []string{"app"}) || !reflect.DeepEqual(rules.Exclude, []string{"rule:*.internal*"}) {
+			t.Errorf("%s/%q: unexpected rules %+v", tt.mode, tt.prefix, rules)
+			continue
+		}
+
+		config, err := ApplyProviderRules(parseNamespaceFixture(t), rules)
+		if err != nil {
+			t.Fatalf("ApplyProviderRules: %v", err)
+		}
+		if got := sortedKeys(config.Routers); got != tt.wantRenamedApp {
+			t.Errorf("%s/%q: routers = %s, want %s", tt.mode, tt.prefix, got, tt.wantRenamedApp)
+		}
+	}
+}
+
+func TestApplyProviderRules_Namespacing(t *testing.T) {
+	input := parseNamespaceFixture(t)
+	config, err := ApplyProviderRules(input, ProviderRules{Mode: models.NamespaceSuffix, Suffix: "-edge"})
+	if err != nil {
+		t.Fatalf("ApplyProviderRules: %v", err)
+	}
+
+	if got := sortedKeys(config.Routers); got != "admin-edge,api-edge,app-edge,dashboard-edge,orphan-edge" {
+		t.Errorf("routers = %s", got)
+	}
+	if got := sortedKeys(config.Services); got != "admin-edge,app-edge,backup-edge,canary-edge,errorpages-edge,v1-edge,v2-edge" {
+		t.Errorf("services = %s", got)
+	}
+	if got := sortedKeys(config.Middlewares); got != "adminonly-edge,chain-edge,errors-edge,secure-edge" {
+		t.Errorf("middlewares = %s", got)
+	}
+	if got := sortedKeys(config.ServersTransports); got != "insecure-edge" {
+		t.Errorf("servers transports = %s", got)
+	}
+
+	// References to the provider's own resources follow the rename, those
+	// qualified with @ or to names it doesn't define don't
+	app := config.Routers["app-edge"]
+	if app.Service != "app-edge" || !reflect.DeepEqual(app.Middlewares, []string{"secure-edge", "auth@file"}) {
+		t.Errorf("unexpected app router %+v", app)
+	}
+	if config.Routers["dashboard-edge"].Service != "api@internal" {
+		t.Errorf("expected api@internal to be left alone, got %s", config.Routers["dashboard-edge"].Service)
+	}
+	if config.Routers["orphan-edge"].Service != "missing" {
+		t.Errorf("expected an undefined service to be left alone, got %s", config.Routers["orphan-edge"].Service)
+	}
+
+	// TLS options aren't part of a provider's HTTP configuration, so they
+	// always refer to options defined elsewhere
+	if app.TLS == nil || app.TLS.Options != "modern" || app.TLS.CertResolver != "le" {
+		t.Errorf("expected TLS options to be left alone, got %+v", app.TLS)
+	}
+
+	if got := config.Services["app-edge"].LoadBalancer.ServersTransport; got != "insecure-edge" {
+		t.Errorf("servers transport = %s, want insecure-edge", got)
+	}
+	var weighted []string
+	for _, s := range config.Services["canary-edge"].Weighted.Services {
+		weighted = append(weighted, s.Name)
+	}
+	if !reflect.DeepEqual(weighted, []string{"v1-edge", "v2-edge"}) {
+		t.Errorf("weighted services = %v", weighted)
+	}
+	if failover := config.Services["backup-edge"].Failover; failover.Service != "app-edge" || failover.Fallback != "errorpages-edge" {
+		t.Errorf("unexpected failover %+v", failover)
+	}
+	if chain := config.Middlewares["chain-edge"].Chain.Middlewares; !reflect.DeepEqual(chain, []string{"secure-edge", "errors-edge", "strip@file"}) {
+		t.Errorf("chain middlewares = %v", chain)
+	}
+	if got := config.Middlewares["errors-edge"].Errors.Service; got != "errorpages-edge" {
+		t.Errorf("errors service = %s, want errorpages-edge", got)
+	}
+
+	// The input is not modified
+	if !reflect.DeepEqual(input, parseNamespaceFixture(t)) {
+		t.Error("expected the input configuration to be left unchanged")
+	}
+}
+
+func TestApplyProviderRules_FiltersBeforeNamespacing(t *testing.T) {
+	// Filters match the names the provider serves, not the namespaced ones
+	config, err := ApplyProviderRules(parseNamespaceFixture(t), ProviderRules{
+		Include: []string{"api"},
+		Mode:    models.NamespacePrefix,
+		Prefix:  "edge-",
+	})
+	if err != nil {
+		t.Fatalf("ApplyProviderRules: %v", err)
+	}
+	if got := sortedKeys(config.Routers); got != "edge-api" {
+		t.Errorf("routers = %s, want edge-api", got)
+	}
+	if got := sortedKeys(config.Services); got != "edge-canary,edge-errorpages,edge-v1,edge-v2" {
+		t.Errorf("services = %s", got)
+	}
+	if chain := config.Middlewares["edge-chain"].Chain.Middlewares; !reflect.DeepEqual(chain, []string{"edge-secure", "edge-errors", "strip@file"}) {
+		t.Errorf("chain middlewares = %v", chain)
+	}
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/metrics"
+	"github.com/traefikx/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// probeTick is how often the prober looks for services due a probe
+const probeTick = time.Second
+
+// UpstreamProber requests the health check path of the servers of active
+// services that have one, every service's interval. Results are stored on
+// the servers and changes are published as BackendHealth events. Traefik
+// runs its own checks; these only feed the UI, metrics and notifications.
+type UpstreamProber struct {
+	db      *gorm.DB
+	bus     *events.Bus
+	client  *http.Client
+	timeout time.Duration
+
+	mu       sync.Mutex
+	running  bool
+	cancel   context.CancelFunc
+	done     chan struct{}
+	probed   map[uint]time.Time // Last probe start by service ID
+	probing  map[uint]bool
+	services map[uint]string // Names of the probed services, to forget their metrics
+}
+
+// NewUpstreamProber creates a prober waiting at most timeout per request
+func NewUpstreamProber(db *gorm.DB, bus *events.Bus, timeout time.Duration) *UpstreamProber {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &UpstreamProber{
+		db:  db,
+		bus: bus,
+		client: &http.Client{
+			// Like Traefik, a redirect is an answer
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		timeout:  timeout,
+		services: make(map[uint]string),
+	}
+}
+
+// Start begins probing. It can be called again after Stop, in HA mode the
+// leader probes. A nil prober does nothing.
+func (p *UpstreamProber) Start() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.running = true
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	p.probed = make(map[uint]time.Time)
+	p.probing = make(map[uint]bool)
+	go p.run(ctx, p.done)
+}
+
+// Stop ends probing, waiting for the probes in progress
+func (p *UpstreamProber) Stop() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return
+	}
+	p.running = false
+	p.cancel()
+	done := p.done
+	p.mu.Unlock()
+	<-done
+}
+
+func (p *UpstreamProber) run(ctx context.Context, done chan struct{}) {
+	var wg sync.WaitGroup
+	defer close(done)
+	defer wg.Wait()
+
+	ticker := time.NewTicker(probeTick)
+	defer ticker.Stop()
+	for {
+		p.probeDue(ctx, &wg)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// probeDue starts probing the services whose interval has elapsed
+func (p *UpstreamProber) probeDue(ctx context.Context, wg *sync.WaitGroup) {
+	var services []models.Service
+	if err := p.db.WithContext(ctx).
+		Where("is_active = ? AND health_check_enabled = ? AND health_check_path <> ?", true, true, "").
+		Preload("Servers").
+		Find(&services).Error; err != nil {
+		if ctx.Err() == nil {
+			slog.Error("Failed to load services to probe", "error", err)
+		}
+		return
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current := make(map[uint]bool, len(services))
+	for i := range services {
+		service := &services[i]
+		current[service.ID] = true
+		if name, ok := p.services[service.ID]; ok && name != service.Name {
+			metrics.ForgetService(name)
+		}
+		p.services[service.ID] = service.Name
+
+		interval := time.Duration(max(service.HealthCheckInterval, 1)) * time.Second
+		if p.probing[service.ID] || now.Sub(p.probed[service.ID]) < interval {
+			continue
+		}
+		p.probed[service.ID] = now
+		p.probing[service.ID] = true
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.probeService(ctx, service, min(p.timeout, interval))
+			p.mu.Lock()
+			delete(p.probing, service.ID)
+			p.mu.Unlock()
+		}()
+	}
+
+	// Services deleted or no longer checked
+	for id, name := range p.services {
+		if !current[id] {
+			metrics.ForgetService(name)
+			delete(p.services, id)
+			delete(p.probed, id)
+		}
+	}
+}
+
+func (p *UpstreamProber) probeService(ctx context.Context, service *models.Service, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for i := range service.Servers {
+		wg.Add(1)
+		go func(server *models.ServiceServer) {
+			defer wg.Done()
+			p.probeServer(ctx, service, server, timeout)
+		}(&service.Servers[i])
+	}
+	wg.Wait()
+}
+
+func (p *UpstreamProber) probeServer(ctx context.Context, service *models.Service, server *models.ServiceServer, timeout time.Duration) {
+	start := time.Now()
+	err := p.check(ctx, probeURL(server.URL, service.HealthCheckPath), timeout)
+	if ctx.Err() != nil {
+		return // Stopped, not a server failure
+	}
+	healthy := err == nil
+	metrics.ObserveProbe(service.Name, time.Since(start), healthy)
+
+	if server.IsHealthy != nil && *server.IsHealthy == healthy {
+		return
+	}
+	if err := p.db.Model(&models.ServiceServer{}).Where("id = ?", server.ID).
+		Update("is_healthy", healthy).Error; err != nil {
+		slog.Error("Failed to save server health", "service", service.Name, "server", server.URL, "error", err)
+	}
+
+	// A server found healthy on the first probe is no news
+	if server.IsHealthy == nil && healthy {
+		return
+	}
+	change := events.BackendChange{
+		ServiceID:   service.ID,
+		ServiceName: service.Name,
+		ServerID:    server.ID,
+		URL:         server.URL,
+		Healthy:     healthy,
+	}
+	if healthy {
+		slog.Info("Server is healthy again", "service", service.Name, "server", server.URL)
+	} else {
+		change.Error = err.Error()
+		slog.Warn("Server is unhealthy", "service", service.Name, "server", server.URL, "error", err)
+	}
+	p.bus.Publish(events.BackendHealth, change)
+}
+
+// check requests url, a 2xx or 3xx response is healthy
+func (p *UpstreamProber) check(ctx context.Context, url string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// probeURL joins a server URL and a health check path
+func probeURL(serverURL, path string) string {
+	return strings.TrimRight(serverURL, "/") + "/" + strings.TrimLeft(path, "/")
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"github.com/traefikx/backend/internal/logging"
+	"github.com/traefikx/backend/internal/models"
+)
+
+// Stages at which a provider test can fail
+const (
+	TestStageClient  = "client"  // Invalid TLS settings or request
+	TestStageConnect = "connect" // DNS, TCP or TLS failure
+	TestStageHTTP    = "http"    // Unexpected status code
+	TestStageRead    = "read"    // Response body couldn't be read
+	TestStageSource  = "source"  // Docker, file or consul source failed
+	TestStageParse   = "parse"   // Invalid configuration
+	TestStageRules   = "rules"   // Invalid filters
+)
+
+// ProviderTestResult is the outcome of a synchronous provider test
+type ProviderTestResult struct {
+	Success    bool           `json:"success"`
+	Stage      string         `json:"stage,omitempty"` // Where the test failed
+	Error      string         `json:"error,omitempty"`
+	StatusCode int            `json:"status_code,omitempty"`
+	Size       int            `json:"size"`
+	Timing     TestTiming     `json:"timing"`
+	ParseError *ParseError    `json:"parse_error,omitempty"`
+	RawCounts  ResourceCounts `json:"raw_counts"` // Resources in the response
+	Counts     ResourceCounts `json:"counts"`     // Resources left after filters and namespacing
+	Warnings   []string       `json:"warnings,omitempty"`
+	Preview    *MergePreview  `json:"preview,omitempty"`
+}
+
+// TestTiming breaks down the duration of a test in milliseconds. Phases
+// that didn't happen, such as TLS for plain HTTP or DNS for IP addresses,
+// are 0.
+type TestTiming struct {
+	DNSMs       float64 `json:"dns_ms"`
+	ConnectMs   float64 `json:"connect_ms"`
+	TLSMs       float64 `json:"tls_ms"`
+	FirstByteMs float64 `json:"first_byte_ms"` // From the start of the test
+	TotalMs     float64 `json:"total_ms"`
+}
+
+// ParseError locates an invalid JSON response
+type ParseError struct {
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+}
+
+// ResourceCounts counts the resources of a configuration
+type ResourceCounts struct {
+	Routers     int `json:"routers"`
+	Services    int `json:"services"`
+	Middlewares int `json:"middlewares"`
+}
+
+func countResources(config *dynamic.HTTPConfiguration) ResourceCounts {
+	return ResourceCounts{
+		Routers:     len(config.Routers),
+		Services:    len(config.Services),
+		Middlewares: len(config.Middlewares),
+	}
+}
+
+// testTrace records the connection phases of a test request. Callbacks may
+// run on other goroutines.
+type testTrace struct {
+	mu                         sync.Mutex
+	start                      time.Time
+	dnsStart, connStart, tlsAt time.Time
+	timing                     TestTiming
+}
+
+func (t *testTrace) clientTrace() *httptrace.ClientTrace {
+	since := func(from time.Time) float64 {
+		return float64(time.Since(from).Microseconds()) / 1000
+	}
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			t.dnsStart = time.Now()
+			t.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			t.timing.DNSMs = since(t.dnsStart)
+			t.mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			t.connStart = time.Now()
+			t.mu.Unlock()
+		},
+		ConnectDone: func(string, string, error) {
+			t.mu.Lock()
+			t.timing.ConnectMs = since(t.connStart)
+			t.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			t.tlsAt = time.Now()
+			t.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			t.timing.TLSMs = since(t.tlsAt)
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			t.timing.FirstByteMs = since(t.start)
+			t.mu.Unlock()
+		},
+	}
+}
+
+func (t *testTrace) result() TestTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timing := t.timing
+	timing.TotalMs = float64(time.Since(t.start).Microseconds()) / 1000
+	return timing
+}
+
+// TestProvider fetches a provider once, bypassing the cache and the poll
+// loop, so unsaved settings can be checked. Nothing is stored. The returned
+// configuration, after filters and namespacing, is nil when the test failed.
+func (a *AggregatorService) TestProvider(ctx context.Context, provider *models.HTTPProvider) (*ProviderTestResult, *dynamic.HTTPConfiguration) {
+	trace := &testTrace{start: time.Now()}
+	result := &ProviderTestResult{}
+	fail := func(stage string, err error) (*ProviderTestResult, *dynamic.HTTPConfiguration) {
+		result.Stage = stage
+		result.Error = err.Error()
+		result.Timing = trace.result()
+		return result, nil
+	}
+
+	// A fresh client so the test doesn't reuse a kept-alive connection
+	client, err := NewProviderClient(provider)
+	if err != nil {
+		return fail(TestStageClient, fmt.Errorf("TLS configuration error: %v", err))
+	}
+	defer client.CloseIdleConnections()
+	ctx = httptrace.WithClientTrace(ctx, trace.clientTrace())
+
+	var body []byte
+	if isHTTPProvider(provider) {
+		req, err := NewProviderRequest(ctx, provider)
+		if err != nil {
+			return fail(TestStageClient, fmt.Errorf("Invalid request: %v", err))
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return fail(TestStageConnect, fmt.Errorf("Connection error: %v", logging.RedactError(err)))
+		}
+		defer resp.Body.Close()
+
+		result.StatusCode = resp.StatusCode
+		if resp.StatusCode != http.StatusOK {
+			return fail(TestStageHTTP, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status))
+		}
+		if body, err = a.readResponse(resp.Body); err != nil {
+			return fail(TestStageRead, err)
+		}
+	} else {
+		source, err := NewSource(provider, client)
+		if err != nil {
+			return fail(TestStageSource, err)
+		}
+		sourceResult, err := source.Fetch(ctx)
+		if err != nil {
+			return fail(TestStageSource, err)
+		}
+		body, result.Warnings = sourceResult.Body, sourceResult.Warnings
+	}
+	result.Size = len(body)
+
+	config, err := parseProviderConfig(body)
+	if err != nil {
+		result.ParseError = locateParseError(body, err)
+		return fail(TestStageParse, fmt.Errorf("JSON parse error: %v", err))
+	}
+	result.RawCounts = countResources(config)
+
+	config, err = ApplyProviderRules(config, RulesForProvider(provider))
+	if err != nil {
+		return fail(TestStageRules, fmt.Errorf("Filter error: %v", err))
+	}
+	result.Counts = countResources(config)
+
+	result.Success = true
+	result.Timing = trace.result()
+	return result, config
+}
+
+// locateParseError turns the byte offset of a JSON error into a line and
+// column, both starting at 1
+func locateParseError(body []byte, err error) *ParseError {
+	parseError := &ParseError{Message: err.Error()}
+
+	var offset int64 = -1
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	}
+	if offset < 0 || offset > int64(len(body)) {
+		return parseError
+	}
+
+	before := body[:offset]
+	parseError.Line = bytes.Count(before, []byte("\n")) + 1
+	parseError.Column = len(before) - bytes.LastIndexByte(before, '\n')
+	return parseError
+}
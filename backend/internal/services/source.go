@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"github.com/traefikx/backend/internal/declarative"
+	"github.com/traefikx/backend/internal/logging"
+	"github.com/traefikx/backend/internal/models"
+)
+
+// Source reads a provider configuration from a system other than a Traefik
+// HTTP provider endpoint. HTTP providers are fetched by the aggregator
+// itself, with conditional requests.
+type Source interface {
+	Fetch(ctx context.Context) (*SourceResult, error)
+}
+
+// SourceResult is the configuration read from a source
+type SourceResult struct {
+	Body     []byte   // Encoded like a Traefik HTTP provider response
+	Warnings []string // Resources that were skipped
+}
+
+// Watcher is implemented by sources that notice changes between polls
+type Watcher interface {
+	// Watch calls changed after changes until ctx is cancelled
+	Watch(ctx context.Context, changed func()) error
+}
+
+// NewSource returns the source of a docker, file or consul provider. client
+// carries the provider's TLS and timeout settings, a default one is used
+// when nil.
+func NewSource(provider *models.HTTPProvider, client *http.Client) (Source, error) {
+	if client == nil {
+		client = &http.Client{Timeout: providerTimeout(provider)}
+	}
+	switch provider.Type {
+	case models.ProviderTypeDocker:
+		return newDockerSource(provider, client)
+	case models.ProviderTypeFile:
+		return newFileSource(provider), nil
+	case models.ProviderTypeConsul:
+		return newConsulSource(provider, client), nil
+	}
+	return nil, fmt.Errorf("unsupported provider type %q", provider.Type)
+}
+
+// isHTTPProvider reports whether a provider is a Traefik HTTP provider
+// endpoint. Providers created before types existed have none.
+func isHTTPProvider(provider *models.HTTPProvider) bool {
+	return provider.Type == "" || provider.Type == models.ProviderTypeHTTP
+}
+
+// labelSourceResult converts label sets, as read from containers or catalog
+// services, into a source result
+func labelSourceResult(sets []declarative.LabelSet) (*SourceResult, error) {
+	config, issues := declarative.LabelsToDynamic(sets)
+
+	body, err := json.Marshal(DynamicConfig{HTTP: config.HTTP})
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		warnings = append(warnings, fmt.Sprintf("%s %s: %s", issue.Kind, issue.Name, issue.Reason))
+	}
+	return &SourceResult{Body: body, Warnings: warnings}, nil
+}
+
+// getSourceJSON queries a JSON API endpoint of a source
+func getSourceJSON(ctx context.Context, client *http.Client, provider *models.HTTPProvider, url string, v interface{}) error {
+	req, err := newSourceRequest(ctx, provider, url)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return logging.RedactError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d from %s: %s", resp.StatusCode, logging.RedactURL(url), strings.TrimSpace(string(message)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// emptyHTTPConfiguration returns a configuration with all maps allocated
+func emptyHTTPConfiguration() *dynamic.HTTPConfiguration {
+	return &dynamic.HTTPConfiguration{
+		Routers:           make(map[string]*dynamic.Router),
+		Services:          make(map[string]*dynamic.Service),
+		Middlewares:       make(map[string]*dynamic.Middleware),
+		ServersTransports: make(map[string]*dynamic.ServersTransport),
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/traefikx/backend/internal/declarative"
+	"github.com/traefikx/backend/internal/models"
+)
+
+// consulSource reads the traefik.* tags of the services in a Consul catalog
+// API. Each passing instance becomes a load balancer server. ACL tokens are
+// sent with bearer auth or an X-Consul-Token custom header.
+type consulSource struct {
+	provider *models.HTTPProvider
+	client   *http.Client
+	baseURL  string
+}
+
+// consulEntry is the part of a /v1/health/service entry the source uses
+type consulEntry struct {
+	Node struct {
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service struct {
+		Address string `json:"Address"`
+		Port    int    `json:"Port"`
+	} `json:"Service"`
+}
+
+func newConsulSource(provider *models.HTTPProvider, client *http.Client) *consulSource {
+	return &consulSource{
+		provider: provider,
+		client:   client,
+		baseURL:  strings.TrimSuffix(provider.URL, "/"),
+	}
+}
+
+func (s *consulSource) Fetch(ctx context.Context) (*SourceResult, error) {
+	var catalog map[string][]string
+	if err := getSourceJSON(ctx, s.client, s.provider, s.baseURL+"/v1/catalog/services", &catalog); err != nil {
+		return nil, fmt.Errorf("Consul API error: %w", err)
+	}
+
+	names := make([]string, 0, len(catalog))
+	for name, tags := range catalog {
+		// Services without traefik tags can't produce a router
+		if len(tagLabels(tags)) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	sets := make([]declarative.LabelSet, 0, len(names))
+	for _, name := range names {
+		var entries []consulEntry
+		endpoint := s.baseURL + "/v1/health/service/" + url.PathEscape(name) + "?passing=true"
+		if err := getSourceJSON(ctx, s.client, s.provider, endpoint, &entries); err != nil {
+			return nil, fmt.Errorf("Consul API error: %w", err)
+		}
+		if len(entries) == 0 {
+			continue
+		}
+
+		hosts := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			address := entry.Service.Address
+			if address == "" {
+				address = entry.Node.Address
+			}
+			hosts = append(hosts, net.JoinHostPort(address, strconv.Itoa(entry.Service.Port)))
+		}
+		sort.Strings(hosts)
+
+		sets = append(sets, declarative.LabelSet{
+			Name:   name,
+			Host:   hosts[0],
+			Hosts:  hosts[1:],
+			Labels: tagLabels(catalog[name]),
+		})
+	}
+
+	return labelSourceResult(sets)
+}
+
+// tagLabels turns traefik.* key=value tags into labels
+func tagLabels(tags []string) map[string]string {
+	labels := map[string]string{}
+	for _, tag := range tags {
+		key, value, ok := strings.Cut(tag, "=")
+		if ok && strings.HasPrefix(key, "traefik.") {
+			labels[key] = value
+		}
+	}
+	return labels
+}
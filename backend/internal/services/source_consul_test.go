@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/traefikx/backend/internal/models"
+)
+
+// consulCatalog is a /v1/catalog/services response
+const consulCatalog = `{
+	"consul": [],
+	"web": ["traefik.http.routers.web.rule=Host(` + "`web.example.com`" + `)", "traefik.http.routers.web.middlewares=web-strip",
+		"traefik.http.middlewares.web-strip.stripprefix.prefixes=/web", "v2"],
+	"down": ["traefik.http.routers.down.rule=Host(` + "`down.example.com`" + `)"],
+	"db": ["primary"]
+}`
+
+// consulHealth are /v1/health/service responses, by service
+var consulHealth = map[string]string{
+	"web": `[
+		{"Node": {"Address": "10.0.0.1"}, "Service": {"Address": "", "Port": 8080}},
+		{"Node": {"Address": "10.0.0.9"}, "Service": {"Address": "10.0.1.2", "Port": 8080}}
+	]`,
+	"down": `[]`,
+}
+
+// startFakeConsul serves a Consul catalog API that requires an ACL token,
+// and returns the paths requested
+func startFakeConsul(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.RequestURI())
+		mu.Unlock()
+
+		if r.Header.Get("X-Consul-Token") != "acl-token" {
+			http.Error(w, "ACL not found", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/catalog/services" {
+			w.Write([]byte(consulCatalog))
+			return
+		}
+		name, ok := strings.CutPrefix(r.URL.Path, "/v1/health/service/")
+		if !ok || r.URL.Query().Get("passing") != "true" || consulHealth[name] == "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(consulHealth[name]))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), requested...)
+	}
+}
+
+func newConsulTestSource(t *testing.T, url, token string) Source {
+	t.Helper()
+	provider := &models.HTTPProvider{Name: "consul", Type: models.ProviderTypeConsul, URL: url + "/", Timeout: 5}
+	provider.SetHeaders(map[string]string{"X-Consul-Token": token})
+	source, err := NewSource(provider, nil)
+	if err != nil {
+		t.Fatalf("NewSource: %v", err)
+	}
+	return source
+}
+
+func TestConsulSource_Fetch(t *testing.T) {
+	server, requested := startFakeConsul(t)
+
+	result, err := newConsulTestSource(t, server.URL, "acl-token").Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	var config DynamicConfig
+	if err := json.Unmarshal(result.Body, &config); err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	cfg := config.HTTP
+
+	// Only services with traefik tags and passing instances get routers
+	if got := sortedKeys(cfg.Routers); got != "web" {
+		t.Fatalf("routers = %s, want web", got)
+	}
+	web := cfg.Routers["web"]
+	if web.Rule != "Host(`web.example.com`)" || web.Service != "web" || len(web.Middlewares) != 1 || web.Middlewares[0] != "web-strip" {
+		t.Errorf("unexpected web router %+v", web)
+	}
+	if strip := cfg.Middlewares["web-strip"]; strip == nil || strip.StripPrefix == nil || strip.StripPrefix.Prefixes[0] != "/web" {
+		t.Errorf("unexpected web-strip middleware %+v", strip)
+	}
+
+	// Each instance is a server, at its service address or else its node's
+	var urls []string
+	for _, server := range cfg.Services["web"].LoadBalancer.Servers {
+		urls = append(urls, server.URL)
+	}
+	if strings.Join(urls, ",") != "http://10.0.0.1:8080,http://10.0.1.2:8080" {
+		t.Errorf("unexpected web servers %v", urls)
+	}
+
+	// The health of services without traefik tags isn't queried
+	want := "/v1/catalog/services,/v1/health/service/down?passing=true,/v1/health/service/web?passing=true"
+	if got := strings.Join(requested(), ","); got != want {
+		t.Errorf("requested %s, want %s", got, want)
+	}
+}
+
+func TestConsulSource_FetchError(t *testing.T) {
+	server, _ := startFakeConsul(t)
+
+	_, err := newConsulTestSource(t, server.URL, "wrong").Fetch(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "Consul API error") || !strings.Contains(err.Error(), "HTTP 403") {
+		t.Fatalf("expected a Consul API error, got %v", err)
+	}
+}
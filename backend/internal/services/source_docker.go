@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/traefikx/backend/internal/declarative"
+	"github.com/traefikx/backend/internal/models"
+)
+
+// dockerSource reads the traefik.* labels of the running containers of a
+// Docker Engine API, the way Traefik's docker provider does
+type dockerSource struct {
+	provider *models.HTTPProvider
+	client   *http.Client
+	baseURL  string
+}
+
+// dockerContainer is the part of a /containers/json entry the source uses
+type dockerContainer struct {
+	ID     string            `json:"Id"`
+	Names  []string          `json:"Names"`
+	Labels map[string]string `json:"Labels"`
+	Ports  []struct {
+		PrivatePort int    `json:"PrivatePort"`
+		Type        string `json:"Type"`
+	} `json:"Ports"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress string `json:"IPAddress"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+func newDockerSource(provider *models.HTTPProvider, client *http.Client) (*dockerSource, error) {
+	u, err := url.Parse(provider.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Docker endpoint: %w", err)
+	}
+
+	source := &dockerSource{provider: provider, client: client}
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		var dialer net.Dialer
+		source.client = &http.Client{
+			Timeout: client.Timeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", socket)
+				},
+				DisableKeepAlives: true,
+			},
+		}
+		source.baseURL = "http://docker"
+	case "tcp":
+		source.baseURL = "http://" + u.Host
+	default:
+		source.baseURL = strings.TrimSuffix(provider.URL, "/")
+	}
+	return source, nil
+}
+
+func (s *dockerSource) Fetch(ctx context.Context) (*SourceResult, error) {
+	var containers []dockerContainer
+	if err := getSourceJSON(ctx, s.client, s.provider, s.baseURL+"/containers/json", &containers); err != nil {
+		return nil, fmt.Errorf("Docker API error: %w", err)
+	}
+
+	sets := make([]declarative.LabelSet, 0, len(containers))
+	for _, container := range containers {
+		sets = append(sets, declarative.LabelSet{
+			Name:   containerName(container),
+			Host:   containerHost(container),
+			Labels: container.Labels,
+		})
+	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Name < sets[j].Name })
+
+	return labelSourceResult(sets)
+}
+
+func containerName(container dockerContainer) string {
+	if len(container.Names) > 0 {
+		return strings.TrimPrefix(container.Names[0], "/")
+	}
+	if len(container.ID) > 12 {
+		return container.ID[:12]
+	}
+	return container.ID
+}
+
+// containerHost returns the container's address on the network named by
+// the traefik.docker.network label, or its first network, with its lowest
+// exposed TCP port
+func containerHost(container dockerContainer) string {
+	networks := container.NetworkSettings.Networks
+	ip := ""
+	if network, ok := networks[container.Labels["traefik.docker.network"]]; ok {
+		ip = network.IPAddress
+	}
+	if ip == "" {
+		names := make([]string, 0, len(networks))
+		for name := range networks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if networks[name].IPAddress != "" {
+				ip = networks[name].IPAddress
+				break
+			}
+		}
+	}
+	if ip == "" {
+		return ""
+	}
+
+	port := 0
+	for _, p := range container.Ports {
+		if (p.Type == "" || p.Type == "tcp") && p.PrivatePort > 0 && (port == 0 || p.PrivatePort < port) {
+			port = p.PrivatePort
+		}
+	}
+	if port == 0 {
+		return ip
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(port))
+}
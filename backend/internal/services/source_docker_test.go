@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/traefikx/backend/internal/models"
+)
+
+// dockerContainers is a /containers/json response
+const dockerContainers = `[
+	{
+		"Id": "c1",
+		"Names": ["/whoami"],
+		"Labels": {"traefik.http.routers.whoami.rule": "Host(` + "`whoami.example.com`" + `)"},
+		"Ports": [{"PrivatePort": 443, "Type": "tcp"}, {"PrivatePort": 80, "Type": "tcp"}, {"PrivatePort": 53, "Type": "udp"}],
+		"NetworkSettings": {"Networks": {"proxy": {"IPAddress": "172.18.0.5"}}}
+	},
+	{
+		"Id": "c2",
+		"Names": ["/api"],
+		"Labels": {
+			"traefik.docker.network": "backend",
+			"traefik.http.routers.api.rule": "Host(` + "`api.example.com`" + `)",
+			"traefik.http.routers.api.middlewares": "api-strip",
+			"traefik.http.middlewares.api-strip.stripprefix.prefixes": "/api",
+			"traefik.http.services.api.loadbalancer.server.port": "8080"
+		},
+		"NetworkSettings": {"Networks": {"bridge": {"IPAddress": "172.17.0.3"}, "backend": {"IPAddress": "10.0.0.7"}}}
+	},
+	{
+		"Id": "c3",
+		"Names": ["/hidden"],
+		"Labels": {"traefik.enable": "false", "traefik.http.routers.hidden.rule": "Host(` + "`hidden.example.com`" + `)"},
+		"NetworkSettings": {"Networks": {"proxy": {"IPAddress": "172.18.0.9"}}}
+	},
+	{
+		"Id": "0123456789abcdef",
+		"Labels": {"traefik.http.routers.orphan.rule": "Host(` + "`orphan.example.com`" + `)"}
+	}
+]`
+
+// startFakeDocker serves the Docker Engine API on a unix socket and returns
+// its path
+func startFakeDocker(t *testing.T) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(dockerContainers))
+	}))
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return socket
+}
+
+func TestDockerSource_Fetch(t *testing.T) {
+	socket := startFakeDocker(t)
+	source, err := NewSource(&models.HTTPProvider{
+		Name:    "docker",
+		Type:    models.ProviderTypeDocker,
+		URL:     "unix://" + socket,
+		Timeout: 5,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewSource: %v", err)
+	}
+
+	result, err := source.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	var config DynamicConfig
+	if err := json.Unmarshal(result.Body, &config); err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	cfg := config.HTTP
+
+	if cfg.Routers["whoami"] == nil || cfg.Routers["api"] == nil {
+		t.Fatalf("expected the whoami and api routers, got %v", keys(cfg.Routers))
+	}
+
+	// Router without service gets one for its container, on its lowest TCP port
+	whoami := cfg.Routers["whoami"]
+	if whoami == nil || whoami.Rule != "Host(`whoami.example.com`)" || whoami.Service != "whoami" {
+		t.Errorf("unexpected whoami router %+v", whoami)
+	}
+	if servers := cfg.Services["whoami"].LoadBalancer.Servers; len(servers) != 1 || servers[0].URL != "http://172.18.0.5:80" {
+		t.Errorf("unexpected whoami servers %+v", servers)
+	}
+
+	// The traefik.docker.network label selects the address
+	api := cfg.Routers["api"]
+	if api == nil || api.Service != "api" || len(api.Middlewares) != 1 || api.Middlewares[0] != "api-strip" {
+		t.Errorf("unexpected api router %+v", api)
+	}
+	if servers := cfg.Services["api"].LoadBalancer.Servers; len(servers) != 1 || servers[0].URL != "http://10.0.0.7:8080" {
+		t.Errorf("unexpected api servers %+v", servers)
+	}
+	if strip := cfg.Middlewares["api-strip"]; strip == nil || strip.StripPrefix == nil || strip.StripPrefix.Prefixes[0] != "/api" {
+		t.Errorf("unexpected api-strip middleware %+v", strip)
+	}
+
+	// Disabled containers are skipped, containers without address reported
+	if _, ok := cfg.Routers["hidden"]; ok {
+		t.Error("expected the container with traefik.enable=false to be skipped")
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "orphan") || !strings.Contains(result.Warnings[0], "0123456789ab") {
+		t.Errorf("expected a warning about the orphan router, got %v", result.Warnings)
+	}
+}
+
+func TestDockerSource_FetchError(t *testing.T) {
+	source, err := NewSource(&models.HTTPProvider{
+		Type: models.ProviderTypeDocker,
+		URL:  "unix://" + filepath.Join(t.TempDir(), "missing.sock"),
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewSource: %v", err)
+	}
+
+	if _, err := source.Fetch(context.Background()); err == nil || !strings.Contains(err.Error(), "Docker API error") {
+		t.Fatalf("expected a Docker API error, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/traefikx/backend/internal/declarative"
+	"github.com/traefikx/backend/internal/models"
+)
+
+// fileDebounce groups the bursts of events editors and deploy tools cause
+const fileDebounce = 500 * time.Millisecond
+
+// fileSource reads the Traefik dynamic configuration files (.yml, .yaml or
+// .toml) of a directory, like Traefik's file provider does. An invalid file
+// fails the whole fetch, so the last good configuration is kept.
+type fileSource struct {
+	dir string
+}
+
+func newFileSource(provider *models.HTTPProvider) *fileSource {
+	return &fileSource{dir: strings.TrimPrefix(provider.URL, "file://")}
+}
+
+func (s *fileSource) Fetch(ctx context.Context) (*SourceResult, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	config := emptyHTTPConfiguration()
+	origin := make(map[string]string)
+	warnings := []string{}
+	for _, entry := range entries {
+		format := dynamicFileFormat(entry.Name())
+		if entry.IsDir() || format == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		file, err := declarative.ParseDynamicConfig(data, format)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		if file.HTTP == nil {
+			continue
+		}
+
+		warnings = append(warnings, addFileResources(config.Routers, file.HTTP.Routers, "router", entry.Name(), origin)...)
+		warnings = append(warnings, addFileResources(config.Services, file.HTTP.Services, "service", entry.Name(), origin)...)
+		warnings = append(warnings, addFileResources(config.Middlewares, file.HTTP.Middlewares, "middleware", entry.Name(), origin)...)
+		warnings = append(warnings, addFileResources(config.ServersTransports, file.HTTP.ServersTransports, "serversTransport", entry.Name(), origin)...)
+	}
+
+	body, err := json.Marshal(DynamicConfig{HTTP: config})
+	if err != nil {
+		return nil, err
+	}
+	return &SourceResult{Body: body, Warnings: warnings}, nil
+}
+
+// Watch reports changes to the directory
+func (s *fileSource) Watch(ctx context.Context, changed func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(s.dir); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				debounce = time.After(fileDebounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Error("Error watching directory", "dir", s.dir, "error", err)
+			case <-debounce:
+				debounce = nil
+				changed()
+			}
+		}
+	}()
+	return nil
+}
+
+// dynamicFileFormat returns the format of a configuration file, or "" for
+// other files
+func dynamicFileFormat(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yml", ".yaml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	}
+	return ""
+}
+
+// addFileResources adds the resources of one file, keeping the first
+// definition of names several files define
+func addFileResources[T any](dst, src map[string]T, kind, file string, origin map[string]string) []string {
+	var warnings []string
+	for name, value := range src {
+		key := kind + "/" + name
+		if other, exists := origin[key]; exists {
+			warnings = append(warnings, fmt.Sprintf("%s %s: defined in %s and %s; keeping %s", kind, name, other, file, other))
+			continue
+		}
+		origin[key] = file
+		dst[name] = value
+	}
+	return warnings
+}
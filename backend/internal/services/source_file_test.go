@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/traefikx/backend/internal/models"
+)
+
+const appYAML = `
+http:
+  routers:
+    app:
+      rule: Host(` + "`app.example.com`" + `)
+      service: app
+      middlewares: [strip]
+  services:
+    app:
+      loadBalancer:
+        servers:
+          - url: http://app:8080
+  middlewares:
+    strip:
+      stripPrefix:
+        prefixes: [/app]
+`
+
+const apiTOML = `
+[http.routers.api]
+  rule = "Host(` + "`api.example.com`" + `)"
+  service = "api"
+
+[[http.services.api.loadBalancer.servers]]
+  url = "http://api:8080"
+`
+
+// writeFile writes a file of a configuration directory
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func fetchFileSource(t *testing.T, dir string) (*SourceResult, error) {
+	t.Helper()
+	source, err := NewSource(&models.HTTPProvider{Name: "files", Type: models.ProviderTypeFile, URL: "file://" + dir}, nil)
+	if err != nil {
+		t.Fatalf("NewSource: %v", err)
+	}
+	return source.Fetch(context.Background())
+}
+
+func TestFileSource_Fetch(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "app.yml", appYAML)
+	writeFile(t, dir, "api.toml", apiTOML)
+	writeFile(t, dir, "z-copy.yaml", "http:\n  services:\n    app:\n      loadBalancer:\n        servers:\n          - url: http://copy:8080\n")
+	writeFile(t, dir, "empty.yml", "# Nothing yet\n")
+	writeFile(t, dir, "README.md", "not: [a config")
+	if err := os.Mkdir(filepath.Join(dir, "old.yml"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	result, err := fetchFileSource(t, dir)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	var config DynamicConfig
+	if err := json.Unmarshal(result.Body, &config); err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	cfg := config.HTTP
+
+	// YAML and TOML files are merged, other files and directories ignored
+	if got := sortedKeys(cfg.Routers); got != "api,app" {
+		t.Fatalf("routers = %s, want api,app", got)
+	}
+	if app := cfg.Routers["app"]; app.Service != "app" || len(app.Middlewares) != 1 || app.Middlewares[0] != "strip" {
+		t.Errorf("unexpected app router %+v", app)
+	}
+	if api := cfg.Routers["api"]; api.Rule != "Host(`api.example.com`)" || api.Service != "api" {
+		t.Errorf("unexpected api router %+v", api)
+	}
+	if servers := cfg.Services["api"].LoadBalancer.Servers; len(servers) != 1 || servers[0].URL != "http://api:8080" {
+		t.Errorf("unexpected api servers %+v", servers)
+	}
+	if strip := cfg.Middlewares["strip"]; strip == nil || strip.StripPrefix == nil || strip.StripPrefix.Prefixes[0] != "/app" {
+		t.Errorf("unexpected strip middleware %+v", strip)
+	}
+
+	// Names defined by several files keep the first definition
+	if servers := cfg.Services["app"].LoadBalancer.Servers; len(servers) != 1 || servers[0].URL != "http://app:8080" {
+		t.Errorf("expected app.yml's app service, got %+v", servers)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "service app: defined in app.yml and z-copy.yaml") {
+		t.Errorf("expected a warning about the app service, got %v", result.Warnings)
+	}
+}
+
+func TestFileSource_FetchError(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "app.yml", appYAML)
+	writeFile(t, dir, "broken.toml", "[http.routers.broken\n")
+
+	if _, err := fetchFileSource(t, dir); err == nil || !strings.HasPrefix(err.Error(), "broken.toml: ") {
+		t.Errorf("expected broken.toml to fail the fetch, got %v", err)
+	}
+	if _, err := fetchFileSource(t, filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected a missing directory to fail the fetch")
+	}
+}
+
+// waitForRouters polls a provider's status until its config has routers
+func waitForRouters(t *testing.T, a *AggregatorService, id uint, routers string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, ok := a.GetStatus(id)
+		if ok && status.Config != nil && sortedKeys(status.Config.Routers) == routers {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for routers %s, last status %+v", routers, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFileSource_WatchReloads(t *testing.T) {
+	db := newTestDB(t)
+	dir := t.TempDir()
+	writeFile(t, dir, "app.yml", appYAML)
+
+	// Polls are an hour apart, only the watch can pick up changes
+	provider := &models.HTTPProvider{Name: "files", Type: models.ProviderTypeFile, URL: "file://" + dir,
+		IsActive: true, RefreshInterval: 3600, Timeout: 5}
+	if err := db.Create(provider).Error; err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+
+	a := NewAggregatorService(db, nil, 1, 0, 0)
+	a.Start()
+	defer a.Stop()
+	waitForRouters(t, a, provider.ID, "app")
+
+	writeFile(t, dir, "api.toml", apiTOML)
+	waitForRouters(t, a, provider.ID, "api,app")
+
+	if err := os.Remove(filepath.Join(dir, "app.yml")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	waitForRouters(t, a, provider.ID, "api")
+
+	// The last response is stored like an HTTP provider's
+	var stored models.HTTPProvider
+	if err := db.First(&stored, provider.ID).Error; err != nil {
+		t.Fatalf("load provider: %v", err)
+	}
+	if !strings.Contains(string(stored.LastResponse), `"api"`) || strings.Contains(string(stored.LastResponse), `"app"`) {
+		t.Errorf("unexpected stored response %s", stored.LastResponse)
+	}
+}
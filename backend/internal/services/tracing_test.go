@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/traefikx/backend/internal/events"
+	"github.com/traefikx/backend/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider keeping ended spans in memory
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		provider.Shutdown(t.Context())
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+// findSpan returns the first ended span named name accepted by match
+func findSpan(spans []sdktrace.ReadOnlySpan, name string, match func(sdktrace.ReadOnlySpan) bool) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.Name() == name && (match == nil || match(span)) {
+			return span
+		}
+	}
+	return nil
+}
+
+// childOf accepts the spans whose parent is parent
+func childOf(parent sdktrace.ReadOnlySpan) func(sdktrace.ReadOnlySpan) bool {
+	return func(span sdktrace.ReadOnlySpan) bool {
+		return span.Parent().SpanID() == parent.SpanContext().SpanID()
+	}
+}
+
+// linkedTo accepts the spans with a link to target
+func linkedTo(target sdktrace.ReadOnlySpan) func(sdktrace.ReadOnlySpan) bool {
+	return func(span sdktrace.ReadOnlySpan) bool {
+		for _, link := range span.Links() {
+			if link.SpanContext.SpanID() == target.SpanContext().SpanID() {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestTracing_RefreshFetchCompile(t *testing.T) {
+	recorder := recordSpans(t)
+	db := newTestDB(t)
+	if err := db.Use(tracing.GORMPlugin{}); err != nil {
+		t.Fatalf("GORM plugin: %v", err)
+	}
+
+	var mu sync.Mutex
+	var traceparent string
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceparent = r.Header.Get("traceparent")
+		mu.Unlock()
+		w.Write([]byte(`{"http":{"routers":{"app":{"rule":"Host(` + "`app.example.com`" + `)","service":"app"}},` +
+			`"services":{"app":{"loadBalancer":{"servers":[{"url":"http://app:8080"}]}}}}}`))
+	}))
+	defer provider.Close()
+	// Inactive, so it's only fetched when refreshed
+	stored := createProvider(t, db, "app", provider.URL)
+	db.Model(stored).Update("is_active", false)
+
+	bus := events.NewBus()
+	aggregator := NewAggregatorService(db, bus, 4, 10, 0)
+	compiler := NewConfigCompiler(db, aggregator, bus)
+	compiler.Start()
+	defer compiler.Stop()
+	aggregator.Start()
+	defer aggregator.Stop()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(tracing.Middleware())
+	router.POST("/api/traefik/http-providers/:id/refresh", func(c *gin.Context) {
+		if err := aggregator.RefreshProvider(c.Request.Context(), stored.ID); err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		c.Status(http.StatusAccepted)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/traefik/http-providers/%d/refresh", stored.ID), nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("refresh returned %d", w.Code)
+	}
+
+	// The compile triggered by the fetch ends last
+	var request, fetch, compile sdktrace.ReadOnlySpan
+	deadline := time.Now().Add(5 * time.Second)
+	for compile == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the spans, got request %v, fetch %v", request != nil, fetch != nil)
+		}
+		time.Sleep(10 * time.Millisecond)
+		spans := recorder.Ended()
+		request = findSpan(spans, "POST /api/traefik/http-providers/:id/refresh", nil)
+		if request == nil {
+			continue
+		}
+		if fetch = findSpan(spans, "provider.fetch", childOf(request)); fetch != nil {
+			compile = findSpan(spans, "config.compile", linkedTo(fetch))
+		}
+	}
+	spans := recorder.Ended()
+
+	if request.SpanKind() != trace.SpanKindServer || request.Parent().IsValid() {
+		t.Errorf("expected the request span to be a server root span, got %v with parent %v", request.SpanKind(), request.Parent())
+	}
+	if fetch.SpanContext().TraceID() != request.SpanContext().TraceID() {
+		t.Error("expected the fetch to join the request's trace")
+	}
+
+	// The fetch's queries and request to the provider are its children
+	if findSpan(spans, "SELECT http_providers", childOf(request)) == nil {
+		t.Error("expected the request's query of the provider to be traced")
+	}
+	if findSpan(spans, "INSERT provider_fetches", childOf(fetch)) == nil {
+		t.Error("expected the fetch's queries to be children of the fetch")
+	}
+	client := findSpan(spans, "HTTP GET", childOf(fetch))
+	if client == nil || client.SpanKind() != trace.SpanKindClient {
+		t.Fatal("expected a client span for the request to the provider")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(traceparent, client.SpanContext().SpanID().String()) ||
+		!strings.Contains(traceparent, fetch.SpanContext().TraceID().String()) {
+		t.Errorf("expected the provider to receive the client span's traceparent, got %q", traceparent)
+	}
+
+	// The compile runs after the request was answered, in its own trace
+	// linked to the fetch, with its merge and queries as children
+	if compile.Parent().IsValid() || compile.SpanContext().TraceID() == fetch.SpanContext().TraceID() {
+		t.Error("expected the compile to start a trace of its own")
+	}
+	if findSpan(spans, "config.merge", childOf(compile)) == nil {
+		t.Error("expected config.merge to be a child of config.compile")
+	}
+	if findSpan(spans, "SELECT routers", childOf(compile)) == nil {
+		t.Error("expected the compile's queries to be children of the compile")
+	}
+}
@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "tracing:span"
+
+// GORMPlugin records a span per query run with a context that carries a
+// span, e.g. db.WithContext(ctx) while handling a traced request. Queries
+// without one, like those of the background workers, aren't traced so that
+// they don't each start a trace. The query text is recorded without its
+// parameters, which may be secrets.
+type GORMPlugin struct{}
+
+// Name implements gorm.Plugin
+func (GORMPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize implements gorm.Plugin
+func (GORMPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	register := []struct {
+		operation string
+		before    func(string, func(*gorm.DB)) error
+		after     func(string, func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("*").Register, callbacks.Create().After("*").Register},
+		{"query", callbacks.Query().Before("*").Register, callbacks.Query().After("*").Register},
+		{"update", callbacks.Update().Before("*").Register, callbacks.Update().After("*").Register},
+		{"delete", callbacks.Delete().Before("*").Register, callbacks.Delete().After("*").Register},
+		{"row", callbacks.Row().Before("*").Register, callbacks.Row().After("*").Register},
+		{"raw", callbacks.Raw().Before("*").Register, callbacks.Raw().After("*").Register},
+	}
+	for _, r := range register {
+		if err := r.before("tracing:before_"+r.operation, startQuery); err != nil {
+			return err
+		}
+		if err := r.after("tracing:after_"+r.operation, endQuery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startQuery(tx *gorm.DB) {
+	ctx := tx.Statement.Context
+	if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+	ctx, span := Start(ctx, "gorm", trace.WithSpanKind(trace.SpanKindClient))
+	tx.Statement.Context = ctx
+	tx.InstanceSet(gormSpanKey, span)
+}
+
+func endQuery(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	query := tx.Statement.SQL.String()
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	operation = strings.ToUpper(operation)
+	table := tx.Statement.Table
+
+	// Named after the operation and table, like other database clients
+	name := strings.TrimSpace(operation + " " + table)
+	if name == "" {
+		name = "gorm"
+	}
+	span.SetName(name)
+
+	attrs := []attribute.KeyValue{dbSystem(tx.Dialector.Name())}
+	if query != "" {
+		attrs = append(attrs, semconv.DBQueryText(query))
+	}
+	if operation != "" {
+		attrs = append(attrs, semconv.DBOperationName(operation))
+	}
+	if table != "" {
+		attrs = append(attrs, semconv.DBCollectionName(table))
+	}
+	if tx.Statement.RowsAffected >= 0 {
+		attrs = append(attrs, attribute.Int64("db.rows_affected", tx.Statement.RowsAffected))
+	}
+	span.SetAttributes(attrs...)
+
+	if err := tx.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		Fail(span, err)
+	}
+}
+
+func dbSystem(dialector string) attribute.KeyValue {
+	switch dialector {
+	case "postgres":
+		return semconv.DBSystemNamePostgreSQL
+	case "mysql":
+		return semconv.DBSystemNameMySQL
+	case "sqlite":
+		return semconv.DBSystemNameSQLite
+	}
+	return semconv.DBSystemNameKey.String(dialector)
+}
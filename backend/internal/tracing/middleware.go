@@ -0,0 +1,24 @@
+package tracing
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// untracedRoutes are polled by monitoring, a trace each would drown the
+// others, or stream for as long as a client stays connected
+var untracedRoutes = map[string]bool{
+	"/api/health": true,
+	"/api/events": true,
+}
+
+// Middleware records a span per API request, named after its route, which
+// continues the trace of the caller if it sent a traceparent header. Static
+// files, monitoring polls and event streams aren't traced.
+func Middleware() gin.HandlerFunc {
+	return otelgin.Middleware(ServiceName, otelgin.WithGinFilter(func(c *gin.Context) bool {
+		return strings.HasPrefix(c.Request.URL.Path, "/api/") && !untracedRoutes[c.FullPath()]
+	}))
+}
@@ -0,0 +1,123 @@
+// Package tracing sets up OpenTelemetry tracing: the exporter, the global
+// tracer provider and the W3C trace context propagation used by the API,
+// provider fetches and database queries.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/traefikx/backend/internal/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// OTLP protocols
+const (
+	ProtocolHTTP = "http/protobuf"
+	ProtocolGRPC = "grpc"
+)
+
+// instrumentationName names the tracer of TraefikX's own spans
+const instrumentationName = "github.com/traefikx/backend"
+
+// ServiceName is the default service.name, OTEL_SERVICE_NAME overrides it
+const ServiceName = "traefikx"
+
+// Options selects where spans go
+type Options struct {
+	Exporter   string // none, otlp or stdout
+	Protocol   string // OTLP protocol, http/protobuf or grpc
+	InstanceID string // service.instance.id, the HA instance ID
+}
+
+// Setup installs the global tracer provider and propagator. The OTLP
+// exporter reads its endpoint, headers and TLS settings from the standard
+// OTEL_EXPORTER_OTLP_* variables and the sampler from OTEL_TRACES_SAMPLER.
+// With no exporter spans aren't recorded, but incoming trace context is
+// still passed on to providers. The returned function flushes pending
+// spans.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(opts.Exporter) {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		switch strings.ToLower(opts.Protocol) {
+		case ProtocolHTTP, "":
+			exporter, err = otlptracehttp.New(ctx)
+		case ProtocolGRPC:
+			exporter, err = otlptracegrpc.New(ctx)
+		default:
+			return nil, fmt.Errorf("invalid OTLP protocol %q, expected http/protobuf or grpc", opts.Protocol)
+		}
+	case ExporterStdout, "console":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("invalid traces exporter %q, expected none, otlp or stdout", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s exporter: %w", opts.Exporter, err)
+	}
+
+	attrs := []resource.Option{resource.WithAttributes(
+		semconv.ServiceName(ServiceName),
+		semconv.ServiceVersion(version.Version),
+	)}
+	if opts.InstanceID != "" {
+		attrs = append(attrs, resource.WithAttributes(semconv.ServiceInstanceID(opts.InstanceID)))
+	}
+	// Applied last, OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win
+	attrs = append(attrs, resource.WithTelemetrySDK(), resource.WithHost(), resource.WithFromEnv())
+	res, err := resource.New(ctx, attrs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of TraefikX's own spans, from the global
+// provider so that it follows Setup
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start begins a span named name, a child of the span of ctx if any
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// Fail marks span failed with err
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
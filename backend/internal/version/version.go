@@ -0,0 +1,41 @@
+// Package version holds the build information embedded by the linker:
+//
+//	go build -ldflags "-X github.com/traefikx/backend/internal/version.Version=v1.2.0 \
+//	  -X github.com/traefikx/backend/internal/version.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/traefikx/backend/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+import "runtime/debug"
+
+// Set at build time, see the package comment
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// Info describes the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information. Without ldflags, the commit comes from
+// the VCS stamp go build records in a checkout.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, BuildDate: BuildDate}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info.GoVersion = build.GoVersion
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" && info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	return info
+}
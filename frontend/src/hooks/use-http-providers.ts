@@ -79,12 +79,13 @@ export function useHTTPProviders() {
   const testProvider = useMutation({
     mutationFn: (id: number) => httpProvidersApi.testProvider(id),
     onSuccess: (data) => {
-      queryClient.invalidateQueries({ queryKey: ["http-providers"] });
-      const provider = data.data;
-      if (provider.last_error) {
-        toast.warning(`HTTP Provider test completed with error: ${provider.last_error}`);
+      const result = data.data;
+      if (!result.success) {
+        toast.warning(`HTTP Provider test failed (${result.stage}): ${result.error}`);
       } else {
-        toast.success("HTTP Provider is healthy");
+        toast.success(
+          `HTTP Provider is healthy: ${result.counts.routers} routers, ${result.counts.services} services, ${result.counts.middlewares} middlewares`
+        );
       }
     },
     onError: (error: any) => {
@@ -157,13 +158,13 @@ export function useHTTPProvider(id: number) {
   const testProvider = useMutation({
     mutationFn: () => httpProvidersApi.testProvider(id),
     onSuccess: (data) => {
-      queryClient.invalidateQueries({ queryKey: ["http-providers", id] });
-      queryClient.invalidateQueries({ queryKey: ["http-providers"] });
-      const provider = data.data;
-      if (provider.last_error) {
-        toast.warning(`HTTP Provider test completed with error: ${provider.last_error}`);
+      const result = data.data;
+      if (!result.success) {
+        toast.warning(`HTTP Provider test failed (${result.stage}): ${result.error}`);
       } else {
-        toast.success("HTTP Provider is healthy");
+        toast.success(
+          `HTTP Provider is healthy: ${result.counts.routers} routers, ${result.counts.services} services, ${result.counts.middlewares} middlewares`
+        );
       }
     },
     onError: (error: any) => {
@@ -3,7 +3,7 @@ import axios, {
   AxiosInstance,
   InternalAxiosRequestConfig,
 } from "axios";
-import { AuthResponse, User, ApiError, Service, Middleware, Router, CreateServiceRequest, UpdateServiceRequest, CreateMiddlewareRequest, UpdateMiddlewareRequest, CreateRouterRequest, UpdateRouterRequest, ProxyHost, CreateProxyHostRequest, UpdateProxyHostRequest, HTTPProvider, CreateHTTPProviderRequest, UpdateHTTPProviderRequest, MergedTraefikConfig } from "@/types";
+import { AuthResponse, User, ApiError, Service, Middleware, Router, CreateServiceRequest, UpdateServiceRequest, CreateMiddlewareRequest, UpdateMiddlewareRequest, CreateRouterRequest, UpdateRouterRequest, ProxyHost, CreateProxyHostRequest, UpdateProxyHostRequest, HTTPProvider, CreateHTTPProviderRequest, UpdateHTTPProviderRequest, MergedTraefikConfig, ConfigFormat, ImportResult, PublisherStatus, ConfigConflict, ConflictPolicy, ConflictPolicyRequest, ProviderFetch, ProviderFetchFilters, ProviderTestResult, TestHTTPProviderRequest } from "@/types";
 
 // Determine the base URL based on environment
 // Development: use full URL to backend on port 8080
@@ -130,6 +130,30 @@ export const authApi = {
     api.get<AuthResponse>("/api/auth/oidc/callback", {
       params: { code, state },
     }),
+
+  // Invitations and password reset
+  getInvite: (token: string) =>
+    api.get<{ email: string; expires_at: string; oidc_enabled: boolean }>(
+      "/api/auth/invite",
+      { params: { token } },
+    ),
+
+  acceptInvite: (token: string, password: string) =>
+    api.post<AuthResponse>("/api/auth/invite/accept", { token, password }),
+
+  acceptInviteWithOIDC: (token: string) =>
+    api.post<{ auth_url: string; state: string }>("/api/auth/invite/oidc", {
+      token,
+    }),
+
+  forgotPassword: (email: string) =>
+    api.post("/api/auth/password/forgot", { email }),
+
+  resetPasswordWithToken: (token: string, newPassword: string) =>
+    api.post("/api/auth/password/reset", {
+      token,
+      new_password: newPassword,
+    }),
 };
 
 // Users API
@@ -143,6 +167,7 @@ export const usersApi = {
     password?: string;
     role: "admin" | "user";
     oidc_enabled: boolean;
+    send_invite?: boolean;
   }) => api.post<User>("/api/users", data),
 
   updateUser: (
@@ -165,6 +190,13 @@ export const usersApi = {
 
   toggleUserOIDC: (id: number, enabled: boolean) =>
     api.post(`/api/users/${id}/oidc/toggle`, { enabled }),
+
+  unlockUser: (id: number) => api.post<User>(`/api/users/${id}/unlock`),
+
+  inviteUser: (id: number) => api.post(`/api/users/${id}/invite`),
+
+  sendPasswordResetEmail: (id: number) =>
+    api.post(`/api/users/${id}/reset-password`, { send_email: true }),
 };
 
 // Services API (under /traefik)
@@ -254,10 +286,98 @@ export const httpProvidersApi = {
     api.post(`/api/traefik/http-providers/${id}/refresh`),
 
   testProvider: (id: number) =>
-    api.post<HTTPProvider>(`/api/traefik/http-providers/${id}/test`),
+    api.post<ProviderTestResult>(`/api/traefik/http-providers/${id}/test`),
+
+  // Tests unsaved settings, or a saved provider by id
+  testSettings: (data: TestHTTPProviderRequest) =>
+    api.post<ProviderTestResult>("/api/traefik/http-providers/test", data),
+
+  listFetches: (id: number, filters: ProviderFetchFilters = {}) =>
+    api.get<{ fetches: ProviderFetch[]; next_before?: number }>(
+      `/api/traefik/http-providers/${id}/fetches`,
+      { params: filters }
+    ),
 
   getMergedConfig: () =>
     api.get<MergedTraefikConfig>("/api/traefik/merged-config"),
 };
 
+// Declarative config API (admin only)
+export const declarativeApi = {
+  exportConfig: (format: ConfigFormat = "yaml", includeUsers = false) =>
+    api.get<string>("/api/traefik/export", {
+      params: { format, include_users: includeUsers },
+      responseType: "text",
+    }),
+
+  importConfig: (
+    document: string,
+    options: {
+      format?: ConfigFormat;
+      mode?: "merge" | "replace";
+      conflict?: "overwrite" | "skip";
+      dryRun?: boolean;
+    } = {}
+  ) =>
+    api.post<ImportResult>("/api/traefik/import", document, {
+      params: {
+        format: options.format ?? "yaml",
+        mode: options.mode ?? "merge",
+        conflict: options.conflict ?? "overwrite",
+        dry_run: options.dryRun ?? false,
+      },
+      headers: { "Content-Type": "text/plain" },
+    }),
+
+  // Import a Traefik dynamic config file or a JSON list of docker label sets
+  importTraefikConfig: (
+    content: string,
+    options: {
+      source?: "file" | "docker";
+      format?: ConfigFormat;
+      mode?: "merge" | "replace";
+      conflict?: "overwrite" | "skip";
+      dryRun?: boolean;
+    } = {}
+  ) =>
+    api.post<ImportResult>("/api/traefik/import/traefik", content, {
+      params: {
+        source: options.source ?? "file",
+        format: options.format ?? (options.source === "docker" ? "json" : "yaml"),
+        mode: options.mode ?? "merge",
+        conflict: options.conflict ?? "overwrite",
+        dry_run: options.dryRun ?? false,
+      },
+      headers: { "Content-Type": "text/plain" },
+    }),
+};
+
+// Config publishers (file provider directory, Redis, etcd)
+export const publishersApi = {
+  list: () =>
+    api.get<{ publishers: PublisherStatus[] }>("/api/traefik/publishers"),
+
+  sync: () => api.post<{ message: string }>("/api/traefik/publishers/sync"),
+};
+
+// Conflicts API (admin only)
+export const conflictsApi = {
+  list: (status: "open" | "resolved" | "all" = "open") =>
+    api.get<{ conflicts: ConfigConflict[] }>("/api/traefik/conflicts", {
+      params: { status },
+    }),
+
+  listPolicies: () =>
+    api.get<{ policies: ConflictPolicy[] }>("/api/traefik/conflict-policies"),
+
+  createPolicy: (data: ConflictPolicyRequest) =>
+    api.post<ConflictPolicy>("/api/traefik/conflict-policies", data),
+
+  updatePolicy: (id: number, data: ConflictPolicyRequest) =>
+    api.put<ConflictPolicy>(`/api/traefik/conflict-policies/${id}`, data),
+
+  deletePolicy: (id: number) =>
+    api.delete<{ message: string }>(`/api/traefik/conflict-policies/${id}`),
+};
+
 export default api;
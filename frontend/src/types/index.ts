@@ -26,6 +26,22 @@ export interface UserResponse {
   created_at: string;
   updated_at: string;
   last_login_at?: string;
+  failed_login_attempts: number;
+  last_failed_login_at?: string;
+  locked_until?: string;
+  is_locked: boolean;
+  failed_login_history?: LoginAttempt[];
+}
+
+export interface LoginAttempt {
+  id: number;
+  user_id?: number;
+  email: string;
+  ip_address: string;
+  user_agent?: string;
+  success: boolean;
+  reason?: string;
+  created_at: string;
 }
 
 export interface LoginRequest {
@@ -46,6 +62,7 @@ export interface CreateUserRequest {
   password?: string;
   role: UserRole;
   oidc_enabled: boolean;
+  send_invite?: boolean;
 }
 
 export interface UpdateUserRequest {
@@ -98,6 +115,7 @@ export interface Server {
   id: number;
   url: string;
   weight: number;
+  is_healthy?: boolean; // Last health probe result
 }
 
 export interface Middleware {
@@ -248,47 +266,238 @@ export interface DashboardStats {
 export interface HTTPProvider {
   id: number;
   name: string;
-  url: string;
+  type: ProviderType;
+  url: string; // Endpoint, Docker socket or directory, depending on type
   priority: number;
   is_active: boolean;
   refresh_interval: number;
+  timeout: number;
+  failure_policy: ProviderFailurePolicy;
+  stale_grace_period: number; // seconds, 0 = no limit
+  namespace_mode: ProviderNamespaceMode;
+  namespace_prefix: string;
+  include_filters: string[]; // Globs on router names, or "rule:<glob>" on rules
+  exclude_filters: string[];
+  state: ProviderState | "inactive";
+  consecutive_failures: number;
+  next_fetch?: string;
+  warnings?: string[]; // Resources a docker, file or consul source skipped
   last_fetched: string | null;
   last_error: string | null;
   router_count: number;
   service_count: number;
   middleware_count: number;
+  auth_type: HTTPProviderAuthType;
+  auth_username: string;
+  has_auth_secret: boolean;
+  header_names: string[];
+  tls_ca: string;
+  tls_cert: string;
+  has_tls_key: boolean;
+  tls_insecure_skip_verify: boolean;
   created_at: string;
   updated_at: string;
 }
 
+export type HTTPProviderAuthType = "none" | "bearer" | "basic";
+
+// healthy: last fetch succeeded; degraded: failing but the last good config
+// is still served; failed: failing and left out of the config
+export type ProviderState = "pending" | "healthy" | "degraded" | "failed";
+
+export type ProviderFailurePolicy = "keep" | "drop";
+
+// Resource names get "-<provider>" appended or a prefix ("<provider>-" by default)
+export type ProviderNamespaceMode = "none" | "suffix" | "prefix";
+
+export type ProviderType = "http" | "docker" | "file" | "consul";
+
+export interface ResourceDiff {
+  added?: string[];
+  removed?: string[];
+  changed?: string[];
+}
+
+export interface ConfigDiff {
+  routers: ResourceDiff;
+  services: ResourceDiff;
+  middlewares: ResourceDiff;
+}
+
+export interface ProviderFetch {
+  id: number;
+  provider_id: number;
+  fetched_at: string;
+  duration_ms: number;
+  status_code?: number;
+  size: number;
+  success: boolean;
+  error?: string;
+  changed: boolean;
+  diff?: ConfigDiff; // Against the previous good fetch
+  router_count: number;
+  service_count: number;
+  middleware_count: number;
+}
+
+export interface ProviderFetchFilters {
+  limit?: number;
+  before?: number;
+  changed?: boolean;
+  errors?: boolean;
+}
+
 export interface CreateHTTPProviderRequest {
   name: string;
+  type?: ProviderType;
   url: string;
   priority: number;
   refresh_interval: number;
+  timeout?: number;
   is_active: boolean;
+  failure_policy?: ProviderFailurePolicy;
+  stale_grace_period?: number;
+  namespace_mode?: ProviderNamespaceMode;
+  namespace_prefix?: string;
+  include_filters?: string[];
+  exclude_filters?: string[];
+  auth_type?: HTTPProviderAuthType;
+  auth_username?: string;
+  auth_secret?: string;
+  headers?: Record<string, string>;
+  tls_ca?: string;
+  tls_cert?: string;
+  tls_key?: string;
+  tls_insecure_skip_verify?: boolean;
+}
+
+// Either id, to test a saved provider, or unsaved settings with a url
+export interface TestHTTPProviderRequest {
+  id?: number;
+  name?: string;
+  type?: ProviderType;
+  url?: string;
+  priority?: number;
+  timeout?: number;
+  namespace_mode?: ProviderNamespaceMode;
+  namespace_prefix?: string;
+  include_filters?: string[];
+  exclude_filters?: string[];
+  auth_type?: HTTPProviderAuthType;
+  auth_username?: string;
+  auth_secret?: string;
+  headers?: Record<string, string>;
+  tls_ca?: string;
+  tls_cert?: string;
+  tls_key?: string;
+  tls_insecure_skip_verify?: boolean;
+}
+
+export type ProviderTestStage = "client" | "connect" | "http" | "read" | "source" | "parse" | "rules";
+
+export interface ResourceCounts {
+  routers: number;
+  services: number;
+  middlewares: number;
+}
+
+export interface ProviderTestResult {
+  success: boolean;
+  stage?: ProviderTestStage; // Where the test failed
+  error?: string;
+  status_code?: number;
+  size: number;
+  timing: {
+    dns_ms: number;
+    connect_ms: number;
+    tls_ms: number;
+    first_byte_ms: number;
+    total_ms: number;
+  };
+  parse_error?: { message: string; line?: number; column?: number };
+  raw_counts: ResourceCounts; // Before filters and namespacing
+  counts: ResourceCounts;
+  warnings?: string[];
+  preview?: {
+    served: { routers: string[]; services: string[]; middlewares: string[] };
+    conflicts: ConflictInfo[];
+    changes: ConfigDiff; // Against the served configuration
+  };
 }
 
+// Omitted fields are unchanged; send "" to clear auth_secret or tls_key
 export interface UpdateHTTPProviderRequest {
   name?: string;
+  type?: ProviderType;
   url?: string;
   priority?: number;
   refresh_interval?: number;
+  timeout?: number;
   is_active?: boolean;
-}
+  failure_policy?: ProviderFailurePolicy;
+  stale_grace_period?: number;
+  namespace_mode?: ProviderNamespaceMode;
+  namespace_prefix?: string;
+  include_filters?: string[];
+  exclude_filters?: string[];
+  auth_type?: HTTPProviderAuthType;
+  auth_username?: string;
+  auth_secret?: string;
+  headers?: Record<string, string>;
+  tls_ca?: string;
+  tls_cert?: string;
+  tls_key?: string;
+  tls_insecure_skip_verify?: boolean;
+}
+
+export type ConflictResourceType = "router" | "service" | "middleware" | "serversTransport";
 
 export interface ConflictInfo {
-  type: "router" | "service" | "middleware";
+  type: ConflictResourceType;
   name: string;
   source: string;
   overridden_by: string;
   source_priority: number;
+  resolution: "priority" | "pin";
+}
+
+export interface ConfigConflict {
+  id: number;
+  resource_type: ConflictResourceType;
+  resource_name: string;
+  source: string;
+  overridden_by: string;
+  source_priority: number;
+  resolution: "priority" | "pin";
+  first_seen: string;
+  last_seen: string;
+  resolved_at: string | null;
+}
+
+export type ConflictStrategy = "pin" | "merge";
+
+export interface ConflictPolicy {
+  id: number;
+  resource_type: ConflictResourceType;
+  resource_name: string;
+  strategy: ConflictStrategy;
+  source: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ConflictPolicyRequest {
+  resource_type: ConflictResourceType;
+  resource_name: string;
+  strategy: ConflictStrategy;
+  source?: string;
 }
 
 export interface ProviderSourceInfo {
   name: string;
+  type?: ProviderType;
   priority: number;
-  status: "healthy" | "degraded" | "unhealthy" | "inactive";
+  status: ProviderState | "inactive";
   last_fetched?: string;
   last_error?: string;
   router_count: number;
@@ -306,4 +515,56 @@ export interface MergedTraefikConfig {
   };
   conflicts: ConflictInfo[];
   sources: ProviderSourceInfo[];
+  version: number;
+  built_at: string;
+}
+
+// Declarative config import/export
+
+export type ConfigFormat = "yaml" | "json" | "toml";
+
+export interface ImportChange {
+  kind: "service" | "middleware" | "router" | "httpProvider" | "user";
+  name: string;
+  action: "create" | "update" | "delete" | "unchanged" | "skip";
+  id?: number;
+  before?: Record<string, unknown>;
+  after?: Record<string, unknown>;
+}
+
+export interface ImportConflict {
+  kind: string;
+  name: string;
+  reason: string;
+}
+
+export interface ImportResult {
+  mode: "merge" | "replace";
+  dry_run: boolean;
+  applied: boolean;
+  changes: ImportChange[];
+  conflicts: ImportConflict[];
+  summary: Record<string, number>;
+  unsupported?: ImportConflict[];
+}
+
+export interface DockerLabelSet {
+  name: string;
+  host?: string;
+  labels: Record<string, string>;
+}
+
+// Config publishers
+
+export interface PublisherStatus {
+  name: string;
+  type: "file" | "redis" | "etcd";
+  target: string;
+  status: "pending" | "healthy" | "error";
+  version?: number;
+  hash?: string;
+  last_published_at?: string;
+  last_attempt_at?: string;
+  last_error?: string;
+  error_count: number;
 }